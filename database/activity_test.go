@@ -0,0 +1,150 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"todo-app/models"
+)
+
+// rename sets the title of a task
+func rename(title string) models.UpdateTask {
+	return models.UpdateTask{Merge: map[string]json.RawMessage{"title": json.RawMessage(fmt.Sprintf("%q", title))}}
+}
+
+// feedTitles lists the action and title after each entry of u's feed that
+// concerns idTask, newest first
+func feedTitles(t *testing.T, s TaskStore, u *models.User, idTask uint) []string {
+	t.Helper()
+	feed, err := s.GetActivity(u, models.ActivityFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []string
+	for _, a := range *feed {
+		if a.TaskID == idTask {
+			entries = append(entries, fmt.Sprintf("%s %v", a.Action, a.Changes["title"].After))
+		}
+	}
+	return entries
+}
+
+func TestTaskHistory(t *testing.T) {
+	eachStore(t, func(t *testing.T, s TaskStore) {
+		alice := addUsers(t, s, "alice", "dave")[0]
+		task, err := s.CreateTask(alice, models.Task{Title: "draft", Description: "d", Priority: "1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		update := models.UpdateTask{Merge: map[string]json.RawMessage{"title": json.RawMessage(`"final"`), "priority": json.RawMessage(`"2"`)}}
+		if _, err := s.UpdateTask(alice, update, int(task.ID)); err != nil {
+			t.Fatal(err)
+		}
+		// an update that changes nothing isn't logged
+		if _, err := s.UpdateTask(alice, rename("final"), int(task.ID)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.DeleteTask(alice, int(task.ID), nil); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.RestoreTask(alice, int(task.ID)); err != nil {
+			t.Fatal(err)
+		}
+
+		history, err := s.GetTaskHistory(alice, int(task.ID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{
+			"create map[description:{<nil> d} priority:{<nil> 1} title:{<nil> draft}]",
+			"update map[priority:{1 2} title:{draft final}]",
+			"delete map[description:{d <nil>} priority:{2 <nil>} title:{final <nil>}]",
+			"restore map[]",
+		}
+		if len(*history) != len(want) {
+			t.Fatalf("got %d history entries, want %d: %v", len(*history), len(want), *history)
+		}
+		for i, a := range *history {
+			if got := fmt.Sprintf("%s %v", a.Action, map[string]models.Change(a.Changes)); got != want[i] {
+				t.Errorf("entry %d: got %s, want %s", i, got, want[i])
+			}
+			if a.TaskID != task.ID || a.ActorID != alice.ID {
+				t.Errorf("entry %d is for task %d by user %d, want task %d by %d", i, a.TaskID, a.ActorID, task.ID, alice.ID)
+			}
+		}
+	})
+}
+
+func TestActivityFeed(t *testing.T) {
+	eachStore(t, func(t *testing.T, s TaskStore) {
+		users := addUsers(t, s, "alice", "bob", "dave")
+		alice, bob, dave := users[0], users[1], users[2]
+		task, err := s.CreateTask(alice, models.Task{Title: "draft", Description: "d", Priority: "1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		change := func(title string) {
+			t.Helper()
+			if _, err := s.UpdateTask(alice, rename(title), int(task.ID)); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		change("before bob")
+		if _, _, err := s.AddUserToTask(alice, int(bob.ID), int(task.ID), models.RoleEditor); err != nil {
+			t.Fatal(err)
+		}
+		change("shared")
+		want := "[update shared add_member <nil> update before bob create draft]"
+		if got := fmt.Sprint(feedTitles(t, s, bob, task.ID)); got != want {
+			t.Errorf("bob's feed as a member: got %s, want %s", got, want)
+		}
+
+		// once removed, bob only keeps the entries about themselves
+		if _, _, err := s.RemoveUserFromTask(alice, int(bob.ID), int(task.ID)); err != nil {
+			t.Fatal(err)
+		}
+		change("private")
+		want = "[remove_member <nil> add_member <nil>]"
+		if got := fmt.Sprint(feedTitles(t, s, bob, task.ID)); got != want {
+			t.Errorf("bob's feed after being removed: got %s, want %s", got, want)
+		}
+		if _, err := s.GetTaskHistory(bob, int(task.ID)); err == nil || err.Error() != "record not found" {
+			t.Errorf("bob reading the history: got %v, want record not found", err)
+		}
+
+		want = "[update private remove_member <nil> update shared add_member <nil> update before bob create draft]"
+		if got := fmt.Sprint(feedTitles(t, s, alice, task.ID)); got != want {
+			t.Errorf("alice's feed: got %s, want %s", got, want)
+		}
+		// pages follow on from the oldest entry of the last one
+		var paged []uint
+		for filter := (models.ActivityFilter{Limit: 3}); ; {
+			page, err := s.GetActivity(alice, filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(*page) == 0 {
+				break
+			}
+			for _, a := range *page {
+				paged = append(paged, a.ID)
+			}
+			filter.Before = (*page)[len(*page)-1].ID
+		}
+		all, err := s.GetActivity(alice, models.ActivityFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []uint
+		for _, a := range *all {
+			ids = append(ids, a.ID)
+		}
+		if len(ids) != 6 || fmt.Sprint(paged) != fmt.Sprint(ids) {
+			t.Errorf("paging through alice's feed: got entries %v, want %v", paged, ids)
+		}
+		if got := feedTitles(t, s, dave, task.ID); len(got) != 0 {
+			t.Errorf("dave's feed on a task of another organization: got %v", got)
+		}
+	})
+}
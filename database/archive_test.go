@@ -0,0 +1,128 @@
+package database
+
+import (
+	"fmt"
+	"testing"
+	"time"
+	"todo-app/models"
+)
+
+// listed returns the IDs GetTasks and SearchTasks find for "archive", with or
+// without archived tasks
+func listed(t *testing.T, s TaskStore, u *models.User, includeArchived bool) (string, string) {
+	t.Helper()
+	page, err := s.GetTasks(u, models.TaskFilter{IncludeArchived: includeArchived})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := s.SearchTasks(u, models.TaskSearch{Query: "archive", Terms: []string{"archive"}, IncludeArchived: includeArchived})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found []uint
+	for _, r := range *results {
+		found = append(found, r.Task.ID)
+	}
+	return fmt.Sprint(pageIDs(page.Tasks)), fmt.Sprint(found)
+}
+
+func TestArchiveTask(t *testing.T) {
+	eachStore(t, func(t *testing.T, s TaskStore) {
+		users := addUsers(t, s, "alice", "bob", "dave")
+		alice, bob := users[0], users[1]
+
+		parent, err := s.CreateTask(alice, models.Task{Title: "archive parent", Description: "d", Priority: "1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var children []uint
+		for _, title := range []string{"archive child", "archive own"} {
+			child, err := s.CreateSubtask(alice, int(parent.ID), models.Task{Title: title, Description: "d", Priority: "1"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			children = append(children, child.ID)
+		}
+		all := fmt.Sprint([]uint{parent.ID, children[0], children[1]})
+
+		// bob edits the parent but isn't a member of its subtasks
+		if _, _, err := s.AddUserToTask(alice, int(bob.ID), int(parent.ID), models.RoleEditor); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.ArchiveTask(bob, int(parent.ID)); err == nil {
+			t.Error("archived subtasks the caller can't edit")
+		} else if _, ok := err.(*RoleError); !ok {
+			t.Errorf("archiving over subtasks the caller can't edit: got %v, want a RoleError", err)
+		}
+		if tasks, found := listed(t, s, alice, false); tasks != all || found != all {
+			t.Errorf("after the refused archive: got tasks %s and search %s, want %s", tasks, found, all)
+		}
+
+		// a subtask archived on its own stays archived when its parent comes back
+		if _, err := s.ArchiveTask(alice, int(children[1])); err != nil {
+			t.Fatal(err)
+		}
+		archived, err := s.ArchiveTask(alice, int(parent.ID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if archived.ArchivedAt == nil {
+			t.Error("archiving returned a task without archived_at")
+		}
+		if tasks, found := listed(t, s, alice, false); tasks != "[]" || found != "[]" {
+			t.Errorf("archived tasks are listed: got tasks %s and search %s", tasks, found)
+		}
+		if tasks, found := listed(t, s, alice, true); tasks != all || found != all {
+			t.Errorf("with include_archived: got tasks %s and search %s, want %s", tasks, found, all)
+		}
+		if _, err := s.UnarchiveTask(bob, int(parent.ID)); err == nil {
+			t.Error("unarchived subtasks the caller can't edit")
+		}
+
+		unarchived, err := s.UnarchiveTask(alice, int(parent.ID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if unarchived.ArchivedAt != nil {
+			t.Error("unarchiving returned a task with archived_at")
+		}
+		want := fmt.Sprint([]uint{parent.ID, children[0]})
+		if tasks, found := listed(t, s, alice, false); tasks != want || found != want {
+			t.Errorf("after unarchiving: got tasks %s and search %s, want %s", tasks, found, want)
+		}
+	})
+}
+
+func TestArchiveCompleted(t *testing.T) {
+	eachStore(t, func(t *testing.T, s TaskStore) {
+		alice := addUsers(t, s, "alice", "dave")[0]
+
+		var ids []uint
+		for _, title := range []string{"archive done", "archive open", "archive also done"} {
+			task, err := s.CreateTask(alice, models.Task{Title: title, Description: "d", Priority: "1"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, task.ID)
+		}
+		for _, id := range []uint{ids[0], ids[2]} {
+			if _, err := s.UpdateTask(alice, complete(true), int(id)); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// tasks completed more recently than the cutoff stay
+		if n, err := s.ArchiveCompleted(time.Now().Add(-time.Hour)); err != nil || n != 0 {
+			t.Errorf("archiving tasks completed an hour ago: got %d, %v, want 0", n, err)
+		}
+		if n, err := s.ArchiveCompleted(time.Now().Add(time.Hour)); err != nil || n != 2 {
+			t.Errorf("archiving every completed task: got %d, %v, want 2", n, err)
+		}
+		if tasks, _ := listed(t, s, alice, false); tasks != fmt.Sprint([]uint{ids[1]}) {
+			t.Errorf("after archiving completed tasks: got %s, want [%d]", tasks, ids[1])
+		}
+		if n, err := s.ArchiveCompleted(time.Now().Add(time.Hour)); err != nil || n != 0 {
+			t.Errorf("archiving again: got %d, %v, want 0", n, err)
+		}
+	})
+}
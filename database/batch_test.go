@@ -0,0 +1,137 @@
+package database
+
+import (
+	"encoding/json"
+	"testing"
+	"todo-app/models"
+)
+
+// batchFixture creates the tasks the batch tests change: a task to update,
+// one to delete and a parent whose subtask waits on an open blocker, which
+// fails its completion after the parent itself was completed
+func batchFixture(t *testing.T, s TaskStore, u *models.User) (update, remove, parent, child uint) {
+	t.Helper()
+	create := func(title string) uint {
+		task, err := s.CreateTask(u, models.Task{Title: title, Description: "d", Priority: "1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return task.ID
+	}
+	update, remove, parent = create("update"), create("delete"), create("parent")
+	sub, err := s.CreateSubtask(u, int(parent), models.Task{Title: "child", Description: "d", Priority: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddBlocker(u, int(sub.ID), int(create("blocker"))); err != nil {
+		t.Fatal(err)
+	}
+	return update, remove, parent, sub.ID
+}
+
+func batchOps(update, remove, parent uint) []models.BatchOp {
+	return []models.BatchOp{
+		{Op: models.BatchCreate, Task: &models.Task{Title: "created", Description: "d", Priority: "1"}},
+		{Op: models.BatchUpdate, ID: int(update), Patch: map[string]json.RawMessage{"priority": json.RawMessage(`"3"`)}},
+		{Op: models.BatchDelete, ID: int(remove)},
+		{Op: models.BatchComplete, ID: int(parent)},
+		{Op: models.BatchUpdate, ID: int(update), Patch: map[string]json.RawMessage{"priority": json.RawMessage(`"9"`)}},
+	}
+}
+
+func countTitled(t *testing.T, s TaskStore, u *models.User, title string) int {
+	t.Helper()
+	page, err := s.GetTasks(u, models.TaskFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	for _, task := range page.Tasks {
+		if task.Title == title {
+			n++
+		}
+	}
+	return n
+}
+
+func TestRunBatchAtomic(t *testing.T) {
+	eachStore(t, func(t *testing.T, s TaskStore) {
+		alice := addUsers(t, s, "alice", "dave")[0]
+		update, remove, parent, child := batchFixture(t, s, alice)
+
+		results, err := s.RunBatch(alice, batchOps(update, remove, parent), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the batch stops at the completion
+		if len(results) != 4 {
+			t.Fatalf("got %d results, want 4", len(results))
+		}
+		for i, r := range results[:3] {
+			if r.Err != nil {
+				t.Errorf("operation %d: %v", i, r.Err)
+			}
+		}
+		if results[3].Err != ErrBlocked {
+			t.Errorf("completing over a blocked subtask: got %v, want %v", results[3].Err, ErrBlocked)
+		}
+
+		// and nothing before it is kept
+		if n := countTitled(t, s, alice, "created"); n != 0 {
+			t.Errorf("got %d created tasks, want 0", n)
+		}
+		got, err := s.GetTask(alice, int(update))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Priority != "1" || got.Version != 1 {
+			t.Errorf("updated task: got priority %s at version %d, want 1 at version 1", got.Priority, got.Version)
+		}
+		if _, err := s.GetTask(alice, int(remove)); err != nil {
+			t.Errorf("deleted task: %v", err)
+		}
+		wantCompleted(t, s, alice, false, parent, child)
+	})
+}
+
+func TestRunBatchPartial(t *testing.T) {
+	eachStore(t, func(t *testing.T, s TaskStore) {
+		alice := addUsers(t, s, "alice", "dave")[0]
+		update, remove, parent, child := batchFixture(t, s, alice)
+
+		results, err := s.RunBatch(alice, batchOps(update, remove, parent), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 5 {
+			t.Fatalf("got %d results, want 5", len(results))
+		}
+		for _, i := range []int{0, 1, 2} {
+			if results[i].Err != nil || results[i].Task == nil {
+				t.Errorf("operation %d: got %v, %v, want its task", i, results[i].Task, results[i].Err)
+			}
+		}
+		if results[3].Err != ErrBlocked {
+			t.Errorf("completing over a blocked subtask: got %v, want %v", results[3].Err, ErrBlocked)
+		}
+		if _, ok := results[4].Err.(*models.PatchError); !ok {
+			t.Errorf("invalid priority: got %v, want a PatchError", results[4].Err)
+		}
+
+		// the good operations are kept, the failed ones leave nothing behind
+		if n := countTitled(t, s, alice, "created"); n != 1 {
+			t.Errorf("got %d created tasks, want 1", n)
+		}
+		got, err := s.GetTask(alice, int(update))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Priority != "3" {
+			t.Errorf("updated task: got priority %s, want 3", got.Priority)
+		}
+		if _, err := s.GetTask(alice, int(remove)); err == nil || err.Error() != "record not found" {
+			t.Errorf("deleted task: got %v, want record not found", err)
+		}
+		wantCompleted(t, s, alice, false, parent, child)
+	})
+}
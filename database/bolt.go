@@ -0,0 +1,1082 @@
+package database
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"sort"
+	"time"
+	"todo-app/models"
+
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	usersBucket     = []byte("users")
+	tasksBucket     = []byte("tasks")
+	userTasksBucket = []byte("user_tasks")
+	taskUsersBucket = []byte("task_users")
+	// parent ID + subtask ID
+	taskChildrenBucket = []byte("task_children")
+	// task ID + blocker ID
+	taskBlockersBucket  = []byte("task_blockers")
+	labelsBucket        = []byte("labels")
+	projectsBucket      = []byte("projects")
+	organizationsBucket = []byte("organizations")
+	// organization ID + user ID and the other way around, valued by role
+	orgMembersBucket = []byte("org_members")
+	userOrgsBucket   = []byte("user_orgs")
+	// organization ID + username, valued by the ID of the member
+	orgUsernamesBucket = []byte("org_usernames")
+	// project ID + user ID and the other way around, valued by role
+	projectMembersBucket = []byte("project_members")
+	userProjectsBucket   = []byte("user_projects")
+	// project ID + task ID
+	projectTasksBucket = []byte("project_tasks")
+	// user ID + label ID
+	userLabelsBucket = []byte("user_labels")
+	// task ID + label ID, and the other way around
+	taskLabelsBucket = []byte("task_labels")
+	labelTasksBucket = []byte("label_tasks")
+	commentsBucket   = []byte("comments")
+	// task ID + comment ID
+	taskCommentsBucket = []byte("task_comments")
+	// comment ID + mentioned user ID
+	commentMentionsBucket = []byte("comment_mentions")
+	attachmentsBucket     = []byte("attachments")
+	// task ID + attachment ID
+	taskAttachmentsBucket = []byte("task_attachments")
+	checklistItemsBucket  = []byte("checklist_items")
+	// task ID + checklist item ID
+	taskChecklistBucket = []byte("task_checklist")
+	activitiesBucket    = []byte("activities")
+	// task ID + activity ID
+	taskActivityBucket = []byte("task_activity")
+	viewsBucket        = []byte("views")
+	// user ID + view ID
+	userViewsBucket = []byte("user_views")
+	// series ID + task ID
+	seriesTasksBucket = []byte("series_tasks")
+	// usernameKey + user ID
+	userNamesBucket = []byte("user_names")
+
+	// secondary indexes keyed by value+task ID
+	completedIndex = []byte("idx_completed")
+	priorityIndex  = []byte("idx_priority")
+)
+
+var boltBuckets = [][]byte{
+	usersBucket, userNamesBucket, tasksBucket, userTasksBucket, taskUsersBucket,
+	taskChildrenBucket, taskBlockersBucket, labelsBucket, userLabelsBucket,
+	taskLabelsBucket, labelTasksBucket, projectsBucket, projectMembersBucket,
+	userProjectsBucket, projectTasksBucket, organizationsBucket, orgMembersBucket,
+	userOrgsBucket, commentsBucket, taskCommentsBucket, commentMentionsBucket,
+	attachmentsBucket, taskAttachmentsBucket, checklistItemsBucket, taskChecklistBucket,
+	activitiesBucket, taskActivityBucket, viewsBucket, userViewsBucket,
+	seriesTasksBucket, orgUsernamesBucket, completedIndex, priorityIndex,
+}
+
+// BoltStore implements TaskStore on an embedded bbolt file
+type BoltStore struct {
+	DB *bolt.DB
+}
+
+var _ TaskStore = &BoltStore{}
+
+// boltUser is how users are persisted, models.User hides the password when
+// marshalled
+type boltUser struct {
+	ID        uint      `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Username  string    `json:"username"`
+	Password  string    `json:"password"`
+}
+
+func NewBolt(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open bolt database")
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range boltBuckets {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		if err := upgradeOrgs(tx); err != nil {
+			return err
+		}
+		if err := upgradeUsernames(tx); err != nil {
+			return err
+		}
+		return upgradeSeries(tx)
+	})
+	if err != nil {
+		db.Close()
+		return nil, errors.Wrap(err, "failed to create bolt buckets")
+	}
+
+	return &BoltStore{DB: db}, nil
+}
+
+func itob(id uint) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(id))
+	return b
+}
+
+func btoi(b []byte) uint {
+	return uint(binary.BigEndian.Uint64(b))
+}
+
+// pairKey builds the composite keys used by the join and index buckets
+func pairKey(prefix []byte, id uint) []byte {
+	return append(append([]byte{}, prefix...), itob(id)...)
+}
+
+// usernameKey ends username in a zero byte so that scanning for it doesn't
+// match longer names
+func usernameKey(username string) []byte {
+	return append([]byte(username), 0)
+}
+
+func orgUsernameKey(idOrg uint, username string) []byte {
+	return append(itob(idOrg), username...)
+}
+
+func completedKey(completed bool) []byte {
+	if completed {
+		return []byte{1}
+	}
+	return []byte{0}
+}
+
+// scanIDs returns the IDs suffixed to every key starting with prefix
+func scanIDs(b *bolt.Bucket, prefix []byte) []uint {
+	var ids []uint
+	c := b.Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		ids = append(ids, btoi(k[len(prefix):]))
+	}
+	return ids
+}
+
+func getUser(tx *bolt.Tx, id uint) (*models.User, error) {
+	v := tx.Bucket(usersBucket).Get(itob(id))
+	if v == nil {
+		return nil, ErrRecordNotFound
+	}
+
+	var bu boltUser
+	if err := json.Unmarshal(v, &bu); err != nil {
+		return nil, err
+	}
+
+	return &models.User{
+		ID:        bu.ID,
+		CreatedAt: bu.CreatedAt,
+		UpdatedAt: bu.UpdatedAt,
+		Username:  bu.Username,
+		Password:  bu.Password,
+	}, nil
+}
+
+// getTask returns a task that has not been deleted
+func getTask(tx *bolt.Tx, id uint) (*models.Task, error) {
+	t, err := loadTask(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if t.DeletedAt != nil {
+		return nil, ErrRecordNotFound
+	}
+
+	return t, nil
+}
+
+// loadTask is getTask including tasks in the trash
+func loadTask(tx *bolt.Tx, id uint) (*models.Task, error) {
+	v := tx.Bucket(tasksBucket).Get(itob(id))
+	if v == nil {
+		return nil, ErrRecordNotFound
+	}
+
+	var t models.Task
+	if err := json.Unmarshal(v, &t); err != nil {
+		return nil, err
+	}
+	// tasks saved before versions were added
+	if t.Version == 0 {
+		t.Version = 1
+	}
+	return &t, nil
+}
+
+// putTask saves t and moves its index entries away from the values in old,
+// t gets the version that follows the one of old
+func putTask(tx *bolt.Tx, t *models.Task, old *models.Task) error {
+	t.Version = 1
+	if old != nil {
+		t.Version = old.Version + 1
+		if err := tx.Bucket(completedIndex).Delete(pairKey(completedKey(old.Completed), old.ID)); err != nil {
+			return err
+		}
+		if err := tx.Bucket(priorityIndex).Delete(pairKey([]byte(old.Priority), old.ID)); err != nil {
+			return err
+		}
+	}
+
+	// relations are stored in their own buckets
+	stored := *t
+	stored.Users, stored.NextOccurrence, stored.Subtasks, stored.Progress = nil, nil, nil, nil
+	stored.Blocked, stored.BlockedBy, stored.Labels, stored.Attachments = false, nil, nil, nil
+	stored.Checklist = nil
+	v, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+	if err := tx.Bucket(tasksBucket).Put(itob(t.ID), v); err != nil {
+		return err
+	}
+	if t.SeriesID != nil {
+		if err := tx.Bucket(seriesTasksBucket).Put(pairKey(itob(*t.SeriesID), t.ID), nil); err != nil {
+			return err
+		}
+	}
+
+	if t.DeletedAt != nil {
+		return nil
+	}
+	if err := tx.Bucket(completedIndex).Put(pairKey(completedKey(t.Completed), t.ID), nil); err != nil {
+		return err
+	}
+	return tx.Bucket(priorityIndex).Put(pairKey([]byte(t.Priority), t.ID), nil)
+}
+
+// insertTask gives t an ID and saves it as a new task
+func insertTask(tx *bolt.Tx, t *models.Task) error {
+	seq, err := tx.Bucket(tasksBucket).NextSequence()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	t.ID, t.CreatedAt, t.UpdatedAt, t.DeletedAt = uint(seq), now, now, nil
+	if t.Priority == "" {
+		t.Priority = "1"
+	}
+	t.StartSeries()
+
+	if t.ParentID != nil {
+		if err := tx.Bucket(taskChildrenBucket).Put(pairKey(itob(*t.ParentID), t.ID), nil); err != nil {
+			return err
+		}
+	}
+	if t.ProjectID != nil {
+		if err := tx.Bucket(projectTasksBucket).Put(pairKey(itob(*t.ProjectID), t.ID), nil); err != nil {
+			return err
+		}
+	}
+	return putTask(tx, t, nil)
+}
+
+// descendantTasks walks down the subtasks of a task one level at a time
+func descendantTasks(tx *bolt.Tx, id uint) ([]models.Task, error) {
+	var all []models.Task
+	parents := []uint{id}
+	for len(parents) > 0 {
+		var next []uint
+		for _, parent := range parents {
+			for _, childID := range scanIDs(tx.Bucket(taskChildrenBucket), itob(parent)) {
+				child, err := getTask(tx, childID)
+				if err == ErrRecordNotFound {
+					continue
+				}
+				if err != nil {
+					return nil, err
+				}
+				all = append(all, *child)
+				next = append(next, child.ID)
+			}
+		}
+		parents = next
+	}
+	return all, nil
+}
+
+// copyMembers shares task to with every member of task from
+func copyMembers(tx *bolt.Tx, from, to uint) error {
+	for _, id := range scanIDs(tx.Bucket(taskUsersBucket), itob(from)) {
+		role, err := memberRole(tx, id, from)
+		if err != nil {
+			return err
+		}
+		if err := addMember(tx, id, to, role); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// spawnNext creates the occurrence that follows a completed recurring task,
+// shared with the same members. Completing a reopened task spawns nothing
+// when its next occurrence exists already, even in the trash.
+func spawnNext(tx *bolt.Tx, task *models.Task) (*models.Task, error) {
+	next := task.NextInSeries()
+	if next == nil {
+		return nil, nil
+	}
+	for _, id := range scanIDs(tx.Bucket(seriesTasksBucket), itob(*next.SeriesID)) {
+		t, err := loadTask(tx, id)
+		if err == ErrRecordNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if t.Occurrence == next.Occurrence {
+			return nil, nil
+		}
+	}
+
+	if err := insertTask(tx, next); err != nil {
+		return nil, err
+	}
+	if err := copyMembers(tx, task.ID, next.ID); err != nil {
+		return nil, err
+	}
+	return next, nil
+}
+
+// upgradeSeries indexes the series of files written before seriesTasksBucket
+// existed
+func upgradeSeries(tx *bolt.Tx) error {
+	if tx.Bucket(seriesTasksBucket).Stats().KeyN > 0 {
+		return nil
+	}
+	return tx.Bucket(tasksBucket).ForEach(func(k, v []byte) error {
+		var t models.Task
+		if err := json.Unmarshal(v, &t); err != nil {
+			return err
+		}
+		if t.SeriesID == nil {
+			return nil
+		}
+		return tx.Bucket(seriesTasksBucket).Put(pairKey(itob(*t.SeriesID), t.ID), nil)
+	})
+}
+
+// memberRole returns the role u holds on task idTask
+func memberRole(tx *bolt.Tx, idUser, idTask uint) (models.Role, error) {
+	v := tx.Bucket(userTasksBucket).Get(pairKey(itob(idUser), idTask))
+	if v == nil {
+		return "", ErrRecordNotFound
+	}
+	// memberships written before roles existed belong to owners
+	if len(v) == 0 {
+		return models.RoleOwner, nil
+	}
+	return models.Role(v), nil
+}
+
+// taskRole returns the higher of u's role on the task and on its project
+func taskRole(tx *bolt.Tx, u *models.User, t *models.Task) (models.Role, error) {
+	if t.OrgID != u.OrgID {
+		return "", ErrRecordNotFound
+	}
+	role, err := memberRole(tx, u.ID, t.ID)
+	if err != nil && err != ErrRecordNotFound {
+		return "", err
+	}
+	if t.ProjectID != nil {
+		if pr := projectRole(tx, *t.ProjectID, u.ID); !role.AtLeast(pr) {
+			role = pr
+		}
+	}
+	if role == "" {
+		return "", ErrRecordNotFound
+	}
+	return role, nil
+}
+
+// requireRoleBolt returns a RoleError unless u has role on every task, for the
+// subtasks a change to their parent goes on to
+func requireRoleBolt(tx *bolt.Tx, u *models.User, tasks []models.Task, role models.Role) error {
+	for i := range tasks {
+		got, err := taskRole(tx, u, &tasks[i])
+		if err != nil && err != ErrRecordNotFound {
+			return err
+		}
+		if !got.AtLeast(role) {
+			return &RoleError{Role: role}
+		}
+	}
+	return nil
+}
+
+// memberTask returns task idTask if u is assigned to it or to its project
+func memberTask(tx *bolt.Tx, u *models.User, idTask uint) (*models.Task, error) {
+	t, err := getTask(tx, idTask)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := taskRole(tx, u, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func taskUsers(tx *bolt.Tx, idTask uint) ([]*models.User, error) {
+	var users []*models.User
+	for _, id := range scanIDs(tx.Bucket(taskUsersBucket), itob(idTask)) {
+		user, err := getUser(tx, id)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+// demotesLastOwnerBolt reports whether taking idUser's owner role away would
+// leave the task without owners
+func demotesLastOwnerBolt(tx *bolt.Tx, idUser, idTask uint) (bool, error) {
+	role, err := memberRole(tx, idUser, idTask)
+	if err == ErrRecordNotFound {
+		return false, nil
+	}
+	if err != nil || role != models.RoleOwner {
+		return false, err
+	}
+
+	owners := 0
+	for _, id := range scanIDs(tx.Bucket(taskUsersBucket), itob(idTask)) {
+		if role, _ := memberRole(tx, id, idTask); role == models.RoleOwner {
+			owners++
+		}
+	}
+	return owners <= 1, nil
+}
+
+func addMember(tx *bolt.Tx, idUser, idTask uint, role models.Role) error {
+	if err := tx.Bucket(userTasksBucket).Put(pairKey(itob(idUser), idTask), []byte(role)); err != nil {
+		return err
+	}
+	return tx.Bucket(taskUsersBucket).Put(pairKey(itob(idTask), idUser), []byte(role))
+}
+
+func removeMember(tx *bolt.Tx, idUser, idTask uint) error {
+	if err := tx.Bucket(userTasksBucket).Delete(pairKey(itob(idUser), idTask)); err != nil {
+		return err
+	}
+	return tx.Bucket(taskUsersBucket).Delete(pairKey(itob(idTask), idUser))
+}
+
+func (s *BoltStore) AddUser(u models.User) (*models.User, error) {
+	err := s.DB.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(usersBucket)
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		u.ID, u.CreatedAt, u.UpdatedAt = uint(seq), now, now
+
+		v, err := json.Marshal(boltUser{u.ID, u.CreatedAt, u.UpdatedAt, u.Username, u.Password})
+		if err != nil {
+			return err
+		}
+		if err := b.Put(itob(u.ID), v); err != nil {
+			return err
+		}
+		if err := tx.Bucket(userNamesBucket).Put(pairKey(usernameKey(u.Username), u.ID), []byte{}); err != nil {
+			return err
+		}
+
+		// every user starts out with their own organization
+		org, err := insertOrg(tx, models.Organization{Name: u.Username})
+		if err != nil {
+			return err
+		}
+		u.OrgID = org.ID
+		return putOrgMember(tx, org.ID, u.ID, models.RoleOwner)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &u, nil
+}
+
+func (s *BoltStore) GetUserByUsername(idOrg uint, username string) (*models.User, error) {
+	var user *models.User
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		var id uint
+		if idOrg != 0 {
+			v := tx.Bucket(orgUsernamesBucket).Get(orgUsernameKey(idOrg, username))
+			if v == nil {
+				return ErrRecordNotFound
+			}
+			id = btoi(v)
+		} else {
+			ids := scanIDs(tx.Bucket(userNamesBucket), usernameKey(username))
+			if len(ids) == 0 {
+				return ErrRecordNotFound
+			}
+			if len(ids) > 1 {
+				return ErrAmbiguousUsername
+			}
+			id = ids[0]
+		}
+
+		var err error
+		user, err = getUser(tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *BoltStore) GetUserById(id uint) (*models.User, error) {
+	var user *models.User
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		var err error
+		user, err = getUser(tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *BoltStore) CreateTask(u *models.User, t models.Task) (*models.Task, error) {
+	err := s.DB.Update(func(tx *bolt.Tx) error {
+		return createTaskBolt(tx, u, &t)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// createTaskBolt makes u the owner of a new task
+func createTaskBolt(tx *bolt.Tx, u *models.User, t *models.Task) error {
+	t.OrgID = u.OrgID
+	if err := insertTask(tx, t); err != nil {
+		return err
+	}
+	if err := addMember(tx, u.ID, t.ID, models.RoleOwner); err != nil {
+		return err
+	}
+	return logActivityBolt(tx, models.TaskActivity(u, nil, t))
+}
+
+func (s *BoltStore) GetTasks(u *models.User, filter models.TaskFilter) (*models.TaskPage, error) {
+	var page *models.TaskPage
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		// start from the user's tasks and narrow down with each index
+		ids := visibleIDs(tx, u)
+
+		if filter.ProjectID != nil {
+			ids = intersect(ids, scanIDs(tx.Bucket(projectTasksBucket), itob(*filter.ProjectID)))
+		}
+
+		if filter.Completed != nil {
+			ids = intersect(ids, scanIDs(tx.Bucket(completedIndex), completedKey(*filter.Completed)))
+		}
+		if filter.Priority != "" {
+			ids = intersect(ids, scanIDs(tx.Bucket(priorityIndex), []byte(filter.Priority)))
+		}
+		if len(filter.Labels) > 0 {
+			labelled, err := labelledTasks(tx, u, filter)
+			if err != nil {
+				return err
+			}
+			ids = intersect(ids, labelled)
+		}
+
+		// only the tasks left are read, in ID order
+		now := time.Now().UTC()
+		tasks := []models.Task{}
+		for _, id := range sortedIDs(ids) {
+			t, err := getTask(tx, id)
+			if err == ErrRecordNotFound {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if t.OrgID != u.OrgID || !matchesSchedule(t, filter, now) {
+				continue
+			}
+			if t.ArchivedAt != nil && !filter.IncludeArchived {
+				continue
+			}
+			if filter.Expr != nil && !models.MatchFilter(filter.Expr, t) {
+				continue
+			}
+			tasks = append(tasks, *t)
+		}
+
+		page = models.NewTaskPage(pageTasks(tasks, filter), filter)
+		for i := range page.Tasks {
+			if err := markBlockedBolt(tx, &page.Tasks[i]); err != nil {
+				return err
+			}
+			if err := markLabelsBolt(tx, u, &page.Tasks[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// visibleIDs returns the tasks u is a member of, directly or through their
+// project. The caller still has to check the organization.
+func visibleIDs(tx *bolt.Tx, u *models.User) map[uint]bool {
+	ids := make(map[uint]bool)
+	for _, id := range scanIDs(tx.Bucket(userTasksBucket), itob(u.ID)) {
+		ids[id] = true
+	}
+	for _, idProject := range scanIDs(tx.Bucket(userProjectsBucket), itob(u.ID)) {
+		for _, id := range scanIDs(tx.Bucket(projectTasksBucket), itob(idProject)) {
+			ids[id] = true
+		}
+	}
+	return ids
+}
+
+// matchesSchedule applies the date filters that have no index
+func matchesSchedule(t *models.Task, filter models.TaskFilter, now time.Time) bool {
+	if filter.DueBefore != nil && (t.DueAt == nil || !t.DueAt.Before(*filter.DueBefore)) {
+		return false
+	}
+	if filter.DueAfter != nil && (t.DueAt == nil || !t.DueAt.After(*filter.DueAfter)) {
+		return false
+	}
+	if filter.Overdue != nil && t.Overdue(now) != *filter.Overdue {
+		return false
+	}
+	return true
+}
+
+// pageTasks sorts tasks in the direction of filter.Cursor and keeps the
+// ones past it, with one more than the limit like the sql store reads
+func pageTasks(tasks []models.Task, filter models.TaskFilter) []models.Task {
+	before := filter.Cursor != nil && filter.Cursor.Before
+	sort.Slice(tasks, func(i, j int) bool {
+		c := models.CompareTasks(&tasks[i], &tasks[j], filter.Sort)
+		if before {
+			return c > 0
+		}
+		return c < 0
+	})
+
+	if filter.Cursor != nil {
+		at := filter.Cursor.Task()
+		past := tasks[:0]
+		for i := range tasks {
+			c := models.CompareTasks(&tasks[i], at, filter.Sort)
+			if (before && c < 0) || (!before && c > 0) {
+				past = append(past, tasks[i])
+			}
+		}
+		tasks = past
+	}
+
+	if filter.Limit > 0 && len(tasks) > filter.Limit+1 {
+		tasks = tasks[:filter.Limit+1]
+	}
+	return tasks
+}
+
+func sortedIDs(set map[uint]bool) []uint {
+	ids := make([]uint, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func intersect(set map[uint]bool, ids []uint) map[uint]bool {
+	out := make(map[uint]bool)
+	for _, id := range ids {
+		if set[id] {
+			out[id] = true
+		}
+	}
+	return out
+}
+
+func (s *BoltStore) GetTask(u *models.User, id int) (*models.Task, error) {
+	var task *models.Task
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		var err error
+		task, err = memberTask(tx, u, uint(id))
+		if err != nil {
+			return err
+		}
+		if task.Users, err = taskUsers(tx, task.ID); err != nil {
+			return err
+		}
+
+		descendants, err := descendantTasks(tx, task.ID)
+		if err != nil {
+			return err
+		}
+		task.Progress = models.NewProgress(descendants)
+
+		if err := markBlockedBolt(tx, task); err != nil {
+			return err
+		}
+		if err := markLabelsBolt(tx, u, task); err != nil {
+			return err
+		}
+		if task.Attachments, err = taskAttachments(tx, task.ID); err != nil {
+			return err
+		}
+		task.Checklist, err = checklistBolt(tx, task.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+func (s *BoltStore) GetTaskRole(u *models.User, idTask int) (models.Role, error) {
+	var role models.Role
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		t, err := getTask(tx, uint(idTask))
+		if err != nil {
+			return err
+		}
+		role, err = taskRole(tx, u, t)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return role, nil
+}
+
+func (s *BoltStore) AddUserToTask(u *models.User, idUser, idTask int, role models.Role) (*models.User, *models.Task, error) {
+	var user *models.User
+	var task *models.Task
+	err := s.DB.Update(func(tx *bolt.Tx) error {
+		var err error
+		if user, err = getUser(tx, uint(idUser)); err != nil {
+			return err
+		}
+		if task, err = getTask(tx, uint(idTask)); err != nil {
+			return err
+		}
+		// tasks are only shared within their organization
+		if orgRole(tx, task.OrgID, user.ID) == "" {
+			return ErrRecordNotFound
+		}
+		before, err := memberRole(tx, user.ID, task.ID)
+		if err != nil && err != ErrRecordNotFound {
+			return err
+		}
+		if before == role {
+			return nil
+		}
+		if role != models.RoleOwner {
+			last, err := demotesLastOwnerBolt(tx, user.ID, task.ID)
+			if err != nil {
+				return err
+			}
+			if last {
+				return ErrLastOwner
+			}
+		}
+		if err := addMember(tx, user.ID, task.ID, role); err != nil {
+			return err
+		}
+		return logActivityBolt(tx, models.MemberActivity(u, task, user.ID, before, role))
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return user, task, nil
+}
+
+func (s *BoltStore) RemoveUserFromTask(u *models.User, idUser, idTask int) (*models.User, *models.Task, error) {
+	var user *models.User
+	var task *models.Task
+	err := s.DB.Update(func(tx *bolt.Tx) error {
+		var err error
+		if user, err = getUser(tx, uint(idUser)); err != nil {
+			return err
+		}
+		if task, err = getTask(tx, uint(idTask)); err != nil {
+			return err
+		}
+		before, err := memberRole(tx, user.ID, task.ID)
+		if err != nil {
+			return err
+		}
+		last, err := demotesLastOwnerBolt(tx, user.ID, task.ID)
+		if err != nil {
+			return err
+		}
+		if last {
+			return ErrLastOwner
+		}
+		if err := removeMember(tx, user.ID, task.ID); err != nil {
+			return err
+		}
+		return logActivityBolt(tx, models.MemberActivity(u, task, user.ID, before, ""))
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return user, task, nil
+}
+
+func (s *BoltStore) UpdateTask(u *models.User, t models.UpdateTask, idTask int) (*models.Task, error) {
+	var task *models.Task
+	err := s.DB.Update(func(tx *bolt.Tx) error {
+		var err error
+		task, err = updateTaskBolt(tx, u, t, idTask)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+func updateTaskBolt(tx *bolt.Tx, u *models.User, t models.UpdateTask, idTask int) (*models.Task, error) {
+	old, err := memberTask(tx, u, uint(idTask))
+	if err != nil {
+		return nil, err
+	}
+	if !versionMatches(t.IfMatch, old.Version) {
+		return nil, ErrVersionMismatch
+	}
+
+	updated := *old
+	if err := t.Apply(&updated); err != nil {
+		return nil, err
+	}
+	if updated.Completed && !old.Completed && !t.Force {
+		if err := markBlockedBolt(tx, &updated); err != nil {
+			return nil, err
+		}
+		if updated.Blocked {
+			return nil, ErrBlocked
+		}
+	}
+	if updated.Completed != old.Completed {
+		updated.CompletedAt = nil
+		if updated.Completed {
+			now := time.Now()
+			updated.CompletedAt = &now
+		}
+	}
+	updated.StartSeries()
+	updated.UpdatedAt = time.Now()
+
+	task := &updated
+	if err := putTask(tx, task, old); err != nil {
+		return nil, err
+	}
+	if err := logActivityBolt(tx, models.TaskActivity(u, old, task)); err != nil {
+		return nil, err
+	}
+
+	if task.Completed && !old.Completed {
+		if task.NextOccurrence, err = spawnNext(tx, task); err != nil {
+			return nil, err
+		}
+		if task.NextOccurrence != nil {
+			if err := logActivityBolt(tx, models.TaskActivity(u, nil, task.NextOccurrence)); err != nil {
+				return nil, err
+			}
+		}
+
+		// completing a task completes everything below it
+		if err := completeDescendantsBolt(tx, u, task, t.Force); err != nil {
+			return nil, err
+		}
+	}
+	return task, nil
+}
+
+// completeDescendantsBolt completes the open tasks below task as completing
+// each one directly would. u has to be an editor of every one, none may wait
+// on a blocker outside the subtree unless force, and recurring ones spawn
+// their next occurrence.
+func completeDescendantsBolt(tx *bolt.Tx, u *models.User, task *models.Task, force bool) error {
+	children, err := descendantTasks(tx, task.ID)
+	if err != nil {
+		return err
+	}
+	subtree := map[uint]bool{task.ID: true}
+	var open []models.Task
+	for _, child := range children {
+		subtree[child.ID] = true
+		if !child.Completed {
+			open = append(open, child)
+		}
+	}
+
+	if err := requireRoleBolt(tx, u, open, models.RoleEditor); err != nil {
+		return err
+	}
+	for i := range open {
+		if force {
+			break
+		}
+		if err := markBlockedBolt(tx, &open[i]); err != nil {
+			return err
+		}
+		for _, id := range open[i].BlockedBy {
+			if !subtree[id] {
+				return ErrBlocked
+			}
+		}
+	}
+
+	for _, child := range open {
+		completed := child
+		completed.Completed, completed.CompletedAt = true, task.CompletedAt
+		completed.UpdatedAt = task.UpdatedAt
+		if err := putTask(tx, &completed, &child); err != nil {
+			return err
+		}
+		if err := logActivityBolt(tx, models.TaskActivity(u, &child, &completed)); err != nil {
+			return err
+		}
+
+		next, err := spawnNext(tx, &completed)
+		if err != nil {
+			return err
+		}
+		if next != nil {
+			if err := logActivityBolt(tx, models.TaskActivity(u, nil, next)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *BoltStore) CreateSubtask(u *models.User, idParent int, t models.Task) (*models.Task, error) {
+	err := s.DB.Update(func(tx *bolt.Tx) error {
+		parent, err := memberTask(tx, u, uint(idParent))
+		if err != nil {
+			return err
+		}
+
+		t.ParentID, t.ProjectID, t.OrgID = &parent.ID, parent.ProjectID, parent.OrgID
+		if err := insertTask(tx, &t); err != nil {
+			return err
+		}
+		if err := copyMembers(tx, parent.ID, t.ID); err != nil {
+			return err
+		}
+		return logActivityBolt(tx, models.TaskActivity(u, nil, &t))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+func (s *BoltStore) GetTaskTree(u *models.User, id int) (*models.Task, error) {
+	var task *models.Task
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		var err error
+		if task, err = memberTask(tx, u, uint(id)); err != nil {
+			return err
+		}
+
+		descendants, err := descendantTasks(tx, task.ID)
+		if err != nil {
+			return err
+		}
+		task.Progress = models.NewProgress(descendants)
+		models.BuildTree(task, descendants)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+func (s *BoltStore) DeleteTask(u *models.User, idTask int, ifMatch []uint) (*models.Task, error) {
+	var task *models.Task
+	err := s.DB.Update(func(tx *bolt.Tx) error {
+		var err error
+		task, err = deleteTaskBolt(tx, u, idTask, ifMatch)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+func deleteTaskBolt(tx *bolt.Tx, u *models.User, idTask int, ifMatch []uint) (*models.Task, error) {
+	old, err := memberTask(tx, u, uint(idTask))
+	if err != nil {
+		return nil, err
+	}
+	if !versionMatches(ifMatch, old.Version) {
+		return nil, ErrVersionMismatch
+	}
+
+	// soft delete like gorm.Model does, subtasks go along with their parent
+	// if u owns them too
+	children, err := descendantTasks(tx, old.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireRoleBolt(tx, u, children, models.RoleOwner); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, child := range children {
+		deleted := child
+		deleted.DeletedAt = &now
+		if err := putTask(tx, &deleted, &child); err != nil {
+			return nil, err
+		}
+		if err := logActivityBolt(tx, models.TaskActivity(u, &child, nil)); err != nil {
+			return nil, err
+		}
+	}
+
+	deleted := *old
+	deleted.DeletedAt = &now
+	if err := putTask(tx, &deleted, old); err != nil {
+		return nil, err
+	}
+	return &deleted, logActivityBolt(tx, models.TaskActivity(u, old, nil))
+}
@@ -0,0 +1,134 @@
+package database
+
+import (
+	"encoding/json"
+	"time"
+	"todo-app/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// logActivityBolt appends a to the activity log, updates that changed
+// nothing are left out
+func logActivityBolt(tx *bolt.Tx, a models.Activity) error {
+	if a.Action == models.ActivityUpdate && len(a.Changes) == 0 {
+		return nil
+	}
+
+	seq, err := tx.Bucket(activitiesBucket).NextSequence()
+	if err != nil {
+		return err
+	}
+	a.ID, a.CreatedAt = uint(seq), time.Now()
+	v, err := json.Marshal(boltActivity{a, a.OrgID})
+	if err != nil {
+		return err
+	}
+	if err := tx.Bucket(activitiesBucket).Put(itob(a.ID), v); err != nil {
+		return err
+	}
+	return tx.Bucket(taskActivityBucket).Put(pairKey(itob(a.TaskID), a.ID), nil)
+}
+
+// boltActivity is how activities are persisted, models.Activity hides its
+// organization when marshalled
+type boltActivity struct {
+	models.Activity
+	OrgID uint `json:"org_id"`
+}
+
+func decodeActivity(tx *bolt.Tx, v []byte) (*models.Activity, error) {
+	var ba boltActivity
+	if err := json.Unmarshal(v, &ba); err != nil {
+		return nil, err
+	}
+	a := ba.Activity
+	a.OrgID = ba.OrgID
+	if actor, err := getUser(tx, a.ActorID); err == nil {
+		a.Actor = actor
+	}
+	return &a, nil
+}
+
+func (s *BoltStore) GetTaskHistory(u *models.User, idTask int) (*[]models.Activity, error) {
+	activities := []models.Activity{}
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		task, err := memberTask(tx, u, uint(idTask))
+		if err != nil {
+			return err
+		}
+
+		for _, id := range scanIDs(tx.Bucket(taskActivityBucket), itob(task.ID)) {
+			a, err := decodeActivity(tx, tx.Bucket(activitiesBucket).Get(itob(id)))
+			if err != nil {
+				return err
+			}
+			activities = append(activities, *a)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &activities, nil
+}
+
+func (s *BoltStore) GetActivity(u *models.User, filter models.ActivityFilter) (*[]models.Activity, error) {
+	activities := []models.Activity{}
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(activitiesBucket).Cursor()
+		k, v := c.Last()
+		if filter.Before != 0 {
+			// step back from the first entry at or after Before
+			if next, _ := c.Seek(itob(filter.Before)); next == nil {
+				k, v = c.Last()
+			} else {
+				k, v = c.Prev()
+			}
+		}
+		for ; k != nil && (filter.Limit <= 0 || len(activities) < filter.Limit); k, v = c.Prev() {
+			a, err := decodeActivity(tx, v)
+			if err != nil {
+				return err
+			}
+			seen, err := activityVisible(tx, u, a)
+			if err != nil {
+				return err
+			}
+			if seen {
+				activities = append(activities, *a)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &activities, nil
+}
+
+// activityVisible reports whether a belongs in u's feed, deleted tasks stay
+// in the feed of their members
+func activityVisible(tx *bolt.Tx, u *models.User, a *models.Activity) (bool, error) {
+	if a.OrgID != u.OrgID {
+		return false, nil
+	}
+	if a.ActorID == u.ID || (a.MemberID != nil && *a.MemberID == u.ID) {
+		return true, nil
+	}
+
+	t, err := loadTask(tx, a.TaskID)
+	if err == ErrRecordNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	_, err = taskRole(tx, u, t)
+	if err == ErrRecordNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,141 @@
+package database
+
+import (
+	"time"
+	"todo-app/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func (s *BoltStore) ArchiveTask(u *models.User, idTask int) (*models.Task, error) {
+	err := s.DB.Update(func(tx *bolt.Tx) error {
+		task, err := memberTask(tx, u, uint(idTask))
+		if err != nil {
+			return err
+		}
+		children, err := descendantTasks(tx, task.ID)
+		if err != nil {
+			return err
+		}
+
+		// u has to edit every subtask the archive reaches, like the task itself
+		var changed []models.Task
+		for _, old := range append(children, *task) {
+			if old.ArchivedAt == nil {
+				changed = append(changed, old)
+			}
+		}
+		if err := requireRoleBolt(tx, u, changed, models.RoleEditor); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, old := range changed {
+			old := old
+			archived := old
+			archived.ArchivedAt, archived.UpdatedAt = &now, now
+			if err := putTask(tx, &archived, &old); err != nil {
+				return err
+			}
+			if err := logActivityBolt(tx, models.TaskActivity(u, &old, &archived)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetTask(u, idTask)
+}
+
+func (s *BoltStore) UnarchiveTask(u *models.User, idTask int) (*models.Task, error) {
+	err := s.DB.Update(func(tx *bolt.Tx) error {
+		task, err := memberTask(tx, u, uint(idTask))
+		if err != nil {
+			return err
+		}
+		if task.ArchivedAt == nil {
+			return nil
+		}
+		children, err := descendantTasks(tx, task.ID)
+		if err != nil {
+			return err
+		}
+
+		var changed []models.Task
+		for _, old := range append(children, *task) {
+			// subtasks archived on their own stay archived
+			if old.ArchivedAt != nil && old.ArchivedAt.Equal(*task.ArchivedAt) {
+				changed = append(changed, old)
+			}
+		}
+		if err := requireRoleBolt(tx, u, changed, models.RoleEditor); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, old := range changed {
+			old := old
+			unarchived := old
+			unarchived.ArchivedAt, unarchived.UpdatedAt = nil, now
+			if err := putTask(tx, &unarchived, &old); err != nil {
+				return err
+			}
+			if err := logActivityBolt(tx, models.TaskActivity(u, &old, &unarchived)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetTask(u, idTask)
+}
+
+func (s *BoltStore) ArchiveCompleted(cutoff time.Time) (int, error) {
+	var count int
+	err := s.DB.Update(func(tx *bolt.Tx) error {
+		var tasks []models.Task
+		for _, id := range scanIDs(tx.Bucket(completedIndex), completedKey(true)) {
+			t, err := loadTask(tx, id)
+			if err == ErrRecordNotFound {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			// tasks completed before completed_at existed go by their last update
+			completedAt := t.UpdatedAt
+			if t.CompletedAt != nil {
+				completedAt = *t.CompletedAt
+			}
+			if t.ArchivedAt == nil && completedAt.Before(cutoff) {
+				tasks = append(tasks, *t)
+			}
+		}
+
+		now := time.Now()
+		for _, old := range tasks {
+			old := old
+			archived := old
+			archived.ArchivedAt, archived.UpdatedAt = &now, now
+			if err := putTask(tx, &archived, &old); err != nil {
+				return err
+			}
+			if err := logActivityBolt(tx, models.TaskActivity(nil, &old, &archived)); err != nil {
+				return err
+			}
+		}
+		count = len(tasks)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
@@ -0,0 +1,134 @@
+package database
+
+import (
+	"encoding/json"
+	"time"
+	"todo-app/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltAttachment is how attachments are persisted, models.Attachment hides
+// its blob key when marshalled
+type boltAttachment struct {
+	models.Attachment
+	Key string `json:"key"`
+}
+
+func getAttachment(tx *bolt.Tx, idTask, id uint) (*models.Attachment, error) {
+	if tx.Bucket(taskAttachmentsBucket).Get(pairKey(itob(idTask), id)) == nil {
+		return nil, ErrRecordNotFound
+	}
+	v := tx.Bucket(attachmentsBucket).Get(itob(id))
+	if v == nil {
+		return nil, ErrRecordNotFound
+	}
+
+	var ba boltAttachment
+	if err := json.Unmarshal(v, &ba); err != nil {
+		return nil, err
+	}
+	a := ba.Attachment
+	a.Key = ba.Key
+	return &a, nil
+}
+
+// taskAttachments returns the attachments of a task in upload order
+func taskAttachments(tx *bolt.Tx, idTask uint) ([]models.Attachment, error) {
+	attachments := []models.Attachment{}
+	for _, id := range scanIDs(tx.Bucket(taskAttachmentsBucket), itob(idTask)) {
+		a, err := getAttachment(tx, idTask, id)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, *a)
+	}
+	return attachments, nil
+}
+
+func (s *BoltStore) CreateAttachment(u *models.User, idTask int, a models.Attachment) (*models.Attachment, error) {
+	err := s.DB.Update(func(tx *bolt.Tx) error {
+		task, err := memberTask(tx, u, uint(idTask))
+		if err != nil {
+			return err
+		}
+
+		seq, err := tx.Bucket(attachmentsBucket).NextSequence()
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		a.ID, a.TaskID, a.UserID, a.CreatedAt, a.UpdatedAt = uint(seq), task.ID, u.ID, now, now
+
+		v, err := json.Marshal(boltAttachment{a, a.Key})
+		if err != nil {
+			return err
+		}
+		if err := tx.Bucket(attachmentsBucket).Put(itob(a.ID), v); err != nil {
+			return err
+		}
+		return tx.Bucket(taskAttachmentsBucket).Put(pairKey(itob(task.ID), a.ID), nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &a, nil
+}
+
+func (s *BoltStore) GetAttachments(u *models.User, idTask int) (*[]models.Attachment, error) {
+	var attachments []models.Attachment
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		task, err := memberTask(tx, u, uint(idTask))
+		if err != nil {
+			return err
+		}
+		attachments, err = taskAttachments(tx, task.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &attachments, nil
+}
+
+func (s *BoltStore) GetAttachment(u *models.User, idTask, id int) (*models.Attachment, error) {
+	var attachment *models.Attachment
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		task, err := memberTask(tx, u, uint(idTask))
+		if err != nil {
+			return err
+		}
+		attachment, err = getAttachment(tx, task.ID, uint(id))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return attachment, nil
+}
+
+func (s *BoltStore) DeleteAttachment(u *models.User, idTask, id int) (*models.Attachment, error) {
+	var attachment *models.Attachment
+	err := s.DB.Update(func(tx *bolt.Tx) error {
+		task, err := memberTask(tx, u, uint(idTask))
+		if err != nil {
+			return err
+		}
+		if attachment, err = getAttachment(tx, task.ID, uint(id)); err != nil {
+			return err
+		}
+
+		if err := tx.Bucket(taskAttachmentsBucket).Delete(pairKey(itob(task.ID), attachment.ID)); err != nil {
+			return err
+		}
+		return tx.Bucket(attachmentsBucket).Delete(itob(attachment.ID))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return attachment, nil
+}
@@ -0,0 +1,81 @@
+package database
+
+import (
+	"todo-app/models"
+
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+// RunBatch runs the batch in one transaction. Bolt can't take back part of a
+// transaction, so when an operation of a partial batch fails the transaction
+// is rolled back and the batch run again without it, until the operations
+// left all succeed.
+func (s *BoltStore) RunBatch(u *models.User, ops []models.BatchOp, atomic bool) ([]models.BatchResult, error) {
+	failed := make(map[int]error)
+	for {
+		var results []models.BatchResult
+		err := s.DB.Update(func(tx *bolt.Tx) error {
+			results = nil
+			for i, op := range ops {
+				if err, ok := failed[i]; ok {
+					results = append(results, models.BatchResult{Err: err})
+					continue
+				}
+
+				task, err := runBatchOpBolt(tx, u, op)
+				results = append(results, models.BatchResult{Task: task, Err: err})
+				if err != nil && atomic {
+					return errBatchFailed
+				}
+				if err != nil {
+					failed[i] = err
+					return errBatchRetry
+				}
+			}
+			return nil
+		})
+		if err == errBatchRetry {
+			continue
+		}
+		if err != nil && err != errBatchFailed {
+			return nil, err
+		}
+
+		return results, nil
+	}
+}
+
+var (
+	// errBatchFailed rolls back an atomic batch
+	errBatchFailed = errors.New("batch failed")
+	// errBatchRetry rolls back a partial batch to run it again
+	errBatchRetry = errors.New("batch retried")
+)
+
+func runBatchOpBolt(tx *bolt.Tx, u *models.User, op models.BatchOp) (*models.Task, error) {
+	if op.Op == models.BatchCreate {
+		t := *op.Task
+		if err := createTaskBolt(tx, u, &t); err != nil {
+			return nil, err
+		}
+		return &t, nil
+	}
+
+	task, err := getTask(tx, uint(op.ID))
+	if err != nil {
+		return nil, err
+	}
+	role, err := taskRole(tx, u, task)
+	if err != nil {
+		return nil, err
+	}
+	if min := op.Role(); !role.AtLeast(min) {
+		return nil, &RoleError{Role: min}
+	}
+
+	if op.Op == models.BatchDelete {
+		return deleteTaskBolt(tx, u, op.ID, op.Update().IfMatch)
+	}
+	return updateTaskBolt(tx, u, op.Update(), op.ID)
+}
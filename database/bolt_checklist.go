@@ -0,0 +1,180 @@
+package database
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+	"todo-app/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func getChecklistItem(tx *bolt.Tx, idTask, id uint) (*models.ChecklistItem, error) {
+	if tx.Bucket(taskChecklistBucket).Get(pairKey(itob(idTask), id)) == nil {
+		return nil, ErrRecordNotFound
+	}
+	v := tx.Bucket(checklistItemsBucket).Get(itob(id))
+	if v == nil {
+		return nil, ErrRecordNotFound
+	}
+
+	var item models.ChecklistItem
+	if err := json.Unmarshal(v, &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func putChecklistItem(tx *bolt.Tx, item *models.ChecklistItem) error {
+	v, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(checklistItemsBucket).Put(itob(item.ID), v)
+}
+
+// checklistBolt returns the items of a task ordered by position
+func checklistBolt(tx *bolt.Tx, idTask uint) ([]models.ChecklistItem, error) {
+	items := []models.ChecklistItem{}
+	for _, id := range scanIDs(tx.Bucket(taskChecklistBucket), itob(idTask)) {
+		item, err := getChecklistItem(tx, idTask, id)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *item)
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Position < items[j].Position })
+	return items, nil
+}
+
+// renumber stores items with positions following their order
+func renumber(tx *bolt.Tx, items []models.ChecklistItem) error {
+	for i := range items {
+		if items[i].Position == i {
+			continue
+		}
+		items[i].Position, items[i].UpdatedAt = i, time.Now()
+		if err := putChecklistItem(tx, &items[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *BoltStore) AddChecklistItem(u *models.User, idTask int, item models.AddChecklistItem) (*models.ChecklistItem, error) {
+	var created models.ChecklistItem
+	err := s.DB.Update(func(tx *bolt.Tx) error {
+		task, err := memberTask(tx, u, uint(idTask))
+		if err != nil {
+			return err
+		}
+		items, err := checklistBolt(tx, task.ID)
+		if err != nil {
+			return err
+		}
+
+		seq, err := tx.Bucket(checklistItemsBucket).NextSequence()
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		created = models.ChecklistItem{ID: uint(seq), CreatedAt: now, UpdatedAt: now, TaskID: task.ID, Text: item.Text}
+
+		position := len(items)
+		if item.Position != nil && *item.Position < len(items) {
+			position = *item.Position
+		}
+		items = append(items[:position], append([]models.ChecklistItem{created}, items[position:]...)...)
+		if err := renumber(tx, items); err != nil {
+			return err
+		}
+		created.Position = position
+		if err := putChecklistItem(tx, &created); err != nil {
+			return err
+		}
+		return tx.Bucket(taskChecklistBucket).Put(pairKey(itob(task.ID), created.ID), nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+func (s *BoltStore) ToggleChecklistItem(u *models.User, idTask, id int) (*models.ChecklistItem, error) {
+	var item *models.ChecklistItem
+	err := s.DB.Update(func(tx *bolt.Tx) error {
+		task, err := memberTask(tx, u, uint(idTask))
+		if err != nil {
+			return err
+		}
+		if item, err = getChecklistItem(tx, task.ID, uint(id)); err != nil {
+			return err
+		}
+
+		item.Done, item.UpdatedAt = !item.Done, time.Now()
+		return putChecklistItem(tx, item)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+func (s *BoltStore) ReorderChecklist(u *models.User, idTask int, order []uint) ([]models.ChecklistItem, error) {
+	var items []models.ChecklistItem
+	err := s.DB.Update(func(tx *bolt.Tx) error {
+		task, err := memberTask(tx, u, uint(idTask))
+		if err != nil {
+			return err
+		}
+		current, err := checklistBolt(tx, task.ID)
+		if err != nil {
+			return err
+		}
+		if !sameItems(current, order) {
+			return ErrChecklistOrder
+		}
+
+		byID := make(map[uint]models.ChecklistItem)
+		for _, item := range current {
+			byID[item.ID] = item
+		}
+		for _, id := range order {
+			items = append(items, byID[id])
+		}
+		return renumber(tx, items)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func (s *BoltStore) DeleteChecklistItem(u *models.User, idTask, id int) error {
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		task, err := memberTask(tx, u, uint(idTask))
+		if err != nil {
+			return err
+		}
+		item, err := getChecklistItem(tx, task.ID, uint(id))
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Bucket(taskChecklistBucket).Delete(pairKey(itob(task.ID), item.ID)); err != nil {
+			return err
+		}
+		if err := tx.Bucket(checklistItemsBucket).Delete(itob(item.ID)); err != nil {
+			return err
+		}
+		// close the gap
+		items, err := checklistBolt(tx, task.ID)
+		if err != nil {
+			return err
+		}
+		return renumber(tx, items)
+	})
+}
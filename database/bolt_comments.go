@@ -0,0 +1,208 @@
+package database
+
+import (
+	"encoding/json"
+	"time"
+	"todo-app/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// getComment returns a comment on task idTask with its author and mentions
+func getComment(tx *bolt.Tx, idTask, id uint) (*models.Comment, error) {
+	if tx.Bucket(taskCommentsBucket).Get(pairKey(itob(idTask), id)) == nil {
+		return nil, ErrRecordNotFound
+	}
+	v := tx.Bucket(commentsBucket).Get(itob(id))
+	if v == nil {
+		return nil, ErrRecordNotFound
+	}
+
+	var c models.Comment
+	if err := json.Unmarshal(v, &c); err != nil {
+		return nil, err
+	}
+
+	if author, err := getUser(tx, c.UserID); err == nil {
+		c.Author = author
+	}
+	for _, idUser := range scanIDs(tx.Bucket(commentMentionsBucket), itob(c.ID)) {
+		if user, err := getUser(tx, idUser); err == nil {
+			c.Mentions = append(c.Mentions, user)
+		}
+	}
+	return &c, nil
+}
+
+func putComment(tx *bolt.Tx, c *models.Comment) error {
+	stored := *c
+	stored.Author, stored.Mentions, stored.Replies = nil, nil, nil
+	v, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(commentsBucket).Put(itob(c.ID), v)
+}
+
+// putMentionsBolt replaces the users mentioned in a comment
+func putMentionsBolt(tx *bolt.Tx, idComment uint, users []*models.User) error {
+	b := tx.Bucket(commentMentionsBucket)
+	for _, idUser := range scanIDs(b, itob(idComment)) {
+		if err := b.Delete(pairKey(itob(idComment), idUser)); err != nil {
+			return err
+		}
+	}
+	for _, user := range users {
+		if err := b.Put(pairKey(itob(idComment), user.ID), nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *BoltStore) CreateComment(u *models.User, idTask int, c models.Comment) (*models.Comment, error) {
+	err := s.DB.Update(func(tx *bolt.Tx) error {
+		task, err := memberTask(tx, u, uint(idTask))
+		if err != nil {
+			return err
+		}
+		// replies stay on the task of the comment they answer
+		if c.ParentID != nil {
+			if _, err := getComment(tx, task.ID, *c.ParentID); err != nil {
+				return err
+			}
+		}
+
+		seq, err := tx.Bucket(commentsBucket).NextSequence()
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		c.ID, c.TaskID, c.UserID, c.CreatedAt, c.UpdatedAt = uint(seq), task.ID, u.ID, now, now
+
+		if err := putComment(tx, &c); err != nil {
+			return err
+		}
+		if err := putMentionsBolt(tx, c.ID, c.Mentions); err != nil {
+			return err
+		}
+		return tx.Bucket(taskCommentsBucket).Put(pairKey(itob(task.ID), c.ID), nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c.Author = u
+	return &c, nil
+}
+
+func (s *BoltStore) GetComments(u *models.User, idTask int) ([]*models.Comment, error) {
+	var comments []models.Comment
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		task, err := memberTask(tx, u, uint(idTask))
+		if err != nil {
+			return err
+		}
+
+		for _, id := range scanIDs(tx.Bucket(taskCommentsBucket), itob(task.ID)) {
+			c, err := getComment(tx, task.ID, id)
+			if err != nil {
+				return err
+			}
+			comments = append(comments, *c)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return models.CommentThreads(comments), nil
+}
+
+func (s *BoltStore) GetComment(u *models.User, idTask, id int) (*models.Comment, error) {
+	var comment *models.Comment
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		task, err := memberTask(tx, u, uint(idTask))
+		if err != nil {
+			return err
+		}
+		comment, err = getComment(tx, task.ID, uint(id))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return comment, nil
+}
+
+func (s *BoltStore) UpdateComment(u *models.User, idTask, id int, c models.Comment) (*models.Comment, error) {
+	var comment *models.Comment
+	err := s.DB.Update(func(tx *bolt.Tx) error {
+		task, err := memberTask(tx, u, uint(idTask))
+		if err != nil {
+			return err
+		}
+		if comment, err = getComment(tx, task.ID, uint(id)); err != nil {
+			return err
+		}
+		if comment.UserID != u.ID {
+			return ErrRecordNotFound
+		}
+
+		comment.Body, comment.Mentions, comment.UpdatedAt = c.Body, c.Mentions, time.Now()
+		if err := putComment(tx, comment); err != nil {
+			return err
+		}
+		return putMentionsBolt(tx, comment.ID, c.Mentions)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return comment, nil
+}
+
+func (s *BoltStore) DeleteComment(u *models.User, idTask, id int) error {
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		task, err := memberTask(tx, u, uint(idTask))
+		if err != nil {
+			return err
+		}
+		comment, err := getComment(tx, task.ID, uint(id))
+		if err != nil {
+			return err
+		}
+
+		doomed := map[uint]bool{comment.ID: true}
+		var all []models.Comment
+		for _, idComment := range scanIDs(tx.Bucket(taskCommentsBucket), itob(task.ID)) {
+			c, err := getComment(tx, task.ID, idComment)
+			if err != nil {
+				return err
+			}
+			all = append(all, *c)
+		}
+		// replies go along with the comment they answer, which always
+		// comes before them
+		for _, c := range all {
+			if c.ParentID != nil && doomed[*c.ParentID] {
+				doomed[c.ID] = true
+			}
+		}
+
+		for idComment := range doomed {
+			if err := putMentionsBolt(tx, idComment, nil); err != nil {
+				return err
+			}
+			if err := tx.Bucket(taskCommentsBucket).Delete(pairKey(itob(task.ID), idComment)); err != nil {
+				return err
+			}
+			if err := tx.Bucket(commentsBucket).Delete(itob(idComment)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"todo-app/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func (s *BoltStore) AddBlocker(u *models.User, idTask, idBlocker int) error {
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		for _, id := range []int{idTask, idBlocker} {
+			if _, err := memberTask(tx, u, uint(id)); err != nil {
+				return err
+			}
+		}
+
+		if reachesBolt(tx, uint(idBlocker), uint(idTask)) {
+			return ErrDependencyCycle
+		}
+		return tx.Bucket(taskBlockersBucket).Put(pairKey(itob(uint(idTask)), uint(idBlocker)), nil)
+	})
+}
+
+// reachesBolt reports whether target is from or one of from's blockers, at
+// any depth
+func reachesBolt(tx *bolt.Tx, from, target uint) bool {
+	seen := map[uint]bool{from: true}
+	frontier := []uint{from}
+	for len(frontier) > 0 && !seen[target] {
+		var next []uint
+		for _, id := range frontier {
+			for _, blocker := range scanIDs(tx.Bucket(taskBlockersBucket), itob(id)) {
+				if !seen[blocker] {
+					seen[blocker] = true
+					next = append(next, blocker)
+				}
+			}
+		}
+		frontier = next
+	}
+	return seen[target]
+}
+
+func (s *BoltStore) RemoveBlocker(u *models.User, idTask, idBlocker int) error {
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		if _, err := memberTask(tx, u, uint(idTask)); err != nil {
+			return err
+		}
+
+		b := tx.Bucket(taskBlockersBucket)
+		key := pairKey(itob(uint(idTask)), uint(idBlocker))
+		if b.Get(key) == nil {
+			return ErrRecordNotFound
+		}
+		return b.Delete(key)
+	})
+}
+
+// markBlockedBolt fills in Blocked and BlockedBy from the blockers that are
+// still open
+func markBlockedBolt(tx *bolt.Tx, t *models.Task) error {
+	t.BlockedBy = nil
+	for _, id := range scanIDs(tx.Bucket(taskBlockersBucket), itob(t.ID)) {
+		blocker, err := getTask(tx, id)
+		if err == ErrRecordNotFound {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !blocker.Completed {
+			t.BlockedBy = append(t.BlockedBy, id)
+		}
+	}
+	t.Blocked = len(t.BlockedBy) > 0
+	return nil
+}
@@ -0,0 +1,252 @@
+package database
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+	"todo-app/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltLabel is how labels are persisted, models.Label hides its organization
+// when marshalled
+type boltLabel struct {
+	models.Label
+	OrgID uint `json:"org_id"`
+}
+
+// getLabel returns one of u's labels in their organization
+func getLabel(tx *bolt.Tx, u *models.User, id uint) (*models.Label, error) {
+	if tx.Bucket(userLabelsBucket).Get(pairKey(itob(u.ID), id)) == nil {
+		return nil, ErrRecordNotFound
+	}
+	v := tx.Bucket(labelsBucket).Get(itob(id))
+	if v == nil {
+		return nil, ErrRecordNotFound
+	}
+
+	var bl boltLabel
+	if err := json.Unmarshal(v, &bl); err != nil {
+		return nil, err
+	}
+	if bl.OrgID != u.OrgID {
+		return nil, ErrRecordNotFound
+	}
+	l := bl.Label
+	l.UserID, l.OrgID = u.ID, bl.OrgID
+	return &l, nil
+}
+
+func putLabel(tx *bolt.Tx, l *models.Label) error {
+	v, err := json.Marshal(boltLabel{*l, l.OrgID})
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(labelsBucket).Put(itob(l.ID), v)
+}
+
+// userLabels returns u's labels in their organization ordered by name
+func userLabels(tx *bolt.Tx, u *models.User) ([]models.Label, error) {
+	labels := []models.Label{}
+	for _, id := range scanIDs(tx.Bucket(userLabelsBucket), itob(u.ID)) {
+		l, err := getLabel(tx, u, id)
+		if err == ErrRecordNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		labels = append(labels, *l)
+	}
+	sort.SliceStable(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+	return labels, nil
+}
+
+func labelNameFreeBolt(tx *bolt.Tx, u *models.User, name string, except uint) error {
+	labels, err := userLabels(tx, u)
+	if err != nil {
+		return err
+	}
+	for _, l := range labels {
+		if l.Name == name && l.ID != except {
+			return ErrLabelExists
+		}
+	}
+	return nil
+}
+
+// labelledTasks returns the tasks that carry any, or all, of the named
+// labels of u
+func labelledTasks(tx *bolt.Tx, u *models.User, filter models.TaskFilter) ([]uint, error) {
+	wanted := make(map[string]bool)
+	for _, name := range filter.Labels {
+		wanted[name] = true
+	}
+
+	labels, err := userLabels(tx, u)
+	if err != nil {
+		return nil, err
+	}
+	matched := make(map[uint]map[string]bool)
+	for _, l := range labels {
+		if !wanted[l.Name] {
+			continue
+		}
+		for _, id := range scanIDs(tx.Bucket(labelTasksBucket), itob(l.ID)) {
+			if matched[id] == nil {
+				matched[id] = make(map[string]bool)
+			}
+			matched[id][l.Name] = true
+		}
+	}
+
+	ids := make(map[uint]bool)
+	for id, names := range matched {
+		if !filter.AllLabels || len(names) == len(wanted) {
+			ids[id] = true
+		}
+	}
+	return sortedIDs(ids), nil
+}
+
+// markLabelsBolt fills in the labels u has put on t
+func markLabelsBolt(tx *bolt.Tx, u *models.User, t *models.Task) error {
+	t.Labels = nil
+	for _, id := range scanIDs(tx.Bucket(taskLabelsBucket), itob(t.ID)) {
+		l, err := getLabel(tx, u, id)
+		if err == ErrRecordNotFound {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		t.Labels = append(t.Labels, *l)
+	}
+	sort.SliceStable(t.Labels, func(i, j int) bool { return t.Labels[i].Name < t.Labels[j].Name })
+	return nil
+}
+
+func (s *BoltStore) CreateLabel(u *models.User, l models.Label) (*models.Label, error) {
+	err := s.DB.Update(func(tx *bolt.Tx) error {
+		if err := labelNameFreeBolt(tx, u, l.Name, 0); err != nil {
+			return err
+		}
+
+		seq, err := tx.Bucket(labelsBucket).NextSequence()
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		l.ID, l.UserID, l.OrgID, l.CreatedAt, l.UpdatedAt = uint(seq), u.ID, u.OrgID, now, now
+
+		if err := putLabel(tx, &l); err != nil {
+			return err
+		}
+		return tx.Bucket(userLabelsBucket).Put(pairKey(itob(u.ID), l.ID), nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &l, nil
+}
+
+func (s *BoltStore) GetLabels(u *models.User) (*[]models.Label, error) {
+	var labels []models.Label
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		var err error
+		labels, err = userLabels(tx, u)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &labels, nil
+}
+
+func (s *BoltStore) UpdateLabel(u *models.User, id int, l models.UpdateLabel) (*models.Label, error) {
+	var label *models.Label
+	err := s.DB.Update(func(tx *bolt.Tx) error {
+		var err error
+		if label, err = getLabel(tx, u, uint(id)); err != nil {
+			return err
+		}
+
+		if l.Name != "" {
+			if err := labelNameFreeBolt(tx, u, l.Name, label.ID); err != nil {
+				return err
+			}
+			label.Name = l.Name
+		}
+		if l.Color != "" {
+			label.Color = l.Color
+		}
+		label.UpdatedAt = time.Now()
+		return putLabel(tx, label)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return label, nil
+}
+
+func (s *BoltStore) DeleteLabel(u *models.User, id int) error {
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		label, err := getLabel(tx, u, uint(id))
+		if err != nil {
+			return err
+		}
+
+		for _, idTask := range scanIDs(tx.Bucket(labelTasksBucket), itob(label.ID)) {
+			if err := removeTaskLabel(tx, idTask, label.ID); err != nil {
+				return err
+			}
+		}
+		if err := tx.Bucket(userLabelsBucket).Delete(pairKey(itob(u.ID), label.ID)); err != nil {
+			return err
+		}
+		return tx.Bucket(labelsBucket).Delete(itob(label.ID))
+	})
+}
+
+func removeTaskLabel(tx *bolt.Tx, idTask, idLabel uint) error {
+	if err := tx.Bucket(taskLabelsBucket).Delete(pairKey(itob(idTask), idLabel)); err != nil {
+		return err
+	}
+	return tx.Bucket(labelTasksBucket).Delete(pairKey(itob(idLabel), idTask))
+}
+
+func (s *BoltStore) AttachLabel(u *models.User, idTask, idLabel int) error {
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		task, err := memberTask(tx, u, uint(idTask))
+		if err != nil {
+			return err
+		}
+		label, err := getLabel(tx, u, uint(idLabel))
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Bucket(taskLabelsBucket).Put(pairKey(itob(task.ID), label.ID), nil); err != nil {
+			return err
+		}
+		return tx.Bucket(labelTasksBucket).Put(pairKey(itob(label.ID), task.ID), nil)
+	})
+}
+
+func (s *BoltStore) DetachLabel(u *models.User, idTask, idLabel int) error {
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		label, err := getLabel(tx, u, uint(idLabel))
+		if err != nil {
+			return err
+		}
+
+		if tx.Bucket(taskLabelsBucket).Get(pairKey(itob(uint(idTask)), label.ID)) == nil {
+			return ErrRecordNotFound
+		}
+		return removeTaskLabel(tx, uint(idTask), label.ID)
+	})
+}
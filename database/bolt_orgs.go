@@ -0,0 +1,319 @@
+package database
+
+import (
+	"encoding/json"
+	"time"
+	"todo-app/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func getOrg(tx *bolt.Tx, id uint) (*models.Organization, error) {
+	v := tx.Bucket(organizationsBucket).Get(itob(id))
+	if v == nil {
+		return nil, ErrRecordNotFound
+	}
+
+	var o models.Organization
+	if err := json.Unmarshal(v, &o); err != nil {
+		return nil, err
+	}
+	if o.DeletedAt != nil {
+		return nil, ErrRecordNotFound
+	}
+	return &o, nil
+}
+
+func insertOrg(tx *bolt.Tx, o models.Organization) (*models.Organization, error) {
+	b := tx.Bucket(organizationsBucket)
+	seq, err := b.NextSequence()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	o.ID, o.CreatedAt, o.UpdatedAt, o.DeletedAt, o.Members = uint(seq), now, now, nil, nil
+
+	v, err := json.Marshal(o)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.Put(itob(o.ID), v); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// orgRole is empty unless idUser belongs to the organization
+func orgRole(tx *bolt.Tx, idOrg, idUser uint) models.Role {
+	return models.Role(tx.Bucket(orgMembersBucket).Get(pairKey(itob(idOrg), idUser)))
+}
+
+// putOrgMember adds idUser to the organization or changes their role, no
+// other member may have the same username
+func putOrgMember(tx *bolt.Tx, idOrg, idUser uint, role models.Role) error {
+	user, err := getUser(tx, idUser)
+	if err != nil {
+		return err
+	}
+	names := tx.Bucket(orgUsernamesBucket)
+	key := orgUsernameKey(idOrg, user.Username)
+	if v := names.Get(key); v != nil && btoi(v) != idUser {
+		return ErrUsernameTaken
+	}
+	if err := names.Put(key, itob(idUser)); err != nil {
+		return err
+	}
+
+	if err := tx.Bucket(orgMembersBucket).Put(pairKey(itob(idOrg), idUser), []byte(role)); err != nil {
+		return err
+	}
+	return tx.Bucket(userOrgsBucket).Put(pairKey(itob(idUser), idOrg), []byte(role))
+}
+
+// upgradeUsernames moves the usernames of files written while they were
+// unique across organizations, keyed by the name alone, into the buckets
+// that allow one per organization
+func upgradeUsernames(tx *bolt.Tx) error {
+	old := tx.Bucket([]byte("usernames"))
+	if old == nil {
+		return nil
+	}
+
+	err := old.ForEach(func(name, id []byte) error {
+		if err := tx.Bucket(userNamesBucket).Put(pairKey(usernameKey(string(name)), btoi(id)), []byte{}); err != nil {
+			return err
+		}
+		for _, idOrg := range scanIDs(tx.Bucket(userOrgsBucket), id) {
+			if err := tx.Bucket(orgUsernamesBucket).Put(orgUsernameKey(idOrg, string(name)), id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return tx.DeleteBucket([]byte("usernames"))
+}
+
+// demotesLastOrgOwnerBolt reports whether taking idUser's owner role away
+// would leave the organization without owners
+func demotesLastOrgOwnerBolt(tx *bolt.Tx, idOrg, idUser uint) bool {
+	if orgRole(tx, idOrg, idUser) != models.RoleOwner {
+		return false
+	}
+
+	owners := 0
+	for _, id := range scanIDs(tx.Bucket(orgMembersBucket), itob(idOrg)) {
+		if orgRole(tx, idOrg, id) == models.RoleOwner {
+			owners++
+		}
+	}
+	return owners <= 1
+}
+
+// upgradeOrgs moves files written before organizations existed into a single
+// organization that all existing users own, like the sql migration does
+func upgradeOrgs(tx *bolt.Tx) error {
+	if tx.Bucket(organizationsBucket).Stats().KeyN > 0 || tx.Bucket(usersBucket).Stats().KeyN == 0 {
+		return nil
+	}
+
+	org, err := insertOrg(tx, models.Organization{Name: "default"})
+	if err != nil {
+		return err
+	}
+	err = tx.Bucket(usersBucket).ForEach(func(k, _ []byte) error {
+		return putOrgMember(tx, org.ID, btoi(k), models.RoleOwner)
+	})
+	if err != nil {
+		return err
+	}
+
+	// rewrite the stored JSON directly, getTask and friends skip deleted rows
+	rewrite := func(bucket []byte, update func(data []byte) ([]byte, error)) error {
+		b := tx.Bucket(bucket)
+		updated := make(map[string][]byte)
+		err := b.ForEach(func(k, data []byte) error {
+			out, err := update(data)
+			updated[string(k)] = out
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		for k, v := range updated {
+			if err := b.Put([]byte(k), v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	err = rewrite(tasksBucket, func(data []byte) ([]byte, error) {
+		var t models.Task
+		if err := json.Unmarshal(data, &t); err != nil {
+			return nil, err
+		}
+		t.OrgID = org.ID
+		return json.Marshal(t)
+	})
+	if err != nil {
+		return err
+	}
+	err = rewrite(projectsBucket, func(data []byte) ([]byte, error) {
+		var p models.Project
+		if err := json.Unmarshal(data, &p); err != nil {
+			return nil, err
+		}
+		p.OrgID = org.ID
+		return json.Marshal(p)
+	})
+	if err != nil {
+		return err
+	}
+	return rewrite(labelsBucket, func(data []byte) ([]byte, error) {
+		var l boltLabel
+		if err := json.Unmarshal(data, &l); err != nil {
+			return nil, err
+		}
+		l.OrgID = org.ID
+		return json.Marshal(l)
+	})
+}
+
+func (s *BoltStore) CreateOrganization(u *models.User, o models.Organization) (*models.Organization, error) {
+	var org *models.Organization
+	err := s.DB.Update(func(tx *bolt.Tx) error {
+		var err error
+		if org, err = insertOrg(tx, o); err != nil {
+			return err
+		}
+		return putOrgMember(tx, org.ID, u.ID, models.RoleOwner)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return org, nil
+}
+
+func (s *BoltStore) GetOrganizations(u *models.User) (*[]models.Organization, error) {
+	orgs := []models.Organization{}
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		for _, id := range scanIDs(tx.Bucket(userOrgsBucket), itob(u.ID)) {
+			o, err := getOrg(tx, id)
+			if err == ErrRecordNotFound {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			orgs = append(orgs, *o)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &orgs, nil
+}
+
+func (s *BoltStore) GetOrganization(u *models.User, id int) (*models.Organization, error) {
+	var org *models.Organization
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		if orgRole(tx, uint(id), u.ID) == "" {
+			return ErrRecordNotFound
+		}
+		var err error
+		if org, err = getOrg(tx, uint(id)); err != nil {
+			return err
+		}
+
+		for _, idUser := range scanIDs(tx.Bucket(orgMembersBucket), itob(org.ID)) {
+			user, err := getUser(tx, idUser)
+			if err != nil {
+				return err
+			}
+			org.Members = append(org.Members, models.OrgMember{
+				OrgID:  org.ID,
+				UserID: idUser,
+				Role:   orgRole(tx, org.ID, idUser),
+				User:   user,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return org, nil
+}
+
+func (s *BoltStore) GetOrgRole(idUser, idOrg uint) (models.Role, error) {
+	var role models.Role
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		if _, err := getOrg(tx, idOrg); err != nil {
+			return err
+		}
+		if role = orgRole(tx, idOrg, idUser); role == "" {
+			return ErrRecordNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return role, nil
+}
+
+func (s *BoltStore) AddOrgMember(idOrg, idUser int, role models.Role) (*models.OrgMember, error) {
+	var member *models.OrgMember
+	err := s.DB.Update(func(tx *bolt.Tx) error {
+		user, err := getUser(tx, uint(idUser))
+		if err != nil {
+			return err
+		}
+		org, err := getOrg(tx, uint(idOrg))
+		if err != nil {
+			return err
+		}
+
+		// already a member, only the role changes
+		if role != models.RoleOwner && demotesLastOrgOwnerBolt(tx, org.ID, user.ID) {
+			return ErrLastOrgOwner
+		}
+		member = &models.OrgMember{OrgID: org.ID, UserID: user.ID, Role: role, User: user}
+		return putOrgMember(tx, org.ID, user.ID, role)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return member, nil
+}
+
+func (s *BoltStore) RemoveOrgMember(idOrg, idUser int) error {
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		if orgRole(tx, uint(idOrg), uint(idUser)) == "" {
+			return ErrRecordNotFound
+		}
+		if demotesLastOrgOwnerBolt(tx, uint(idOrg), uint(idUser)) {
+			return ErrLastOrgOwner
+		}
+		user, err := getUser(tx, uint(idUser))
+		if err != nil {
+			return err
+		}
+		if err := tx.Bucket(orgUsernamesBucket).Delete(orgUsernameKey(uint(idOrg), user.Username)); err != nil {
+			return err
+		}
+		if err := tx.Bucket(orgMembersBucket).Delete(pairKey(itob(uint(idOrg)), uint(idUser))); err != nil {
+			return err
+		}
+		return tx.Bucket(userOrgsBucket).Delete(pairKey(itob(uint(idUser)), uint(idOrg)))
+	})
+}
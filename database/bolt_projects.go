@@ -0,0 +1,283 @@
+package database
+
+import (
+	"encoding/json"
+	"time"
+	"todo-app/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// getProject returns a project that has not been deleted
+func getProject(tx *bolt.Tx, id uint) (*models.Project, error) {
+	v := tx.Bucket(projectsBucket).Get(itob(id))
+	if v == nil {
+		return nil, ErrRecordNotFound
+	}
+
+	var p models.Project
+	if err := json.Unmarshal(v, &p); err != nil {
+		return nil, err
+	}
+	if p.DeletedAt != nil {
+		return nil, ErrRecordNotFound
+	}
+	return &p, nil
+}
+
+func putProject(tx *bolt.Tx, p *models.Project) error {
+	stored := *p
+	stored.Members = nil
+	v, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(projectsBucket).Put(itob(p.ID), v)
+}
+
+// projectRole is empty unless idUser is a member of the project
+func projectRole(tx *bolt.Tx, idProject, idUser uint) models.Role {
+	return models.Role(tx.Bucket(projectMembersBucket).Get(pairKey(itob(idProject), idUser)))
+}
+
+// memberProject returns project id if u is a member of it
+func memberProject(tx *bolt.Tx, u *models.User, id uint) (*models.Project, error) {
+	if projectRole(tx, id, u.ID) == "" {
+		return nil, ErrRecordNotFound
+	}
+	p, err := getProject(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if p.OrgID != u.OrgID {
+		return nil, ErrRecordNotFound
+	}
+	return p, nil
+}
+
+func putProjectMember(tx *bolt.Tx, idProject, idUser uint, role models.Role) error {
+	if err := tx.Bucket(projectMembersBucket).Put(pairKey(itob(idProject), idUser), []byte(role)); err != nil {
+		return err
+	}
+	return tx.Bucket(userProjectsBucket).Put(pairKey(itob(idUser), idProject), []byte(role))
+}
+
+func deleteProjectMember(tx *bolt.Tx, idProject, idUser uint) error {
+	if err := tx.Bucket(projectMembersBucket).Delete(pairKey(itob(idProject), idUser)); err != nil {
+		return err
+	}
+	return tx.Bucket(userProjectsBucket).Delete(pairKey(itob(idUser), idProject))
+}
+
+// demotesLastProjectOwnerBolt reports whether taking idUser's owner role
+// away would leave the project without owners
+func demotesLastProjectOwnerBolt(tx *bolt.Tx, idProject, idUser uint) bool {
+	if projectRole(tx, idProject, idUser) != models.RoleOwner {
+		return false
+	}
+
+	owners := 0
+	for _, id := range scanIDs(tx.Bucket(projectMembersBucket), itob(idProject)) {
+		if projectRole(tx, idProject, id) == models.RoleOwner {
+			owners++
+		}
+	}
+	return owners <= 1
+}
+
+func (s *BoltStore) CreateProject(u *models.User, p models.Project) (*models.Project, error) {
+	err := s.DB.Update(func(tx *bolt.Tx) error {
+		seq, err := tx.Bucket(projectsBucket).NextSequence()
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		p.ID, p.CreatedAt, p.UpdatedAt, p.DeletedAt, p.Members = uint(seq), now, now, nil, nil
+		p.OrgID = u.OrgID
+
+		if err := putProject(tx, &p); err != nil {
+			return err
+		}
+		return putProjectMember(tx, p.ID, u.ID, models.RoleOwner)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+func (s *BoltStore) GetProjects(u *models.User) (*[]models.Project, error) {
+	projects := []models.Project{}
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		for _, id := range scanIDs(tx.Bucket(userProjectsBucket), itob(u.ID)) {
+			p, err := memberProject(tx, u, id)
+			if err == ErrRecordNotFound {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			projects = append(projects, *p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &projects, nil
+}
+
+func (s *BoltStore) GetProject(u *models.User, id int) (*models.Project, error) {
+	var project *models.Project
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		var err error
+		if project, err = memberProject(tx, u, uint(id)); err != nil {
+			return err
+		}
+
+		for _, idUser := range scanIDs(tx.Bucket(projectMembersBucket), itob(project.ID)) {
+			user, err := getUser(tx, idUser)
+			if err != nil {
+				return err
+			}
+			project.Members = append(project.Members, models.ProjectMember{
+				ProjectID: project.ID,
+				UserID:    idUser,
+				Role:      projectRole(tx, project.ID, idUser),
+				User:      user,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return project, nil
+}
+
+func (s *BoltStore) UpdateProject(u *models.User, id int, p models.UpdateProject) (*models.Project, error) {
+	var project *models.Project
+	err := s.DB.Update(func(tx *bolt.Tx) error {
+		var err error
+		if project, err = memberProject(tx, u, uint(id)); err != nil {
+			return err
+		}
+
+		if p.Name != "" {
+			project.Name = p.Name
+		}
+		if p.Description != "" {
+			project.Description = p.Description
+		}
+		project.UpdatedAt = time.Now()
+		return putProject(tx, project)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return project, nil
+}
+
+func (s *BoltStore) DeleteProject(u *models.User, id int) (*models.Project, error) {
+	var project *models.Project
+	err := s.DB.Update(func(tx *bolt.Tx) error {
+		var err error
+		if project, err = memberProject(tx, u, uint(id)); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, idTask := range scanIDs(tx.Bucket(projectTasksBucket), itob(project.ID)) {
+			old, err := getTask(tx, idTask)
+			if err == ErrRecordNotFound {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			deleted := *old
+			deleted.DeletedAt = &now
+			if err := putTask(tx, &deleted, old); err != nil {
+				return err
+			}
+			if err := logActivityBolt(tx, models.TaskActivity(u, old, nil)); err != nil {
+				return err
+			}
+		}
+
+		for _, idUser := range scanIDs(tx.Bucket(projectMembersBucket), itob(project.ID)) {
+			if err := deleteProjectMember(tx, project.ID, idUser); err != nil {
+				return err
+			}
+		}
+
+		project.DeletedAt = &now
+		return putProject(tx, project)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return project, nil
+}
+
+func (s *BoltStore) GetProjectRole(u *models.User, idProject int) (models.Role, error) {
+	var role models.Role
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		if _, err := memberProject(tx, u, uint(idProject)); err != nil {
+			return err
+		}
+		role = projectRole(tx, uint(idProject), u.ID)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return role, nil
+}
+
+func (s *BoltStore) AddProjectMember(idProject, idUser int, role models.Role) (*models.ProjectMember, error) {
+	var member *models.ProjectMember
+	err := s.DB.Update(func(tx *bolt.Tx) error {
+		user, err := getUser(tx, uint(idUser))
+		if err != nil {
+			return err
+		}
+		project, err := getProject(tx, uint(idProject))
+		if err != nil {
+			return err
+		}
+		if orgRole(tx, project.OrgID, user.ID) == "" {
+			return ErrRecordNotFound
+		}
+
+		// already a member, only the role changes
+		if role != models.RoleOwner && demotesLastProjectOwnerBolt(tx, project.ID, user.ID) {
+			return ErrLastProjectOwner
+		}
+		member = &models.ProjectMember{ProjectID: project.ID, UserID: user.ID, Role: role, User: user}
+		return putProjectMember(tx, project.ID, user.ID, role)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return member, nil
+}
+
+func (s *BoltStore) RemoveProjectMember(idProject, idUser int) error {
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		if projectRole(tx, uint(idProject), uint(idUser)) == "" {
+			return ErrRecordNotFound
+		}
+		if demotesLastProjectOwnerBolt(tx, uint(idProject), uint(idUser)) {
+			return ErrLastProjectOwner
+		}
+		return deleteProjectMember(tx, uint(idProject), uint(idUser))
+	})
+}
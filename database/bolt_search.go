@@ -0,0 +1,67 @@
+package database
+
+import (
+	"encoding/json"
+	"todo-app/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func (s *BoltStore) SearchTasks(u *models.User, search models.TaskSearch) (*[]models.SearchResult, error) {
+	results := []models.SearchResult{}
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		for _, id := range sortedIDs(visibleIDs(tx, u)) {
+			t, err := getTask(tx, id)
+			if err == ErrRecordNotFound {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if t.OrgID != u.OrgID || (t.ArchivedAt != nil && !search.IncludeArchived) {
+				continue
+			}
+
+			comments, err := commentBodies(tx, t.ID)
+			if err != nil {
+				return err
+			}
+			if result, ok := models.MatchTask(search.Terms, t, comments); ok {
+				results = append(results, result)
+			}
+		}
+
+		results = models.SortResults(results, search.Limit)
+		for i := range results {
+			if err := markBlockedBolt(tx, &results[i].Task); err != nil {
+				return err
+			}
+			if err := markLabelsBolt(tx, u, &results[i].Task); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &results, nil
+}
+
+// commentBodies returns the text of the comments on a task, oldest first
+func commentBodies(tx *bolt.Tx, idTask uint) ([]string, error) {
+	var bodies []string
+	for _, id := range scanIDs(tx.Bucket(taskCommentsBucket), itob(idTask)) {
+		v := tx.Bucket(commentsBucket).Get(itob(id))
+		if v == nil {
+			continue
+		}
+		var c models.Comment
+		if err := json.Unmarshal(v, &c); err != nil {
+			return nil, err
+		}
+		bodies = append(bodies, c.Body)
+	}
+	return bodies, nil
+}
@@ -0,0 +1,319 @@
+package database
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"time"
+	"todo-app/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// trashedTask returns task idTask if it is in the trash and u can reach it
+func trashedTask(tx *bolt.Tx, u *models.User, idTask uint) (*models.Task, error) {
+	t, err := loadTask(tx, idTask)
+	if err != nil {
+		return nil, err
+	}
+	if t.DeletedAt == nil {
+		return nil, ErrRecordNotFound
+	}
+	if _, err := taskRole(tx, u, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// trashedWithBolt returns the descendants of t that were deleted along with
+// it, or every descendant when all is set
+func trashedWithBolt(tx *bolt.Tx, t *models.Task, all bool) ([]models.Task, error) {
+	var found []models.Task
+	parents := []uint{t.ID}
+	for len(parents) > 0 {
+		var next []uint
+		for _, parent := range parents {
+			for _, childID := range scanIDs(tx.Bucket(taskChildrenBucket), itob(parent)) {
+				child, err := loadTask(tx, childID)
+				if err != nil {
+					return nil, err
+				}
+				if !all && (child.DeletedAt == nil || !child.DeletedAt.Equal(*t.DeletedAt)) {
+					continue
+				}
+				found = append(found, *child)
+				next = append(next, child.ID)
+			}
+		}
+		parents = next
+	}
+	return found, nil
+}
+
+func (s *BoltStore) GetTrash(u *models.User) (*[]models.Task, error) {
+	tasks := []models.Task{}
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		ids := make(map[uint]bool)
+		for _, id := range scanIDs(tx.Bucket(userTasksBucket), itob(u.ID)) {
+			ids[id] = true
+		}
+		for _, idProject := range scanIDs(tx.Bucket(userProjectsBucket), itob(u.ID)) {
+			for _, id := range scanIDs(tx.Bucket(projectTasksBucket), itob(idProject)) {
+				ids[id] = true
+			}
+		}
+
+		for _, id := range sortedIDs(ids) {
+			t, err := loadTask(tx, id)
+			if err == ErrRecordNotFound {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if t.DeletedAt != nil && t.OrgID == u.OrgID {
+				tasks = append(tasks, *t)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].DeletedAt.After(*tasks[j].DeletedAt)
+	})
+	return &tasks, nil
+}
+
+func (s *BoltStore) GetTrashedTaskRole(u *models.User, idTask int) (models.Role, error) {
+	var role models.Role
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		t, err := trashedTask(tx, u, uint(idTask))
+		if err != nil {
+			return err
+		}
+		role, err = taskRole(tx, u, t)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return role, nil
+}
+
+func (s *BoltStore) RestoreTask(u *models.User, idTask int) (*models.Task, error) {
+	err := s.DB.Update(func(tx *bolt.Tx) error {
+		task, err := trashedTask(tx, u, uint(idTask))
+		if err != nil {
+			return err
+		}
+		if task.ParentID != nil {
+			parent, err := loadTask(tx, *task.ParentID)
+			if err != nil {
+				return err
+			}
+			if parent.DeletedAt != nil {
+				return ErrParentDeleted
+			}
+		}
+
+		// tasks of a project deleted since come back without it
+		dropProject := false
+		if task.ProjectID != nil {
+			_, err := getProject(tx, *task.ProjectID)
+			if err != nil && err != ErrRecordNotFound {
+				return err
+			}
+			dropProject = err == ErrRecordNotFound
+		}
+
+		children, err := trashedWithBolt(tx, task, false)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		for _, old := range append(children, *task) {
+			old := old
+			restored := old
+			restored.DeletedAt, restored.UpdatedAt = nil, now
+			if dropProject {
+				if err := tx.Bucket(projectTasksBucket).Delete(pairKey(itob(*old.ProjectID), old.ID)); err != nil {
+					return err
+				}
+				restored.ProjectID = nil
+			}
+			if err := putTask(tx, &restored, &old); err != nil {
+				return err
+			}
+			if err := logActivityBolt(tx, models.TrashActivity(u, &old, &restored)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetTask(u, idTask)
+}
+
+func (s *BoltStore) PurgeTask(u *models.User, idTask int) ([]models.Attachment, error) {
+	var attachments []models.Attachment
+	err := s.DB.Update(func(tx *bolt.Tx) error {
+		task, err := trashedTask(tx, u, uint(idTask))
+		if err != nil {
+			return err
+		}
+		// subtasks of a task in the trash are all in the trash too
+		children, err := trashedWithBolt(tx, task, true)
+		if err != nil {
+			return err
+		}
+		attachments, err = purgeTasksBolt(tx, u, append(children, *task))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+func (s *BoltStore) PurgeTrash(cutoff time.Time) ([]models.Attachment, error) {
+	var attachments []models.Attachment
+	err := s.DB.Update(func(tx *bolt.Tx) error {
+		// subtasks are deleted with or before their parent, so none is left behind
+		var tasks []models.Task
+		err := tx.Bucket(tasksBucket).ForEach(func(k, v []byte) error {
+			var t models.Task
+			if err := json.Unmarshal(v, &t); err != nil {
+				return err
+			}
+			if t.DeletedAt != nil && t.DeletedAt.Before(cutoff) {
+				tasks = append(tasks, t)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		attachments, err = purgeTasksBolt(tx, nil, tasks)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+// purgeTasksBolt deletes tasks and everything hanging off them for good,
+// only their activity log is kept
+func purgeTasksBolt(tx *bolt.Tx, actor *models.User, tasks []models.Task) ([]models.Attachment, error) {
+	var attachments []models.Attachment
+	doomed := make(map[uint]bool)
+	for _, t := range tasks {
+		doomed[t.ID] = true
+	}
+
+	for i := range tasks {
+		t := &tasks[i]
+		for _, idUser := range scanIDs(tx.Bucket(taskUsersBucket), itob(t.ID)) {
+			if err := removeMember(tx, idUser, t.ID); err != nil {
+				return nil, err
+			}
+		}
+		for _, idLabel := range scanIDs(tx.Bucket(taskLabelsBucket), itob(t.ID)) {
+			if err := removeTaskLabel(tx, t.ID, idLabel); err != nil {
+				return nil, err
+			}
+		}
+		for _, idComment := range scanIDs(tx.Bucket(taskCommentsBucket), itob(t.ID)) {
+			if err := putMentionsBolt(tx, idComment, nil); err != nil {
+				return nil, err
+			}
+			if err := tx.Bucket(commentsBucket).Delete(itob(idComment)); err != nil {
+				return nil, err
+			}
+		}
+		files, err := taskAttachments(tx, t.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range files {
+			if err := tx.Bucket(attachmentsBucket).Delete(itob(a.ID)); err != nil {
+				return nil, err
+			}
+		}
+		attachments = append(attachments, files...)
+		for _, idItem := range scanIDs(tx.Bucket(taskChecklistBucket), itob(t.ID)) {
+			if err := tx.Bucket(checklistItemsBucket).Delete(itob(idItem)); err != nil {
+				return nil, err
+			}
+		}
+		for _, b := range [][]byte{taskCommentsBucket, taskAttachmentsBucket, taskChecklistBucket, taskChildrenBucket, taskBlockersBucket} {
+			if err := deletePrefix(tx.Bucket(b), itob(t.ID)); err != nil {
+				return nil, err
+			}
+		}
+
+		if t.ParentID != nil {
+			if err := tx.Bucket(taskChildrenBucket).Delete(pairKey(itob(*t.ParentID), t.ID)); err != nil {
+				return nil, err
+			}
+		}
+		if t.ProjectID != nil {
+			if err := tx.Bucket(projectTasksBucket).Delete(pairKey(itob(*t.ProjectID), t.ID)); err != nil {
+				return nil, err
+			}
+		}
+		if t.SeriesID != nil {
+			if err := tx.Bucket(seriesTasksBucket).Delete(pairKey(itob(*t.SeriesID), t.ID)); err != nil {
+				return nil, err
+			}
+		}
+		if err := tx.Bucket(tasksBucket).Delete(itob(t.ID)); err != nil {
+			return nil, err
+		}
+		if err := logActivityBolt(tx, models.TrashActivity(actor, t, nil)); err != nil {
+			return nil, err
+		}
+	}
+
+	// other tasks may still be blocked by the purged ones
+	var blocked [][]byte
+	err := tx.Bucket(taskBlockersBucket).ForEach(func(k, v []byte) error {
+		if doomed[btoi(k[8:])] {
+			blocked = append(blocked, append([]byte{}, k...))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range blocked {
+		if err := tx.Bucket(taskBlockersBucket).Delete(k); err != nil {
+			return nil, err
+		}
+	}
+	return attachments, nil
+}
+
+// deletePrefix removes every key of b starting with prefix
+func deletePrefix(b *bolt.Bucket, prefix []byte) error {
+	var keys [][]byte
+	c := b.Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		keys = append(keys, append([]byte{}, k...))
+	}
+	for _, k := range keys {
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+package database
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+	"todo-app/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltView is how views are persisted, models.View hides its organization
+// when marshalled
+type boltView struct {
+	models.View
+	OrgID uint `json:"org_id"`
+}
+
+func loadView(tx *bolt.Tx, id uint) (*models.View, error) {
+	v := tx.Bucket(viewsBucket).Get(itob(id))
+	if v == nil {
+		return nil, ErrRecordNotFound
+	}
+
+	var bv boltView
+	if err := json.Unmarshal(v, &bv); err != nil {
+		return nil, err
+	}
+	view := bv.View
+	view.OrgID = bv.OrgID
+	return &view, nil
+}
+
+func putView(tx *bolt.Tx, v *models.View) error {
+	data, err := json.Marshal(boltView{*v, v.OrgID})
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(viewsBucket).Put(itob(v.ID), data)
+}
+
+// ownView returns one of u's views in their organization
+func ownView(tx *bolt.Tx, u *models.User, id uint) (*models.View, error) {
+	if tx.Bucket(userViewsBucket).Get(pairKey(itob(u.ID), id)) == nil {
+		return nil, ErrRecordNotFound
+	}
+	view, err := loadView(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if view.OrgID != u.OrgID {
+		return nil, ErrRecordNotFound
+	}
+	return view, nil
+}
+
+// collaboratorsBolt returns the users that share a task outside the trash or
+// a project with u, u included
+func collaboratorsBolt(tx *bolt.Tx, u *models.User) (map[uint]bool, error) {
+	users := map[uint]bool{u.ID: true}
+	tasks := make(map[uint]bool)
+	for _, idTask := range scanIDs(tx.Bucket(userTasksBucket), itob(u.ID)) {
+		tasks[idTask] = true
+	}
+	for _, idProject := range scanIDs(tx.Bucket(userProjectsBucket), itob(u.ID)) {
+		for _, idUser := range scanIDs(tx.Bucket(projectMembersBucket), itob(idProject)) {
+			users[idUser] = true
+		}
+		for _, idTask := range scanIDs(tx.Bucket(projectTasksBucket), itob(idProject)) {
+			tasks[idTask] = true
+		}
+	}
+
+	for idTask := range tasks {
+		t, err := loadTask(tx, idTask)
+		if err == ErrRecordNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if t.DeletedAt != nil {
+			continue
+		}
+		for _, idUser := range scanIDs(tx.Bucket(taskUsersBucket), itob(idTask)) {
+			users[idUser] = true
+		}
+		if t.ProjectID != nil {
+			for _, idUser := range scanIDs(tx.Bucket(projectMembersBucket), itob(*t.ProjectID)) {
+				users[idUser] = true
+			}
+		}
+	}
+	return users, nil
+}
+
+// visibleViewsBolt returns u's views and the views shared with u ordered by
+// name
+func visibleViewsBolt(tx *bolt.Tx, u *models.User) ([]models.View, error) {
+	collaborators, err := collaboratorsBolt(tx, u)
+	if err != nil {
+		return nil, err
+	}
+	views := []models.View{}
+	for _, idUser := range sortedIDs(collaborators) {
+		for _, id := range scanIDs(tx.Bucket(userViewsBucket), itob(idUser)) {
+			view, err := loadView(tx, id)
+			if err != nil {
+				return nil, err
+			}
+			if view.OrgID != u.OrgID || (view.UserID != u.ID && !view.Shared) {
+				continue
+			}
+			views = append(views, *view)
+		}
+	}
+	sort.SliceStable(views, func(i, j int) bool {
+		if views[i].Name != views[j].Name {
+			return views[i].Name < views[j].Name
+		}
+		return views[i].ID < views[j].ID
+	})
+	return views, nil
+}
+
+func viewNameFreeBolt(tx *bolt.Tx, u *models.User, name string, except uint) error {
+	for _, id := range scanIDs(tx.Bucket(userViewsBucket), itob(u.ID)) {
+		view, err := ownView(tx, u, id)
+		if err == ErrRecordNotFound {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if view.Name == name && view.ID != except {
+			return ErrViewExists
+		}
+	}
+	return nil
+}
+
+func (s *BoltStore) CreateView(u *models.User, v models.View) (*models.View, error) {
+	err := s.DB.Update(func(tx *bolt.Tx) error {
+		if err := viewNameFreeBolt(tx, u, v.Name, 0); err != nil {
+			return err
+		}
+
+		seq, err := tx.Bucket(viewsBucket).NextSequence()
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		v.ID, v.UserID, v.OrgID, v.CreatedAt, v.UpdatedAt = uint(seq), u.ID, u.OrgID, now, now
+
+		if err := putView(tx, &v); err != nil {
+			return err
+		}
+		return tx.Bucket(userViewsBucket).Put(pairKey(itob(u.ID), v.ID), nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &v, nil
+}
+
+func (s *BoltStore) GetViews(u *models.User) (*[]models.View, error) {
+	var views []models.View
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		var err error
+		views, err = visibleViewsBolt(tx, u)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &views, nil
+}
+
+func (s *BoltStore) GetView(u *models.User, id int) (*models.View, error) {
+	var view *models.View
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		var err error
+		if view, err = loadView(tx, uint(id)); err != nil {
+			return err
+		}
+		if view.OrgID != u.OrgID {
+			return ErrRecordNotFound
+		}
+		if view.UserID == u.ID {
+			return nil
+		}
+		collaborators, err := collaboratorsBolt(tx, u)
+		if err != nil {
+			return err
+		}
+		if !view.Shared || !collaborators[view.UserID] {
+			return ErrRecordNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return view, nil
+}
+
+func (s *BoltStore) UpdateView(u *models.User, id int, v models.UpdateView) (*models.View, error) {
+	var view *models.View
+	err := s.DB.Update(func(tx *bolt.Tx) error {
+		var err error
+		if view, err = ownView(tx, u, uint(id)); err != nil {
+			return err
+		}
+
+		if v.Name != nil {
+			if err := viewNameFreeBolt(tx, u, *v.Name, view.ID); err != nil {
+				return err
+			}
+		}
+		v.Apply(view)
+		view.UpdatedAt = time.Now()
+		return putView(tx, view)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return view, nil
+}
+
+func (s *BoltStore) DeleteView(u *models.User, id int) error {
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		view, err := ownView(tx, u, uint(id))
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Bucket(userViewsBucket).Delete(pairKey(itob(u.ID), view.ID)); err != nil {
+			return err
+		}
+		return tx.Bucket(viewsBucket).Delete(itob(view.ID))
+	})
+}
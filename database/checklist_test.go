@@ -0,0 +1,109 @@
+package database
+
+import (
+	"fmt"
+	"testing"
+	"todo-app/models"
+)
+
+// wantChecklist checks the order of a task's checklist and that positions
+// count up from 0 without gaps
+func wantChecklist(t *testing.T, s TaskStore, u *models.User, idTask uint, want ...uint) []models.ChecklistItem {
+	t.Helper()
+	task, err := s.GetTask(u, int(idTask))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []uint
+	for i, item := range task.Checklist {
+		ids = append(ids, item.ID)
+		if item.Position != i {
+			t.Errorf("item %d is at position %d, want %d", item.ID, item.Position, i)
+		}
+	}
+	if fmt.Sprint(ids) != fmt.Sprint(want) {
+		t.Errorf("checklist: got %v, want %v", ids, want)
+	}
+	return task.Checklist
+}
+
+func itemIDs(items []models.ChecklistItem) []uint {
+	var ids []uint
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
+
+func TestChecklist(t *testing.T) {
+	eachStore(t, func(t *testing.T, s TaskStore) {
+		alice := addUsers(t, s, "alice", "dave")[0]
+		var tasks []uint
+		for _, title := range []string{"task", "other"} {
+			task, err := s.CreateTask(alice, models.Task{Title: title, Description: "d", Priority: "1"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			tasks = append(tasks, task.ID)
+		}
+		task := tasks[0]
+
+		add := func(idTask uint, text string, position *int) uint {
+			item, err := s.AddChecklistItem(alice, int(idTask), models.AddChecklistItem{Text: text, Position: position})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return item.ID
+		}
+		one, ten := 1, 10
+		a, b, c := add(task, "a", nil), add(task, "b", nil), add(task, "c", nil)
+		d := add(task, "d", &one)
+		// a position past the end appends
+		e := add(task, "e", &ten)
+		elsewhere := add(tasks[1], "elsewhere", nil)
+		wantChecklist(t, s, alice, task, a, d, b, c, e)
+
+		if item, err := s.ToggleChecklistItem(alice, int(task), int(b)); err != nil || !item.Done {
+			t.Fatalf("toggling b: got %v, %v", item, err)
+		}
+		if _, err := s.ToggleChecklistItem(alice, int(task), int(elsewhere)); err == nil || err.Error() != "record not found" {
+			t.Errorf("toggling an item of another task: got %v, want record not found", err)
+		}
+
+		// deleting closes the gap
+		if err := s.DeleteChecklistItem(alice, int(task), int(d)); err != nil {
+			t.Fatal(err)
+		}
+		wantChecklist(t, s, alice, task, a, b, c, e)
+		if err := s.DeleteChecklistItem(alice, int(task), int(d)); err == nil || err.Error() != "record not found" {
+			t.Errorf("deleting an item twice: got %v, want record not found", err)
+		}
+
+		for _, order := range [][]uint{
+			{},
+			{a, b, c},
+			{a, b, c, e, d},
+			{a, a, b, c},
+			{a, b, c, e, a},
+			{a, b, c, elsewhere},
+		} {
+			if _, err := s.ReorderChecklist(alice, int(task), order); err != ErrChecklistOrder {
+				t.Errorf("reordering to %v: got %v, want %v", order, err, ErrChecklistOrder)
+			}
+		}
+		wantChecklist(t, s, alice, task, a, b, c, e)
+
+		items, err := s.ReorderChecklist(alice, int(task), []uint{e, c, a, b})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fmt.Sprint(itemIDs(items)); got != fmt.Sprint([]uint{e, c, a, b}) {
+			t.Errorf("reordering returned %s", got)
+		}
+		checklist := wantChecklist(t, s, alice, task, e, c, a, b)
+		if !checklist[3].Done || checklist[0].Done {
+			t.Error("reordering changed which items are done")
+		}
+		wantChecklist(t, s, alice, tasks[1], elsewhere)
+	})
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"fmt"
+	"testing"
+	"todo-app/models"
+)
+
+func usernames(users []*models.User) []string {
+	var names []string
+	for _, u := range users {
+		names = append(names, u.Username)
+	}
+	return names
+}
+
+func TestCreateComment(t *testing.T) {
+	eachStore(t, func(t *testing.T, s TaskStore) {
+		users := addUsers(t, s, "alice", "bob", "dave")
+		alice, bob, dave := users[0], users[1], users[2]
+
+		var tasks []uint
+		for _, title := range []string{"first", "second"} {
+			task, err := s.CreateTask(alice, models.Task{Title: title, Description: "d", Priority: "1"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			tasks = append(tasks, task.ID)
+		}
+		first, second := int(tasks[0]), int(tasks[1])
+
+		// mentions are looked up in the author's organization, dave is in
+		// another one
+		if u, err := s.GetUserByUsername(alice.OrgID, "bob"); err != nil || u.ID != bob.ID {
+			t.Errorf("looking up bob in alice's organization: got %v, %v", u, err)
+		}
+		if _, err := s.GetUserByUsername(alice.OrgID, "dave"); err == nil || err.Error() != "record not found" {
+			t.Errorf("looking up dave in alice's organization: got %v, want record not found", err)
+		}
+
+		comment, err := s.CreateComment(alice, first, models.Comment{Body: "@bob @dave", Mentions: []*models.User{bob}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		reply, err := s.CreateComment(alice, first, models.Comment{ParentID: &comment.ID, Body: "reply"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// replies stay on the task of the comment they answer
+		for _, parent := range []uint{comment.ID, reply.ID + 1} {
+			if _, err := s.CreateComment(alice, second, models.Comment{ParentID: &parent, Body: "reply"}); err == nil || err.Error() != "record not found" {
+				t.Errorf("replying on another task to comment %d: got %v, want record not found", parent, err)
+			}
+		}
+		if comments, err := s.GetComments(alice, second); err != nil || len(comments) != 0 {
+			t.Errorf("comments on the second task: got %d, %v, want none", len(comments), err)
+		}
+
+		comments, err := s.GetComments(alice, first)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(comments) != 1 || len(comments[0].Replies) != 1 || comments[0].Replies[0].ID != reply.ID {
+			t.Fatalf("comments on the first task: got %d top level comments, want one with reply %d", len(comments), reply.ID)
+		}
+		if got := fmt.Sprint(usernames(comments[0].Mentions)); got != "[bob]" {
+			t.Errorf("mentions: got %s, want [bob]", got)
+		}
+		if _, err := s.CreateComment(dave, first, models.Comment{Body: "hi"}); err == nil || err.Error() != "record not found" {
+			t.Errorf("commenting on a task of another organization: got %v, want record not found", err)
+		}
+	})
+}
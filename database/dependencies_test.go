@@ -0,0 +1,109 @@
+package database
+
+import (
+	"testing"
+	"todo-app/models"
+)
+
+func TestBlockerCycles(t *testing.T) {
+	eachStore(t, func(t *testing.T, s TaskStore) {
+		alice := addUsers(t, s, "alice", "dave")[0]
+		var ids []int
+		for _, title := range []string{"a", "b", "c"} {
+			task, err := s.CreateTask(alice, models.Task{Title: title, Description: "d", Priority: "1"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, int(task.ID))
+		}
+		a, b, c := ids[0], ids[1], ids[2]
+
+		// c waits on b, which waits on a
+		if err := s.AddBlocker(alice, b, a); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.AddBlocker(alice, c, b); err != nil {
+			t.Fatal(err)
+		}
+		// adding a blocker twice is fine
+		if err := s.AddBlocker(alice, c, b); err != nil {
+			t.Errorf("adding a blocker again: %v", err)
+		}
+
+		tests := []struct {
+			name          string
+			task, blocker int
+		}{
+			{"self", a, a},
+			{"direct", a, b},
+			{"transitive", a, c},
+		}
+		for _, tt := range tests {
+			if err := s.AddBlocker(alice, tt.task, tt.blocker); err != ErrDependencyCycle {
+				t.Errorf("%s cycle: got %v, want %v", tt.name, err, ErrDependencyCycle)
+			}
+		}
+
+		// a shortcut along the chain is no cycle
+		if err := s.AddBlocker(alice, c, a); err != nil {
+			t.Errorf("c blocked by a: %v", err)
+		}
+
+		task, err := s.GetTask(alice, a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if task.Blocked {
+			t.Errorf("a is blocked by %v after the rejected cycles", task.BlockedBy)
+		}
+	})
+}
+
+func TestBlockedCompletion(t *testing.T) {
+	eachStore(t, func(t *testing.T, s TaskStore) {
+		alice := addUsers(t, s, "alice", "dave")[0]
+		task, err := s.CreateTask(alice, models.Task{Title: "task", Description: "d", Priority: "1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		blocker, err := s.CreateTask(alice, models.Task{Title: "blocker", Description: "d", Priority: "1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.AddBlocker(alice, int(task.ID), int(blocker.ID)); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := s.UpdateTask(alice, complete(true), int(task.ID)); err != ErrBlocked {
+			t.Errorf("completing a blocked task: got %v, want %v", err, ErrBlocked)
+		}
+		got, err := s.GetTask(alice, int(task.ID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Completed || !got.Blocked || len(got.BlockedBy) != 1 || got.BlockedBy[0] != blocker.ID {
+			t.Errorf("got completed %v, blocked by %v, want open and blocked by %d", got.Completed, got.BlockedBy, blocker.ID)
+		}
+
+		force := complete(true)
+		force.Force = true
+		if done, err := s.UpdateTask(alice, force, int(task.ID)); err != nil || !done.Completed {
+			t.Errorf("forcing the completion: got %v", err)
+		}
+
+		// a completed blocker blocks nothing
+		other, err := s.CreateTask(alice, models.Task{Title: "other", Description: "d", Priority: "1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.AddBlocker(alice, int(other.ID), int(blocker.ID)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.UpdateTask(alice, complete(true), int(blocker.ID)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.UpdateTask(alice, complete(true), int(other.ID)); err != nil {
+			t.Errorf("completing a task whose blocker is done: %v", err)
+		}
+	})
+}
@@ -0,0 +1,144 @@
+package database
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+	"todo-app/models"
+)
+
+func TestFilterSQL(t *testing.T) {
+	tests := []struct {
+		filter string
+		sql    string
+		args   []interface{}
+	}{
+		{`completed=true OR priority>=2 AND NOT due_at=null`,
+			"((tasks.completed IS NOT NULL AND tasks.completed = ?) OR ((tasks.priority IS NOT NULL AND tasks.priority >= ?) AND (NOT tasks.due_at IS NULL)))",
+			[]interface{}{true, models.Priority("2")}},
+		{`(completed=true OR priority>=2) AND parent_id!=null`,
+			"(((tasks.completed IS NOT NULL AND tasks.completed = ?) OR (tasks.priority IS NOT NULL AND tasks.priority >= ?)) AND tasks.parent_id IS NOT NULL)",
+			[]interface{}{true, models.Priority("2")}},
+		{`project_id!=3`, "(tasks.project_id IS NULL OR tasks.project_id <> ?)", []interface{}{uint(3)}},
+		// values only go in as parameters, LIKE wildcards escaped
+		{`title="x' OR '1'='1"`, "(tasks.title IS NOT NULL AND tasks.title = ?)", []interface{}{"x' OR '1'='1"}},
+		{`title~"50%_OFF\\"`, `LOWER(tasks.title) LIKE ? ESCAPE '\'`, []interface{}{`%50\%\_off\\%`}},
+	}
+	for _, tt := range tests {
+		expr, err := models.ParseFilter(tt.filter, time.UTC)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sql, args := filterSQL(expr)
+		if sql != tt.sql || !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("%s: got %s %v, want %s %v", tt.filter, sql, args, tt.sql, tt.args)
+		}
+	}
+
+	// only the columns of filterColumns ever reach the SQL
+	if sql, args := filterSQL(models.FilterCompare{Field: "1=1; DROP TABLE tasks", Op: models.FilterEq, Value: nil}); sql != "1 = 0" || args != nil {
+		t.Errorf("unknown field: got %s %v, want 1 = 0", sql, args)
+	}
+}
+
+// TestFilterParity checks that the sql store's WHERE clause selects the tasks
+// models.MatchFilter does in the other stores
+func TestFilterParity(t *testing.T) {
+	var results []string
+	eachStore(t, func(t *testing.T, s TaskStore) {
+		alice := addUsers(t, s, "alice", "dave")[0]
+		day := func(d int) *time.Time {
+			due := time.Date(2026, 3, d, 9, 0, 0, 0, time.UTC)
+			return &due
+		}
+		var ids []uint
+		for _, task := range []models.Task{
+			{Title: "Deploy API", Description: "it's done", Priority: "3", DueAt: day(1)},
+			{Title: "deploy docs", Description: "d", Priority: "2", DueAt: day(3)},
+			{Title: "50% off", Description: "d", Priority: "1"},
+			{Title: "500 off", Description: "under_score", Priority: "2", StartAt: day(2), DueAt: day(4)},
+			{Title: "notes", Description: `back\slash`, Priority: "1"},
+			{Title: "Café crème", Description: "déjà vu", Priority: "1"},
+		} {
+			created, err := s.CreateTask(alice, task)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, created.ID)
+		}
+		child, err := s.CreateSubtask(alice, int(ids[0]), models.Task{Title: "child", Description: "d", Priority: "2"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.UpdateTask(alice, complete(true), int(ids[1])); err != nil {
+			t.Fatal(err)
+		}
+
+		all, err := s.GetTasks(alice, models.TaskFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, filter := range []string{
+			`title~"deploy"`,
+			`title="deploy docs"`,
+			`title~"50%"`,
+			`title~"0_"`,
+			`description~"_"`,
+			`description~"\\"`,
+			`description="it's done"`,
+			// values are folded the same everywhere, stored text in lower case
+			// is all SQLite can fold outside of ASCII
+			`title~"CAFÉ"`,
+			`title~"é CR"`,
+			`description~"DÉJÀ"`,
+			`NOT description~"à"`,
+			`title="x' OR '1'='1"`,
+			`priority>=2 AND completed=false OR title~"notes"`,
+			`priority>=2 AND (completed=false OR title~"notes")`,
+			`NOT (priority=1 OR completed=true)`,
+			`due_at=null`,
+			`due_at!=null`,
+			`due_at<"2026-03-03"`,
+			`NOT due_at<"2026-03-03"`,
+			`due_at!="2026-03-01T09:00:00Z"`,
+			`due_at>="2026-03-01T09:00:00Z" AND start_at=null`,
+			`completed_at!=null`,
+			`NOT completed_at>"2000-01-01"`,
+			fmt.Sprintf(`parent_id=%d`, ids[0]),
+			fmt.Sprintf(`parent_id!=%d`, ids[0]),
+			fmt.Sprintf(`id>%d AND id<=%d`, ids[1], child.ID),
+			`created_at>"2000-01-01" AND updated_at<"2100-01-01"`,
+			`project_id=null AND archived_at=null`,
+		} {
+			expr, err := models.ParseFilter(filter, time.UTC)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var want []uint
+			for i := range all.Tasks {
+				if models.MatchFilter(expr, &all.Tasks[i]) {
+					want = append(want, all.Tasks[i].ID)
+				}
+			}
+			page, err := s.GetTasks(alice, models.TaskFilter{Expr: expr})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids := fmt.Sprint(pageIDs(page.Tasks))
+			if ids != fmt.Sprint(want) {
+				t.Errorf("%s: got %s, want %v", filter, ids, want)
+			}
+			got = append(got, filter+": "+ids)
+		}
+		results = append(results, fmt.Sprint(got))
+	})
+
+	// and every store agrees
+	for i := 1; i < len(results); i++ {
+		if results[i] != results[0] {
+			t.Errorf("stores disagree:\n%s\n%s", results[0], results[i])
+		}
+	}
+}
@@ -0,0 +1,831 @@
+package database
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+	"todo-app/models"
+)
+
+// MemoryStore implements TaskStore on plain maps that live only as long as
+// the process. It needs neither cgo nor a file, and nothing to migrate.
+type MemoryStore struct {
+	mu   sync.RWMutex
+	data *memData
+}
+
+var _ TaskStore = &MemoryStore{}
+
+// memData holds the records of a MemoryStore. Relations are kept both ways
+// where both are looked up, like the join buckets of the bolt store.
+type memData struct {
+	seq struct {
+		users, orgs, tasks, projects, labels, comments, attachments, items, views uint
+	}
+
+	users       map[uint]models.User
+	usernames   map[orgUsername]uint
+	orgs        map[uint]models.Organization
+	tasks       map[uint]models.Task
+	projects    map[uint]models.Project
+	labels      map[uint]models.Label
+	comments    map[uint]models.Comment
+	attachments map[uint]models.Attachment
+	items       map[uint]models.ChecklistItem
+	views       map[uint]models.View
+	// the activity with ID n is at n-1
+	activities []models.Activity
+
+	orgMembers, userOrgs         relation
+	taskUsers, userTasks         relation
+	projectMembers, userProjects relation
+	// parent ID to subtask IDs, task ID to blocker IDs, series ID to task IDs
+	taskChildren, taskBlockers, seriesTasks relation
+	projectTasks                            relation
+	userLabels, taskLabels, labelTasks      relation
+	// comment ID to mentioned user IDs
+	taskComments, commentMentions  relation
+	taskAttachments, taskChecklist relation
+	taskActivity, userViews        relation
+}
+
+// orgUsername keys the member of an organization with a username
+type orgUsername struct {
+	org  uint
+	name string
+}
+
+// relation links IDs to IDs, valued by a role for memberships
+type relation map[uint]map[uint]models.Role
+
+// ids returns the IDs linked to a in ascending order
+func (r relation) ids(a uint) []uint {
+	ids := make([]uint, 0, len(r[a]))
+	for id := range r[a] {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func (r relation) has(a, b uint) bool {
+	_, ok := r[a][b]
+	return ok
+}
+
+// memTx is a write to a MemoryStore, or a read when undo stays empty
+type memTx struct {
+	*memData
+	// undo takes back the changes made so far, last one first
+	undo []func()
+}
+
+func NewMemory() (*MemoryStore, error) {
+	return &MemoryStore{data: &memData{
+		users:       make(map[uint]models.User),
+		usernames:   make(map[orgUsername]uint),
+		orgs:        make(map[uint]models.Organization),
+		tasks:       make(map[uint]models.Task),
+		projects:    make(map[uint]models.Project),
+		labels:      make(map[uint]models.Label),
+		comments:    make(map[uint]models.Comment),
+		attachments: make(map[uint]models.Attachment),
+		items:       make(map[uint]models.ChecklistItem),
+		views:       make(map[uint]models.View),
+
+		orgMembers:      make(relation),
+		userOrgs:        make(relation),
+		taskUsers:       make(relation),
+		userTasks:       make(relation),
+		projectMembers:  make(relation),
+		userProjects:    make(relation),
+		taskChildren:    make(relation),
+		taskBlockers:    make(relation),
+		seriesTasks:     make(relation),
+		projectTasks:    make(relation),
+		userLabels:      make(relation),
+		taskLabels:      make(relation),
+		labelTasks:      make(relation),
+		taskComments:    make(relation),
+		commentMentions: make(relation),
+		taskAttachments: make(relation),
+		taskChecklist:   make(relation),
+		taskActivity:    make(relation),
+		userViews:       make(relation),
+	}}, nil
+}
+
+// view runs fn with the store locked for reading
+func (s *MemoryStore) view(fn func(tx *memTx) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return fn(&memTx{memData: s.data})
+}
+
+// update runs fn with the store locked for writing and takes back what fn
+// changed when it fails
+func (s *MemoryStore) update(fn func(tx *memTx) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tx := &memTx{memData: s.data}
+	if err := fn(tx); err != nil {
+		tx.rollbackTo(0)
+		return err
+	}
+	return nil
+}
+
+// rollbackTo undoes the changes made since the undo log was n long
+func (tx *memTx) rollbackTo(n int) {
+	for i := len(tx.undo) - 1; i >= n; i-- {
+		tx.undo[i]()
+	}
+	tx.undo = tx.undo[:n]
+}
+
+// remember queues the undoing of whatever is about to happen to m[key], m is
+// one of the maps of memData
+func (tx *memTx) remember(m, key interface{}) {
+	mv, kv := reflect.ValueOf(m), reflect.ValueOf(key)
+	// SetMapIndex deletes the key again when it wasn't there
+	old := mv.MapIndex(kv)
+	tx.undo = append(tx.undo, func() { mv.SetMapIndex(kv, old) })
+}
+
+// nextID counts up one of the sequences of memData, IDs aren't handed out
+// again after a rollback
+func nextID(seq *uint) uint {
+	*seq++
+	return *seq
+}
+
+func (tx *memTx) link(r relation, a, b uint, role models.Role) {
+	if r[a] == nil {
+		r[a] = make(map[uint]models.Role)
+	}
+	tx.remember(r[a], b)
+	r[a][b] = role
+}
+
+func (tx *memTx) unlink(r relation, a, b uint) {
+	if !r.has(a, b) {
+		return
+	}
+	tx.remember(r[a], b)
+	delete(r[a], b)
+}
+
+// unlinkAll removes every link from a
+func (tx *memTx) unlinkAll(r relation, a uint) {
+	for _, b := range r.ids(a) {
+		tx.unlink(r, a, b)
+	}
+}
+
+func (tx *memTx) getUser(id uint) (*models.User, error) {
+	u, ok := tx.users[id]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+	return &u, nil
+}
+
+// getTask returns a task that has not been deleted
+func (tx *memTx) getTask(id uint) (*models.Task, error) {
+	t, err := tx.loadTask(id)
+	if err != nil {
+		return nil, err
+	}
+	if t.DeletedAt != nil {
+		return nil, ErrRecordNotFound
+	}
+	return t, nil
+}
+
+// loadTask is getTask including tasks in the trash
+func (tx *memTx) loadTask(id uint) (*models.Task, error) {
+	t, ok := tx.tasks[id]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+	return &t, nil
+}
+
+// putTask saves t, which gets the version that follows the one of old
+func (tx *memTx) putTask(t *models.Task, old *models.Task) {
+	t.Version = 1
+	if old != nil {
+		t.Version = old.Version + 1
+	}
+
+	// relations are kept apart
+	stored := *t
+	stored.Users, stored.NextOccurrence, stored.Subtasks, stored.Progress = nil, nil, nil, nil
+	stored.Blocked, stored.BlockedBy, stored.Labels, stored.Attachments = false, nil, nil, nil
+	stored.Checklist = nil
+	tx.remember(tx.tasks, t.ID)
+	tx.tasks[t.ID] = stored
+	if t.SeriesID != nil {
+		tx.link(tx.seriesTasks, *t.SeriesID, t.ID, "")
+	}
+}
+
+// insertTask gives t an ID and saves it as a new task
+func (tx *memTx) insertTask(t *models.Task) {
+	now := time.Now()
+	t.ID, t.CreatedAt, t.UpdatedAt, t.DeletedAt = nextID(&tx.seq.tasks), now, now, nil
+	if t.Priority == "" {
+		t.Priority = "1"
+	}
+	t.StartSeries()
+
+	if t.ParentID != nil {
+		tx.link(tx.taskChildren, *t.ParentID, t.ID, "")
+	}
+	if t.ProjectID != nil {
+		tx.link(tx.projectTasks, *t.ProjectID, t.ID, "")
+	}
+	tx.putTask(t, nil)
+}
+
+// descendantTasks walks down the subtasks of a task one level at a time
+func (tx *memTx) descendantTasks(id uint) []models.Task {
+	var all []models.Task
+	parents := []uint{id}
+	for len(parents) > 0 {
+		var next []uint
+		for _, parent := range parents {
+			for _, childID := range tx.taskChildren.ids(parent) {
+				child, err := tx.getTask(childID)
+				if err != nil {
+					continue
+				}
+				all = append(all, *child)
+				next = append(next, child.ID)
+			}
+		}
+		parents = next
+	}
+	return all
+}
+
+// copyMembers shares task to with every member of task from
+func (tx *memTx) copyMembers(from, to uint) {
+	for _, id := range tx.taskUsers.ids(from) {
+		tx.addMember(id, to, tx.taskUsers[from][id])
+	}
+}
+
+// spawnNext creates the occurrence that follows a completed recurring task,
+// shared with the same members. Completing a reopened task spawns nothing
+// when its next occurrence exists already, even in the trash.
+func (tx *memTx) spawnNext(task *models.Task) *models.Task {
+	next := task.NextInSeries()
+	if next == nil {
+		return nil
+	}
+	for _, id := range tx.seriesTasks.ids(*next.SeriesID) {
+		if t, err := tx.loadTask(id); err == nil && t.Occurrence == next.Occurrence {
+			return nil
+		}
+	}
+
+	tx.insertTask(next)
+	tx.copyMembers(task.ID, next.ID)
+	return next
+}
+
+// memberRole returns the role idUser holds on task idTask
+func (tx *memTx) memberRole(idUser, idTask uint) (models.Role, error) {
+	role, ok := tx.userTasks[idUser][idTask]
+	if !ok {
+		return "", ErrRecordNotFound
+	}
+	return role, nil
+}
+
+// taskRole returns the higher of u's role on the task and on its project
+func (tx *memTx) taskRole(u *models.User, t *models.Task) (models.Role, error) {
+	if t.OrgID != u.OrgID {
+		return "", ErrRecordNotFound
+	}
+	role := tx.userTasks[u.ID][t.ID]
+	if t.ProjectID != nil {
+		if pr := tx.projectMembers[*t.ProjectID][u.ID]; !role.AtLeast(pr) {
+			role = pr
+		}
+	}
+	if role == "" {
+		return "", ErrRecordNotFound
+	}
+	return role, nil
+}
+
+// requireRole returns a RoleError unless u has role on every task, for the
+// subtasks a change to their parent goes on to
+func (tx *memTx) requireRole(u *models.User, tasks []models.Task, role models.Role) error {
+	for i := range tasks {
+		got, err := tx.taskRole(u, &tasks[i])
+		if err != nil && err != ErrRecordNotFound {
+			return err
+		}
+		if !got.AtLeast(role) {
+			return &RoleError{Role: role}
+		}
+	}
+	return nil
+}
+
+// memberTask returns task idTask if u is assigned to it or to its project
+func (tx *memTx) memberTask(u *models.User, idTask uint) (*models.Task, error) {
+	t, err := tx.getTask(idTask)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.taskRole(u, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (tx *memTx) taskUsersOf(idTask uint) []*models.User {
+	var users []*models.User
+	for _, id := range tx.taskUsers.ids(idTask) {
+		if user, err := tx.getUser(id); err == nil {
+			users = append(users, user)
+		}
+	}
+	return users
+}
+
+// demotesLastOwner reports whether taking idUser's owner role away would
+// leave the task without owners
+func (tx *memTx) demotesLastOwner(idUser, idTask uint) bool {
+	if tx.taskUsers[idTask][idUser] != models.RoleOwner {
+		return false
+	}
+
+	owners := 0
+	for _, role := range tx.taskUsers[idTask] {
+		if role == models.RoleOwner {
+			owners++
+		}
+	}
+	return owners <= 1
+}
+
+func (tx *memTx) addMember(idUser, idTask uint, role models.Role) {
+	tx.link(tx.userTasks, idUser, idTask, role)
+	tx.link(tx.taskUsers, idTask, idUser, role)
+}
+
+func (tx *memTx) removeMember(idUser, idTask uint) {
+	tx.unlink(tx.userTasks, idUser, idTask)
+	tx.unlink(tx.taskUsers, idTask, idUser)
+}
+
+func (s *MemoryStore) AddUser(u models.User) (*models.User, error) {
+	err := s.update(func(tx *memTx) error {
+		now := time.Now()
+		u.ID, u.CreatedAt, u.UpdatedAt = nextID(&tx.seq.users), now, now
+		stored := u
+		stored.Tasks, stored.OrgID = nil, 0
+		tx.remember(tx.users, u.ID)
+		tx.users[u.ID] = stored
+
+		// every user starts out with their own organization
+		org := tx.insertOrg(models.Organization{Name: u.Username})
+		u.OrgID = org.ID
+		tx.putOrgMember(org.ID, u.ID, models.RoleOwner)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &u, nil
+}
+
+func (s *MemoryStore) GetUserByUsername(idOrg uint, username string) (*models.User, error) {
+	var user *models.User
+	err := s.view(func(tx *memTx) error {
+		if idOrg != 0 {
+			id, ok := tx.usernames[orgUsername{idOrg, username}]
+			if !ok {
+				return ErrRecordNotFound
+			}
+			var err error
+			user, err = tx.getUser(id)
+			return err
+		}
+
+		for _, u := range tx.users {
+			if u.Username != username {
+				continue
+			}
+			if user != nil {
+				return ErrAmbiguousUsername
+			}
+			u := u
+			user = &u
+		}
+		if user == nil {
+			return ErrRecordNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *MemoryStore) GetUserById(id uint) (*models.User, error) {
+	var user *models.User
+	err := s.view(func(tx *memTx) error {
+		var err error
+		user, err = tx.getUser(id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *MemoryStore) CreateTask(u *models.User, t models.Task) (*models.Task, error) {
+	err := s.update(func(tx *memTx) error {
+		tx.createTask(u, &t)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// createTask makes u the owner of a new task
+func (tx *memTx) createTask(u *models.User, t *models.Task) {
+	t.OrgID = u.OrgID
+	tx.insertTask(t)
+	tx.addMember(u.ID, t.ID, models.RoleOwner)
+	tx.logActivity(models.TaskActivity(u, nil, t))
+}
+
+func (s *MemoryStore) GetTasks(u *models.User, filter models.TaskFilter) (*models.TaskPage, error) {
+	var page *models.TaskPage
+	err := s.view(func(tx *memTx) error {
+		ids := tx.visibleIDs(u)
+		if filter.ProjectID != nil {
+			ids = intersect(ids, tx.projectTasks.ids(*filter.ProjectID))
+		}
+		if len(filter.Labels) > 0 {
+			ids = intersect(ids, tx.labelledTasks(u, filter))
+		}
+
+		now := time.Now().UTC()
+		tasks := []models.Task{}
+		for _, id := range sortedIDs(ids) {
+			t, err := tx.getTask(id)
+			if err != nil {
+				continue
+			}
+			if filter.Completed != nil && t.Completed != *filter.Completed {
+				continue
+			}
+			if filter.Priority != "" && string(t.Priority) != filter.Priority {
+				continue
+			}
+			if t.OrgID != u.OrgID || !matchesSchedule(t, filter, now) {
+				continue
+			}
+			if t.ArchivedAt != nil && !filter.IncludeArchived {
+				continue
+			}
+			if filter.Expr != nil && !models.MatchFilter(filter.Expr, t) {
+				continue
+			}
+			tasks = append(tasks, *t)
+		}
+
+		page = models.NewTaskPage(pageTasks(tasks, filter), filter)
+		for i := range page.Tasks {
+			tx.markBlocked(&page.Tasks[i])
+			tx.markLabels(u, &page.Tasks[i])
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// visibleIDs returns the tasks u is a member of, directly or through their
+// project. The caller still has to check the organization.
+func (tx *memTx) visibleIDs(u *models.User) map[uint]bool {
+	ids := make(map[uint]bool)
+	for id := range tx.userTasks[u.ID] {
+		ids[id] = true
+	}
+	for idProject := range tx.userProjects[u.ID] {
+		for id := range tx.projectTasks[idProject] {
+			ids[id] = true
+		}
+	}
+	return ids
+}
+
+func (s *MemoryStore) GetTask(u *models.User, id int) (*models.Task, error) {
+	var task *models.Task
+	err := s.view(func(tx *memTx) error {
+		var err error
+		if task, err = tx.memberTask(u, uint(id)); err != nil {
+			return err
+		}
+		task.Users = tx.taskUsersOf(task.ID)
+		task.Progress = models.NewProgress(tx.descendantTasks(task.ID))
+		tx.markBlocked(task)
+		tx.markLabels(u, task)
+		task.Attachments = tx.taskAttachmentsOf(task.ID)
+		task.Checklist = tx.checklist(task.ID)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+func (s *MemoryStore) GetTaskRole(u *models.User, idTask int) (models.Role, error) {
+	var role models.Role
+	err := s.view(func(tx *memTx) error {
+		t, err := tx.getTask(uint(idTask))
+		if err != nil {
+			return err
+		}
+		role, err = tx.taskRole(u, t)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return role, nil
+}
+
+func (s *MemoryStore) AddUserToTask(u *models.User, idUser, idTask int, role models.Role) (*models.User, *models.Task, error) {
+	var user *models.User
+	var task *models.Task
+	err := s.update(func(tx *memTx) error {
+		var err error
+		if user, err = tx.getUser(uint(idUser)); err != nil {
+			return err
+		}
+		if task, err = tx.getTask(uint(idTask)); err != nil {
+			return err
+		}
+		// tasks are only shared within their organization
+		if tx.orgMembers[task.OrgID][user.ID] == "" {
+			return ErrRecordNotFound
+		}
+		before := tx.taskUsers[task.ID][user.ID]
+		if before == role {
+			return nil
+		}
+		if role != models.RoleOwner && tx.demotesLastOwner(user.ID, task.ID) {
+			return ErrLastOwner
+		}
+		tx.addMember(user.ID, task.ID, role)
+		tx.logActivity(models.MemberActivity(u, task, user.ID, before, role))
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return user, task, nil
+}
+
+func (s *MemoryStore) RemoveUserFromTask(u *models.User, idUser, idTask int) (*models.User, *models.Task, error) {
+	var user *models.User
+	var task *models.Task
+	err := s.update(func(tx *memTx) error {
+		var err error
+		if user, err = tx.getUser(uint(idUser)); err != nil {
+			return err
+		}
+		if task, err = tx.getTask(uint(idTask)); err != nil {
+			return err
+		}
+		before, err := tx.memberRole(user.ID, task.ID)
+		if err != nil {
+			return err
+		}
+		if tx.demotesLastOwner(user.ID, task.ID) {
+			return ErrLastOwner
+		}
+		tx.removeMember(user.ID, task.ID)
+		tx.logActivity(models.MemberActivity(u, task, user.ID, before, ""))
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return user, task, nil
+}
+
+func (s *MemoryStore) UpdateTask(u *models.User, t models.UpdateTask, idTask int) (*models.Task, error) {
+	var task *models.Task
+	err := s.update(func(tx *memTx) error {
+		var err error
+		task, err = tx.updateTask(u, t, idTask)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+func (tx *memTx) updateTask(u *models.User, t models.UpdateTask, idTask int) (*models.Task, error) {
+	old, err := tx.memberTask(u, uint(idTask))
+	if err != nil {
+		return nil, err
+	}
+	if !versionMatches(t.IfMatch, old.Version) {
+		return nil, ErrVersionMismatch
+	}
+
+	updated := *old
+	if err := t.Apply(&updated); err != nil {
+		return nil, err
+	}
+	if updated.Completed && !old.Completed && !t.Force {
+		if tx.markBlocked(&updated); updated.Blocked {
+			return nil, ErrBlocked
+		}
+	}
+	if updated.Completed != old.Completed {
+		updated.CompletedAt = nil
+		if updated.Completed {
+			now := time.Now()
+			updated.CompletedAt = &now
+		}
+	}
+	updated.StartSeries()
+	updated.UpdatedAt = time.Now()
+
+	task := &updated
+	tx.putTask(task, old)
+	tx.logActivity(models.TaskActivity(u, old, task))
+
+	if task.Completed && !old.Completed {
+		if task.NextOccurrence = tx.spawnNext(task); task.NextOccurrence != nil {
+			tx.logActivity(models.TaskActivity(u, nil, task.NextOccurrence))
+		}
+
+		// completing a task completes everything below it
+		if err := tx.completeDescendants(u, task, t.Force); err != nil {
+			return nil, err
+		}
+	}
+	return task, nil
+}
+
+// completeDescendants completes the open tasks below task as completing each
+// one directly would. u has to be an editor of every one, none may wait on a
+// blocker outside the subtree unless force, and recurring ones spawn their
+// next occurrence.
+func (tx *memTx) completeDescendants(u *models.User, task *models.Task, force bool) error {
+	children := tx.descendantTasks(task.ID)
+	subtree := map[uint]bool{task.ID: true}
+	var open []models.Task
+	for _, child := range children {
+		subtree[child.ID] = true
+		if !child.Completed {
+			open = append(open, child)
+		}
+	}
+
+	if err := tx.requireRole(u, open, models.RoleEditor); err != nil {
+		return err
+	}
+	for i := range open {
+		if force {
+			break
+		}
+		tx.markBlocked(&open[i])
+		for _, id := range open[i].BlockedBy {
+			if !subtree[id] {
+				return ErrBlocked
+			}
+		}
+	}
+
+	for _, child := range open {
+		child := child
+		completed := child
+		completed.Completed, completed.CompletedAt = true, task.CompletedAt
+		completed.UpdatedAt = task.UpdatedAt
+		tx.putTask(&completed, &child)
+		tx.logActivity(models.TaskActivity(u, &child, &completed))
+		if next := tx.spawnNext(&completed); next != nil {
+			tx.logActivity(models.TaskActivity(u, nil, next))
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) CreateSubtask(u *models.User, idParent int, t models.Task) (*models.Task, error) {
+	err := s.update(func(tx *memTx) error {
+		parent, err := tx.memberTask(u, uint(idParent))
+		if err != nil {
+			return err
+		}
+
+		t.ParentID, t.ProjectID, t.OrgID = &parent.ID, parent.ProjectID, parent.OrgID
+		tx.insertTask(&t)
+		tx.copyMembers(parent.ID, t.ID)
+		tx.logActivity(models.TaskActivity(u, nil, &t))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+func (s *MemoryStore) GetTaskTree(u *models.User, id int) (*models.Task, error) {
+	var task *models.Task
+	err := s.view(func(tx *memTx) error {
+		var err error
+		if task, err = tx.memberTask(u, uint(id)); err != nil {
+			return err
+		}
+
+		descendants := tx.descendantTasks(task.ID)
+		task.Progress = models.NewProgress(descendants)
+		models.BuildTree(task, descendants)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+func (s *MemoryStore) DeleteTask(u *models.User, idTask int, ifMatch []uint) (*models.Task, error) {
+	var task *models.Task
+	err := s.update(func(tx *memTx) error {
+		var err error
+		task, err = tx.deleteTask(u, idTask, ifMatch)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+func (tx *memTx) deleteTask(u *models.User, idTask int, ifMatch []uint) (*models.Task, error) {
+	old, err := tx.memberTask(u, uint(idTask))
+	if err != nil {
+		return nil, err
+	}
+	if !versionMatches(ifMatch, old.Version) {
+		return nil, ErrVersionMismatch
+	}
+
+	// soft delete like the other stores, subtasks go along with their parent
+	// if u owns them too
+	children := tx.descendantTasks(old.ID)
+	if err := tx.requireRole(u, children, models.RoleOwner); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, child := range children {
+		child := child
+		deleted := child
+		deleted.DeletedAt = &now
+		tx.putTask(&deleted, &child)
+		tx.logActivity(models.TaskActivity(u, &child, nil))
+	}
+
+	deleted := *old
+	deleted.DeletedAt = &now
+	tx.putTask(&deleted, old)
+	tx.logActivity(models.TaskActivity(u, old, nil))
+	return &deleted, nil
+}
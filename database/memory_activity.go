@@ -0,0 +1,89 @@
+package database
+
+import (
+	"time"
+	"todo-app/models"
+)
+
+// logActivity appends a to the activity log, updates that changed nothing
+// are left out
+func (tx *memTx) logActivity(a models.Activity) {
+	if a.Action == models.ActivityUpdate && len(a.Changes) == 0 {
+		return
+	}
+
+	n := len(tx.activities)
+	a.ID, a.CreatedAt, a.Actor = uint(n+1), time.Now(), nil
+	tx.activities = append(tx.activities, a)
+	tx.undo = append(tx.undo, func() { tx.activities = tx.activities[:n] })
+	tx.link(tx.taskActivity, a.TaskID, a.ID, "")
+}
+
+// activity returns the activity with ID id along with its actor
+func (tx *memTx) activity(id uint) models.Activity {
+	a := tx.activities[id-1]
+	if actor, err := tx.getUser(a.ActorID); err == nil {
+		a.Actor = actor
+	}
+	return a
+}
+
+func (s *MemoryStore) GetTaskHistory(u *models.User, idTask int) (*[]models.Activity, error) {
+	activities := []models.Activity{}
+	err := s.view(func(tx *memTx) error {
+		task, err := tx.memberTask(u, uint(idTask))
+		if err != nil {
+			return err
+		}
+
+		for _, id := range tx.taskActivity.ids(task.ID) {
+			activities = append(activities, tx.activity(id))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &activities, nil
+}
+
+func (s *MemoryStore) GetActivity(u *models.User, filter models.ActivityFilter) (*[]models.Activity, error) {
+	activities := []models.Activity{}
+	err := s.view(func(tx *memTx) error {
+		id := uint(len(tx.activities))
+		if filter.Before != 0 && filter.Before <= id {
+			id = filter.Before - 1
+		}
+		for ; id > 0 && (filter.Limit <= 0 || len(activities) < filter.Limit); id-- {
+			a := tx.activity(id)
+			if tx.activityVisible(u, &a) {
+				activities = append(activities, a)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &activities, nil
+}
+
+// activityVisible reports whether a belongs in u's feed, deleted tasks stay
+// in the feed of their members
+func (tx *memTx) activityVisible(u *models.User, a *models.Activity) bool {
+	if a.OrgID != u.OrgID {
+		return false
+	}
+	if a.ActorID == u.ID || (a.MemberID != nil && *a.MemberID == u.ID) {
+		return true
+	}
+
+	t, err := tx.loadTask(a.TaskID)
+	if err != nil {
+		return false
+	}
+	_, err = tx.taskRole(u, t)
+	return err == nil
+}
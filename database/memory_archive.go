@@ -0,0 +1,121 @@
+package database
+
+import (
+	"time"
+	"todo-app/models"
+)
+
+func (s *MemoryStore) ArchiveTask(u *models.User, idTask int) (*models.Task, error) {
+	err := s.update(func(tx *memTx) error {
+		task, err := tx.memberTask(u, uint(idTask))
+		if err != nil {
+			return err
+		}
+
+		// u has to edit every subtask the archive reaches, like the task itself
+		var changed []models.Task
+		for _, old := range append(tx.descendantTasks(task.ID), *task) {
+			if old.ArchivedAt == nil {
+				changed = append(changed, old)
+			}
+		}
+		if err := tx.requireRole(u, changed, models.RoleEditor); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, old := range changed {
+			old := old
+			archived := old
+			archived.ArchivedAt, archived.UpdatedAt = &now, now
+			tx.putTask(&archived, &old)
+			tx.logActivity(models.TaskActivity(u, &old, &archived))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetTask(u, idTask)
+}
+
+func (s *MemoryStore) UnarchiveTask(u *models.User, idTask int) (*models.Task, error) {
+	err := s.update(func(tx *memTx) error {
+		task, err := tx.memberTask(u, uint(idTask))
+		if err != nil {
+			return err
+		}
+		if task.ArchivedAt == nil {
+			return nil
+		}
+
+		var changed []models.Task
+		for _, old := range append(tx.descendantTasks(task.ID), *task) {
+			// subtasks archived on their own stay archived
+			if old.ArchivedAt != nil && old.ArchivedAt.Equal(*task.ArchivedAt) {
+				changed = append(changed, old)
+			}
+		}
+		if err := tx.requireRole(u, changed, models.RoleEditor); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, old := range changed {
+			old := old
+			unarchived := old
+			unarchived.ArchivedAt, unarchived.UpdatedAt = nil, now
+			tx.putTask(&unarchived, &old)
+			tx.logActivity(models.TaskActivity(u, &old, &unarchived))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetTask(u, idTask)
+}
+
+func (s *MemoryStore) ArchiveCompleted(cutoff time.Time) (int, error) {
+	var count int
+	err := s.update(func(tx *memTx) error {
+		now := time.Now()
+		for _, id := range sortedIDs(tx.taskIDs()) {
+			old := tx.tasks[id]
+			if !old.Completed || old.DeletedAt != nil || old.ArchivedAt != nil {
+				continue
+			}
+			// tasks completed before completed_at existed go by their last update
+			completedAt := old.UpdatedAt
+			if old.CompletedAt != nil {
+				completedAt = *old.CompletedAt
+			}
+			if !completedAt.Before(cutoff) {
+				continue
+			}
+
+			archived := old
+			archived.ArchivedAt, archived.UpdatedAt = &now, now
+			tx.putTask(&archived, &old)
+			tx.logActivity(models.TaskActivity(nil, &old, &archived))
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// taskIDs returns every task, deleted ones included
+func (tx *memTx) taskIDs() map[uint]bool {
+	ids := make(map[uint]bool, len(tx.tasks))
+	for id := range tx.tasks {
+		ids[id] = true
+	}
+	return ids
+}
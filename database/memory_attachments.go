@@ -0,0 +1,104 @@
+package database
+
+import (
+	"time"
+	"todo-app/models"
+)
+
+func (tx *memTx) getAttachment(idTask, id uint) (*models.Attachment, error) {
+	if !tx.taskAttachments.has(idTask, id) {
+		return nil, ErrRecordNotFound
+	}
+	a, ok := tx.attachments[id]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+	return &a, nil
+}
+
+// taskAttachmentsOf returns the attachments of a task in upload order
+func (tx *memTx) taskAttachmentsOf(idTask uint) []models.Attachment {
+	attachments := []models.Attachment{}
+	for _, id := range tx.taskAttachments.ids(idTask) {
+		attachments = append(attachments, tx.attachments[id])
+	}
+	return attachments
+}
+
+func (s *MemoryStore) CreateAttachment(u *models.User, idTask int, a models.Attachment) (*models.Attachment, error) {
+	err := s.update(func(tx *memTx) error {
+		task, err := tx.memberTask(u, uint(idTask))
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		a.ID, a.TaskID, a.UserID, a.CreatedAt, a.UpdatedAt = nextID(&tx.seq.attachments), task.ID, u.ID, now, now
+		tx.remember(tx.attachments, a.ID)
+		tx.attachments[a.ID] = a
+		tx.link(tx.taskAttachments, task.ID, a.ID, "")
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &a, nil
+}
+
+func (s *MemoryStore) GetAttachments(u *models.User, idTask int) (*[]models.Attachment, error) {
+	var attachments []models.Attachment
+	err := s.view(func(tx *memTx) error {
+		task, err := tx.memberTask(u, uint(idTask))
+		if err != nil {
+			return err
+		}
+		attachments = tx.taskAttachmentsOf(task.ID)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &attachments, nil
+}
+
+func (s *MemoryStore) GetAttachment(u *models.User, idTask, id int) (*models.Attachment, error) {
+	var attachment *models.Attachment
+	err := s.view(func(tx *memTx) error {
+		task, err := tx.memberTask(u, uint(idTask))
+		if err != nil {
+			return err
+		}
+		attachment, err = tx.getAttachment(task.ID, uint(id))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return attachment, nil
+}
+
+func (s *MemoryStore) DeleteAttachment(u *models.User, idTask, id int) (*models.Attachment, error) {
+	var attachment *models.Attachment
+	err := s.update(func(tx *memTx) error {
+		task, err := tx.memberTask(u, uint(idTask))
+		if err != nil {
+			return err
+		}
+		if attachment, err = tx.getAttachment(task.ID, uint(id)); err != nil {
+			return err
+		}
+
+		tx.unlink(tx.taskAttachments, task.ID, attachment.ID)
+		tx.remember(tx.attachments, attachment.ID)
+		delete(tx.attachments, attachment.ID)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return attachment, nil
+}
@@ -0,0 +1,53 @@
+package database
+
+import "todo-app/models"
+
+// RunBatch takes back the writes of a failed operation on its own, or of the
+// whole batch when it is atomic
+func (s *MemoryStore) RunBatch(u *models.User, ops []models.BatchOp, atomic bool) ([]models.BatchResult, error) {
+	var results []models.BatchResult
+	err := s.update(func(tx *memTx) error {
+		for _, op := range ops {
+			savepoint := len(tx.undo)
+			task, err := tx.runBatchOp(u, op)
+			results = append(results, models.BatchResult{Task: task, Err: err})
+			if err != nil && atomic {
+				return errBatchFailed
+			}
+			if err != nil {
+				tx.rollbackTo(savepoint)
+			}
+		}
+		return nil
+	})
+	if err != nil && err != errBatchFailed {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (tx *memTx) runBatchOp(u *models.User, op models.BatchOp) (*models.Task, error) {
+	if op.Op == models.BatchCreate {
+		t := *op.Task
+		tx.createTask(u, &t)
+		return &t, nil
+	}
+
+	task, err := tx.getTask(uint(op.ID))
+	if err != nil {
+		return nil, err
+	}
+	role, err := tx.taskRole(u, task)
+	if err != nil {
+		return nil, err
+	}
+	if min := op.Role(); !role.AtLeast(min) {
+		return nil, &RoleError{Role: min}
+	}
+
+	if op.Op == models.BatchDelete {
+		return tx.deleteTask(u, op.ID, op.Update().IfMatch)
+	}
+	return tx.updateTask(u, op.Update(), op.ID)
+}
@@ -0,0 +1,145 @@
+package database
+
+import (
+	"sort"
+	"time"
+	"todo-app/models"
+)
+
+func (tx *memTx) getChecklistItem(idTask, id uint) (*models.ChecklistItem, error) {
+	if !tx.taskChecklist.has(idTask, id) {
+		return nil, ErrRecordNotFound
+	}
+	item, ok := tx.items[id]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+	return &item, nil
+}
+
+func (tx *memTx) putChecklistItem(item *models.ChecklistItem) {
+	tx.remember(tx.items, item.ID)
+	tx.items[item.ID] = *item
+}
+
+// checklist returns the items of a task ordered by position
+func (tx *memTx) checklist(idTask uint) []models.ChecklistItem {
+	items := []models.ChecklistItem{}
+	for _, id := range tx.taskChecklist.ids(idTask) {
+		items = append(items, tx.items[id])
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Position < items[j].Position })
+	return items
+}
+
+// renumber stores items with positions following their order
+func (tx *memTx) renumber(items []models.ChecklistItem) {
+	for i := range items {
+		if items[i].Position == i {
+			continue
+		}
+		items[i].Position, items[i].UpdatedAt = i, time.Now()
+		tx.putChecklistItem(&items[i])
+	}
+}
+
+func (s *MemoryStore) AddChecklistItem(u *models.User, idTask int, item models.AddChecklistItem) (*models.ChecklistItem, error) {
+	var created models.ChecklistItem
+	err := s.update(func(tx *memTx) error {
+		task, err := tx.memberTask(u, uint(idTask))
+		if err != nil {
+			return err
+		}
+		items := tx.checklist(task.ID)
+
+		now := time.Now()
+		created = models.ChecklistItem{ID: nextID(&tx.seq.items), CreatedAt: now, UpdatedAt: now, TaskID: task.ID, Text: item.Text}
+
+		position := len(items)
+		if item.Position != nil && *item.Position < len(items) {
+			position = *item.Position
+		}
+		items = append(items[:position], append([]models.ChecklistItem{created}, items[position:]...)...)
+		tx.renumber(items)
+		created.Position = position
+		tx.putChecklistItem(&created)
+		tx.link(tx.taskChecklist, task.ID, created.ID, "")
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+func (s *MemoryStore) ToggleChecklistItem(u *models.User, idTask, id int) (*models.ChecklistItem, error) {
+	var item *models.ChecklistItem
+	err := s.update(func(tx *memTx) error {
+		task, err := tx.memberTask(u, uint(idTask))
+		if err != nil {
+			return err
+		}
+		if item, err = tx.getChecklistItem(task.ID, uint(id)); err != nil {
+			return err
+		}
+
+		item.Done, item.UpdatedAt = !item.Done, time.Now()
+		tx.putChecklistItem(item)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+func (s *MemoryStore) ReorderChecklist(u *models.User, idTask int, order []uint) ([]models.ChecklistItem, error) {
+	var items []models.ChecklistItem
+	err := s.update(func(tx *memTx) error {
+		task, err := tx.memberTask(u, uint(idTask))
+		if err != nil {
+			return err
+		}
+		current := tx.checklist(task.ID)
+		if !sameItems(current, order) {
+			return ErrChecklistOrder
+		}
+
+		byID := make(map[uint]models.ChecklistItem)
+		for _, item := range current {
+			byID[item.ID] = item
+		}
+		for _, id := range order {
+			items = append(items, byID[id])
+		}
+		tx.renumber(items)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func (s *MemoryStore) DeleteChecklistItem(u *models.User, idTask, id int) error {
+	return s.update(func(tx *memTx) error {
+		task, err := tx.memberTask(u, uint(idTask))
+		if err != nil {
+			return err
+		}
+		item, err := tx.getChecklistItem(task.ID, uint(id))
+		if err != nil {
+			return err
+		}
+
+		tx.unlink(tx.taskChecklist, task.ID, item.ID)
+		tx.remember(tx.items, item.ID)
+		delete(tx.items, item.ID)
+		// close the gap
+		tx.renumber(tx.checklist(task.ID))
+		return nil
+	})
+}
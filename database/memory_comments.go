@@ -0,0 +1,172 @@
+package database
+
+import (
+	"time"
+	"todo-app/models"
+)
+
+// getComment returns a comment on task idTask with its author and mentions
+func (tx *memTx) getComment(idTask, id uint) (*models.Comment, error) {
+	if !tx.taskComments.has(idTask, id) {
+		return nil, ErrRecordNotFound
+	}
+	c, ok := tx.comments[id]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+
+	if author, err := tx.getUser(c.UserID); err == nil {
+		c.Author = author
+	}
+	for _, idUser := range tx.commentMentions.ids(c.ID) {
+		if user, err := tx.getUser(idUser); err == nil {
+			c.Mentions = append(c.Mentions, user)
+		}
+	}
+	return &c, nil
+}
+
+func (tx *memTx) putComment(c *models.Comment) {
+	stored := *c
+	stored.Author, stored.Mentions, stored.Replies = nil, nil, nil
+	tx.remember(tx.comments, c.ID)
+	tx.comments[c.ID] = stored
+}
+
+// putMentions replaces the users mentioned in a comment
+func (tx *memTx) putMentions(idComment uint, users []*models.User) {
+	tx.unlinkAll(tx.commentMentions, idComment)
+	for _, user := range users {
+		tx.link(tx.commentMentions, idComment, user.ID, "")
+	}
+}
+
+// deleteComment removes a comment and its mentions, replies are left to the
+// caller
+func (tx *memTx) deleteComment(idTask, id uint) {
+	tx.putMentions(id, nil)
+	tx.unlink(tx.taskComments, idTask, id)
+	tx.remember(tx.comments, id)
+	delete(tx.comments, id)
+}
+
+func (s *MemoryStore) CreateComment(u *models.User, idTask int, c models.Comment) (*models.Comment, error) {
+	err := s.update(func(tx *memTx) error {
+		task, err := tx.memberTask(u, uint(idTask))
+		if err != nil {
+			return err
+		}
+		// replies stay on the task of the comment they answer
+		if c.ParentID != nil {
+			if _, err := tx.getComment(task.ID, *c.ParentID); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		c.ID, c.TaskID, c.UserID, c.CreatedAt, c.UpdatedAt = nextID(&tx.seq.comments), task.ID, u.ID, now, now
+		tx.putComment(&c)
+		tx.putMentions(c.ID, c.Mentions)
+		tx.link(tx.taskComments, task.ID, c.ID, "")
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c.Author = u
+	return &c, nil
+}
+
+func (s *MemoryStore) GetComments(u *models.User, idTask int) ([]*models.Comment, error) {
+	var comments []models.Comment
+	err := s.view(func(tx *memTx) error {
+		task, err := tx.memberTask(u, uint(idTask))
+		if err != nil {
+			return err
+		}
+
+		for _, id := range tx.taskComments.ids(task.ID) {
+			c, err := tx.getComment(task.ID, id)
+			if err != nil {
+				return err
+			}
+			comments = append(comments, *c)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return models.CommentThreads(comments), nil
+}
+
+func (s *MemoryStore) GetComment(u *models.User, idTask, id int) (*models.Comment, error) {
+	var comment *models.Comment
+	err := s.view(func(tx *memTx) error {
+		task, err := tx.memberTask(u, uint(idTask))
+		if err != nil {
+			return err
+		}
+		comment, err = tx.getComment(task.ID, uint(id))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return comment, nil
+}
+
+func (s *MemoryStore) UpdateComment(u *models.User, idTask, id int, c models.Comment) (*models.Comment, error) {
+	var comment *models.Comment
+	err := s.update(func(tx *memTx) error {
+		task, err := tx.memberTask(u, uint(idTask))
+		if err != nil {
+			return err
+		}
+		if comment, err = tx.getComment(task.ID, uint(id)); err != nil {
+			return err
+		}
+		if comment.UserID != u.ID {
+			return ErrRecordNotFound
+		}
+
+		comment.Body, comment.Mentions, comment.UpdatedAt = c.Body, c.Mentions, time.Now()
+		tx.putComment(comment)
+		tx.putMentions(comment.ID, c.Mentions)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return comment, nil
+}
+
+func (s *MemoryStore) DeleteComment(u *models.User, idTask, id int) error {
+	return s.update(func(tx *memTx) error {
+		task, err := tx.memberTask(u, uint(idTask))
+		if err != nil {
+			return err
+		}
+		comment, err := tx.getComment(task.ID, uint(id))
+		if err != nil {
+			return err
+		}
+
+		// replies go along with the comment they answer, which always
+		// comes before them
+		doomed := map[uint]bool{comment.ID: true}
+		for _, idComment := range tx.taskComments.ids(task.ID) {
+			if c := tx.comments[idComment]; c.ParentID != nil && doomed[*c.ParentID] {
+				doomed[c.ID] = true
+			}
+		}
+		for idComment := range doomed {
+			tx.deleteComment(task.ID, idComment)
+		}
+		return nil
+	})
+}
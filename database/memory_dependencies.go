@@ -0,0 +1,64 @@
+package database
+
+import "todo-app/models"
+
+func (s *MemoryStore) AddBlocker(u *models.User, idTask, idBlocker int) error {
+	return s.update(func(tx *memTx) error {
+		for _, id := range []int{idTask, idBlocker} {
+			if _, err := tx.memberTask(u, uint(id)); err != nil {
+				return err
+			}
+		}
+
+		if tx.reaches(uint(idBlocker), uint(idTask)) {
+			return ErrDependencyCycle
+		}
+		tx.link(tx.taskBlockers, uint(idTask), uint(idBlocker), "")
+		return nil
+	})
+}
+
+// reaches reports whether target is from or one of from's blockers, at any
+// depth
+func (tx *memTx) reaches(from, target uint) bool {
+	seen := map[uint]bool{from: true}
+	frontier := []uint{from}
+	for len(frontier) > 0 && !seen[target] {
+		var next []uint
+		for _, id := range frontier {
+			for blocker := range tx.taskBlockers[id] {
+				if !seen[blocker] {
+					seen[blocker] = true
+					next = append(next, blocker)
+				}
+			}
+		}
+		frontier = next
+	}
+	return seen[target]
+}
+
+func (s *MemoryStore) RemoveBlocker(u *models.User, idTask, idBlocker int) error {
+	return s.update(func(tx *memTx) error {
+		if _, err := tx.memberTask(u, uint(idTask)); err != nil {
+			return err
+		}
+		if !tx.taskBlockers.has(uint(idTask), uint(idBlocker)) {
+			return ErrRecordNotFound
+		}
+		tx.unlink(tx.taskBlockers, uint(idTask), uint(idBlocker))
+		return nil
+	})
+}
+
+// markBlocked fills in Blocked and BlockedBy from the blockers that are
+// still open
+func (tx *memTx) markBlocked(t *models.Task) {
+	t.BlockedBy = nil
+	for _, id := range tx.taskBlockers.ids(t.ID) {
+		if blocker, err := tx.getTask(id); err == nil && !blocker.Completed {
+			t.BlockedBy = append(t.BlockedBy, id)
+		}
+	}
+	t.Blocked = len(t.BlockedBy) > 0
+}
@@ -0,0 +1,200 @@
+package database
+
+import (
+	"sort"
+	"time"
+	"todo-app/models"
+)
+
+// getLabel returns one of u's labels in their organization
+func (tx *memTx) getLabel(u *models.User, id uint) (*models.Label, error) {
+	if !tx.userLabels.has(u.ID, id) {
+		return nil, ErrRecordNotFound
+	}
+	l, ok := tx.labels[id]
+	if !ok || l.OrgID != u.OrgID {
+		return nil, ErrRecordNotFound
+	}
+	return &l, nil
+}
+
+func (tx *memTx) putLabel(l *models.Label) {
+	tx.remember(tx.labels, l.ID)
+	tx.labels[l.ID] = *l
+}
+
+// userLabelsOf returns u's labels in their organization ordered by name
+func (tx *memTx) userLabelsOf(u *models.User) []models.Label {
+	labels := []models.Label{}
+	for _, id := range tx.userLabels.ids(u.ID) {
+		if l, err := tx.getLabel(u, id); err == nil {
+			labels = append(labels, *l)
+		}
+	}
+	sort.SliceStable(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+	return labels
+}
+
+func (tx *memTx) labelNameFree(u *models.User, name string, except uint) error {
+	for _, l := range tx.userLabelsOf(u) {
+		if l.Name == name && l.ID != except {
+			return ErrLabelExists
+		}
+	}
+	return nil
+}
+
+// labelledTasks returns the tasks that carry any, or all, of the named
+// labels of u
+func (tx *memTx) labelledTasks(u *models.User, filter models.TaskFilter) []uint {
+	wanted := make(map[string]bool)
+	for _, name := range filter.Labels {
+		wanted[name] = true
+	}
+
+	matched := make(map[uint]map[string]bool)
+	for _, l := range tx.userLabelsOf(u) {
+		if !wanted[l.Name] {
+			continue
+		}
+		for id := range tx.labelTasks[l.ID] {
+			if matched[id] == nil {
+				matched[id] = make(map[string]bool)
+			}
+			matched[id][l.Name] = true
+		}
+	}
+
+	ids := make(map[uint]bool)
+	for id, names := range matched {
+		if !filter.AllLabels || len(names) == len(wanted) {
+			ids[id] = true
+		}
+	}
+	return sortedIDs(ids)
+}
+
+// markLabels fills in the labels u has put on t
+func (tx *memTx) markLabels(u *models.User, t *models.Task) {
+	t.Labels = nil
+	for _, id := range tx.taskLabels.ids(t.ID) {
+		if l, err := tx.getLabel(u, id); err == nil {
+			t.Labels = append(t.Labels, *l)
+		}
+	}
+	sort.SliceStable(t.Labels, func(i, j int) bool { return t.Labels[i].Name < t.Labels[j].Name })
+}
+
+func (tx *memTx) removeTaskLabel(idTask, idLabel uint) {
+	tx.unlink(tx.taskLabels, idTask, idLabel)
+	tx.unlink(tx.labelTasks, idLabel, idTask)
+}
+
+func (s *MemoryStore) CreateLabel(u *models.User, l models.Label) (*models.Label, error) {
+	err := s.update(func(tx *memTx) error {
+		if err := tx.labelNameFree(u, l.Name, 0); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		l.ID, l.UserID, l.OrgID, l.CreatedAt, l.UpdatedAt = nextID(&tx.seq.labels), u.ID, u.OrgID, now, now
+		tx.putLabel(&l)
+		tx.link(tx.userLabels, u.ID, l.ID, "")
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &l, nil
+}
+
+func (s *MemoryStore) GetLabels(u *models.User) (*[]models.Label, error) {
+	var labels []models.Label
+	err := s.view(func(tx *memTx) error {
+		labels = tx.userLabelsOf(u)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &labels, nil
+}
+
+func (s *MemoryStore) UpdateLabel(u *models.User, id int, l models.UpdateLabel) (*models.Label, error) {
+	var label *models.Label
+	err := s.update(func(tx *memTx) error {
+		var err error
+		if label, err = tx.getLabel(u, uint(id)); err != nil {
+			return err
+		}
+
+		if l.Name != "" {
+			if err := tx.labelNameFree(u, l.Name, label.ID); err != nil {
+				return err
+			}
+			label.Name = l.Name
+		}
+		if l.Color != "" {
+			label.Color = l.Color
+		}
+		label.UpdatedAt = time.Now()
+		tx.putLabel(label)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return label, nil
+}
+
+func (s *MemoryStore) DeleteLabel(u *models.User, id int) error {
+	return s.update(func(tx *memTx) error {
+		label, err := tx.getLabel(u, uint(id))
+		if err != nil {
+			return err
+		}
+
+		for _, idTask := range tx.labelTasks.ids(label.ID) {
+			tx.removeTaskLabel(idTask, label.ID)
+		}
+		tx.unlink(tx.userLabels, u.ID, label.ID)
+		tx.remember(tx.labels, label.ID)
+		delete(tx.labels, label.ID)
+		return nil
+	})
+}
+
+func (s *MemoryStore) AttachLabel(u *models.User, idTask, idLabel int) error {
+	return s.update(func(tx *memTx) error {
+		task, err := tx.memberTask(u, uint(idTask))
+		if err != nil {
+			return err
+		}
+		label, err := tx.getLabel(u, uint(idLabel))
+		if err != nil {
+			return err
+		}
+
+		tx.link(tx.taskLabels, task.ID, label.ID, "")
+		tx.link(tx.labelTasks, label.ID, task.ID, "")
+		return nil
+	})
+}
+
+func (s *MemoryStore) DetachLabel(u *models.User, idTask, idLabel int) error {
+	return s.update(func(tx *memTx) error {
+		label, err := tx.getLabel(u, uint(idLabel))
+		if err != nil {
+			return err
+		}
+
+		if !tx.taskLabels.has(uint(idTask), label.ID) {
+			return ErrRecordNotFound
+		}
+		tx.removeTaskLabel(uint(idTask), label.ID)
+		return nil
+	})
+}
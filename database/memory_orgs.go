@@ -0,0 +1,174 @@
+package database
+
+import (
+	"time"
+	"todo-app/models"
+)
+
+func (tx *memTx) getOrg(id uint) (*models.Organization, error) {
+	o, ok := tx.orgs[id]
+	if !ok || o.DeletedAt != nil {
+		return nil, ErrRecordNotFound
+	}
+	return &o, nil
+}
+
+func (tx *memTx) insertOrg(o models.Organization) *models.Organization {
+	now := time.Now()
+	o.ID, o.CreatedAt, o.UpdatedAt, o.DeletedAt, o.Members = nextID(&tx.seq.orgs), now, now, nil, nil
+	tx.remember(tx.orgs, o.ID)
+	tx.orgs[o.ID] = o
+	return &o
+}
+
+func (tx *memTx) putOrgMember(idOrg, idUser uint, role models.Role) {
+	tx.link(tx.orgMembers, idOrg, idUser, role)
+	tx.link(tx.userOrgs, idUser, idOrg, role)
+	key := orgUsername{idOrg, tx.users[idUser].Username}
+	tx.remember(tx.usernames, key)
+	tx.usernames[key] = idUser
+}
+
+// demotesLastOrgOwner reports whether taking idUser's owner role away would
+// leave the organization without owners
+func (tx *memTx) demotesLastOrgOwner(idOrg, idUser uint) bool {
+	if tx.orgMembers[idOrg][idUser] != models.RoleOwner {
+		return false
+	}
+
+	owners := 0
+	for _, role := range tx.orgMembers[idOrg] {
+		if role == models.RoleOwner {
+			owners++
+		}
+	}
+	return owners <= 1
+}
+
+func (s *MemoryStore) CreateOrganization(u *models.User, o models.Organization) (*models.Organization, error) {
+	var org *models.Organization
+	err := s.update(func(tx *memTx) error {
+		org = tx.insertOrg(o)
+		tx.putOrgMember(org.ID, u.ID, models.RoleOwner)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return org, nil
+}
+
+func (s *MemoryStore) GetOrganizations(u *models.User) (*[]models.Organization, error) {
+	orgs := []models.Organization{}
+	err := s.view(func(tx *memTx) error {
+		for _, id := range tx.userOrgs.ids(u.ID) {
+			if o, err := tx.getOrg(id); err == nil {
+				orgs = append(orgs, *o)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &orgs, nil
+}
+
+func (s *MemoryStore) GetOrganization(u *models.User, id int) (*models.Organization, error) {
+	var org *models.Organization
+	err := s.view(func(tx *memTx) error {
+		if !tx.orgMembers.has(uint(id), u.ID) {
+			return ErrRecordNotFound
+		}
+		var err error
+		if org, err = tx.getOrg(uint(id)); err != nil {
+			return err
+		}
+
+		for _, idUser := range tx.orgMembers.ids(org.ID) {
+			user, err := tx.getUser(idUser)
+			if err != nil {
+				return err
+			}
+			org.Members = append(org.Members, models.OrgMember{
+				OrgID:  org.ID,
+				UserID: idUser,
+				Role:   tx.orgMembers[org.ID][idUser],
+				User:   user,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return org, nil
+}
+
+func (s *MemoryStore) GetOrgRole(idUser, idOrg uint) (models.Role, error) {
+	var role models.Role
+	err := s.view(func(tx *memTx) error {
+		if _, err := tx.getOrg(idOrg); err != nil {
+			return err
+		}
+		if role = tx.orgMembers[idOrg][idUser]; role == "" {
+			return ErrRecordNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return role, nil
+}
+
+func (s *MemoryStore) AddOrgMember(idOrg, idUser int, role models.Role) (*models.OrgMember, error) {
+	var member *models.OrgMember
+	err := s.update(func(tx *memTx) error {
+		user, err := tx.getUser(uint(idUser))
+		if err != nil {
+			return err
+		}
+		org, err := tx.getOrg(uint(idOrg))
+		if err != nil {
+			return err
+		}
+
+		if id, ok := tx.usernames[orgUsername{org.ID, user.Username}]; ok && id != user.ID {
+			return ErrUsernameTaken
+		}
+		// already a member, only the role changes
+		if role != models.RoleOwner && tx.demotesLastOrgOwner(org.ID, user.ID) {
+			return ErrLastOrgOwner
+		}
+		member = &models.OrgMember{OrgID: org.ID, UserID: user.ID, Role: role, User: user}
+		tx.putOrgMember(org.ID, user.ID, role)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return member, nil
+}
+
+func (s *MemoryStore) RemoveOrgMember(idOrg, idUser int) error {
+	return s.update(func(tx *memTx) error {
+		if !tx.orgMembers.has(uint(idOrg), uint(idUser)) {
+			return ErrRecordNotFound
+		}
+		if tx.demotesLastOrgOwner(uint(idOrg), uint(idUser)) {
+			return ErrLastOrgOwner
+		}
+		tx.unlink(tx.orgMembers, uint(idOrg), uint(idUser))
+		tx.unlink(tx.userOrgs, uint(idUser), uint(idOrg))
+		key := orgUsername{uint(idOrg), tx.users[uint(idUser)].Username}
+		tx.remember(tx.usernames, key)
+		delete(tx.usernames, key)
+		return nil
+	})
+}
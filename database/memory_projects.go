@@ -0,0 +1,245 @@
+package database
+
+import (
+	"time"
+	"todo-app/models"
+)
+
+// getProject returns a project that has not been deleted
+func (tx *memTx) getProject(id uint) (*models.Project, error) {
+	p, ok := tx.projects[id]
+	if !ok || p.DeletedAt != nil {
+		return nil, ErrRecordNotFound
+	}
+	return &p, nil
+}
+
+func (tx *memTx) putProject(p *models.Project) {
+	stored := *p
+	stored.Members = nil
+	tx.remember(tx.projects, p.ID)
+	tx.projects[p.ID] = stored
+}
+
+// memberProject returns project id if u is a member of it
+func (tx *memTx) memberProject(u *models.User, id uint) (*models.Project, error) {
+	if !tx.projectMembers.has(id, u.ID) {
+		return nil, ErrRecordNotFound
+	}
+	p, err := tx.getProject(id)
+	if err != nil {
+		return nil, err
+	}
+	if p.OrgID != u.OrgID {
+		return nil, ErrRecordNotFound
+	}
+	return p, nil
+}
+
+func (tx *memTx) putProjectMember(idProject, idUser uint, role models.Role) {
+	tx.link(tx.projectMembers, idProject, idUser, role)
+	tx.link(tx.userProjects, idUser, idProject, role)
+}
+
+func (tx *memTx) deleteProjectMember(idProject, idUser uint) {
+	tx.unlink(tx.projectMembers, idProject, idUser)
+	tx.unlink(tx.userProjects, idUser, idProject)
+}
+
+// demotesLastProjectOwner reports whether taking idUser's owner role away
+// would leave the project without owners
+func (tx *memTx) demotesLastProjectOwner(idProject, idUser uint) bool {
+	if tx.projectMembers[idProject][idUser] != models.RoleOwner {
+		return false
+	}
+
+	owners := 0
+	for _, role := range tx.projectMembers[idProject] {
+		if role == models.RoleOwner {
+			owners++
+		}
+	}
+	return owners <= 1
+}
+
+func (s *MemoryStore) CreateProject(u *models.User, p models.Project) (*models.Project, error) {
+	err := s.update(func(tx *memTx) error {
+		now := time.Now()
+		p.ID, p.CreatedAt, p.UpdatedAt, p.DeletedAt, p.Members = nextID(&tx.seq.projects), now, now, nil, nil
+		p.OrgID = u.OrgID
+
+		tx.putProject(&p)
+		tx.putProjectMember(p.ID, u.ID, models.RoleOwner)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+func (s *MemoryStore) GetProjects(u *models.User) (*[]models.Project, error) {
+	projects := []models.Project{}
+	err := s.view(func(tx *memTx) error {
+		for _, id := range tx.userProjects.ids(u.ID) {
+			if p, err := tx.memberProject(u, id); err == nil {
+				projects = append(projects, *p)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &projects, nil
+}
+
+func (s *MemoryStore) GetProject(u *models.User, id int) (*models.Project, error) {
+	var project *models.Project
+	err := s.view(func(tx *memTx) error {
+		var err error
+		if project, err = tx.memberProject(u, uint(id)); err != nil {
+			return err
+		}
+
+		for _, idUser := range tx.projectMembers.ids(project.ID) {
+			user, err := tx.getUser(idUser)
+			if err != nil {
+				return err
+			}
+			project.Members = append(project.Members, models.ProjectMember{
+				ProjectID: project.ID,
+				UserID:    idUser,
+				Role:      tx.projectMembers[project.ID][idUser],
+				User:      user,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return project, nil
+}
+
+func (s *MemoryStore) UpdateProject(u *models.User, id int, p models.UpdateProject) (*models.Project, error) {
+	var project *models.Project
+	err := s.update(func(tx *memTx) error {
+		var err error
+		if project, err = tx.memberProject(u, uint(id)); err != nil {
+			return err
+		}
+
+		if p.Name != "" {
+			project.Name = p.Name
+		}
+		if p.Description != "" {
+			project.Description = p.Description
+		}
+		project.UpdatedAt = time.Now()
+		tx.putProject(project)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return project, nil
+}
+
+func (s *MemoryStore) DeleteProject(u *models.User, id int) (*models.Project, error) {
+	var project *models.Project
+	err := s.update(func(tx *memTx) error {
+		var err error
+		if project, err = tx.memberProject(u, uint(id)); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, idTask := range tx.projectTasks.ids(project.ID) {
+			old, err := tx.getTask(idTask)
+			if err != nil {
+				continue
+			}
+			deleted := *old
+			deleted.DeletedAt = &now
+			tx.putTask(&deleted, old)
+			tx.logActivity(models.TaskActivity(u, old, nil))
+		}
+
+		for _, idUser := range tx.projectMembers.ids(project.ID) {
+			tx.deleteProjectMember(project.ID, idUser)
+		}
+
+		project.DeletedAt = &now
+		tx.putProject(project)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return project, nil
+}
+
+func (s *MemoryStore) GetProjectRole(u *models.User, idProject int) (models.Role, error) {
+	var role models.Role
+	err := s.view(func(tx *memTx) error {
+		if _, err := tx.memberProject(u, uint(idProject)); err != nil {
+			return err
+		}
+		role = tx.projectMembers[uint(idProject)][u.ID]
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return role, nil
+}
+
+func (s *MemoryStore) AddProjectMember(idProject, idUser int, role models.Role) (*models.ProjectMember, error) {
+	var member *models.ProjectMember
+	err := s.update(func(tx *memTx) error {
+		user, err := tx.getUser(uint(idUser))
+		if err != nil {
+			return err
+		}
+		project, err := tx.getProject(uint(idProject))
+		if err != nil {
+			return err
+		}
+		if !tx.orgMembers.has(project.OrgID, user.ID) {
+			return ErrRecordNotFound
+		}
+
+		// already a member, only the role changes
+		if role != models.RoleOwner && tx.demotesLastProjectOwner(project.ID, user.ID) {
+			return ErrLastProjectOwner
+		}
+		member = &models.ProjectMember{ProjectID: project.ID, UserID: user.ID, Role: role, User: user}
+		tx.putProjectMember(project.ID, user.ID, role)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return member, nil
+}
+
+func (s *MemoryStore) RemoveProjectMember(idProject, idUser int) error {
+	return s.update(func(tx *memTx) error {
+		if !tx.projectMembers.has(uint(idProject), uint(idUser)) {
+			return ErrRecordNotFound
+		}
+		if tx.demotesLastProjectOwner(uint(idProject), uint(idUser)) {
+			return ErrLastProjectOwner
+		}
+		tx.deleteProjectMember(uint(idProject), uint(idUser))
+		return nil
+	})
+}
@@ -0,0 +1,38 @@
+package database
+
+import "todo-app/models"
+
+func (s *MemoryStore) SearchTasks(u *models.User, search models.TaskSearch) (*[]models.SearchResult, error) {
+	results := []models.SearchResult{}
+	err := s.view(func(tx *memTx) error {
+		for _, id := range sortedIDs(tx.visibleIDs(u)) {
+			t, err := tx.getTask(id)
+			if err != nil {
+				continue
+			}
+			if t.OrgID != u.OrgID || (t.ArchivedAt != nil && !search.IncludeArchived) {
+				continue
+			}
+
+			var comments []string
+			for _, idComment := range tx.taskComments.ids(t.ID) {
+				comments = append(comments, tx.comments[idComment].Body)
+			}
+			if result, ok := models.MatchTask(search.Terms, t, comments); ok {
+				results = append(results, result)
+			}
+		}
+
+		results = models.SortResults(results, search.Limit)
+		for i := range results {
+			tx.markBlocked(&results[i].Task)
+			tx.markLabels(u, &results[i].Task)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &results, nil
+}
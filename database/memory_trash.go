@@ -0,0 +1,212 @@
+package database
+
+import (
+	"sort"
+	"time"
+	"todo-app/models"
+)
+
+// trashedTask returns task idTask if it is in the trash and u can reach it
+func (tx *memTx) trashedTask(u *models.User, idTask uint) (*models.Task, error) {
+	t, err := tx.loadTask(idTask)
+	if err != nil {
+		return nil, err
+	}
+	if t.DeletedAt == nil {
+		return nil, ErrRecordNotFound
+	}
+	if _, err := tx.taskRole(u, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// trashedWith returns the descendants of t that were deleted along with it,
+// or every descendant when all is set
+func (tx *memTx) trashedWith(t *models.Task, all bool) []models.Task {
+	var found []models.Task
+	parents := []uint{t.ID}
+	for len(parents) > 0 {
+		var next []uint
+		for _, parent := range parents {
+			for _, childID := range tx.taskChildren.ids(parent) {
+				child := tx.tasks[childID]
+				if !all && (child.DeletedAt == nil || !child.DeletedAt.Equal(*t.DeletedAt)) {
+					continue
+				}
+				found = append(found, child)
+				next = append(next, child.ID)
+			}
+		}
+		parents = next
+	}
+	return found
+}
+
+func (s *MemoryStore) GetTrash(u *models.User) (*[]models.Task, error) {
+	tasks := []models.Task{}
+	err := s.view(func(tx *memTx) error {
+		for _, id := range sortedIDs(tx.visibleIDs(u)) {
+			t, err := tx.loadTask(id)
+			if err != nil {
+				continue
+			}
+			if t.DeletedAt != nil && t.OrgID == u.OrgID {
+				tasks = append(tasks, *t)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].DeletedAt.After(*tasks[j].DeletedAt)
+	})
+	return &tasks, nil
+}
+
+func (s *MemoryStore) GetTrashedTaskRole(u *models.User, idTask int) (models.Role, error) {
+	var role models.Role
+	err := s.view(func(tx *memTx) error {
+		t, err := tx.trashedTask(u, uint(idTask))
+		if err != nil {
+			return err
+		}
+		role, err = tx.taskRole(u, t)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return role, nil
+}
+
+func (s *MemoryStore) RestoreTask(u *models.User, idTask int) (*models.Task, error) {
+	err := s.update(func(tx *memTx) error {
+		task, err := tx.trashedTask(u, uint(idTask))
+		if err != nil {
+			return err
+		}
+		if task.ParentID != nil {
+			if parent, err := tx.loadTask(*task.ParentID); err == nil && parent.DeletedAt != nil {
+				return ErrParentDeleted
+			}
+		}
+
+		// tasks of a project deleted since come back without it
+		dropProject := false
+		if task.ProjectID != nil {
+			_, err := tx.getProject(*task.ProjectID)
+			dropProject = err == ErrRecordNotFound
+		}
+
+		now := time.Now()
+		for _, old := range append(tx.trashedWith(task, false), *task) {
+			old := old
+			restored := old
+			restored.DeletedAt, restored.UpdatedAt = nil, now
+			if dropProject {
+				tx.unlink(tx.projectTasks, *old.ProjectID, old.ID)
+				restored.ProjectID = nil
+			}
+			tx.putTask(&restored, &old)
+			tx.logActivity(models.TrashActivity(u, &old, &restored))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetTask(u, idTask)
+}
+
+func (s *MemoryStore) PurgeTask(u *models.User, idTask int) ([]models.Attachment, error) {
+	var attachments []models.Attachment
+	err := s.update(func(tx *memTx) error {
+		task, err := tx.trashedTask(u, uint(idTask))
+		if err != nil {
+			return err
+		}
+		// subtasks of a task in the trash are all in the trash too
+		attachments = tx.purgeTasks(u, append(tx.trashedWith(task, true), *task))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+func (s *MemoryStore) PurgeTrash(cutoff time.Time) ([]models.Attachment, error) {
+	var attachments []models.Attachment
+	err := s.update(func(tx *memTx) error {
+		// subtasks are deleted with or before their parent, so none is left behind
+		var tasks []models.Task
+		for _, id := range sortedIDs(tx.taskIDs()) {
+			if t := tx.tasks[id]; t.DeletedAt != nil && t.DeletedAt.Before(cutoff) {
+				tasks = append(tasks, t)
+			}
+		}
+		attachments = tx.purgeTasks(nil, tasks)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+// purgeTasks deletes tasks and everything hanging off them for good, only
+// their activity log is kept
+func (tx *memTx) purgeTasks(actor *models.User, tasks []models.Task) []models.Attachment {
+	var attachments []models.Attachment
+	for i := range tasks {
+		t := &tasks[i]
+		for _, idUser := range tx.taskUsers.ids(t.ID) {
+			tx.removeMember(idUser, t.ID)
+		}
+		for _, idLabel := range tx.taskLabels.ids(t.ID) {
+			tx.removeTaskLabel(t.ID, idLabel)
+		}
+		for _, idComment := range tx.taskComments.ids(t.ID) {
+			tx.deleteComment(t.ID, idComment)
+		}
+		for _, a := range tx.taskAttachmentsOf(t.ID) {
+			tx.unlink(tx.taskAttachments, t.ID, a.ID)
+			tx.remember(tx.attachments, a.ID)
+			delete(tx.attachments, a.ID)
+			attachments = append(attachments, a)
+		}
+		for _, idItem := range tx.taskChecklist.ids(t.ID) {
+			tx.unlink(tx.taskChecklist, t.ID, idItem)
+			tx.remember(tx.items, idItem)
+			delete(tx.items, idItem)
+		}
+		tx.unlinkAll(tx.taskChildren, t.ID)
+		tx.unlinkAll(tx.taskBlockers, t.ID)
+		// other tasks may still be blocked by the purged one
+		for idTask := range tx.taskBlockers {
+			tx.unlink(tx.taskBlockers, idTask, t.ID)
+		}
+
+		if t.ParentID != nil {
+			tx.unlink(tx.taskChildren, *t.ParentID, t.ID)
+		}
+		if t.ProjectID != nil {
+			tx.unlink(tx.projectTasks, *t.ProjectID, t.ID)
+		}
+		if t.SeriesID != nil {
+			tx.unlink(tx.seriesTasks, *t.SeriesID, t.ID)
+		}
+		tx.remember(tx.tasks, t.ID)
+		delete(tx.tasks, t.ID)
+		tx.logActivity(models.TrashActivity(actor, t, nil))
+	}
+	return attachments
+}
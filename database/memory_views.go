@@ -0,0 +1,180 @@
+package database
+
+import (
+	"sort"
+	"time"
+	"todo-app/models"
+)
+
+// ownView returns one of u's views in their organization
+func (tx *memTx) ownView(u *models.User, id uint) (*models.View, error) {
+	if !tx.userViews.has(u.ID, id) {
+		return nil, ErrRecordNotFound
+	}
+	view, ok := tx.views[id]
+	if !ok || view.OrgID != u.OrgID {
+		return nil, ErrRecordNotFound
+	}
+	return &view, nil
+}
+
+func (tx *memTx) putView(v *models.View) {
+	tx.remember(tx.views, v.ID)
+	tx.views[v.ID] = *v
+}
+
+// collaborators returns the users that share a task outside the trash or a
+// project with u, u included
+func (tx *memTx) collaborators(u *models.User) map[uint]bool {
+	users := map[uint]bool{u.ID: true}
+	tasks := make(map[uint]bool)
+	for idTask := range tx.userTasks[u.ID] {
+		tasks[idTask] = true
+	}
+	for idProject := range tx.userProjects[u.ID] {
+		for idUser := range tx.projectMembers[idProject] {
+			users[idUser] = true
+		}
+		for idTask := range tx.projectTasks[idProject] {
+			tasks[idTask] = true
+		}
+	}
+
+	for idTask := range tasks {
+		t, ok := tx.tasks[idTask]
+		if !ok || t.DeletedAt != nil {
+			continue
+		}
+		for idUser := range tx.taskUsers[idTask] {
+			users[idUser] = true
+		}
+		if t.ProjectID != nil {
+			for idUser := range tx.projectMembers[*t.ProjectID] {
+				users[idUser] = true
+			}
+		}
+	}
+	return users
+}
+
+// visibleViews returns u's views and the views shared with u ordered by name
+func (tx *memTx) visibleViews(u *models.User) []models.View {
+	views := []models.View{}
+	for idUser := range tx.collaborators(u) {
+		for id := range tx.userViews[idUser] {
+			view := tx.views[id]
+			if view.OrgID != u.OrgID || (view.UserID != u.ID && !view.Shared) {
+				continue
+			}
+			views = append(views, view)
+		}
+	}
+	sort.Slice(views, func(i, j int) bool {
+		if views[i].Name != views[j].Name {
+			return views[i].Name < views[j].Name
+		}
+		return views[i].ID < views[j].ID
+	})
+	return views
+}
+
+func (tx *memTx) viewNameFree(u *models.User, name string, except uint) error {
+	for _, id := range tx.userViews.ids(u.ID) {
+		view, err := tx.ownView(u, id)
+		if err == nil && view.Name == name && view.ID != except {
+			return ErrViewExists
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) CreateView(u *models.User, v models.View) (*models.View, error) {
+	err := s.update(func(tx *memTx) error {
+		if err := tx.viewNameFree(u, v.Name, 0); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		v.ID, v.UserID, v.OrgID, v.CreatedAt, v.UpdatedAt = nextID(&tx.seq.views), u.ID, u.OrgID, now, now
+		tx.putView(&v)
+		tx.link(tx.userViews, u.ID, v.ID, "")
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &v, nil
+}
+
+func (s *MemoryStore) GetViews(u *models.User) (*[]models.View, error) {
+	var views []models.View
+	err := s.view(func(tx *memTx) error {
+		views = tx.visibleViews(u)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &views, nil
+}
+
+func (s *MemoryStore) GetView(u *models.User, id int) (*models.View, error) {
+	var view *models.View
+	err := s.view(func(tx *memTx) error {
+		v, ok := tx.views[uint(id)]
+		if !ok {
+			return ErrRecordNotFound
+		}
+		if v.OrgID != u.OrgID || (v.UserID != u.ID && (!v.Shared || !tx.collaborators(u)[v.UserID])) {
+			return ErrRecordNotFound
+		}
+		view = &v
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return view, nil
+}
+
+func (s *MemoryStore) UpdateView(u *models.User, id int, v models.UpdateView) (*models.View, error) {
+	var view *models.View
+	err := s.update(func(tx *memTx) error {
+		var err error
+		if view, err = tx.ownView(u, uint(id)); err != nil {
+			return err
+		}
+
+		if v.Name != nil {
+			if err := tx.viewNameFree(u, *v.Name, view.ID); err != nil {
+				return err
+			}
+		}
+		v.Apply(view)
+		view.UpdatedAt = time.Now()
+		tx.putView(view)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return view, nil
+}
+
+func (s *MemoryStore) DeleteView(u *models.User, id int) error {
+	return s.update(func(tx *memTx) error {
+		view, err := tx.ownView(u, uint(id))
+		if err != nil {
+			return err
+		}
+
+		tx.unlink(tx.userViews, u.ID, view.ID)
+		tx.remember(tx.views, view.ID)
+		delete(tx.views, view.ID)
+		return nil
+	})
+}
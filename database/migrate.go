@@ -0,0 +1,211 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// Migration is one versioned step of the sql schema. Up and Down run inside
+// the same transaction that records the step in schema_migrations.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// MigrationStatus reports whether a migration has been applied
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+type schemaMigration struct {
+	Version   int `gorm:"primary_key;auto_increment:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+// ErrSchemaBehind is returned by CheckSchema when migrations are pending
+var ErrSchemaBehind = errors.New("database schema is behind, run `migrate up`")
+
+func (s *SQLStore) ensureMigrationsTable() error {
+	if s.DB.HasTable(&schemaMigration{}) {
+		return nil
+	}
+	return s.DB.CreateTable(&schemaMigration{}).Error
+}
+
+func (s *SQLStore) applied() (map[int]schemaMigration, error) {
+	if err := s.ensureMigrationsTable(); err != nil {
+		return nil, errors.Wrap(err, "failed to create schema_migrations")
+	}
+
+	var rows []schemaMigration
+	if err := s.DB.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int]schemaMigration)
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// SchemaVersion is the highest applied migration, 0 for an empty database
+func (s *SQLStore) SchemaVersion() (int, error) {
+	applied, err := s.applied()
+	if err != nil {
+		return 0, err
+	}
+
+	version := 0
+	for v := range applied {
+		if v > version {
+			version = v
+		}
+	}
+	return version, nil
+}
+
+// LatestVersion is the version the code expects the schema to be at
+func LatestVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+func (s *SQLStore) MigrationStatus() ([]MigrationStatus, error) {
+	applied, err := s.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	status := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		st := MigrationStatus{Version: m.Version, Name: m.Name}
+		if row, ok := applied[m.Version]; ok {
+			appliedAt := row.AppliedAt
+			st.AppliedAt = &appliedAt
+		}
+		status = append(status, st)
+	}
+	return status, nil
+}
+
+// CheckSchema fails unless every migration has been applied
+func (s *SQLStore) CheckSchema() error {
+	version, err := s.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	if version < LatestVersion() {
+		return errors.Wrapf(ErrSchemaBehind, "schema at version %d, want %d", version, LatestVersion())
+	}
+	return nil
+}
+
+// MigrateUp applies every pending migration
+func (s *SQLStore) MigrateUp() error {
+	return s.MigrateTo(LatestVersion())
+}
+
+// MigrateDown rolls back the most recent migration
+func (s *SQLStore) MigrateDown() error {
+	version, err := s.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	if version == 0 {
+		return nil
+	}
+
+	target := 0
+	for _, m := range migrations {
+		if m.Version < version {
+			target = m.Version
+		}
+	}
+	return s.MigrateTo(target)
+}
+
+// MigrateTo moves the schema up or down until version is the latest applied
+func (s *SQLStore) MigrateTo(version int) error {
+	if version != 0 && findMigration(version) == nil {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+
+	applied, err := s.applied()
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok || m.Version > version {
+			continue
+		}
+		if err := s.run(m, true); err != nil {
+			return err
+		}
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if _, ok := applied[m.Version]; !ok || m.Version <= version {
+			continue
+		}
+		if err := s.run(m, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *SQLStore) run(m Migration, up bool) error {
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	step := m.Down
+	if up {
+		step = m.Up
+	}
+	if err := step(tx); err != nil {
+		tx.Rollback()
+		return errors.Wrapf(err, "migration %d %s failed", m.Version, m.Name)
+	}
+
+	var err error
+	if up {
+		err = tx.Create(&schemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+	} else {
+		err = tx.Delete(&schemaMigration{Version: m.Version}).Error
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+func findMigration(version int) *Migration {
+	for i := range migrations {
+		if migrations[i].Version == version {
+			return &migrations[i]
+		}
+	}
+	return nil
+}
+
+func init() {
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+}
@@ -0,0 +1,141 @@
+package database
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+// wantSchema checks that schema_migrations lists exactly the migrations up
+// to version, and whether CheckSchema lets the API start
+func wantSchema(t *testing.T, s *SQLStore, version int) {
+	t.Helper()
+	// SchemaVersion creates schema_migrations on a new database
+	if v, err := s.SchemaVersion(); err != nil || v != version {
+		t.Errorf("SchemaVersion: got %d, %v, want %d", v, err, version)
+	}
+	var rows []schemaMigration
+	if err := s.DB.Order("version").Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	var got, want []string
+	for _, row := range rows {
+		got = append(got, fmt.Sprintf("%d %s", row.Version, row.Name))
+	}
+	for _, m := range migrations {
+		if m.Version <= version {
+			want = append(want, fmt.Sprintf("%d %s", m.Version, m.Name))
+		}
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("schema_migrations at version %d: got %q, want %q", version, got, want)
+	}
+
+	err := s.CheckSchema()
+	if version == LatestVersion() && err != nil {
+		t.Errorf("CheckSchema at the latest version: %v", err)
+	}
+	if version < LatestVersion() && errors.Cause(err) != ErrSchemaBehind {
+		t.Errorf("CheckSchema at version %d: got %v, want %v", version, err, ErrSchemaBehind)
+	}
+}
+
+// tables lists the tables of a SQLite database besides schema_migrations
+func tables(t *testing.T, s *SQLStore) []string {
+	t.Helper()
+	var names []string
+	err := s.DB.Raw("SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT IN ('schema_migrations', 'sqlite_sequence') ORDER BY name").
+		Pluck("name", &names).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	return names
+}
+
+func TestMigrations(t *testing.T) {
+	s, err := NewSQLite(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.DB.Close()
+	s.DB.DB().SetMaxOpenConns(1)
+	s.DB.LogMode(false)
+
+	wantSchema(t, s, 0)
+	for round := 0; round < 2; round++ {
+		if err := s.MigrateUp(); err != nil {
+			t.Fatalf("round %d: %v", round, err)
+		}
+		wantSchema(t, s, LatestVersion())
+		if round == 0 && len(tables(t, s)) == 0 {
+			t.Fatal("migrating up created no tables")
+		}
+
+		// every migration rolls back on its own
+		for i := len(migrations) - 1; i >= 0; i-- {
+			if err := s.MigrateDown(); err != nil {
+				t.Fatalf("round %d, rolling back migration %d: %v", round, migrations[i].Version, err)
+			}
+			version := 0
+			if i > 0 {
+				version = migrations[i-1].Version
+			}
+			wantSchema(t, s, version)
+		}
+		if left := tables(t, s); len(left) != 0 {
+			t.Errorf("round %d: tables left at version 0: %v", round, left)
+		}
+		if err := s.MigrateDown(); err != nil {
+			t.Errorf("rolling back at version 0: %v", err)
+		}
+	}
+
+	if err := s.MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	if status, err := s.MigrationStatus(); err != nil || len(status) != len(migrations) {
+		t.Errorf("MigrationStatus: got %d migrations, %v, want %d", len(status), err, len(migrations))
+	} else {
+		for _, st := range status {
+			if st.AppliedAt == nil {
+				t.Errorf("migration %d is pending after migrating up", st.Version)
+			}
+		}
+	}
+}
+
+func TestMigrateTo(t *testing.T) {
+	s, err := NewSQLite(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.DB.Close()
+	s.DB.DB().SetMaxOpenConns(1)
+	s.DB.LogMode(false)
+
+	middle := migrations[len(migrations)/2].Version
+	for _, step := range []struct {
+		to, want int
+		ok       bool
+	}{
+		{middle, middle, true},
+		{LatestVersion(), LatestVersion(), true},
+		{LatestVersion(), LatestVersion(), true},
+		{middle, middle, true},
+		// versions that don't exist leave the schema alone
+		{-1, middle, false},
+		{LatestVersion() + 1, middle, false},
+		{0, 0, true},
+		{0, 0, true},
+	} {
+		err := s.MigrateTo(step.to)
+		if step.ok && err != nil {
+			t.Fatalf("migrating to %d: %v", step.to, err)
+		}
+		if !step.ok && err == nil {
+			t.Errorf("migrated to unknown version %d", step.to)
+		}
+		wantSchema(t, s, step.want)
+	}
+}
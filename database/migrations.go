@@ -0,0 +1,643 @@
+package database
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Migrations declare their own copies of the tables they touch so that later
+// changes to models don't rewrite history.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create users and tasks",
+		Up: func(tx *gorm.DB) error {
+			type user struct {
+				ID        uint `gorm:"primary_key"`
+				CreatedAt time.Time
+				UpdatedAt time.Time
+				DeletedAt *time.Time `sql:"index"`
+				Username  string     `gorm:"type:varchar(50);not null;unique"`
+				Password  string     `gorm:"not null"`
+			}
+			type task struct {
+				gorm.Model
+				Title       string `gorm:"type:varchar(50);not null"`
+				Description string `gorm:"type:varchar(200);not null"`
+				Priority    string `sql:"type:priority" gorm:"default:'1'"`
+				Completed   bool   `gorm:"default:false"`
+			}
+			type userTask struct {
+				UserID uint `gorm:"primary_key;auto_increment:false"`
+				TaskID uint `gorm:"primary_key;auto_increment:false"`
+			}
+
+			if isPostgres(tx) {
+				// databases set up before migrations already have the type
+				err := tx.Exec(`DO $$ BEGIN
+					CREATE TYPE priority AS ENUM ('1', '2', '3');
+				EXCEPTION WHEN duplicate_object THEN NULL;
+				END $$`).Error
+				if err != nil {
+					return err
+				}
+			}
+			return createTables(tx, &user{}, &task{}, &userTask{})
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.DropTableIfExists("user_tasks", "tasks", "users").Error; err != nil {
+				return err
+			}
+			if isPostgres(tx) {
+				return tx.Exec("DROP TYPE IF EXISTS priority").Error
+			}
+			return nil
+		},
+	},
+	{
+		Version: 2,
+		Name:    "create sessions",
+		Up: func(tx *gorm.DB) error {
+			type session struct {
+				Token     string `gorm:"primary_key"`
+				UserID    uint   `gorm:"not null"`
+				ExpiresAt *time.Time
+			}
+			return createTables(tx, &session{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.DropTableIfExists("sessions").Error
+		},
+	},
+	{
+		Version: 3,
+		Name:    "add role to user_tasks",
+		Up: func(tx *gorm.DB) error {
+			// everyone assigned so far could do anything with the task
+			return tx.Exec("ALTER TABLE user_tasks ADD COLUMN role varchar(10) NOT NULL DEFAULT 'owner'").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Table("user_tasks").DropColumn("role").Error
+		},
+	},
+	{
+		Version: 4,
+		Name:    "add start_at and due_at to tasks",
+		Up: func(tx *gorm.DB) error {
+			for _, column := range []string{"start_at", "due_at"} {
+				if err := tx.Exec("ALTER TABLE tasks ADD COLUMN " + column + " " + timestampType(tx)).Error; err != nil {
+					return err
+				}
+			}
+			return tx.Table("tasks").AddIndex("idx_tasks_due_at", "due_at").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Table("tasks").RemoveIndex("idx_tasks_due_at").Error; err != nil {
+				return err
+			}
+			if err := tx.Table("tasks").DropColumn("due_at").Error; err != nil {
+				return err
+			}
+			return tx.Table("tasks").DropColumn("start_at").Error
+		},
+	},
+	{
+		Version: 5,
+		Name:    "add recurrence to tasks",
+		Up: func(tx *gorm.DB) error {
+			for _, column := range []string{
+				"recurrence varchar(255)",
+				"series_id integer",
+				"occurrence integer NOT NULL DEFAULT 0",
+			} {
+				if err := tx.Exec("ALTER TABLE tasks ADD COLUMN " + column).Error; err != nil {
+					return err
+				}
+			}
+			return tx.Table("tasks").AddIndex("idx_tasks_series_id", "series_id").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Table("tasks").RemoveIndex("idx_tasks_series_id").Error; err != nil {
+				return err
+			}
+			for _, column := range []string{"occurrence", "series_id", "recurrence"} {
+				if err := tx.Table("tasks").DropColumn(column).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 6,
+		Name:    "add parent_id to tasks",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Exec("ALTER TABLE tasks ADD COLUMN parent_id integer").Error; err != nil {
+				return err
+			}
+			return tx.Table("tasks").AddIndex("idx_tasks_parent_id", "parent_id").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Table("tasks").RemoveIndex("idx_tasks_parent_id").Error; err != nil {
+				return err
+			}
+			return tx.Table("tasks").DropColumn("parent_id").Error
+		},
+	},
+	{
+		Version: 7,
+		Name:    "create task_dependencies",
+		Up: func(tx *gorm.DB) error {
+			type taskDependency struct {
+				TaskID    uint `gorm:"primary_key;auto_increment:false"`
+				BlockerID uint `gorm:"primary_key;auto_increment:false"`
+			}
+			if err := createTables(tx, &taskDependency{}); err != nil {
+				return err
+			}
+			return tx.Table("task_dependencies").AddIndex("idx_task_dependencies_blocker_id", "blocker_id").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.DropTableIfExists("task_dependencies").Error
+		},
+	},
+	{
+		Version: 8,
+		Name:    "create labels",
+		Up: func(tx *gorm.DB) error {
+			type label struct {
+				ID        uint `gorm:"primary_key"`
+				CreatedAt time.Time
+				UpdatedAt time.Time
+				UserID    uint   `gorm:"not null"`
+				Name      string `gorm:"type:varchar(50);not null"`
+				Color     string `gorm:"type:varchar(7)"`
+			}
+			type taskLabel struct {
+				TaskID  uint `gorm:"primary_key;auto_increment:false"`
+				LabelID uint `gorm:"primary_key;auto_increment:false"`
+			}
+			if err := createTables(tx, &label{}, &taskLabel{}); err != nil {
+				return err
+			}
+			if err := tx.Table("labels").AddUniqueIndex("idx_labels_user_id_name", "user_id", "name").Error; err != nil {
+				return err
+			}
+			return tx.Table("task_labels").AddIndex("idx_task_labels_label_id", "label_id").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.DropTableIfExists("task_labels", "labels").Error
+		},
+	},
+	{
+		Version: 9,
+		Name:    "create projects",
+		Up: func(tx *gorm.DB) error {
+			type project struct {
+				gorm.Model
+				Name        string `gorm:"type:varchar(50);not null"`
+				Description string `gorm:"type:varchar(200)"`
+			}
+			type projectMember struct {
+				ProjectID uint   `gorm:"primary_key;auto_increment:false"`
+				UserID    uint   `gorm:"primary_key;auto_increment:false"`
+				Role      string `gorm:"type:varchar(10);not null"`
+			}
+			if err := createTables(tx, &project{}, &projectMember{}); err != nil {
+				return err
+			}
+			if err := tx.Table("project_members").AddIndex("idx_project_members_user_id", "user_id").Error; err != nil {
+				return err
+			}
+			if err := tx.Exec("ALTER TABLE tasks ADD COLUMN project_id integer").Error; err != nil {
+				return err
+			}
+			return tx.Table("tasks").AddIndex("idx_tasks_project_id", "project_id").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Table("tasks").RemoveIndex("idx_tasks_project_id").Error; err != nil {
+				return err
+			}
+			if err := tx.Table("tasks").DropColumn("project_id").Error; err != nil {
+				return err
+			}
+			return tx.DropTableIfExists("project_members", "projects").Error
+		},
+	},
+	{
+		Version: 10,
+		Name:    "create organizations",
+		Up: func(tx *gorm.DB) error {
+			type organization struct {
+				gorm.Model
+				Name string `gorm:"type:varchar(50);not null"`
+			}
+			type orgMember struct {
+				OrgID  uint   `gorm:"primary_key;auto_increment:false"`
+				UserID uint   `gorm:"primary_key;auto_increment:false"`
+				Role   string `gorm:"type:varchar(10);not null"`
+			}
+			if err := createTables(tx, &organization{}, &orgMember{}); err != nil {
+				return err
+			}
+			if err := tx.Table("org_members").AddIndex("idx_org_members_user_id", "user_id").Error; err != nil {
+				return err
+			}
+
+			for _, table := range []string{"tasks", "projects", "labels"} {
+				if err := tx.Exec("ALTER TABLE " + table + " ADD COLUMN org_id integer NOT NULL DEFAULT 0").Error; err != nil {
+					return err
+				}
+			}
+			if err := tx.Table("tasks").AddIndex("idx_tasks_org_id", "org_id").Error; err != nil {
+				return err
+			}
+			if err := tx.Table("projects").AddIndex("idx_projects_org_id", "org_id").Error; err != nil {
+				return err
+			}
+			if err := tx.Table("labels").RemoveIndex("idx_labels_user_id_name").Error; err != nil {
+				return err
+			}
+			if err := tx.Table("labels").AddUniqueIndex("idx_labels_org_id_user_id_name", "org_id", "user_id", "name").Error; err != nil {
+				return err
+			}
+
+			// everything so far shared one namespace, it becomes one
+			// organization that all existing users own
+			var users int
+			if err := tx.Table("users").Where("deleted_at IS NULL").Count(&users).Error; err != nil {
+				return err
+			}
+			if users == 0 {
+				return nil
+			}
+			org := organization{Name: "default"}
+			if err := tx.Create(&org).Error; err != nil {
+				return err
+			}
+			err := tx.Exec("INSERT INTO org_members (org_id, user_id, role) SELECT ?, id, 'owner' FROM users WHERE deleted_at IS NULL", org.ID).Error
+			if err != nil {
+				return err
+			}
+			for _, table := range []string{"tasks", "projects", "labels"} {
+				if err := tx.Exec("UPDATE "+table+" SET org_id = ?", org.ID).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Table("labels").RemoveIndex("idx_labels_org_id_user_id_name").Error; err != nil {
+				return err
+			}
+			if err := tx.Table("projects").RemoveIndex("idx_projects_org_id").Error; err != nil {
+				return err
+			}
+			if err := tx.Table("tasks").RemoveIndex("idx_tasks_org_id").Error; err != nil {
+				return err
+			}
+			for _, table := range []string{"labels", "projects", "tasks"} {
+				if err := tx.Table(table).DropColumn("org_id").Error; err != nil {
+					return err
+				}
+			}
+			if err := tx.Table("labels").AddUniqueIndex("idx_labels_user_id_name", "user_id", "name").Error; err != nil {
+				return err
+			}
+			return tx.DropTableIfExists("org_members", "organizations").Error
+		},
+	},
+	{
+		Version: 11,
+		Name:    "create comments",
+		Up: func(tx *gorm.DB) error {
+			type comment struct {
+				gorm.Model
+				TaskID   uint `gorm:"not null"`
+				UserID   uint `gorm:"not null"`
+				ParentID *uint
+				Body     string `gorm:"type:varchar(2000);not null"`
+			}
+			type commentMention struct {
+				CommentID uint `gorm:"primary_key;auto_increment:false"`
+				UserID    uint `gorm:"primary_key;auto_increment:false"`
+			}
+			if err := createTables(tx, &comment{}, &commentMention{}); err != nil {
+				return err
+			}
+			if err := tx.Table("comments").AddIndex("idx_comments_task_id", "task_id").Error; err != nil {
+				return err
+			}
+			return tx.Table("comments").AddIndex("idx_comments_parent_id", "parent_id").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.DropTableIfExists("comment_mentions", "comments").Error
+		},
+	},
+	{
+		Version: 12,
+		Name:    "create attachments",
+		Up: func(tx *gorm.DB) error {
+			type attachment struct {
+				gorm.Model
+				TaskID      uint   `gorm:"not null"`
+				UserID      uint   `gorm:"not null"`
+				Filename    string `gorm:"type:varchar(255);not null"`
+				ContentType string `gorm:"type:varchar(100);not null"`
+				Size        int64  `gorm:"not null"`
+				Key         string `gorm:"column:blob_key;type:varchar(255);not null"`
+			}
+			if err := createTables(tx, &attachment{}); err != nil {
+				return err
+			}
+			return tx.Table("attachments").AddIndex("idx_attachments_task_id", "task_id").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.DropTableIfExists("attachments").Error
+		},
+	},
+	{
+		Version: 13,
+		Name:    "create checklist_items",
+		Up: func(tx *gorm.DB) error {
+			type checklistItem struct {
+				ID        uint `gorm:"primary_key"`
+				CreatedAt time.Time
+				UpdatedAt time.Time
+				TaskID    uint   `gorm:"not null"`
+				Text      string `gorm:"type:varchar(200);not null"`
+				Done      bool   `gorm:"not null;default:false"`
+				Position  int    `gorm:"not null"`
+			}
+			if err := createTables(tx, &checklistItem{}); err != nil {
+				return err
+			}
+			return tx.Table("checklist_items").AddIndex("idx_checklist_items_task_id", "task_id").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.DropTableIfExists("checklist_items").Error
+		},
+	},
+	{
+		Version: 14,
+		Name:    "create activities",
+		Up: func(tx *gorm.DB) error {
+			type activity struct {
+				ID        uint `gorm:"primary_key"`
+				CreatedAt time.Time
+				OrgID     uint   `gorm:"not null"`
+				TaskID    uint   `gorm:"not null"`
+				ActorID   uint   `gorm:"not null"`
+				Action    string `gorm:"type:varchar(20);not null"`
+				MemberID  *uint
+				Changes   *string `gorm:"type:text"`
+			}
+			if err := createTables(tx, &activity{}); err != nil {
+				return err
+			}
+			if err := tx.Table("activities").AddIndex("idx_activities_task_id", "task_id").Error; err != nil {
+				return err
+			}
+			return tx.Table("activities").AddIndex("idx_activities_org_id", "org_id").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.DropTableIfExists("activities").Error
+		},
+	},
+	{
+		Version: 15,
+		Name:    "add completed_at and archived_at to tasks",
+		Up: func(tx *gorm.DB) error {
+			for _, column := range []string{"completed_at", "archived_at"} {
+				if err := tx.Exec("ALTER TABLE tasks ADD COLUMN " + column + " " + timestampType(tx)).Error; err != nil {
+					return err
+				}
+			}
+			// the last update is the best guess for tasks completed until now
+			if err := tx.Exec("UPDATE tasks SET completed_at = updated_at WHERE completed = ?", true).Error; err != nil {
+				return err
+			}
+			return tx.Table("tasks").AddIndex("idx_tasks_archived_at", "archived_at").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Table("tasks").RemoveIndex("idx_tasks_archived_at").Error; err != nil {
+				return err
+			}
+			if err := tx.Table("tasks").DropColumn("archived_at").Error; err != nil {
+				return err
+			}
+			return tx.Table("tasks").DropColumn("completed_at").Error
+		},
+	},
+	{
+		Version: 16,
+		Name:    "add version to tasks",
+		Up: func(tx *gorm.DB) error {
+			return tx.Exec("ALTER TABLE tasks ADD COLUMN version integer NOT NULL DEFAULT 1").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Table("tasks").DropColumn("version").Error
+		},
+	},
+	{
+		Version: 17,
+		Name:    "create views",
+		Up: func(tx *gorm.DB) error {
+			type view struct {
+				ID              uint `gorm:"primary_key"`
+				CreatedAt       time.Time
+				UpdatedAt       time.Time
+				UserID          uint   `gorm:"not null"`
+				OrgID           uint   `gorm:"not null"`
+				Name            string `gorm:"type:varchar(50);not null"`
+				Filter          string `gorm:"type:varchar(1000);not null"`
+				Sort            string `gorm:"type:varchar(100);not null"`
+				GroupBy         string `gorm:"type:varchar(20);not null"`
+				IncludeArchived bool   `gorm:"not null;default:false"`
+				Shared          bool   `gorm:"not null;default:false"`
+			}
+			if err := createTables(tx, &view{}); err != nil {
+				return err
+			}
+			return tx.Table("views").AddUniqueIndex("idx_views_user_id_org_id_name", "user_id", "org_id", "name").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.DropTableIfExists("views").Error
+		},
+	},
+	{
+		Version: 18,
+		Name:    "add search_vector to tasks",
+		Up: func(tx *gorm.DB) error {
+			// only Postgres has text search, the other databases search with LIKE
+			if !isPostgres(tx) {
+				return nil
+			}
+			statements := []string{
+				"ALTER TABLE tasks ADD COLUMN search_vector tsvector",
+				// the title weighs above the description above the comments
+				`CREATE FUNCTION tasks_search_vector() RETURNS trigger AS $$
+				BEGIN
+					NEW.search_vector :=
+						setweight(to_tsvector('english', NEW.title), 'A') ||
+						setweight(to_tsvector('english', NEW.description), 'B') ||
+						setweight(to_tsvector('english', coalesce((SELECT string_agg(body, ' ' ORDER BY id) FROM comments
+							WHERE task_id = NEW.id AND deleted_at IS NULL), '')), 'C');
+					RETURN NEW;
+				END
+				$$ LANGUAGE plpgsql`,
+				`CREATE TRIGGER tasks_search_vector BEFORE INSERT OR UPDATE OF title, description ON tasks
+				FOR EACH ROW EXECUTE PROCEDURE tasks_search_vector()`,
+				// touching the title recomputes the vector of the task a comment is on
+				`CREATE FUNCTION comments_search_vector() RETURNS trigger AS $$
+				BEGIN
+					IF TG_OP <> 'INSERT' THEN
+						UPDATE tasks SET title = title WHERE id = OLD.task_id;
+					END IF;
+					IF TG_OP <> 'DELETE' THEN
+						UPDATE tasks SET title = title WHERE id = NEW.task_id;
+					END IF;
+					RETURN NULL;
+				END
+				$$ LANGUAGE plpgsql`,
+				`CREATE TRIGGER comments_search_vector AFTER INSERT OR UPDATE OR DELETE ON comments
+				FOR EACH ROW EXECUTE PROCEDURE comments_search_vector()`,
+				"UPDATE tasks SET title = title",
+				"CREATE INDEX idx_tasks_search_vector ON tasks USING GIN (search_vector)",
+			}
+			for _, statement := range statements {
+				if err := tx.Exec(statement).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			if !isPostgres(tx) {
+				return nil
+			}
+			statements := []string{
+				"DROP TRIGGER IF EXISTS comments_search_vector ON comments",
+				"DROP FUNCTION IF EXISTS comments_search_vector()",
+				"DROP TRIGGER IF EXISTS tasks_search_vector ON tasks",
+				"DROP FUNCTION IF EXISTS tasks_search_vector()",
+				"DROP INDEX IF EXISTS idx_tasks_search_vector",
+				"ALTER TABLE tasks DROP COLUMN IF EXISTS search_vector",
+			}
+			for _, statement := range statements {
+				if err := tx.Exec(statement).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 19,
+		Name:    "add timezone to tasks",
+		Up: func(tx *gorm.DB) error {
+			// recurrences were computed in UTC so far, which empty stands for
+			return tx.Exec("ALTER TABLE tasks ADD COLUMN timezone varchar(64) NOT NULL DEFAULT ''").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Table("tasks").DropColumn("timezone").Error
+		},
+	},
+	{
+		Version: 20,
+		Name:    "scope usernames to organizations",
+		Up: func(tx *gorm.DB) error {
+			// org_members keeps a copy of the username for the unique index
+			if err := tx.Exec("ALTER TABLE org_members ADD COLUMN username varchar(50) NOT NULL DEFAULT ''").Error; err != nil {
+				return err
+			}
+			err := tx.Exec("UPDATE org_members SET username = (SELECT username FROM users WHERE users.id = org_members.user_id) " +
+				"WHERE user_id IN (SELECT id FROM users)").Error
+			if err != nil {
+				return err
+			}
+			if err := tx.Table("org_members").AddUniqueIndex("idx_org_members_org_id_username", "org_id", "username").Error; err != nil {
+				return err
+			}
+
+			if isPostgres(tx) {
+				if err := tx.Exec("ALTER TABLE users DROP CONSTRAINT IF EXISTS users_username_key").Error; err != nil {
+					return err
+				}
+			} else if err := rebuildUsers(tx, ""); err != nil {
+				return err
+			}
+			return tx.Table("users").AddIndex("idx_users_username", "username").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			// fails when users of different organizations share a username
+			if err := tx.Table("users").RemoveIndex("idx_users_username").Error; err != nil {
+				return err
+			}
+			if isPostgres(tx) {
+				if err := tx.Exec("ALTER TABLE users ADD CONSTRAINT users_username_key UNIQUE (username)").Error; err != nil {
+					return err
+				}
+			} else if err := rebuildUsers(tx, " UNIQUE"); err != nil {
+				return err
+			}
+			if err := tx.Table("org_members").RemoveIndex("idx_org_members_org_id_username").Error; err != nil {
+				return err
+			}
+			return tx.Table("org_members").DropColumn("username").Error
+		},
+	},
+}
+
+// rebuildUsers copies the users table into a new one whose username column
+// ends in unique, as SQLite can't drop or add a column constraint in place
+func rebuildUsers(tx *gorm.DB, unique string) error {
+	statements := []string{
+		`CREATE TABLE users_rebuilt (
+			id integer PRIMARY KEY AUTOINCREMENT,
+			created_at datetime,
+			updated_at datetime,
+			deleted_at datetime,
+			username varchar(50) NOT NULL` + unique + `,
+			password varchar(255) NOT NULL
+		)`,
+		"INSERT INTO users_rebuilt (id, created_at, updated_at, deleted_at, username, password) " +
+			"SELECT id, created_at, updated_at, deleted_at, username, password FROM users",
+		"DROP TABLE users",
+		"ALTER TABLE users_rebuilt RENAME TO users",
+		"CREATE INDEX idx_users_deleted_at ON users (deleted_at)",
+	}
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isPostgres(tx *gorm.DB) bool {
+	return tx.Dialect().GetName() == "postgres"
+}
+
+// timestampType is the column type gorm picks for time.Time
+func timestampType(tx *gorm.DB) string {
+	if isPostgres(tx) {
+		return "timestamp with time zone"
+	}
+	return "datetime"
+}
+
+// createTables skips tables that exist already, which is the case for
+// databases that were created by AutoMigrate
+func createTables(tx *gorm.DB, tables ...interface{}) error {
+	for _, table := range tables {
+		if tx.HasTable(table) {
+			continue
+		}
+		if err := tx.CreateTable(table).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
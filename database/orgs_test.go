@@ -0,0 +1,158 @@
+package database
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"todo-app/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestUsernamesPerOrg(t *testing.T) {
+	eachStore(t, func(t *testing.T, s TaskStore) {
+		users := addUsers(t, s, "alice", "bob", "alice")
+		alice, bob, other := users[0], users[1], users[2]
+
+		// alice is taken in the first organization only
+		if _, err := s.GetUserByUsername(0, "alice"); err != ErrAmbiguousUsername {
+			t.Errorf("alice in any organization: got %v, want %v", err, ErrAmbiguousUsername)
+		}
+		for _, u := range []*models.User{alice, other} {
+			got, err := s.GetUserByUsername(u.OrgID, "alice")
+			if err != nil || got.ID != u.ID {
+				t.Errorf("alice in organization %d: got %v, %v, want user %d", u.OrgID, got, err, u.ID)
+			}
+		}
+		if got, err := s.GetUserByUsername(0, "bob"); err != nil || got.ID != bob.ID {
+			t.Errorf("bob in any organization: got %v, %v, want user %d", got, err, bob.ID)
+		}
+		if _, err := s.GetUserByUsername(other.OrgID, "bob"); err == nil || err.Error() != "record not found" {
+			t.Errorf("bob outside their organizations: got %v, want record not found", err)
+		}
+
+		if _, err := s.AddOrgMember(int(alice.OrgID), int(other.ID), models.RoleMember); err != ErrUsernameTaken {
+			t.Errorf("adding a second alice: got %v, want %v", err, ErrUsernameTaken)
+		}
+		// changing a member's role isn't taking their name again
+		if _, err := s.AddOrgMember(int(alice.OrgID), int(bob.ID), models.RoleOwner); err != nil {
+			t.Errorf("promoting bob: %v", err)
+		}
+
+		// the name is free again once its member leaves
+		if _, err := s.AddOrgMember(int(other.OrgID), int(alice.ID), models.RoleMember); err == nil {
+			t.Fatal("added alice next to the other alice")
+		}
+		if _, err := s.AddOrgMember(int(other.OrgID), int(bob.ID), models.RoleOwner); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.RemoveOrgMember(int(other.OrgID), int(other.ID)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.AddOrgMember(int(other.OrgID), int(alice.ID), models.RoleMember); err != nil {
+			t.Errorf("adding alice after the other alice left: %v", err)
+		}
+		if got, err := s.GetUserByUsername(other.OrgID, "alice"); err != nil || got.ID != alice.ID {
+			t.Errorf("alice in organization %d: got %v, %v, want user %d", other.OrgID, got, err, alice.ID)
+		}
+	})
+}
+
+func TestMigrateUsernames(t *testing.T) {
+	s, err := NewSQLite(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.DB.Close()
+	s.DB.DB().SetMaxOpenConns(1)
+	// the failures below are expected
+	s.DB.LogMode(false)
+	if err := s.MigrateTo(19); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DB.Exec("INSERT INTO users (username, password) VALUES ('alice', 'secret')").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DB.Exec("INSERT INTO users (username, password) VALUES ('alice', 'secret')").Error; err == nil {
+		t.Fatal("usernames were not unique before migration 20")
+	}
+	if err := s.DB.Exec("INSERT INTO organizations (name) VALUES ('alice')").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DB.Exec("INSERT INTO org_members (org_id, user_id, role) VALUES (1, 1, 'owner')").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.GetUserByUsername(1, "alice"); err != nil || got.ID != 1 {
+		t.Errorf("alice in organization 1: got %v, %v, want user 1", got, err)
+	}
+	if _, err := s.AddUser(models.User{Username: "alice", Password: "secret"}); err != nil {
+		t.Errorf("signing up a second alice: %v", err)
+	}
+
+	// going back needs the usernames to be unique again
+	if err := s.MigrateTo(19); err == nil {
+		t.Error("migrated down with two users named alice")
+	}
+	for _, statement := range []string{"DELETE FROM org_members WHERE user_id = 2", "DELETE FROM users WHERE id = 2"} {
+		if err := s.DB.Exec(statement).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.MigrateTo(19); err != nil {
+		t.Errorf("migrating down: %v", err)
+	}
+	if err := s.MigrateUp(); err != nil {
+		t.Errorf("migrating up again: %v", err)
+	}
+}
+
+func TestUpgradeBoltUsernames(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bolt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "todo.db")
+
+	s, err := NewBolt(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice, err := s.AddUser(models.User{Username: "alice", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// write the name the way files did while usernames were global
+	err = s.DB.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(userNamesBucket); err != nil {
+			return err
+		}
+		if err := tx.DeleteBucket(orgUsernamesBucket); err != nil {
+			return err
+		}
+		old, err := tx.CreateBucket([]byte("usernames"))
+		if err != nil {
+			return err
+		}
+		return old.Put([]byte("alice"), itob(alice.ID))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.DB.Close()
+
+	if s, err = NewBolt(path); err != nil {
+		t.Fatal(err)
+	}
+	defer s.DB.Close()
+	for _, idOrg := range []uint{0, alice.OrgID} {
+		if got, err := s.GetUserByUsername(idOrg, "alice"); err != nil || got.ID != alice.ID {
+			t.Errorf("alice in organization %d: got %v, %v, want user %d", idOrg, got, err, alice.ID)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+	"todo-app/models"
+)
+
+// pageTasksFixture creates tasks that share priorities, titles and due
+// dates, some of them without a due date
+func pageTasksFixture(t *testing.T, s TaskStore, u *models.User) []models.Task {
+	t.Helper()
+	day := func(d int) *time.Time {
+		due := time.Date(2026, 3, d, 9, 0, 0, 0, time.UTC)
+		return &due
+	}
+	var tasks []models.Task
+	for _, task := range []models.Task{
+		{Title: "b", Priority: "2", DueAt: day(3)},
+		{Title: "a", Priority: "1"},
+		{Title: "b", Priority: "3", DueAt: day(1)},
+		{Title: "c", Priority: "2", DueAt: day(3)},
+		{Title: "a", Priority: "2"},
+		{Title: "c", Priority: "1", DueAt: day(2)},
+		{Title: "b", Priority: "3"},
+		{Title: "a", Priority: "3", DueAt: day(3)},
+		{Title: "c", Priority: "2", DueAt: day(1)},
+	} {
+		task.Description = "d"
+		created, err := s.CreateTask(u, task)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tasks = append(tasks, *created)
+	}
+	return tasks
+}
+
+// resend passes a cursor through JSON the way clients get it back
+func resend(t *testing.T, c *models.Cursor) *models.Cursor {
+	t.Helper()
+	b, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := models.ParseCursor(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func pageIDs(tasks []models.Task) []uint {
+	ids := make([]uint, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.ID
+	}
+	return ids
+}
+
+func TestPageTasks(t *testing.T) {
+	eachStore(t, func(t *testing.T, s TaskStore) {
+		alice := addUsers(t, s, "alice", "dave")[0]
+		fixture := pageTasksFixture(t, s, alice)
+
+		for _, order := range []string{"", "priority", "-priority", "due_at", "-due_at", "title,-due_at", "-priority,due_at", "due_at,title,-priority"} {
+			keys, err := models.ParseSort(order)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sorted := append([]models.Task(nil), fixture...)
+			sort.Slice(sorted, func(i, j int) bool { return models.CompareTasks(&sorted[i], &sorted[j], keys) < 0 })
+			want := fmt.Sprint(pageIDs(sorted))
+
+			for _, limit := range []int{1, 2, 4, len(fixture)} {
+				name := fmt.Sprintf("sort %q limit %d", order, limit)
+				filter := models.TaskFilter{Sort: keys, Limit: limit}
+
+				// forward from the first page
+				var forward []uint
+				var last *models.TaskPage
+				for n := 0; n <= len(fixture); n++ {
+					page, err := s.GetTasks(alice, filter)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if filter.Cursor == nil && page.Prev != nil {
+						t.Errorf("%s: the first page has a previous page", name)
+					}
+					forward = append(forward, pageIDs(page.Tasks)...)
+					last = page
+					if page.Next == nil {
+						break
+					}
+					filter.Cursor = resend(t, page.Next)
+				}
+				if got := fmt.Sprint(forward); got != want {
+					t.Errorf("%s: paging forward got %s, want %s", name, got, want)
+				}
+				if last.Prev == nil && len(forward) > limit {
+					t.Errorf("%s: the last page has no previous page", name)
+					continue
+				}
+
+				// and back from the last page
+				backward := pageIDs(last.Tasks)
+				for n, prev := 0, last.Prev; prev != nil && n <= len(fixture); n++ {
+					filter.Cursor = resend(t, prev)
+					page, err := s.GetTasks(alice, filter)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if page.Next == nil {
+						t.Errorf("%s: a page read backwards has no next page", name)
+					}
+					backward = append(pageIDs(page.Tasks), backward...)
+					prev = page.Prev
+				}
+				if got := fmt.Sprint(backward); got != want {
+					t.Errorf("%s: paging back got %s, want %s", name, got, want)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,56 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+	"todo-app/models"
+)
+
+func complete(done bool) models.UpdateTask {
+	return models.UpdateTask{Merge: map[string]json.RawMessage{"completed": json.RawMessage(fmt.Sprint(done))}}
+}
+
+func TestReopenedOccurrence(t *testing.T) {
+	eachStore(t, func(t *testing.T, s TaskStore) {
+		alice := addUsers(t, s, "alice", "dave")[0]
+		due := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+		task, err := s.CreateTask(alice, models.Task{
+			Title: "chore", Description: "d", Priority: "1", DueAt: &due, Recurrence: "FREQ=DAILY",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		done, err := s.UpdateTask(alice, complete(true), int(task.ID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if done.NextOccurrence == nil || done.NextOccurrence.Occurrence != 2 {
+			t.Fatalf("completing occurrence 1 spawned %+v, want occurrence 2", done.NextOccurrence)
+		}
+		if _, err := s.UpdateTask(alice, complete(false), int(task.ID)); err != nil {
+			t.Fatal(err)
+		}
+		again, err := s.UpdateTask(alice, complete(true), int(task.ID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if again.NextOccurrence != nil {
+			t.Errorf("completing occurrence 1 again spawned task %d", again.NextOccurrence.ID)
+		}
+
+		page, err := s.GetTasks(alice, models.TaskFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		occurrences := make(map[int]int)
+		for _, task := range page.Tasks {
+			occurrences[task.Occurrence]++
+		}
+		if len(page.Tasks) != 2 || occurrences[1] != 1 || occurrences[2] != 1 {
+			t.Errorf("got occurrences %v, want 1 and 2 once each", occurrences)
+		}
+	})
+}
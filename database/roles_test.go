@@ -0,0 +1,240 @@
+package database
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"todo-app/models"
+)
+
+// eachStore runs test against a fresh store of every kind, the SQL one is
+// SQLite in memory
+func eachStore(t *testing.T, test func(t *testing.T, s TaskStore)) {
+	t.Run("sql", func(t *testing.T) {
+		s, err := NewSQLite(":memory:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.DB.Close()
+		// every new connection to :memory: would get its own empty database
+		s.DB.DB().SetMaxOpenConns(1)
+		if err := s.MigrateUp(); err != nil {
+			t.Fatal(err)
+		}
+		test(t, s)
+	})
+	t.Run("memory", func(t *testing.T) {
+		s, err := NewMemory()
+		if err != nil {
+			t.Fatal(err)
+		}
+		test(t, s)
+	})
+	t.Run("bolt", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "bolt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		s, err := NewBolt(filepath.Join(dir, "todo.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.DB.Close()
+		test(t, s)
+	})
+}
+
+// addUsers signs up users named after names, every one but the last joins
+// the organization of the first
+func addUsers(t *testing.T, s TaskStore, names ...string) []*models.User {
+	var users []*models.User
+	for i, name := range names {
+		u, err := s.AddUser(models.User{Username: name, Password: "secret"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i > 0 && i < len(names)-1 {
+			if _, err := s.AddOrgMember(int(users[0].OrgID), int(u.ID), models.RoleMember); err != nil {
+				t.Fatal(err)
+			}
+			u.OrgID = users[0].OrgID
+		}
+		users = append(users, u)
+	}
+	return users
+}
+
+func wantRole(t *testing.T, s TaskStore, u *models.User, idTask uint, want models.Role) {
+	t.Helper()
+	role, err := s.GetTaskRole(u, int(idTask))
+	if want == "" {
+		if err == nil || err.Error() != "record not found" {
+			t.Errorf("%s's role: got %q, %v, want record not found", u.Username, role, err)
+		}
+		return
+	}
+	if err != nil || role != want {
+		t.Errorf("%s's role: got %q, %v, want %q", u.Username, role, err, want)
+	}
+}
+
+func TestTaskRoles(t *testing.T) {
+	eachStore(t, func(t *testing.T, s TaskStore) {
+		users := addUsers(t, s, "alice", "bob", "carol", "dave")
+		alice, bob, carol, dave := users[0], users[1], users[2], users[3]
+
+		task, err := s.CreateTask(alice, models.Task{Title: "task", Description: "d", Priority: "1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := s.AddUserToTask(alice, int(bob.ID), int(task.ID), models.RoleEditor); err != nil {
+			t.Fatal(err)
+		}
+		wantRole(t, s, alice, task.ID, models.RoleOwner)
+		wantRole(t, s, bob, task.ID, models.RoleEditor)
+		wantRole(t, s, carol, task.ID, "")
+		wantRole(t, s, dave, task.ID, "")
+
+		// tasks stay within their organization
+		if _, _, err := s.AddUserToTask(alice, int(dave.ID), int(task.ID), models.RoleViewer); err == nil {
+			t.Error("added a user of another organization")
+		}
+
+		if _, _, err := s.AddUserToTask(alice, int(bob.ID), int(task.ID), models.RoleViewer); err != nil {
+			t.Fatal(err)
+		}
+		wantRole(t, s, bob, task.ID, models.RoleViewer)
+		if _, _, err := s.RemoveUserFromTask(alice, int(bob.ID), int(task.ID)); err != nil {
+			t.Fatal(err)
+		}
+		wantRole(t, s, bob, task.ID, "")
+	})
+}
+
+func TestProjectRoles(t *testing.T) {
+	eachStore(t, func(t *testing.T, s TaskStore) {
+		users := addUsers(t, s, "alice", "bob", "carol", "dave")
+		alice, bob, carol := users[0], users[1], users[2]
+
+		project, err := s.CreateProject(alice, models.Project{Name: "project"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.AddProjectMember(int(project.ID), int(bob.ID), models.RoleEditor); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.AddProjectMember(int(project.ID), int(carol.ID), models.RoleViewer); err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []struct {
+			user *models.User
+			role models.Role
+		}{{alice, models.RoleOwner}, {bob, models.RoleEditor}, {carol, models.RoleViewer}} {
+			role, err := s.GetProjectRole(want.user, int(project.ID))
+			if err != nil || role != want.role {
+				t.Errorf("%s's project role: got %q, %v, want %q", want.user.Username, role, err, want.role)
+			}
+		}
+		if _, err := s.GetProjectRole(users[3], int(project.ID)); err == nil || err.Error() != "record not found" {
+			t.Errorf("dave's project role: got %v, want record not found", err)
+		}
+
+		// the project role carries over to its tasks, the higher role wins
+		task, err := s.CreateTask(alice, models.Task{Title: "task", Description: "d", Priority: "1", ProjectID: &project.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := s.AddUserToTask(alice, int(carol.ID), int(task.ID), models.RoleEditor); err != nil {
+			t.Fatal(err)
+		}
+		wantRole(t, s, bob, task.ID, models.RoleEditor)
+		wantRole(t, s, carol, task.ID, models.RoleEditor)
+		wantRole(t, s, users[3], task.ID, "")
+	})
+}
+
+func TestLastOwner(t *testing.T) {
+	eachStore(t, func(t *testing.T, s TaskStore) {
+		users := addUsers(t, s, "alice", "bob", "dave")
+		alice, bob := users[0], users[1]
+
+		task, err := s.CreateTask(alice, models.Task{Title: "task", Description: "d", Priority: "1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := s.RemoveUserFromTask(alice, int(alice.ID), int(task.ID)); err != ErrLastOwner {
+			t.Errorf("removing the last owner: got %v, want %v", err, ErrLastOwner)
+		}
+		if _, _, err := s.AddUserToTask(alice, int(alice.ID), int(task.ID), models.RoleEditor); err != ErrLastOwner {
+			t.Errorf("demoting the last owner: got %v, want %v", err, ErrLastOwner)
+		}
+		if _, _, err := s.AddUserToTask(alice, int(bob.ID), int(task.ID), models.RoleOwner); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := s.RemoveUserFromTask(alice, int(alice.ID), int(task.ID)); err != nil {
+			t.Errorf("removing an owner next to another: %v", err)
+		}
+		wantRole(t, s, bob, task.ID, models.RoleOwner)
+
+		project, err := s.CreateProject(alice, models.Project{Name: "project"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.RemoveProjectMember(int(project.ID), int(alice.ID)); err != ErrLastProjectOwner {
+			t.Errorf("removing the last project owner: got %v, want %v", err, ErrLastProjectOwner)
+		}
+		if _, err := s.AddProjectMember(int(project.ID), int(alice.ID), models.RoleViewer); err != ErrLastProjectOwner {
+			t.Errorf("demoting the last project owner: got %v, want %v", err, ErrLastProjectOwner)
+		}
+		if _, err := s.AddProjectMember(int(project.ID), int(bob.ID), models.RoleOwner); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.RemoveProjectMember(int(project.ID), int(alice.ID)); err != nil {
+			t.Errorf("removing a project owner next to another: %v", err)
+		}
+	})
+}
+
+func TestBatchRoles(t *testing.T) {
+	eachStore(t, func(t *testing.T, s TaskStore) {
+		users := addUsers(t, s, "alice", "bob", "dave")
+		alice, bob := users[0], users[1]
+
+		task, err := s.CreateTask(alice, models.Task{Title: "task", Description: "d", Priority: "1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := s.AddUserToTask(alice, int(bob.ID), int(task.ID), models.RoleEditor); err != nil {
+			t.Fatal(err)
+		}
+
+		// an editor can complete the task but not delete it, the partial
+		// batch keeps the completion
+		ops := []models.BatchOp{
+			{Op: models.BatchComplete, ID: int(task.ID)},
+			{Op: models.BatchDelete, ID: int(task.ID)},
+		}
+		results, err := s.RunBatch(bob, ops, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 2 {
+			t.Fatalf("got %d results, want 2", len(results))
+		}
+		if results[0].Err != nil {
+			t.Errorf("completing as an editor: %v", results[0].Err)
+		}
+		if _, ok := results[1].Err.(*RoleError); !ok {
+			t.Errorf("deleting as an editor: got %v, want a RoleError", results[1].Err)
+		}
+		got, err := s.GetTask(alice, int(task.ID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Completed {
+			t.Error("the completion was rolled back with the failed delete")
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package database
+
+import (
+	"fmt"
+	"testing"
+	"todo-app/models"
+)
+
+// TestSearchFallback covers the search of the stores without Postgres, sql
+// runs on SQLite here
+func TestSearchFallback(t *testing.T) {
+	eachStore(t, func(t *testing.T, s TaskStore) {
+		users := addUsers(t, s, "alice", "dave")
+		alice, dave := users[0], users[1]
+
+		var ids []uint
+		for _, task := range []models.Task{
+			{Title: "notes", Description: "read before the deploy"},
+			{Title: "Deploy the API", Description: "after the release"},
+			{Title: "release", Description: "tag it"},
+			{Title: "retro", Description: "sprint 12"},
+		} {
+			task.Priority = "1"
+			created, err := s.CreateTask(alice, task)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, created.ID)
+		}
+		for _, c := range []struct {
+			task uint
+			body string
+		}{{ids[2], "deploy on Friday"}, {ids[3], "went fine"}} {
+			if _, err := s.CreateComment(alice, int(c.task), models.Comment{Body: c.body}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := s.CreateTask(dave, models.Task{Title: "deploy", Description: "d", Priority: "1"}); err != nil {
+			t.Fatal(err)
+		}
+
+		tests := []struct {
+			query string
+			want  []uint
+		}{
+			// the title ranks above the description, which ranks above comments
+			{"deploy", []uint{ids[1], ids[0], ids[2]}},
+			{"DEPLOY release", []uint{ids[1], ids[2]}},
+			{"fine", []uint{ids[3]}},
+			{"fine deploy", nil},
+			{"deploy%", []uint{ids[1], ids[0], ids[2]}},
+			{"nothing", nil},
+		}
+		for _, tt := range tests {
+			results, err := s.SearchTasks(alice, models.TaskSearch{Query: tt.query, Terms: models.SearchTerms(tt.query)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []uint
+			for _, r := range *results {
+				got = append(got, r.Task.ID)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("%q: got tasks %v, want %v", tt.query, got, tt.want)
+			}
+		}
+
+		results, err := s.SearchTasks(alice, models.TaskSearch{Query: "friday", Terms: []string{"friday"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "release tag it deploy on <mark>Friday</mark>"; len(*results) != 1 || (*results)[0].Snippet != want {
+			t.Errorf("snippet of a comment match: got %+v, want %q", *results, want)
+		}
+	})
+}
@@ -0,0 +1,735 @@
+package database
+
+import (
+	"fmt"
+	"time"
+	"todo-app/models"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/pkg/errors"
+)
+
+// SQLStore implements TaskStore on top of gorm for postgres and sqlite
+type SQLStore struct {
+	DB *gorm.DB
+}
+
+var _ TaskStore = &SQLStore{}
+
+func NewPostgres(host, user, name, password string) (*SQLStore, error) {
+	credentials := fmt.Sprintf("host=%s user=%s dbname=%s sslmode=disable password=%s", host, user, name, password)
+
+	db, err := gorm.Open("postgres", credentials)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to connect to database")
+	}
+
+	return &SQLStore{
+		DB: db,
+	}, nil
+}
+
+func NewSQLite(path string) (*SQLStore, error) {
+	db, err := gorm.Open("sqlite3", path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open sqlite database")
+	}
+
+	return &SQLStore{
+		DB: db,
+	}, nil
+}
+
+// AddUser also creates the user's own organization
+func (s *SQLStore) AddUser(u models.User) (*models.User, error) {
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer tx.RollbackUnlessCommitted()
+
+	result := tx.Create(&u)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	org := models.Organization{Name: u.Username}
+	if err := tx.Create(&org).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Create(&models.OrgMember{OrgID: org.ID, UserID: u.ID, Role: models.RoleOwner, Username: u.Username}).Error; err != nil {
+		return nil, err
+	}
+	u.OrgID = org.ID
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (s *SQLStore) GetUserByUsername(idOrg uint, username string) (*models.User, error) {
+	if idOrg != 0 {
+		var user models.User
+		result := s.DB.Joins("JOIN org_members ON org_members.user_id = users.id").
+			Where("org_members.org_id = ? AND org_members.username = ?", idOrg, username).
+			First(&user)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		return &user, nil
+	}
+
+	var users []models.User
+	if err := s.DB.Where("username = ?", username).Order("id").Limit(2).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	switch len(users) {
+	case 0:
+		return nil, ErrRecordNotFound
+	case 1:
+		return &users[0], nil
+	}
+	return nil, ErrAmbiguousUsername
+}
+
+func (s *SQLStore) GetUserById(id uint) (*models.User, error) {
+	var user models.User
+	result := s.DB.First(&user, id)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &user, nil
+}
+
+func (s *SQLStore) CreateTask(u *models.User, t models.Task) (*models.Task, error) {
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer tx.RollbackUnlessCommitted()
+
+	if err := createTask(tx, u, &t); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// createTask makes u the owner of a new task
+func createTask(tx *gorm.DB, u *models.User, t *models.Task) error {
+	t.OrgID = u.OrgID
+	if err := tx.Create(t).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Create(&models.UserTask{UserID: u.ID, TaskID: t.ID, Role: models.RoleOwner}).Error; err != nil {
+		return err
+	}
+
+	if t.StartSeries() {
+		if err := tx.Model(t).Updates(map[string]interface{}{"series_id": t.SeriesID, "occurrence": t.Occurrence}).Error; err != nil {
+			return err
+		}
+	}
+	return logActivity(tx, models.TaskActivity(u, nil, t))
+}
+
+func (s *SQLStore) GetTasks(u *models.User, filter models.TaskFilter) (*models.TaskPage, error) {
+	q := visibleTo(s.DB.Table("tasks").Select("tasks.*"), u)
+
+	if filter.Completed != nil {
+		q = q.Where("tasks.completed = ?", *filter.Completed)
+	}
+	if filter.Priority != "" {
+		q = q.Where("tasks.priority = ?", filter.Priority)
+	}
+	if filter.DueBefore != nil {
+		q = q.Where("tasks.due_at < ?", *filter.DueBefore)
+	}
+	if filter.DueAfter != nil {
+		q = q.Where("tasks.due_at > ?", *filter.DueAfter)
+	}
+	if filter.Overdue != nil {
+		now := time.Now().UTC()
+		if *filter.Overdue {
+			q = q.Where("tasks.completed = ? AND tasks.due_at < ?", false, now)
+		} else {
+			q = q.Where("tasks.completed = ? OR tasks.due_at IS NULL OR tasks.due_at >= ?", true, now)
+		}
+	}
+	q = filterLabels(q, u, filter)
+	if filter.ProjectID != nil {
+		q = q.Where("tasks.project_id = ?", *filter.ProjectID)
+	}
+	if !filter.IncludeArchived {
+		q = q.Where("tasks.archived_at IS NULL")
+	}
+	if filter.Expr != nil {
+		where, args := filterSQL(filter.Expr)
+		q = q.Where(where, args...)
+	}
+
+	before := filter.Cursor != nil && filter.Cursor.Before
+	if filter.Cursor != nil {
+		where, args := pastCursor(filter.Sort, filter.Cursor)
+		q = q.Where(where, args...)
+	}
+	q = q.Order(orderBy(filter.Sort, before))
+	if filter.Limit > 0 {
+		// one more tells whether there is another page
+		q = q.Limit(filter.Limit + 1)
+	}
+
+	tasks := []models.Task{}
+	if err := q.Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+	page := models.NewTaskPage(tasks, filter)
+
+	ptrs := make([]*models.Task, len(page.Tasks))
+	for i := range page.Tasks {
+		ptrs[i] = &page.Tasks[i]
+	}
+	if err := markBlocked(s.DB, ptrs...); err != nil {
+		return nil, err
+	}
+	if err := markLabels(s.DB, u, ptrs...); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// visibleTo limits q to the tasks of u's organization that u is a member of,
+// directly or through their project
+func visibleTo(q *gorm.DB, u *models.User) *gorm.DB {
+	return q.Where("tasks.org_id = ?", u.OrgID).
+		Where("tasks.id IN (SELECT task_id FROM user_tasks WHERE user_id = ?) OR "+
+			"tasks.project_id IN (SELECT project_id FROM project_members WHERE user_id = ?)", u.ID, u.ID)
+}
+
+// findTask loads task id into task if u can see it
+func findTask(db *gorm.DB, u *models.User, id int, task *models.Task) error {
+	return visibleTo(db.Where("tasks.id = ?", id), u).First(task).Error
+}
+
+func (s *SQLStore) GetTask(u *models.User, id int) (*models.Task, error) {
+	var task models.Task
+	if err := findTask(s.DB.Preload("Users"), u, id, &task); err != nil {
+		return nil, err
+	}
+
+	descendants, err := descendants(s.DB, task.ID)
+	if err != nil {
+		return nil, err
+	}
+	task.Progress = models.NewProgress(descendants)
+
+	if err := markBlocked(s.DB, &task); err != nil {
+		return nil, err
+	}
+	if err := markLabels(s.DB, u, &task); err != nil {
+		return nil, err
+	}
+	if err := s.DB.Where("task_id = ?", task.ID).Order("id").Find(&task.Attachments).Error; err != nil {
+		return nil, err
+	}
+	if task.Checklist, err = checklist(s.DB, task.ID); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// descendants walks down the subtasks of a task one level at a time
+func descendants(db *gorm.DB, id uint) ([]models.Task, error) {
+	var all []models.Task
+	parents := []uint{id}
+	for len(parents) > 0 {
+		var children []models.Task
+		if err := db.Where("parent_id IN (?)", parents).Order("id").Find(&children).Error; err != nil {
+			return nil, err
+		}
+
+		parents = nil
+		for _, child := range children {
+			parents = append(parents, child.ID)
+		}
+		all = append(all, children...)
+	}
+	return all, nil
+}
+
+func taskIDs(tasks []models.Task) []uint {
+	ids := make([]uint, 0, len(tasks))
+	for _, t := range tasks {
+		ids = append(ids, t.ID)
+	}
+	return ids
+}
+
+func (s *SQLStore) CreateSubtask(u *models.User, idParent int, t models.Task) (*models.Task, error) {
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer tx.RollbackUnlessCommitted()
+
+	var parent models.Task
+	if err := findTask(tx, u, idParent, &parent); err != nil {
+		return nil, err
+	}
+
+	t.ParentID, t.ProjectID, t.OrgID = &parent.ID, parent.ProjectID, parent.OrgID
+	if err := tx.Create(&t).Error; err != nil {
+		return nil, err
+	}
+	err := tx.Exec("INSERT INTO user_tasks (user_id, task_id, role) SELECT user_id, ?, role FROM user_tasks WHERE task_id = ?", t.ID, parent.ID).Error
+	if err != nil {
+		return nil, err
+	}
+	if t.StartSeries() {
+		if err := tx.Model(&t).Updates(map[string]interface{}{"series_id": t.SeriesID, "occurrence": t.Occurrence}).Error; err != nil {
+			return nil, err
+		}
+	}
+	if err := logActivity(tx, models.TaskActivity(u, nil, &t)); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (s *SQLStore) GetTaskTree(u *models.User, id int) (*models.Task, error) {
+	var task models.Task
+	if err := findTask(s.DB, u, id, &task); err != nil {
+		return nil, err
+	}
+
+	descendants, err := descendants(s.DB, task.ID)
+	if err != nil {
+		return nil, err
+	}
+	task.Progress = models.NewProgress(descendants)
+	models.BuildTree(&task, descendants)
+
+	return &task, nil
+}
+
+// GetTaskRole returns the higher of u's role on the task and on its project
+func (s *SQLStore) GetTaskRole(u *models.User, idTask int) (models.Role, error) {
+	var task models.Task
+	if err := findTask(s.DB, u, idTask, &task); err != nil {
+		return "", err
+	}
+	return userRole(s.DB, u, &task)
+}
+
+// userRole is the higher of u's role on task and on its project
+func userRole(db *gorm.DB, u *models.User, task *models.Task) (models.Role, error) {
+	var member models.UserTask
+	err := db.Where("user_id = ? AND task_id = ?", u.ID, task.ID).Take(&member).Error
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		return "", err
+	}
+	role := member.Role
+
+	if task.ProjectID != nil {
+		var pm models.ProjectMember
+		err := db.Where("project_id = ? AND user_id = ?", *task.ProjectID, u.ID).Take(&pm).Error
+		if err != nil && !gorm.IsRecordNotFoundError(err) {
+			return "", err
+		}
+		if !role.AtLeast(pm.Role) {
+			role = pm.Role
+		}
+	}
+	return role, nil
+}
+
+// requireRole returns a RoleError unless u has role on every task, for the
+// subtasks a change to their parent goes on to
+func requireRole(db *gorm.DB, u *models.User, tasks []models.Task, role models.Role) error {
+	for i := range tasks {
+		got, err := userRole(db, u, &tasks[i])
+		if err != nil {
+			return err
+		}
+		if !got.AtLeast(role) {
+			return &RoleError{Role: role}
+		}
+	}
+	return nil
+}
+
+// demotesLastOwner reports whether taking member's owner role away would
+// leave the task without owners
+func demotesLastOwner(db *gorm.DB, member models.UserTask) (bool, error) {
+	if member.Role != models.RoleOwner {
+		return false, nil
+	}
+
+	var owners int
+	err := db.Model(&models.UserTask{}).
+		Where("task_id = ? AND role = ?", member.TaskID, models.RoleOwner).
+		Count(&owners).Error
+	return owners <= 1, err
+}
+
+func (s *SQLStore) AddUserToTask(u *models.User, idUser, idTask int, role models.Role) (*models.User, *models.Task, error) {
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return nil, nil, tx.Error
+	}
+	defer tx.RollbackUnlessCommitted()
+
+	var user models.User
+	if err := tx.First(&user, idUser).Error; err != nil {
+		return nil, nil, err
+	}
+	var task models.Task
+	if err := tx.First(&task, idTask).Error; err != nil {
+		return nil, nil, err
+	}
+	// tasks are only shared within their organization
+	if _, err := orgMemberRole(tx, user.ID, task.OrgID); err != nil {
+		return nil, nil, err
+	}
+
+	var member models.UserTask
+	err := tx.Where("user_id = ? AND task_id = ?", user.ID, task.ID).Take(&member).Error
+	before := member.Role
+	if gorm.IsRecordNotFoundError(err) {
+		err = tx.Create(&models.UserTask{UserID: user.ID, TaskID: task.ID, Role: role}).Error
+	} else if err == nil && member.Role != role {
+		// already a member, only the role changes
+		if role != models.RoleOwner {
+			last, err := demotesLastOwner(tx, member)
+			if err != nil {
+				return nil, nil, err
+			}
+			if last {
+				return nil, nil, ErrLastOwner
+			}
+		}
+		err = tx.Model(&member).Update("role", role).Error
+	} else if err == nil {
+		return &user, &task, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := logActivity(tx, models.MemberActivity(u, &task, user.ID, before, role)); err != nil {
+		return nil, nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, nil, err
+	}
+	return &user, &task, nil
+}
+
+func (s *SQLStore) RemoveUserFromTask(u *models.User, idUser, idTask int) (*models.User, *models.Task, error) {
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return nil, nil, tx.Error
+	}
+	defer tx.RollbackUnlessCommitted()
+
+	var user models.User
+	if err := tx.First(&user, idUser).Error; err != nil {
+		return nil, nil, err
+	}
+	var task models.Task
+	if err := tx.First(&task, idTask).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var member models.UserTask
+	if err := tx.Where("user_id = ? AND task_id = ?", user.ID, task.ID).Take(&member).Error; err != nil {
+		return nil, nil, err
+	}
+	last, err := demotesLastOwner(tx, member)
+	if err != nil {
+		return nil, nil, err
+	}
+	if last {
+		return nil, nil, ErrLastOwner
+	}
+
+	if err := tx.Delete(&member).Error; err != nil {
+		return nil, nil, err
+	}
+	if err := logActivity(tx, models.MemberActivity(u, &task, user.ID, member.Role, "")); err != nil {
+		return nil, nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, nil, err
+	}
+	return &user, &task, nil
+}
+
+func (s *SQLStore) UpdateTask(u *models.User, t models.UpdateTask, idTask int) (*models.Task, error) {
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer tx.RollbackUnlessCommitted()
+
+	task, err := s.updateTask(tx, u, t, idTask)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+func (s *SQLStore) updateTask(tx *gorm.DB, u *models.User, t models.UpdateTask, idTask int) (*models.Task, error) {
+	var task models.Task
+	if err := findTask(tx, u, idTask, &task); err != nil {
+		return nil, err
+	}
+	if !versionMatches(t.IfMatch, task.Version) {
+		return nil, ErrVersionMismatch
+	}
+	before := task
+	if err := t.Apply(&task); err != nil {
+		return nil, err
+	}
+	wasCompleted := before.Completed
+
+	if task.Completed && !wasCompleted && !t.Force {
+		blockers, err := openBlockers(tx, []uint{task.ID})
+		if err != nil {
+			return nil, err
+		}
+		if len(blockers[task.ID]) > 0 {
+			return nil, ErrBlocked
+		}
+	}
+	now := time.Now()
+	if task.Completed != wasCompleted {
+		task.CompletedAt = nil
+		if task.Completed {
+			task.CompletedAt = &now
+		}
+	}
+	task.StartSeries()
+
+	// a map so that cleared fields are written too, the version guards
+	// against changes made since the task was read
+	result := tx.Model(&task).Where("version = ?", before.Version).Updates(map[string]interface{}{
+		"title":        task.Title,
+		"description":  task.Description,
+		"priority":     task.Priority,
+		"completed":    task.Completed,
+		"completed_at": task.CompletedAt,
+		"start_at":     task.StartAt,
+		"due_at":       task.DueAt,
+		"recurrence":   task.Recurrence,
+		"timezone":     task.Timezone,
+		"series_id":    task.SeriesID,
+		"occurrence":   task.Occurrence,
+		"version":      gorm.Expr("version + 1"),
+	})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrVersionMismatch
+	}
+	task.Version = before.Version + 1
+	if err := logActivity(tx, models.TaskActivity(u, &before, &task)); err != nil {
+		return nil, err
+	}
+
+	if task.Completed && !wasCompleted {
+		next, err := s.spawnNext(tx, &task)
+		if err != nil {
+			return nil, err
+		}
+		if next != nil {
+			if err := logActivity(tx, models.TaskActivity(u, nil, next)); err != nil {
+				return nil, err
+			}
+		}
+		task.NextOccurrence = next
+
+		// completing a task completes everything below it
+		if err := s.completeDescendants(tx, u, &task, t.Force, now); err != nil {
+			return nil, err
+		}
+	}
+
+	return &task, nil
+}
+
+// completeDescendants completes the open tasks below task as completing each
+// one directly would. u has to be an editor of every one, none may wait on a
+// blocker outside the subtree unless force, and recurring ones spawn their
+// next occurrence.
+func (s *SQLStore) completeDescendants(tx *gorm.DB, u *models.User, task *models.Task, force bool, now time.Time) error {
+	children, err := descendants(tx, task.ID)
+	if err != nil {
+		return err
+	}
+	subtree := map[uint]bool{task.ID: true}
+	var open []models.Task
+	for _, child := range children {
+		subtree[child.ID] = true
+		if !child.Completed {
+			open = append(open, child)
+		}
+	}
+	if len(open) == 0 {
+		return nil
+	}
+
+	if err := requireRole(tx, u, open, models.RoleEditor); err != nil {
+		return err
+	}
+	if !force {
+		blockers, err := openBlockers(tx, taskIDs(open))
+		if err != nil {
+			return err
+		}
+		for _, ids := range blockers {
+			for _, id := range ids {
+				if !subtree[id] {
+					return ErrBlocked
+				}
+			}
+		}
+	}
+
+	for _, child := range open {
+		completed := child
+		completed.Completed, completed.CompletedAt = true, &now
+		result := tx.Model(&completed).Where("version = ?", child.Version).
+			Updates(map[string]interface{}{"completed": true, "completed_at": now, "version": gorm.Expr("version + 1")})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrVersionMismatch
+		}
+		completed.Version = child.Version + 1
+		if err := logActivity(tx, models.TaskActivity(u, &child, &completed)); err != nil {
+			return err
+		}
+
+		next, err := s.spawnNext(tx, &completed)
+		if err != nil {
+			return err
+		}
+		if next != nil {
+			if err := logActivity(tx, models.TaskActivity(u, nil, next)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// spawnNext creates the occurrence that follows a completed recurring task,
+// shared with the same members. Completing a reopened task spawns nothing
+// when its next occurrence exists already, even in the trash.
+func (s *SQLStore) spawnNext(tx *gorm.DB, task *models.Task) (*models.Task, error) {
+	next := task.NextInSeries()
+	if next == nil {
+		return nil, nil
+	}
+	var spawned int
+	err := tx.Unscoped().Model(&models.Task{}).
+		Where("series_id = ? AND occurrence = ?", *next.SeriesID, next.Occurrence).
+		Count(&spawned).Error
+	if err != nil {
+		return nil, err
+	}
+	if spawned > 0 {
+		return nil, nil
+	}
+
+	if err := tx.Create(next).Error; err != nil {
+		return nil, err
+	}
+	err = tx.Exec("INSERT INTO user_tasks (user_id, task_id, role) SELECT user_id, ?, role FROM user_tasks WHERE task_id = ?", next.ID, task.ID).Error
+	if err != nil {
+		return nil, err
+	}
+	return next, nil
+}
+
+func (s *SQLStore) DeleteTask(u *models.User, idTask int, ifMatch []uint) (*models.Task, error) {
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer tx.RollbackUnlessCommitted()
+
+	task, err := deleteTask(tx, u, idTask, ifMatch)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+func deleteTask(tx *gorm.DB, u *models.User, idTask int, ifMatch []uint) (*models.Task, error) {
+	var task models.Task
+	if err := findTask(tx, u, idTask, &task); err != nil {
+		return nil, err
+	}
+	if !versionMatches(ifMatch, task.Version) {
+		return nil, ErrVersionMismatch
+	}
+
+	// subtasks go along with their parent, if u owns them too
+	children, err := descendants(tx, task.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireRole(tx, u, children, models.RoleOwner); err != nil {
+		return nil, err
+	}
+	// soft deleted by hand so the version can guard the task, the trash
+	// needs the subtasks to share its deleted_at. Trashing is a change like
+	// any other and bumps the version, as restoring does.
+	now := gorm.NowFunc()
+	trashed := map[string]interface{}{"deleted_at": now, "version": gorm.Expr("version + 1")}
+	result := tx.Model(&models.Task{}).Where("id = ? AND version = ?", task.ID, task.Version).UpdateColumns(trashed)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrVersionMismatch
+	}
+	if len(children) > 0 {
+		if err := tx.Model(&models.Task{}).Where("id IN (?)", taskIDs(children)).UpdateColumns(trashed).Error; err != nil {
+			return nil, err
+		}
+	}
+	for _, deleted := range append(children, task) {
+		if err := logActivity(tx, models.TaskActivity(u, &deleted, nil)); err != nil {
+			return nil, err
+		}
+	}
+
+	task.DeletedAt, task.Version = &now, task.Version+1
+	return &task, nil
+}
@@ -0,0 +1,81 @@
+package database
+
+import (
+	"todo-app/models"
+
+	"github.com/jinzhu/gorm"
+)
+
+// logActivity appends a to the activity log, updates that changed nothing
+// are left out
+func logActivity(db *gorm.DB, a models.Activity) error {
+	if a.Action == models.ActivityUpdate && len(a.Changes) == 0 {
+		return nil
+	}
+	a.ID = 0
+	return db.Create(&a).Error
+}
+
+func (s *SQLStore) GetTaskHistory(u *models.User, idTask int) (*[]models.Activity, error) {
+	var task models.Task
+	if err := findTask(s.DB, u, idTask, &task); err != nil {
+		return nil, err
+	}
+
+	activities := []models.Activity{}
+	if err := s.DB.Where("task_id = ?", task.ID).Order("id").Find(&activities).Error; err != nil {
+		return nil, err
+	}
+	if err := loadActors(s.DB, activities); err != nil {
+		return nil, err
+	}
+	return &activities, nil
+}
+
+func (s *SQLStore) GetActivity(u *models.User, filter models.ActivityFilter) (*[]models.Activity, error) {
+	// deleted tasks stay in the feed of their members
+	q := s.DB.Where("org_id = ?", u.OrgID).
+		Where("actor_id = ? OR member_id = ? OR "+
+			"task_id IN (SELECT task_id FROM user_tasks WHERE user_id = ?) OR "+
+			"task_id IN (SELECT id FROM tasks WHERE project_id IN (SELECT project_id FROM project_members WHERE user_id = ?))",
+			u.ID, u.ID, u.ID, u.ID)
+	if filter.Before != 0 {
+		q = q.Where("id < ?", filter.Before)
+	}
+	if filter.Limit > 0 {
+		q = q.Limit(filter.Limit)
+	}
+
+	activities := []models.Activity{}
+	if err := q.Order("id DESC").Find(&activities).Error; err != nil {
+		return nil, err
+	}
+	if err := loadActors(s.DB, activities); err != nil {
+		return nil, err
+	}
+	return &activities, nil
+}
+
+// loadActors fills in who did each activity
+func loadActors(db *gorm.DB, activities []models.Activity) error {
+	if len(activities) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(activities))
+	for i := range activities {
+		ids[i] = activities[i].ActorID
+	}
+
+	var users []models.User
+	if err := db.Where("id IN (?)", ids).Find(&users).Error; err != nil {
+		return err
+	}
+	byID := make(map[uint]*models.User)
+	for i := range users {
+		byID[users[i].ID] = &users[i]
+	}
+	for i := range activities {
+		activities[i].Actor = byID[activities[i].ActorID]
+	}
+	return nil
+}
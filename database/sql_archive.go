@@ -0,0 +1,131 @@
+package database
+
+import (
+	"time"
+	"todo-app/models"
+
+	"github.com/jinzhu/gorm"
+)
+
+func (s *SQLStore) ArchiveTask(u *models.User, idTask int) (*models.Task, error) {
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer tx.RollbackUnlessCommitted()
+
+	var task models.Task
+	if err := findTask(tx, u, idTask, &task); err != nil {
+		return nil, err
+	}
+	children, err := descendants(tx, task.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// u has to edit every subtask the archive reaches, like the task itself
+	var changed []models.Task
+	for _, old := range append(children, task) {
+		if old.ArchivedAt == nil {
+			changed = append(changed, old)
+		}
+	}
+	if err := requireRole(tx, u, changed, models.RoleEditor); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for _, old := range changed {
+		if err := tx.Model(&models.Task{}).Where("id = ?", old.ID).Updates(map[string]interface{}{"archived_at": now, "version": gorm.Expr("version + 1")}).Error; err != nil {
+			return nil, err
+		}
+		archived := old
+		archived.ArchivedAt = &now
+		if err := logActivity(tx, models.TaskActivity(u, &old, &archived)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return s.GetTask(u, idTask)
+}
+
+func (s *SQLStore) UnarchiveTask(u *models.User, idTask int) (*models.Task, error) {
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer tx.RollbackUnlessCommitted()
+
+	var task models.Task
+	if err := findTask(tx, u, idTask, &task); err != nil {
+		return nil, err
+	}
+	if task.ArchivedAt != nil {
+		children, err := descendants(tx, task.ID)
+		if err != nil {
+			return nil, err
+		}
+		var changed []models.Task
+		for _, old := range append(children, task) {
+			// subtasks archived on their own stay archived
+			if old.ArchivedAt != nil && old.ArchivedAt.Equal(*task.ArchivedAt) {
+				changed = append(changed, old)
+			}
+		}
+		if err := requireRole(tx, u, changed, models.RoleEditor); err != nil {
+			return nil, err
+		}
+		for _, old := range changed {
+			if err := tx.Model(&models.Task{}).Where("id = ?", old.ID).Updates(map[string]interface{}{"archived_at": nil, "version": gorm.Expr("version + 1")}).Error; err != nil {
+				return nil, err
+			}
+			unarchived := old
+			unarchived.ArchivedAt = nil
+			if err := logActivity(tx, models.TaskActivity(u, &old, &unarchived)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return s.GetTask(u, idTask)
+}
+
+func (s *SQLStore) ArchiveCompleted(cutoff time.Time) (int, error) {
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return 0, tx.Error
+	}
+	defer tx.RollbackUnlessCommitted()
+
+	var tasks []models.Task
+	err := tx.Where("completed = ? AND completed_at < ? AND archived_at IS NULL", true, cutoff).Find(&tasks).Error
+	if err != nil {
+		return 0, err
+	}
+	if len(tasks) == 0 {
+		return 0, nil
+	}
+
+	now := time.Now()
+	if err := tx.Model(&models.Task{}).Where("id IN (?)", taskIDs(tasks)).Updates(map[string]interface{}{"archived_at": now, "version": gorm.Expr("version + 1")}).Error; err != nil {
+		return 0, err
+	}
+	for _, old := range tasks {
+		archived := old
+		archived.ArchivedAt = &now
+		if err := logActivity(tx, models.TaskActivity(nil, &old, &archived)); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return 0, err
+	}
+	return len(tasks), nil
+}
@@ -0,0 +1,56 @@
+package database
+
+import (
+	"todo-app/models"
+)
+
+func (s *SQLStore) CreateAttachment(u *models.User, idTask int, a models.Attachment) (*models.Attachment, error) {
+	var task models.Task
+	if err := findTask(s.DB, u, idTask, &task); err != nil {
+		return nil, err
+	}
+
+	a.TaskID, a.UserID = task.ID, u.ID
+	if err := s.DB.Create(&a).Error; err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (s *SQLStore) GetAttachments(u *models.User, idTask int) (*[]models.Attachment, error) {
+	var task models.Task
+	if err := findTask(s.DB, u, idTask, &task); err != nil {
+		return nil, err
+	}
+
+	attachments := []models.Attachment{}
+	if err := s.DB.Where("task_id = ?", task.ID).Order("id").Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+	return &attachments, nil
+}
+
+func (s *SQLStore) GetAttachment(u *models.User, idTask, id int) (*models.Attachment, error) {
+	var task models.Task
+	if err := findTask(s.DB, u, idTask, &task); err != nil {
+		return nil, err
+	}
+
+	var attachment models.Attachment
+	if err := s.DB.Where("id = ? AND task_id = ?", id, task.ID).First(&attachment).Error; err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+func (s *SQLStore) DeleteAttachment(u *models.User, idTask, id int) (*models.Attachment, error) {
+	attachment, err := s.GetAttachment(u, idTask, id)
+	if err != nil {
+		return nil, err
+	}
+	// the blob goes away with the row, so there is nothing to restore
+	if err := s.DB.Unscoped().Delete(attachment).Error; err != nil {
+		return nil, err
+	}
+	return attachment, nil
+}
@@ -0,0 +1,73 @@
+package database
+
+import (
+	"todo-app/models"
+
+	"github.com/jinzhu/gorm"
+)
+
+func (s *SQLStore) RunBatch(u *models.User, ops []models.BatchOp, atomic bool) ([]models.BatchResult, error) {
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer tx.RollbackUnlessCommitted()
+
+	var results []models.BatchResult
+	for _, op := range ops {
+		// a failed operation only takes back its own changes
+		if !atomic {
+			if err := tx.Exec("SAVEPOINT batch_op").Error; err != nil {
+				return nil, err
+			}
+		}
+
+		task, err := s.runBatchOp(tx, u, op)
+		results = append(results, models.BatchResult{Task: task, Err: err})
+		switch {
+		case err != nil && atomic:
+			return results, nil
+		case err != nil:
+			if err := tx.Exec("ROLLBACK TO SAVEPOINT batch_op").Error; err != nil {
+				return nil, err
+			}
+		case !atomic:
+			if err := tx.Exec("RELEASE SAVEPOINT batch_op").Error; err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (s *SQLStore) runBatchOp(tx *gorm.DB, u *models.User, op models.BatchOp) (*models.Task, error) {
+	if op.Op == models.BatchCreate {
+		t := *op.Task
+		if err := createTask(tx, u, &t); err != nil {
+			return nil, err
+		}
+		return &t, nil
+	}
+
+	// the role is read in the transaction, so it holds while the op runs
+	var task models.Task
+	if err := findTask(tx, u, op.ID, &task); err != nil {
+		return nil, err
+	}
+	role, err := userRole(tx, u, &task)
+	if err != nil {
+		return nil, err
+	}
+	if min := op.Role(); !role.AtLeast(min) {
+		return nil, &RoleError{Role: min}
+	}
+
+	if op.Op == models.BatchDelete {
+		return deleteTask(tx, u, op.ID, op.Update().IfMatch)
+	}
+	return s.updateTask(tx, u, op.Update(), op.ID)
+}
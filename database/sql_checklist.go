@@ -0,0 +1,149 @@
+package database
+
+import (
+	"todo-app/models"
+
+	"github.com/jinzhu/gorm"
+)
+
+// checklist returns the items of a task ordered by position
+func checklist(db *gorm.DB, idTask uint) ([]models.ChecklistItem, error) {
+	items := []models.ChecklistItem{}
+	if err := db.Where("task_id = ?", idTask).Order("position").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (s *SQLStore) AddChecklistItem(u *models.User, idTask int, item models.AddChecklistItem) (*models.ChecklistItem, error) {
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer tx.RollbackUnlessCommitted()
+
+	var task models.Task
+	if err := findTask(tx, u, idTask, &task); err != nil {
+		return nil, err
+	}
+	var count int
+	if err := tx.Model(&models.ChecklistItem{}).Where("task_id = ?", task.ID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+
+	position := count
+	if item.Position != nil && *item.Position < count {
+		position = *item.Position
+		err := tx.Model(&models.ChecklistItem{}).
+			Where("task_id = ? AND position >= ?", task.ID, position).
+			UpdateColumn("position", gorm.Expr("position + 1")).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	created := models.ChecklistItem{TaskID: task.ID, Text: item.Text, Position: position}
+	if err := tx.Create(&created).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// findChecklistItem loads item id of a task u can see
+func findChecklistItem(db *gorm.DB, u *models.User, idTask, id int, item *models.ChecklistItem) error {
+	var task models.Task
+	if err := findTask(db, u, idTask, &task); err != nil {
+		return err
+	}
+	return db.Where("id = ? AND task_id = ?", id, task.ID).First(item).Error
+}
+
+func (s *SQLStore) ToggleChecklistItem(u *models.User, idTask, id int) (*models.ChecklistItem, error) {
+	var item models.ChecklistItem
+	if err := findChecklistItem(s.DB, u, idTask, id, &item); err != nil {
+		return nil, err
+	}
+	if err := s.DB.Model(&item).Update("done", !item.Done).Error; err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (s *SQLStore) ReorderChecklist(u *models.User, idTask int, order []uint) ([]models.ChecklistItem, error) {
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer tx.RollbackUnlessCommitted()
+
+	var task models.Task
+	if err := findTask(tx, u, idTask, &task); err != nil {
+		return nil, err
+	}
+	items, err := checklist(tx, task.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !sameItems(items, order) {
+		return nil, ErrChecklistOrder
+	}
+
+	for position, id := range order {
+		err := tx.Model(&models.ChecklistItem{}).Where("id = ?", id).UpdateColumn("position", position).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	if items, err = checklist(tx, task.ID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// sameItems reports whether order lists each of items exactly once
+func sameItems(items []models.ChecklistItem, order []uint) bool {
+	if len(items) != len(order) {
+		return false
+	}
+	left := make(map[uint]bool)
+	for _, item := range items {
+		left[item.ID] = true
+	}
+	for _, id := range order {
+		if !left[id] {
+			return false
+		}
+		delete(left, id)
+	}
+	return true
+}
+
+func (s *SQLStore) DeleteChecklistItem(u *models.User, idTask, id int) error {
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	defer tx.RollbackUnlessCommitted()
+
+	var item models.ChecklistItem
+	if err := findChecklistItem(tx, u, idTask, id, &item); err != nil {
+		return err
+	}
+	if err := tx.Delete(&item).Error; err != nil {
+		return err
+	}
+	// close the gap
+	err := tx.Model(&models.ChecklistItem{}).
+		Where("task_id = ? AND position > ?", item.TaskID, item.Position).
+		UpdateColumn("position", gorm.Expr("position - 1")).Error
+	if err != nil {
+		return err
+	}
+	return tx.Commit().Error
+}
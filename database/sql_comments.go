@@ -0,0 +1,202 @@
+package database
+
+import (
+	"todo-app/models"
+
+	"github.com/jinzhu/gorm"
+)
+
+func (s *SQLStore) CreateComment(u *models.User, idTask int, c models.Comment) (*models.Comment, error) {
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer tx.RollbackUnlessCommitted()
+
+	var task models.Task
+	if err := findTask(tx, u, idTask, &task); err != nil {
+		return nil, err
+	}
+	// replies stay on the task of the comment they answer
+	if c.ParentID != nil {
+		var parent models.Comment
+		if err := tx.Where("id = ? AND task_id = ?", *c.ParentID, task.ID).First(&parent).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	c.TaskID, c.UserID = task.ID, u.ID
+	mentions := c.Mentions
+	if err := tx.Create(&c).Error; err != nil {
+		return nil, err
+	}
+	if err := putMentions(tx, c.ID, mentions); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	c.Author, c.Mentions = u, mentions
+	return &c, nil
+}
+
+// putMentions replaces the users mentioned in a comment
+func putMentions(db *gorm.DB, idComment uint, users []*models.User) error {
+	if err := db.Where("comment_id = ?", idComment).Delete(&models.CommentMention{}).Error; err != nil {
+		return err
+	}
+	for _, user := range users {
+		if err := db.Create(&models.CommentMention{CommentID: idComment, UserID: user.ID}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQLStore) GetComments(u *models.User, idTask int) ([]*models.Comment, error) {
+	var task models.Task
+	if err := findTask(s.DB, u, idTask, &task); err != nil {
+		return nil, err
+	}
+
+	comments := []models.Comment{}
+	if err := s.DB.Where("task_id = ?", task.ID).Order("created_at, id").Find(&comments).Error; err != nil {
+		return nil, err
+	}
+	if err := loadCommentUsers(s.DB, comments); err != nil {
+		return nil, err
+	}
+	return models.CommentThreads(comments), nil
+}
+
+func (s *SQLStore) GetComment(u *models.User, idTask, id int) (*models.Comment, error) {
+	var task models.Task
+	if err := findTask(s.DB, u, idTask, &task); err != nil {
+		return nil, err
+	}
+
+	var comment models.Comment
+	if err := s.DB.Where("id = ? AND task_id = ?", id, task.ID).First(&comment).Error; err != nil {
+		return nil, err
+	}
+	comments := []models.Comment{comment}
+	if err := loadCommentUsers(s.DB, comments); err != nil {
+		return nil, err
+	}
+	return &comments[0], nil
+}
+
+// loadCommentUsers fills in the authors and mentions of comments
+func loadCommentUsers(db *gorm.DB, comments []models.Comment) error {
+	if len(comments) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(comments))
+	for i := range comments {
+		ids[i] = comments[i].ID
+	}
+
+	var rows []models.CommentMention
+	if err := db.Where("comment_id IN (?)", ids).Find(&rows).Error; err != nil {
+		return err
+	}
+
+	userIDs := []uint{}
+	for _, c := range comments {
+		userIDs = append(userIDs, c.UserID)
+	}
+	for _, row := range rows {
+		userIDs = append(userIDs, row.UserID)
+	}
+	var users []models.User
+	if err := db.Where("id IN (?)", userIDs).Find(&users).Error; err != nil {
+		return err
+	}
+	byID := map[uint]*models.User{}
+	for i := range users {
+		byID[users[i].ID] = &users[i]
+	}
+
+	index := map[uint]*models.Comment{}
+	for i := range comments {
+		comments[i].Author = byID[comments[i].UserID]
+		index[comments[i].ID] = &comments[i]
+	}
+	for _, row := range rows {
+		if user, ok := byID[row.UserID]; ok {
+			c := index[row.CommentID]
+			c.Mentions = append(c.Mentions, user)
+		}
+	}
+	return nil
+}
+
+func (s *SQLStore) UpdateComment(u *models.User, idTask, id int, c models.Comment) (*models.Comment, error) {
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer tx.RollbackUnlessCommitted()
+
+	var task models.Task
+	if err := findTask(tx, u, idTask, &task); err != nil {
+		return nil, err
+	}
+	var comment models.Comment
+	if err := tx.Where("id = ? AND task_id = ? AND user_id = ?", id, task.ID, u.ID).First(&comment).Error; err != nil {
+		return nil, err
+	}
+
+	if err := tx.Model(&comment).Update("body", c.Body).Error; err != nil {
+		return nil, err
+	}
+	if err := putMentions(tx, comment.ID, c.Mentions); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	comment.Author, comment.Mentions = u, c.Mentions
+	return &comment, nil
+}
+
+func (s *SQLStore) DeleteComment(u *models.User, idTask, id int) error {
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	defer tx.RollbackUnlessCommitted()
+
+	var task models.Task
+	if err := findTask(tx, u, idTask, &task); err != nil {
+		return err
+	}
+	var comment models.Comment
+	if err := tx.Where("id = ? AND task_id = ?", id, task.ID).First(&comment).Error; err != nil {
+		return err
+	}
+
+	// replies go along with the comment they answer
+	ids := []uint{comment.ID}
+	for level := ids; len(level) > 0; {
+		var replies []models.Comment
+		if err := tx.Where("parent_id IN (?)", level).Find(&replies).Error; err != nil {
+			return err
+		}
+		level = nil
+		for _, reply := range replies {
+			level = append(level, reply.ID)
+		}
+		ids = append(ids, level...)
+	}
+
+	if err := tx.Where("id IN (?)", ids).Delete(&models.Comment{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("comment_id IN (?)", ids).Delete(&models.CommentMention{}).Error; err != nil {
+		return err
+	}
+	return tx.Commit().Error
+}
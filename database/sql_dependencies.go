@@ -0,0 +1,120 @@
+package database
+
+import (
+	"todo-app/models"
+
+	"github.com/jinzhu/gorm"
+)
+
+func (s *SQLStore) AddBlocker(u *models.User, idTask, idBlocker int) error {
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	defer tx.RollbackUnlessCommitted()
+
+	for _, id := range []int{idTask, idBlocker} {
+		var task models.Task
+		if err := findTask(tx, u, id, &task); err != nil {
+			return err
+		}
+	}
+
+	cycle, err := reaches(tx, uint(idBlocker), uint(idTask))
+	if err != nil {
+		return err
+	}
+	if cycle {
+		return ErrDependencyCycle
+	}
+
+	dep := models.TaskDependency{TaskID: uint(idTask), BlockerID: uint(idBlocker)}
+	if err := tx.Where(dep).FirstOrCreate(&dep).Error; err != nil {
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// reaches reports whether target is from or one of from's blockers, at any
+// depth
+func reaches(db *gorm.DB, from, target uint) (bool, error) {
+	seen := map[uint]bool{from: true}
+	frontier := []uint{from}
+	for len(frontier) > 0 {
+		if seen[target] {
+			return true, nil
+		}
+
+		var deps []models.TaskDependency
+		if err := db.Where("task_id IN (?)", frontier).Find(&deps).Error; err != nil {
+			return false, err
+		}
+
+		frontier = nil
+		for _, dep := range deps {
+			if !seen[dep.BlockerID] {
+				seen[dep.BlockerID] = true
+				frontier = append(frontier, dep.BlockerID)
+			}
+		}
+	}
+	return seen[target], nil
+}
+
+func (s *SQLStore) RemoveBlocker(u *models.User, idTask, idBlocker int) error {
+	var task models.Task
+	if err := findTask(s.DB, u, idTask, &task); err != nil {
+		return err
+	}
+
+	result := s.DB.Where("task_id = ? AND blocker_id = ?", idTask, idBlocker).Delete(&models.TaskDependency{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// openBlockers maps each of ids to the blockers it is still waiting for
+func openBlockers(db *gorm.DB, ids []uint) (map[uint][]uint, error) {
+	blockers := make(map[uint][]uint)
+	if len(ids) == 0 {
+		return blockers, nil
+	}
+
+	var deps []models.TaskDependency
+	err := db.Table("task_dependencies").Select("task_dependencies.*").
+		Joins("JOIN tasks ON tasks.id = task_dependencies.blocker_id").
+		Where("tasks.deleted_at IS NULL AND tasks.completed = ?", false).
+		Where("task_dependencies.task_id IN (?)", ids).
+		Order("task_dependencies.blocker_id").
+		Find(&deps).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dep := range deps {
+		blockers[dep.TaskID] = append(blockers[dep.TaskID], dep.BlockerID)
+	}
+	return blockers, nil
+}
+
+// markBlocked fills in Blocked and BlockedBy
+func markBlocked(db *gorm.DB, tasks ...*models.Task) error {
+	ids := make([]uint, 0, len(tasks))
+	for _, t := range tasks {
+		ids = append(ids, t.ID)
+	}
+
+	blockers, err := openBlockers(db, ids)
+	if err != nil {
+		return err
+	}
+	for _, t := range tasks {
+		t.BlockedBy = blockers[t.ID]
+		t.Blocked = len(t.BlockedBy) > 0
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package database
+
+import (
+	"strings"
+	"todo-app/models"
+)
+
+// filterColumns maps the fields of a filter expression to their columns
+var filterColumns = map[string]string{
+	"id":           "tasks.id",
+	"title":        "tasks.title",
+	"description":  "tasks.description",
+	"priority":     "tasks.priority",
+	"completed":    "tasks.completed",
+	"start_at":     "tasks.start_at",
+	"due_at":       "tasks.due_at",
+	"completed_at": "tasks.completed_at",
+	"archived_at":  "tasks.archived_at",
+	"created_at":   "tasks.created_at",
+	"updated_at":   "tasks.updated_at",
+	"project_id":   "tasks.project_id",
+	"parent_id":    "tasks.parent_id",
+}
+
+// likeEscaper keeps LIKE from reading wildcards in what is searched for
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// filterSQL turns a parsed filter into a WHERE condition. Values only ever go
+// in as parameters and columns come from filterColumns. Comparisons with an
+// empty column are false rather than NULL so that NOT agrees with
+// models.MatchFilter.
+func filterSQL(expr models.FilterExpr) (string, []interface{}) {
+	switch e := expr.(type) {
+	case models.FilterAnd:
+		left, leftArgs := filterSQL(e.Left)
+		right, rightArgs := filterSQL(e.Right)
+		return "(" + left + " AND " + right + ")", append(leftArgs, rightArgs...)
+	case models.FilterOr:
+		left, leftArgs := filterSQL(e.Left)
+		right, rightArgs := filterSQL(e.Right)
+		return "(" + left + " OR " + right + ")", append(leftArgs, rightArgs...)
+	case models.FilterNot:
+		inner, args := filterSQL(e.Expr)
+		return "(NOT " + inner + ")", args
+	case models.FilterCompare:
+		return compareSQL(e)
+	}
+	// the parser makes nothing else
+	return "1 = 0", nil
+}
+
+func compareSQL(c models.FilterCompare) (string, []interface{}) {
+	column, ok := filterColumns[c.Field]
+	if !ok {
+		// only fields of filterColumns ever reach the SQL
+		return "1 = 0", nil
+	}
+	if c.Value == nil {
+		if c.Op == models.FilterEq {
+			return column + " IS NULL", nil
+		}
+		return column + " IS NOT NULL", nil
+	}
+
+	switch c.Op {
+	case models.FilterContains:
+		// the value is folded like MatchFilter does, SQLite's LOWER leaves
+		// the column's non-ASCII letters alone
+		like := "%" + likeEscaper.Replace(strings.ToLower(c.Value.(string))) + "%"
+		return "LOWER(" + column + `) LIKE ? ESCAPE '\'`, []interface{}{like}
+	case models.FilterNe:
+		// an empty column differs from every value
+		return "(" + column + " IS NULL OR " + column + " <> ?)", []interface{}{c.Value}
+	}
+	return "(" + column + " IS NOT NULL AND " + column + " " + c.Op + " ?)", []interface{}{c.Value}
+}
@@ -0,0 +1,171 @@
+package database
+
+import (
+	"todo-app/models"
+
+	"github.com/jinzhu/gorm"
+)
+
+func (s *SQLStore) CreateLabel(u *models.User, l models.Label) (*models.Label, error) {
+	l.ID, l.UserID, l.OrgID = 0, u.ID, u.OrgID
+	if err := labelNameFree(s.DB, u, l.Name, 0); err != nil {
+		return nil, err
+	}
+	if err := s.DB.Create(&l).Error; err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// labelNameFree returns ErrLabelExists when another of u's labels is called
+// name
+func labelNameFree(db *gorm.DB, u *models.User, name string, except uint) error {
+	var count int
+	err := db.Model(&models.Label{}).
+		Where("user_id = ? AND org_id = ? AND name = ? AND id <> ?", u.ID, u.OrgID, name, except).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrLabelExists
+	}
+	return nil
+}
+
+func (s *SQLStore) GetLabels(u *models.User) (*[]models.Label, error) {
+	labels := []models.Label{}
+	if err := s.DB.Where("user_id = ? AND org_id = ?", u.ID, u.OrgID).Order("name").Find(&labels).Error; err != nil {
+		return nil, err
+	}
+	return &labels, nil
+}
+
+func (s *SQLStore) UpdateLabel(u *models.User, id int, l models.UpdateLabel) (*models.Label, error) {
+	var label models.Label
+	if err := s.DB.Where("id = ? AND user_id = ? AND org_id = ?", id, u.ID, u.OrgID).First(&label).Error; err != nil {
+		return nil, err
+	}
+
+	if l.Name != "" {
+		if err := labelNameFree(s.DB, u, l.Name, label.ID); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.DB.Model(&label).Updates(l).Error; err != nil {
+		return nil, err
+	}
+	return &label, nil
+}
+
+func (s *SQLStore) DeleteLabel(u *models.User, id int) error {
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	defer tx.RollbackUnlessCommitted()
+
+	result := tx.Where("id = ? AND user_id = ? AND org_id = ?", id, u.ID, u.OrgID).Delete(&models.Label{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	if err := tx.Where("label_id = ?", id).Delete(&models.TaskLabel{}).Error; err != nil {
+		return err
+	}
+	return tx.Commit().Error
+}
+
+func (s *SQLStore) AttachLabel(u *models.User, idTask, idLabel int) error {
+	var task models.Task
+	if err := findTask(s.DB, u, idTask, &task); err != nil {
+		return err
+	}
+	var label models.Label
+	if err := s.DB.Where("id = ? AND user_id = ? AND org_id = ?", idLabel, u.ID, u.OrgID).First(&label).Error; err != nil {
+		return err
+	}
+
+	tl := models.TaskLabel{TaskID: task.ID, LabelID: label.ID}
+	return s.DB.Where(tl).FirstOrCreate(&tl).Error
+}
+
+func (s *SQLStore) DetachLabel(u *models.User, idTask, idLabel int) error {
+	var label models.Label
+	if err := s.DB.Where("id = ? AND user_id = ? AND org_id = ?", idLabel, u.ID, u.OrgID).First(&label).Error; err != nil {
+		return err
+	}
+
+	result := s.DB.Where("task_id = ? AND label_id = ?", idTask, label.ID).Delete(&models.TaskLabel{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// filterLabels keeps the tasks that carry any, or all, of the named labels
+// of u
+func filterLabels(q *gorm.DB, u *models.User, filter models.TaskFilter) *gorm.DB {
+	if len(filter.Labels) == 0 {
+		return q
+	}
+
+	sub := q.New().Table("task_labels").Select("task_labels.task_id").
+		Joins("JOIN labels ON labels.id = task_labels.label_id").
+		Where("labels.user_id = ? AND labels.org_id = ? AND labels.name IN (?)", u.ID, u.OrgID, filter.Labels)
+	if filter.AllLabels {
+		sub = sub.Group("task_labels.task_id").
+			Having("COUNT(DISTINCT labels.name) = ?", len(uniqueStrings(filter.Labels)))
+	}
+	return q.Where("tasks.id IN ?", sub.SubQuery())
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// markLabels fills in the labels u has put on each task
+func markLabels(db *gorm.DB, u *models.User, tasks ...*models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	ids := make([]uint, 0, len(tasks))
+	for _, t := range tasks {
+		ids = append(ids, t.ID)
+	}
+
+	var rows []struct {
+		TaskID uint
+		models.Label
+	}
+	err := db.Table("labels").Select("task_labels.task_id, labels.*").
+		Joins("JOIN task_labels ON task_labels.label_id = labels.id").
+		Where("labels.user_id = ? AND labels.org_id = ? AND task_labels.task_id IN (?)", u.ID, u.OrgID, ids).
+		Order("labels.name").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	labels := make(map[uint][]models.Label)
+	for _, row := range rows {
+		labels[row.TaskID] = append(labels[row.TaskID], row.Label)
+	}
+	for _, t := range tasks {
+		t.Labels = labels[t.ID]
+	}
+	return nil
+}
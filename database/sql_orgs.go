@@ -0,0 +1,164 @@
+package database
+
+import (
+	"todo-app/models"
+
+	"github.com/jinzhu/gorm"
+)
+
+func (s *SQLStore) CreateOrganization(u *models.User, o models.Organization) (*models.Organization, error) {
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer tx.RollbackUnlessCommitted()
+
+	o.Members = nil
+	if err := tx.Create(&o).Error; err != nil {
+		return nil, err
+	}
+	owner := models.OrgMember{OrgID: o.ID, UserID: u.ID, Role: models.RoleOwner, Username: u.Username}
+	if err := tx.Create(&owner).Error; err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+func (s *SQLStore) GetOrganizations(u *models.User) (*[]models.Organization, error) {
+	orgs := []models.Organization{}
+	err := s.DB.Joins("JOIN org_members ON org_members.org_id = organizations.id").
+		Where("org_members.user_id = ?", u.ID).
+		Order("organizations.id").
+		Find(&orgs).Error
+	if err != nil {
+		return nil, err
+	}
+	return &orgs, nil
+}
+
+func (s *SQLStore) GetOrganization(u *models.User, id int) (*models.Organization, error) {
+	var org models.Organization
+	err := s.DB.Joins("JOIN org_members ON org_members.org_id = organizations.id").
+		Where("organizations.id = ? AND org_members.user_id = ?", id, u.ID).
+		First(&org).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var members []models.OrgMember
+	if err := s.DB.Where("org_id = ?", org.ID).Order("user_id").Find(&members).Error; err != nil {
+		return nil, err
+	}
+	for i := range members {
+		var user models.User
+		if err := s.DB.First(&user, members[i].UserID).Error; err != nil {
+			return nil, err
+		}
+		members[i].User = &user
+	}
+	org.Members = members
+
+	return &org, nil
+}
+
+func (s *SQLStore) GetOrgRole(idUser, idOrg uint) (models.Role, error) {
+	return orgMemberRole(s.DB, idUser, idOrg)
+}
+
+func orgMemberRole(db *gorm.DB, idUser, idOrg uint) (models.Role, error) {
+	var member models.OrgMember
+	err := db.Joins("JOIN organizations ON organizations.id = org_members.org_id AND organizations.deleted_at IS NULL").
+		Where("org_members.user_id = ? AND org_members.org_id = ?", idUser, idOrg).
+		Take(&member).Error
+	if err != nil {
+		return "", err
+	}
+
+	return member.Role, nil
+}
+
+// demotesLastOrgOwner reports whether taking member's owner role away would
+// leave the organization without owners
+func demotesLastOrgOwner(db *gorm.DB, member models.OrgMember) (bool, error) {
+	if member.Role != models.RoleOwner {
+		return false, nil
+	}
+
+	var owners int
+	err := db.Model(&models.OrgMember{}).
+		Where("org_id = ? AND role = ?", member.OrgID, models.RoleOwner).
+		Count(&owners).Error
+	return owners <= 1, err
+}
+
+func (s *SQLStore) AddOrgMember(idOrg, idUser int, role models.Role) (*models.OrgMember, error) {
+	var user models.User
+	if err := s.DB.First(&user, idUser).Error; err != nil {
+		return nil, err
+	}
+	var org models.Organization
+	if err := s.DB.First(&org, idOrg).Error; err != nil {
+		return nil, err
+	}
+
+	var member models.OrgMember
+	err := s.DB.Where("org_id = ? AND user_id = ?", org.ID, user.ID).Take(&member).Error
+	if gorm.IsRecordNotFoundError(err) {
+		var taken int
+		err := s.DB.Model(&models.OrgMember{}).Where("org_id = ? AND username = ?", org.ID, user.Username).Count(&taken).Error
+		if err != nil {
+			return nil, err
+		}
+		if taken > 0 {
+			return nil, ErrUsernameTaken
+		}
+
+		member = models.OrgMember{OrgID: org.ID, UserID: user.ID, Role: role, Username: user.Username}
+		if err := s.DB.Create(&member).Error; err != nil {
+			return nil, err
+		}
+		member.User = &user
+		return &member, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// already a member, only the role changes
+	if role != models.RoleOwner {
+		last, err := demotesLastOrgOwner(s.DB, member)
+		if err != nil {
+			return nil, err
+		}
+		if last {
+			return nil, ErrLastOrgOwner
+		}
+	}
+	if err := s.DB.Model(&member).Update("role", role).Error; err != nil {
+		return nil, err
+	}
+	member.User = &user
+	return &member, nil
+}
+
+// RemoveOrgMember keeps the user's task and project memberships, they only
+// count again once the user is added back
+func (s *SQLStore) RemoveOrgMember(idOrg, idUser int) error {
+	var member models.OrgMember
+	if err := s.DB.Where("org_id = ? AND user_id = ?", idOrg, idUser).Take(&member).Error; err != nil {
+		return err
+	}
+
+	last, err := demotesLastOrgOwner(s.DB, member)
+	if err != nil {
+		return err
+	}
+	if last {
+		return ErrLastOrgOwner
+	}
+	return s.DB.Delete(&member).Error
+}
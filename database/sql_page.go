@@ -0,0 +1,83 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"todo-app/models"
+)
+
+// sortColumns maps the fields tasks can be sorted by to their columns
+var sortColumns = map[string]string{
+	models.SortPriority:  "tasks.priority",
+	models.SortCreatedAt: "tasks.created_at",
+	models.SortUpdatedAt: "tasks.updated_at",
+	models.SortDueAt:     "tasks.due_at",
+	models.SortTitle:     "tasks.title",
+}
+
+// orderBy follows models.CompareTasks, reversed to read the page before a
+// cursor
+func orderBy(keys []models.SortKey, reverse bool) string {
+	var parts []string
+	for _, k := range keys {
+		column := sortColumns[k.Field]
+		if k.Field == models.SortDueAt {
+			parts = append(parts, column+" IS NULL"+direction(reverse))
+		}
+		parts = append(parts, column+direction(k.Desc != reverse))
+	}
+	return strings.Join(append(parts, "tasks.id"+direction(reverse)), ", ")
+}
+
+func direction(desc bool) string {
+	if desc {
+		return " DESC"
+	}
+	return " ASC"
+}
+
+// pastCursor selects the tasks that come after c in the order of keys, or
+// before it for c.Before
+func pastCursor(keys []models.SortKey, c *models.Cursor) (string, []interface{}) {
+	at := c.Task()
+	if len(keys) == 0 {
+		if c.Before {
+			return "tasks.id < ?", []interface{}{at.ID}
+		}
+		return "tasks.id > ?", []interface{}{at.ID}
+	}
+
+	k := keys[0]
+	column := sortColumns[k.Field]
+	rest, args := pastCursor(keys[1:], c)
+	op := ">"
+	if k.Desc != c.Before {
+		op = "<"
+	}
+
+	var value interface{}
+	switch k.Field {
+	case models.SortPriority:
+		value = at.Priority
+	case models.SortCreatedAt:
+		value = at.CreatedAt
+	case models.SortUpdatedAt:
+		value = at.UpdatedAt
+	case models.SortTitle:
+		value = at.Title
+	case models.SortDueAt:
+		// tasks without a due date come after every task with one
+		switch {
+		case at.DueAt == nil && c.Before:
+			return fmt.Sprintf("(%s IS NOT NULL OR (%s IS NULL AND %s))", column, column, rest), args
+		case at.DueAt == nil:
+			return fmt.Sprintf("(%s IS NULL AND %s)", column, rest), args
+		case !c.Before:
+			return fmt.Sprintf("(%s %s ? OR %s IS NULL OR (%s = ? AND %s))", column, op, column, column, rest),
+				append([]interface{}{*at.DueAt, *at.DueAt}, args...)
+		}
+		value = *at.DueAt
+	}
+	return fmt.Sprintf("(%s %s ? OR (%s = ? AND %s))", column, op, column, rest),
+		append([]interface{}{value, value}, args...)
+}
@@ -0,0 +1,207 @@
+package database
+
+import (
+	"todo-app/models"
+
+	"github.com/jinzhu/gorm"
+)
+
+func (s *SQLStore) CreateProject(u *models.User, p models.Project) (*models.Project, error) {
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer tx.RollbackUnlessCommitted()
+
+	p.Members, p.OrgID = nil, u.OrgID
+	if err := tx.Create(&p).Error; err != nil {
+		return nil, err
+	}
+	owner := models.ProjectMember{ProjectID: p.ID, UserID: u.ID, Role: models.RoleOwner}
+	if err := tx.Create(&owner).Error; err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (s *SQLStore) GetProjects(u *models.User) (*[]models.Project, error) {
+	projects := []models.Project{}
+	err := s.DB.Joins("JOIN project_members ON project_members.project_id = projects.id").
+		Where("project_members.user_id = ? AND projects.org_id = ?", u.ID, u.OrgID).
+		Order("projects.id").
+		Find(&projects).Error
+	if err != nil {
+		return nil, err
+	}
+	return &projects, nil
+}
+
+// findProject loads project id into p if u is a member of it
+func findProject(db *gorm.DB, u *models.User, id int, p *models.Project) error {
+	return db.Joins("JOIN project_members ON project_members.project_id = projects.id").
+		Where("projects.id = ? AND project_members.user_id = ? AND projects.org_id = ?", id, u.ID, u.OrgID).
+		First(p).Error
+}
+
+func (s *SQLStore) GetProject(u *models.User, id int) (*models.Project, error) {
+	var p models.Project
+	if err := findProject(s.DB, u, id, &p); err != nil {
+		return nil, err
+	}
+
+	var members []models.ProjectMember
+	if err := s.DB.Where("project_id = ?", p.ID).Order("user_id").Find(&members).Error; err != nil {
+		return nil, err
+	}
+	for i := range members {
+		var user models.User
+		if err := s.DB.First(&user, members[i].UserID).Error; err != nil {
+			return nil, err
+		}
+		members[i].User = &user
+	}
+	p.Members = members
+
+	return &p, nil
+}
+
+func (s *SQLStore) UpdateProject(u *models.User, id int, p models.UpdateProject) (*models.Project, error) {
+	var project models.Project
+	if err := findProject(s.DB, u, id, &project); err != nil {
+		return nil, err
+	}
+	if err := s.DB.Model(&project).Updates(p).Error; err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+func (s *SQLStore) DeleteProject(u *models.User, id int) (*models.Project, error) {
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer tx.RollbackUnlessCommitted()
+
+	var project models.Project
+	if err := findProject(tx, u, id, &project); err != nil {
+		return nil, err
+	}
+
+	var tasks []models.Task
+	if err := tx.Where("project_id = ?", project.ID).Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+	// by hand to bump the version like deleting a single task does
+	trashed := map[string]interface{}{"deleted_at": gorm.NowFunc(), "version": gorm.Expr("version + 1")}
+	if err := tx.Model(&models.Task{}).Where("project_id = ?", project.ID).UpdateColumns(trashed).Error; err != nil {
+		return nil, err
+	}
+	for _, deleted := range tasks {
+		if err := logActivity(tx, models.TaskActivity(u, &deleted, nil)); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Where("project_id = ?", project.ID).Delete(&models.ProjectMember{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Delete(&project).Error; err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+func (s *SQLStore) GetProjectRole(u *models.User, idProject int) (models.Role, error) {
+	var member models.ProjectMember
+	err := s.DB.Joins("JOIN projects ON projects.id = project_members.project_id AND projects.deleted_at IS NULL").
+		Where("project_members.user_id = ? AND project_members.project_id = ?", u.ID, idProject).
+		Where("projects.org_id = ?", u.OrgID).
+		Take(&member).Error
+	if err != nil {
+		return "", err
+	}
+
+	return member.Role, nil
+}
+
+// demotesLastProjectOwner reports whether taking member's owner role away
+// would leave the project without owners
+func demotesLastProjectOwner(db *gorm.DB, member models.ProjectMember) (bool, error) {
+	if member.Role != models.RoleOwner {
+		return false, nil
+	}
+
+	var owners int
+	err := db.Model(&models.ProjectMember{}).
+		Where("project_id = ? AND role = ?", member.ProjectID, models.RoleOwner).
+		Count(&owners).Error
+	return owners <= 1, err
+}
+
+func (s *SQLStore) AddProjectMember(idProject, idUser int, role models.Role) (*models.ProjectMember, error) {
+	var user models.User
+	if err := s.DB.First(&user, idUser).Error; err != nil {
+		return nil, err
+	}
+	var project models.Project
+	if err := s.DB.First(&project, idProject).Error; err != nil {
+		return nil, err
+	}
+	if _, err := s.GetOrgRole(user.ID, project.OrgID); err != nil {
+		return nil, err
+	}
+
+	var member models.ProjectMember
+	err := s.DB.Where("project_id = ? AND user_id = ?", project.ID, user.ID).Take(&member).Error
+	if gorm.IsRecordNotFoundError(err) {
+		member = models.ProjectMember{ProjectID: project.ID, UserID: user.ID, Role: role}
+		if err := s.DB.Create(&member).Error; err != nil {
+			return nil, err
+		}
+		member.User = &user
+		return &member, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// already a member, only the role changes
+	if role != models.RoleOwner {
+		last, err := demotesLastProjectOwner(s.DB, member)
+		if err != nil {
+			return nil, err
+		}
+		if last {
+			return nil, ErrLastProjectOwner
+		}
+	}
+	if err := s.DB.Model(&member).Update("role", role).Error; err != nil {
+		return nil, err
+	}
+	member.User = &user
+	return &member, nil
+}
+
+func (s *SQLStore) RemoveProjectMember(idProject, idUser int) error {
+	var member models.ProjectMember
+	if err := s.DB.Where("project_id = ? AND user_id = ?", idProject, idUser).Take(&member).Error; err != nil {
+		return err
+	}
+
+	last, err := demotesLastProjectOwner(s.DB, member)
+	if err != nil {
+		return err
+	}
+	if last {
+		return ErrLastProjectOwner
+	}
+	return s.DB.Delete(&member).Error
+}
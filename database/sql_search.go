@@ -0,0 +1,146 @@
+package database
+
+import (
+	"todo-app/models"
+
+	"github.com/jinzhu/gorm"
+)
+
+// searchComments joins the comments of each task into one text
+const searchComments = "LEFT JOIN LATERAL (SELECT string_agg(body, ' ' ORDER BY id) AS body FROM comments " +
+	"WHERE comments.task_id = tasks.id AND comments.deleted_at IS NULL) task_comments ON true"
+
+// searchText is the text of a task and its comments, without the characters
+// searchHeadline marks matches with
+const searchText = "translate(tasks.title || ' ' || tasks.description || ' ' || coalesce(task_comments.body, ''), " +
+	"chr(2) || chr(3), '')"
+
+// searchHeadline marks the matches in the raw text with chr(2) and chr(3),
+// escapes the headline like html.EscapeString and only then turns the marks
+// into <mark> tags, so that no mark lands inside an entity. The snippet is
+// HTML.
+const searchHeadline = "replace(replace(replace(replace(replace(replace(replace(" +
+	"ts_headline('english', " + searchText + ", search_query, 'StartSel=' || chr(2) || ', StopSel=' || chr(3)), " +
+	`'&', '&amp;'), '''', '&#39;'), '<', '&lt;'), '>', '&gt;'), '"', '&#34;'), ` +
+	"chr(2), '<mark>'), chr(3), '</mark>')"
+
+// searchRow is a task read along with its rank
+type searchRow struct {
+	models.Task
+	Rank float64
+}
+
+// searchSnippet is the headline of a task
+type searchSnippet struct {
+	ID      uint
+	Snippet string
+}
+
+func (s *SQLStore) SearchTasks(u *models.User, search models.TaskSearch) (*[]models.SearchResult, error) {
+	q := visibleTo(s.DB.Table("tasks"), u).Where("tasks.deleted_at IS NULL")
+	if !search.IncludeArchived {
+		q = q.Where("tasks.archived_at IS NULL")
+	}
+
+	var results []models.SearchResult
+	var err error
+	if isPostgres(s.DB) {
+		results, err = searchPostgres(s.DB, q, search)
+	} else {
+		results, err = searchFallback(s.DB, q, search)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := make([]*models.Task, len(results))
+	for i := range results {
+		tasks[i] = &results[i].Task
+	}
+	if err := markBlocked(s.DB, tasks...); err != nil {
+		return nil, err
+	}
+	if err := markLabels(s.DB, u, tasks...); err != nil {
+		return nil, err
+	}
+	return &results, nil
+}
+
+// searchPostgres matches and ranks on the search_vector the triggers of
+// migration 18 keep up to date, and only makes headlines for the page
+func searchPostgres(db, q *gorm.DB, search models.TaskSearch) ([]models.SearchResult, error) {
+	var rows []searchRow
+	err := q.Select("tasks.*, ts_rank(tasks.search_vector, search_query) AS rank").
+		Joins("CROSS JOIN plainto_tsquery('english', ?) search_query", search.Query).
+		Where("tasks.search_vector @@ search_query").
+		Order("rank DESC, tasks.id").
+		Limit(search.Limit).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return []models.SearchResult{}, nil
+	}
+
+	ids := make([]uint, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
+	var snippets []searchSnippet
+	err = db.Table("tasks").
+		Select("tasks.id, "+searchHeadline+" AS snippet").
+		Joins(searchComments).
+		Joins("CROSS JOIN plainto_tsquery('english', ?) search_query", search.Query).
+		Where("tasks.id IN (?)", ids).
+		Scan(&snippets).Error
+	if err != nil {
+		return nil, err
+	}
+	headlines := make(map[uint]string)
+	for _, s := range snippets {
+		headlines[s.ID] = s.Snippet
+	}
+
+	results := make([]models.SearchResult, len(rows))
+	for i, row := range rows {
+		results[i] = models.SearchResult{Task: row.Task, Rank: row.Rank, Snippet: headlines[row.ID]}
+	}
+	return results, nil
+}
+
+// searchFallback narrows the tasks down with LIKE and leaves matching and
+// ranking to models.MatchTask
+func searchFallback(db, q *gorm.DB, search models.TaskSearch) ([]models.SearchResult, error) {
+	for _, term := range search.Terms {
+		// terms are letters and digits only, nothing to escape
+		like := "%" + term + "%"
+		q = q.Where("LOWER(tasks.title) LIKE ? OR LOWER(tasks.description) LIKE ? OR "+
+			"tasks.id IN (SELECT task_id FROM comments WHERE deleted_at IS NULL AND LOWER(body) LIKE ?)", like, like, like)
+	}
+
+	var tasks []models.Task
+	if err := q.Select("tasks.*").Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 {
+		return []models.SearchResult{}, nil
+	}
+
+	var comments []models.Comment
+	if err := db.Where("task_id IN (?)", taskIDs(tasks)).Order("id").Find(&comments).Error; err != nil {
+		return nil, err
+	}
+	bodies := make(map[uint][]string)
+	for _, c := range comments {
+		bodies[c.TaskID] = append(bodies[c.TaskID], c.Body)
+	}
+
+	results := []models.SearchResult{}
+	for i := range tasks {
+		if result, ok := models.MatchTask(search.Terms, &tasks[i], bodies[tasks[i].ID]); ok {
+			results = append(results, result)
+		}
+	}
+	return models.SortResults(results, search.Limit), nil
+}
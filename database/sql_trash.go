@@ -0,0 +1,193 @@
+package database
+
+import (
+	"time"
+	"todo-app/models"
+
+	"github.com/jinzhu/gorm"
+)
+
+// findTrashedTask is findTask for deleted tasks
+func findTrashedTask(db *gorm.DB, u *models.User, id int, task *models.Task) error {
+	return visibleTo(db.Unscoped().Where("tasks.id = ? AND tasks.deleted_at IS NOT NULL", id), u).First(task).Error
+}
+
+// trashedWith returns the descendants of task that were deleted along with it
+func trashedWith(db *gorm.DB, task *models.Task) ([]models.Task, error) {
+	all, err := descendants(db.Unscoped(), task.ID)
+	if err != nil {
+		return nil, err
+	}
+	var children []models.Task
+	for _, child := range all {
+		if child.DeletedAt != nil && child.DeletedAt.Equal(*task.DeletedAt) {
+			children = append(children, child)
+		}
+	}
+	return children, nil
+}
+
+func (s *SQLStore) GetTrash(u *models.User) (*[]models.Task, error) {
+	tasks := []models.Task{}
+	err := visibleTo(s.DB.Unscoped().Where("tasks.deleted_at IS NOT NULL"), u).
+		Order("tasks.deleted_at DESC, tasks.id").
+		Find(&tasks).Error
+	if err != nil {
+		return nil, err
+	}
+	return &tasks, nil
+}
+
+func (s *SQLStore) GetTrashedTaskRole(u *models.User, idTask int) (models.Role, error) {
+	var task models.Task
+	if err := findTrashedTask(s.DB, u, idTask, &task); err != nil {
+		return "", err
+	}
+	return userRole(s.DB, u, &task)
+}
+
+func (s *SQLStore) RestoreTask(u *models.User, idTask int) (*models.Task, error) {
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer tx.RollbackUnlessCommitted()
+
+	var task models.Task
+	if err := findTrashedTask(tx, u, idTask, &task); err != nil {
+		return nil, err
+	}
+	if task.ParentID != nil {
+		var parent models.Task
+		if err := tx.Unscoped().First(&parent, *task.ParentID).Error; err != nil {
+			return nil, err
+		}
+		if parent.DeletedAt != nil {
+			return nil, ErrParentDeleted
+		}
+	}
+
+	// tasks of a project deleted since come back without it
+	updates := map[string]interface{}{"deleted_at": nil, "version": gorm.Expr("version + 1")}
+	if task.ProjectID != nil {
+		err := tx.First(&models.Project{}, *task.ProjectID).Error
+		if gorm.IsRecordNotFoundError(err) {
+			updates["project_id"] = nil
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	children, err := trashedWith(tx, &task)
+	if err != nil {
+		return nil, err
+	}
+	restored := append(children, task)
+	if err := tx.Unscoped().Model(&models.Task{}).Where("id IN (?)", taskIDs(restored)).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	for _, old := range restored {
+		after := old
+		after.DeletedAt = nil
+		if _, ok := updates["project_id"]; ok {
+			after.ProjectID = nil
+		}
+		if err := logActivity(tx, models.TrashActivity(u, &old, &after)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return s.GetTask(u, idTask)
+}
+
+func (s *SQLStore) PurgeTask(u *models.User, idTask int) ([]models.Attachment, error) {
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer tx.RollbackUnlessCommitted()
+
+	var task models.Task
+	if err := findTrashedTask(tx, u, idTask, &task); err != nil {
+		return nil, err
+	}
+	// subtasks of a task in the trash are all in the trash too
+	children, err := descendants(tx.Unscoped(), task.ID)
+	if err != nil {
+		return nil, err
+	}
+	attachments, err := purgeTasks(tx, u, append(children, task))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+func (s *SQLStore) PurgeTrash(cutoff time.Time) ([]models.Attachment, error) {
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer tx.RollbackUnlessCommitted()
+
+	// subtasks are deleted with or before their parent, so none is left behind
+	var tasks []models.Task
+	if err := tx.Unscoped().Where("deleted_at < ?", cutoff).Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+	attachments, err := purgeTasks(tx, nil, tasks)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// purgeTasks deletes tasks and everything hanging off them for good, only
+// their activity log is kept
+func purgeTasks(db *gorm.DB, actor *models.User, tasks []models.Task) ([]models.Attachment, error) {
+	if len(tasks) == 0 {
+		return nil, nil
+	}
+	ids := taskIDs(tasks)
+
+	var attachments []models.Attachment
+	if err := db.Where("task_id IN (?)", ids).Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+
+	err := db.Where("comment_id IN (SELECT id FROM comments WHERE task_id IN (?))", ids).Delete(&models.CommentMention{}).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, model := range []interface{}{
+		&models.Comment{}, &models.Attachment{}, &models.ChecklistItem{}, &models.TaskLabel{}, &models.UserTask{},
+	} {
+		if err := db.Unscoped().Where("task_id IN (?)", ids).Delete(model).Error; err != nil {
+			return nil, err
+		}
+	}
+	if err := db.Where("task_id IN (?) OR blocker_id IN (?)", ids, ids).Delete(&models.TaskDependency{}).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Unscoped().Where("id IN (?)", ids).Delete(&models.Task{}).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range tasks {
+		if err := logActivity(db, models.TrashActivity(actor, &tasks[i], nil)); err != nil {
+			return nil, err
+		}
+	}
+	return attachments, nil
+}
@@ -0,0 +1,97 @@
+package database
+
+import (
+	"todo-app/models"
+
+	"github.com/jinzhu/gorm"
+)
+
+// collaborators selects the users that share a task outside the trash or a
+// project with the user given as its parameters: the members of the tasks the
+// user sees, directly or through their project, and the members of the
+// user's projects and of the projects of the user's tasks
+const collaborators = "SELECT user_tasks.user_id FROM user_tasks " +
+	"JOIN tasks ON tasks.id = user_tasks.task_id AND tasks.deleted_at IS NULL " +
+	"WHERE tasks.id IN (SELECT task_id FROM user_tasks WHERE user_id = ?) OR " +
+	"tasks.project_id IN (SELECT project_id FROM project_members WHERE user_id = ?) " +
+	"UNION SELECT project_members.user_id FROM project_members " +
+	"WHERE project_members.project_id IN (SELECT project_id FROM project_members WHERE user_id = ?) OR " +
+	"project_members.project_id IN (SELECT tasks.project_id FROM tasks JOIN user_tasks ON user_tasks.task_id = tasks.id " +
+	"WHERE user_tasks.user_id = ? AND tasks.deleted_at IS NULL)"
+
+// visibleViews limits q to u's views and the views shared with u
+func visibleViews(q *gorm.DB, u *models.User) *gorm.DB {
+	return q.Where("org_id = ?", u.OrgID).
+		Where("user_id = ? OR (shared = ? AND user_id IN ("+collaborators+"))", u.ID, true, u.ID, u.ID, u.ID, u.ID)
+}
+
+func (s *SQLStore) CreateView(u *models.User, v models.View) (*models.View, error) {
+	v.ID, v.UserID, v.OrgID = 0, u.ID, u.OrgID
+	if err := viewNameFree(s.DB, u, v.Name, 0); err != nil {
+		return nil, err
+	}
+	if err := s.DB.Create(&v).Error; err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// viewNameFree returns ErrViewExists when another of u's views is called name
+func viewNameFree(db *gorm.DB, u *models.User, name string, except uint) error {
+	var count int
+	err := db.Model(&models.View{}).
+		Where("user_id = ? AND org_id = ? AND name = ? AND id <> ?", u.ID, u.OrgID, name, except).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrViewExists
+	}
+	return nil
+}
+
+func (s *SQLStore) GetViews(u *models.User) (*[]models.View, error) {
+	views := []models.View{}
+	if err := visibleViews(s.DB, u).Order("name, id").Find(&views).Error; err != nil {
+		return nil, err
+	}
+	return &views, nil
+}
+
+func (s *SQLStore) GetView(u *models.User, id int) (*models.View, error) {
+	var view models.View
+	if err := visibleViews(s.DB.Where("id = ?", id), u).First(&view).Error; err != nil {
+		return nil, err
+	}
+	return &view, nil
+}
+
+func (s *SQLStore) UpdateView(u *models.User, id int, v models.UpdateView) (*models.View, error) {
+	var view models.View
+	if err := s.DB.Where("id = ? AND user_id = ? AND org_id = ?", id, u.ID, u.OrgID).First(&view).Error; err != nil {
+		return nil, err
+	}
+
+	if v.Name != nil {
+		if err := viewNameFree(s.DB, u, *v.Name, view.ID); err != nil {
+			return nil, err
+		}
+	}
+	v.Apply(&view)
+	if err := s.DB.Save(&view).Error; err != nil {
+		return nil, err
+	}
+	return &view, nil
+}
+
+func (s *SQLStore) DeleteView(u *models.User, id int) error {
+	result := s.DB.Where("id = ? AND user_id = ? AND org_id = ?", id, u.ID, u.OrgID).Delete(&models.View{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
@@ -2,149 +2,240 @@ package database
 
 import (
 	"fmt"
+	"path/filepath"
+	"time"
 	"todo-app/models"
 	"todo-app/util"
 
-	"github.com/jinzhu/gorm"
-	_ "github.com/jinzhu/gorm/dialects/postgres"
 	"github.com/pkg/errors"
 )
 
-type Store struct {
-	DB *gorm.DB
+// TaskStore is the persistence layer used by the handlers
+type TaskStore interface {
+	AddUser(u models.User) (*models.User, error)
+	// GetUserByUsername finds the member of organization idOrg named
+	// username. An idOrg of 0 looks in every organization and fails with
+	// ErrAmbiguousUsername when more than one user has that name.
+	GetUserByUsername(idOrg uint, username string) (*models.User, error)
+	GetUserById(id uint) (*models.User, error)
+
+	CreateTask(u *models.User, t models.Task) (*models.Task, error)
+	// GetTasks returns a page of the tasks u can see that match filter
+	GetTasks(u *models.User, filter models.TaskFilter) (*models.TaskPage, error)
+	// SearchTasks returns the tasks u can see that match every term of the
+	// search, best matches first
+	SearchTasks(u *models.User, search models.TaskSearch) (*[]models.SearchResult, error)
+	GetTask(u *models.User, id int) (*models.Task, error)
+	UpdateTask(u *models.User, t models.UpdateTask, idTask int) (*models.Task, error)
+	// DeleteTask fails with ErrVersionMismatch unless the task is at one of
+	// the ifMatch versions, any version will do when there are none
+	DeleteTask(u *models.User, idTask int, ifMatch []uint) (*models.Task, error)
+	// RunBatch carries out create, update, complete and delete operations in
+	// one transaction. Atomic batches stop at the first operation that fails
+	// and change nothing, the results then end with the failing operation.
+	// Otherwise failing operations are left out and the rest is kept.
+	RunBatch(u *models.User, ops []models.BatchOp, atomic bool) ([]models.BatchResult, error)
+
+	// ArchiveTask archives a task along with its subtasks
+	ArchiveTask(u *models.User, idTask int) (*models.Task, error)
+	// UnarchiveTask brings back a task and the subtasks archived with it
+	UnarchiveTask(u *models.User, idTask int) (*models.Task, error)
+	// ArchiveCompleted archives the tasks completed before cutoff and returns
+	// how many there were
+	ArchiveCompleted(cutoff time.Time) (int, error)
+
+	// GetTrash returns the deleted tasks u can reach, most recently deleted first
+	GetTrash(u *models.User) (*[]models.Task, error)
+	// GetTrashedTaskRole is GetTaskRole for tasks in the trash
+	GetTrashedTaskRole(u *models.User, idTask int) (models.Role, error)
+	// RestoreTask brings a task back along with the subtasks deleted with it,
+	// ErrParentDeleted while its parent is still in the trash
+	RestoreTask(u *models.User, idTask int) (*models.Task, error)
+	// PurgeTask deletes a task in the trash and its subtasks for good, the
+	// returned attachments are left for the caller to remove from blob storage
+	PurgeTask(u *models.User, idTask int) ([]models.Attachment, error)
+	// PurgeTrash is PurgeTask for every task deleted before cutoff
+	PurgeTrash(cutoff time.Time) ([]models.Attachment, error)
+
+	// CreateSubtask adds t under idParent, shared with the parent's members
+	CreateSubtask(u *models.User, idParent int, t models.Task) (*models.Task, error)
+	// GetTaskTree returns the task with every descendant nested in Subtasks
+	GetTaskTree(u *models.User, id int) (*models.Task, error)
+
+	// AddBlocker makes idBlocker a dependency of idTask, u must be a member of both
+	AddBlocker(u *models.User, idTask, idBlocker int) error
+	RemoveBlocker(u *models.User, idTask, idBlocker int) error
+
+	// CreateOrganization makes u the owner of a new organization
+	CreateOrganization(u *models.User, o models.Organization) (*models.Organization, error)
+	GetOrganizations(u *models.User) (*[]models.Organization, error)
+	// GetOrganization returns the organization with its members
+	GetOrganization(u *models.User, id int) (*models.Organization, error)
+	// GetOrgRole returns ErrRecordNotFound unless the user belongs to the organization
+	GetOrgRole(idUser, idOrg uint) (models.Role, error)
+	AddOrgMember(idOrg, idUser int, role models.Role) (*models.OrgMember, error)
+	RemoveOrgMember(idOrg, idUser int) error
+
+	CreateProject(u *models.User, p models.Project) (*models.Project, error)
+	GetProjects(u *models.User) (*[]models.Project, error)
+	// GetProject returns the project with its members
+	GetProject(u *models.User, id int) (*models.Project, error)
+	UpdateProject(u *models.User, id int, p models.UpdateProject) (*models.Project, error)
+	// DeleteProject deletes the project along with its tasks
+	DeleteProject(u *models.User, id int) (*models.Project, error)
+	// GetProjectRole returns ErrRecordNotFound unless u is a member of the project
+	GetProjectRole(u *models.User, idProject int) (models.Role, error)
+	AddProjectMember(idProject, idUser int, role models.Role) (*models.ProjectMember, error)
+	RemoveProjectMember(idProject, idUser int) error
+
+	CreateLabel(u *models.User, l models.Label) (*models.Label, error)
+	GetLabels(u *models.User) (*[]models.Label, error)
+	UpdateLabel(u *models.User, id int, l models.UpdateLabel) (*models.Label, error)
+	DeleteLabel(u *models.User, id int) error
+	// AttachLabel puts one of u's labels on a task u is a member of
+	AttachLabel(u *models.User, idTask, idLabel int) error
+	DetachLabel(u *models.User, idTask, idLabel int) error
+
+	CreateView(u *models.User, v models.View) (*models.View, error)
+	// GetViews returns u's views and the views shared with u by the users
+	// u shares tasks outside the trash or projects with
+	GetViews(u *models.User) (*[]models.View, error)
+	// GetView returns one of the views GetViews lists
+	GetView(u *models.User, id int) (*models.View, error)
+	// UpdateView and DeleteView only reach u's own views
+	UpdateView(u *models.User, id int, v models.UpdateView) (*models.View, error)
+	DeleteView(u *models.User, id int) error
+
+	// CreateComment posts c on a task u can see, c.Mentions are stored with it
+	CreateComment(u *models.User, idTask int, c models.Comment) (*models.Comment, error)
+	// GetComments returns the top level comments of a task with their replies nested
+	GetComments(u *models.User, idTask int) ([]*models.Comment, error)
+	GetComment(u *models.User, idTask, id int) (*models.Comment, error)
+	// UpdateComment replaces the body and mentions of one of u's comments
+	UpdateComment(u *models.User, idTask, id int, c models.Comment) (*models.Comment, error)
+	// DeleteComment deletes the comment along with its replies
+	DeleteComment(u *models.User, idTask, id int) error
+
+	// CreateAttachment records a file stored in blob storage on a task u can see
+	CreateAttachment(u *models.User, idTask int, a models.Attachment) (*models.Attachment, error)
+	GetAttachments(u *models.User, idTask int) (*[]models.Attachment, error)
+	GetAttachment(u *models.User, idTask, id int) (*models.Attachment, error)
+	// DeleteAttachment returns the deleted attachment so its blob can be removed
+	DeleteAttachment(u *models.User, idTask, id int) (*models.Attachment, error)
+
+	AddChecklistItem(u *models.User, idTask int, item models.AddChecklistItem) (*models.ChecklistItem, error)
+	ToggleChecklistItem(u *models.User, idTask, id int) (*models.ChecklistItem, error)
+	// ReorderChecklist returns ErrChecklistOrder unless order names every item once
+	ReorderChecklist(u *models.User, idTask int, order []uint) ([]models.ChecklistItem, error)
+	DeleteChecklistItem(u *models.User, idTask, id int) error
+
+	// GetTaskRole returns ErrRecordNotFound unless u is a member of the task
+	GetTaskRole(u *models.User, idTask int) (models.Role, error)
+	// AddUserToTask and RemoveUserFromTask are done by u, authorization is
+	// left to the caller
+	AddUserToTask(u *models.User, idUser, idTask int, role models.Role) (*models.User, *models.Task, error)
+	RemoveUserFromTask(u *models.User, idUser, idTask int) (*models.User, *models.Task, error)
+
+	// GetTaskHistory returns every change made to a task u can see, oldest first
+	GetTaskHistory(u *models.User, idTask int) (*[]models.Activity, error)
+	// GetActivity returns the changes made by u or to tasks u can see, deleted
+	// ones included, newest first
+	GetActivity(u *models.User, filter models.ActivityFilter) (*[]models.Activity, error)
 }
 
-func New() (*Store, error) {
-	conf, err := util.GetConfig()
-	if err != nil {
-		return &Store{}, errors.Wrap(err, "Failed to Read config file")
-	}
+// ErrRecordNotFound matches the message of gorm's not found error so the
+// handlers can treat every backend alike
+var ErrRecordNotFound = errors.New("record not found")
 
-	credentials := fmt.Sprintf("host=%s user=%s dbname=%s sslmode=disable password=%s", conf.PGHost, conf.PGUsername, conf.PGName, conf.PGPassword)
+// ErrUsernameTaken is returned when adding a user to an organization that has
+// a member with the same username
+var ErrUsernameTaken = errors.New("username is already taken in this organization")
 
-	db, err := gorm.Open("postgres", credentials)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to connect to database")
-	}
+// ErrAmbiguousUsername is returned when looking up a username that users of
+// several organizations have without naming the organization
+var ErrAmbiguousUsername = errors.New("several users have this username, pick an organization")
 
-	// ENUM not working with GORM Postgres
-	db.Raw("CREATE TYPE priority AS ENUM ('1', '2', '3')").Row()
-	db.AutoMigrate(&models.User{}, &models.Task{})
-	return &Store{
-		DB: db,
-	}, nil
-}
-
-func (s *Store) AddUser(u models.User) (*models.User, error) {
-	result := s.DB.Create(&u)
-	if result.Error != nil {
-		return nil, result.Error
-	}
+// ErrDependencyCycle is returned when a blocker would end up blocking itself
+var ErrDependencyCycle = errors.New("dependency would create a cycle")
 
-	return &u, nil
-}
+// ErrBlocked is returned when completing a task whose blockers are still open
+var ErrBlocked = errors.New("task is blocked by open tasks")
 
-func (s *Store) GetUserByUsername(username string) (*models.User, error) {
-	var user models.User
-	result := s.DB.Where("username=?", username).First(&user)
-	if result.Error != nil {
-		return nil, result.Error
-	}
+// ErrLabelExists is returned when a user already has a label with that name
+var ErrLabelExists = errors.New("label already exists")
 
-	return &user, nil
-}
+// ErrViewExists is returned when a user already has a view with that name
+var ErrViewExists = errors.New("view already exists")
 
-func (s *Store) GetUserById(id uint) (*models.User, error) {
-	var user models.User
-	result := s.DB.First(&user, id)
-	if result.Error != nil {
-		return nil, result.Error
-	}
+// ErrChecklistOrder is returned when a new checklist order leaves out items
+// or names ones that aren't on the task
+var ErrChecklistOrder = errors.New("order must list every checklist item once")
 
-	return &user, nil
-}
+// ErrParentDeleted is returned when restoring a subtask whose parent is
+// still in the trash
+var ErrParentDeleted = errors.New("parent task is in the trash, restore it first")
 
-func (s *Store) CreateTask(u *models.User, t models.Task) (*models.Task, error) {
-	result := s.DB.Create(&t)
-	if result.Error != nil {
-		return nil, result.Error
-	}
+// ErrVersionMismatch is returned when a task isn't at the version a change
+// was made against
+var ErrVersionMismatch = errors.New("task has been changed since it was read")
 
-	if err := s.DB.Model(u).Association("Tasks").Append(&t).Error; err != nil {
-		return nil, err
-	}
+// ErrLastOwner is returned when a change would leave a task without an owner
+var ErrLastOwner = errors.New("task must keep at least one owner")
 
-	return &t, nil
-}
+// ErrLastProjectOwner is ErrLastOwner for project members
+var ErrLastProjectOwner = errors.New("project must keep at least one owner")
 
-func (s *Store) GetTasks(u *models.User, params map[string]interface{}) (*[]models.Task, error) {
-	var tasks []models.Task
-	if err := s.DB.Where(params).Model(u).Association("Tasks").Find(&tasks).Error; err != nil {
-		return nil, err
-	}
-	return &tasks, nil
-}
+// ErrLastOrgOwner is ErrLastOwner for organization members
+var ErrLastOrgOwner = errors.New("organization must keep at least one owner")
 
-func (s *Store) GetTask(u *models.User, id int) (*models.Task, error) {
-	var task models.Task
-	if err := s.DB.Model(u).Preload("Users").Where("ID = ?", id).Association("Tasks").Find(&task).Error; err != nil {
-		return nil, err
-	}
-	return &task, nil
+// RoleError is returned when u's role on a task is below the one an
+// operation needs
+type RoleError struct {
+	Role models.Role
 }
 
-func (s *Store) AddUserToTask(u *models.User, t models.Task, idUser, idTask int) (*models.User, *models.Task, error) {
-	if err := s.DB.First(u, idUser).Error; err != nil {
-		return nil, nil, err
-	}
-	if err := s.DB.First(&t, idTask).Error; err != nil {
-		return nil, nil, err
-	}
-
-	if err := s.DB.Model(&t).Association("Users").Append(u).Error; err != nil {
-		return nil, nil, err
-	}
-
-	return u, &t, nil
+func (e *RoleError) Error() string {
+	return fmt.Sprintf("Forbidden: requires %s role on task", e.Role)
 }
 
-func (s *Store) RemoveUserFromTask(u *models.User, t models.Task, idUser, idTask int) (*models.User, *models.Task, error) {
-	if err := s.DB.First(u, idUser).Error; err != nil {
-		return nil, nil, err
-	}
-	if err := s.DB.First(&t, idTask).Error; err != nil {
-		return nil, nil, err
+// versionMatches reports whether version is one of ifMatch, or ifMatch is empty
+func versionMatches(ifMatch []uint, version uint) bool {
+	if len(ifMatch) == 0 {
+		return true
 	}
-
-	if err := s.DB.Model(&t).Association("Users").Delete(u).Error; err != nil {
-		return nil, nil, err
+	for _, v := range ifMatch {
+		if v == version {
+			return true
+		}
 	}
-
-	return u, &t, nil
+	return false
 }
 
-func (s *Store) UpdateTask(u *models.User, t models.UpdateTask, idTask int) (*models.Task, error) {
-	var task models.Task
-	if err := s.DB.Model(u).Where("ID = ?", idTask).Association("Tasks").Find(&task).Error; err != nil {
-		return nil, err
-	}
-
-	if err := s.DB.Model(&task).Update(t).Error; err != nil {
-		return nil, err
-	}
-
-	return &task, nil
-}
+// Database types accepted in TODO_DATABASETYPE
+const (
+	Postgres = "postgres"
+	SQLite   = "sqlite"
+	Memory   = "memory"
+	Bolt     = "bolt"
+)
 
-func (s *Store) DeleteTask(u *models.User, idTask int) (*models.Task, error) {
-	var task models.Task
-	if err := s.DB.Model(u).Where("ID = ?", idTask).Association("Tasks").Find(&task).Error; err != nil {
-		return nil, err
+// New returns the store selected by TODO_DATABASETYPE, defaulting to postgres
+func New() (TaskStore, error) {
+	conf, err := util.GetConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to Read config file")
 	}
 
-	if err := s.DB.Delete(task).Error; err != nil {
-		return nil, err
+	switch conf.DatabaseType {
+	case "", Postgres:
+		return NewPostgres(conf.PGHost, conf.PGUsername, conf.PGName, conf.PGPassword)
+	case SQLite:
+		return NewSQLite(filepath.Join(conf.FileDBPath, conf.SQLiteDBName))
+	case Memory:
+		return NewMemory()
+	case Bolt:
+		return NewBolt(filepath.Join(conf.FileDBPath, conf.BoltDBName))
+	default:
+		return nil, fmt.Errorf("unknown database type %q", conf.DatabaseType)
 	}
-
-	return &task, nil
 }
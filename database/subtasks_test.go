@@ -0,0 +1,146 @@
+package database
+
+import (
+	"testing"
+	"time"
+	"todo-app/models"
+)
+
+func wantCompleted(t *testing.T, s TaskStore, u *models.User, want bool, ids ...uint) {
+	t.Helper()
+	for _, id := range ids {
+		task, err := s.GetTask(u, int(id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if task.Completed != want {
+			t.Errorf("task %d: got completed %v, want %v", id, task.Completed, want)
+		}
+	}
+}
+
+func TestCompleteSubtasks(t *testing.T) {
+	eachStore(t, func(t *testing.T, s TaskStore) {
+		users := addUsers(t, s, "alice", "bob", "dave")
+		alice, bob := users[0], users[1]
+
+		subtask := func(parent uint, task models.Task) uint {
+			task.Description, task.Priority = "d", "1"
+			child, err := s.CreateSubtask(alice, int(parent), task)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return child.ID
+		}
+		parent, err := s.CreateTask(alice, models.Task{Title: "parent", Description: "d", Priority: "1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		due := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+		chore := subtask(parent.ID, models.Task{Title: "chore", DueAt: &due, Recurrence: "FREQ=DAILY"})
+		child := subtask(parent.ID, models.Task{Title: "child"})
+		grandchild := subtask(child, models.Task{Title: "grandchild"})
+		blocker, err := s.CreateTask(alice, models.Task{Title: "blocker", Description: "d", Priority: "1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// blockers within the subtree are completed along with it
+		if err := s.AddBlocker(alice, int(chore), int(grandchild)); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.AddBlocker(alice, int(child), int(blocker.ID)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.UpdateTask(alice, complete(true), int(parent.ID)); err != ErrBlocked {
+			t.Errorf("completing with a blocked subtask: got %v, want %v", err, ErrBlocked)
+		}
+		wantCompleted(t, s, alice, false, parent.ID, chore, child, grandchild)
+
+		force := complete(true)
+		force.Force = true
+		if _, err := s.UpdateTask(alice, force, int(parent.ID)); err != nil {
+			t.Fatal(err)
+		}
+		wantCompleted(t, s, alice, true, parent.ID, chore, child, grandchild)
+		wantCompleted(t, s, alice, false, blocker.ID)
+
+		// the recurring subtask spawned its next occurrence
+		page, err := s.GetTasks(alice, models.TaskFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var spawned int
+		for _, task := range page.Tasks {
+			if task.Title == "chore" && task.Occurrence == 2 && !task.Completed {
+				spawned++
+			}
+		}
+		if spawned != 1 {
+			t.Errorf("got %d open second occurrences of the recurring subtask, want 1", spawned)
+		}
+
+		// bob edits the parent but isn't a member of its subtask
+		shared, err := s.CreateTask(alice, models.Task{Title: "shared", Description: "d", Priority: "1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		private := subtask(shared.ID, models.Task{Title: "private"})
+		if _, _, err := s.AddUserToTask(alice, int(bob.ID), int(shared.ID), models.RoleEditor); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.UpdateTask(bob, complete(true), int(shared.ID)); err == nil {
+			t.Error("completed a subtask the caller can't edit")
+		} else if _, ok := err.(*RoleError); !ok {
+			t.Errorf("completing over a subtask the caller can't edit: got %v, want a RoleError", err)
+		}
+		wantCompleted(t, s, alice, false, shared.ID, private)
+	})
+}
+
+func TestDeleteSubtasks(t *testing.T) {
+	eachStore(t, func(t *testing.T, s TaskStore) {
+		users := addUsers(t, s, "alice", "bob", "dave")
+		alice, bob := users[0], users[1]
+
+		parent, err := s.CreateTask(alice, models.Task{Title: "parent", Description: "d", Priority: "1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		child, err := s.CreateSubtask(alice, int(parent.ID), models.Task{Title: "child", Description: "d", Priority: "1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// bob owns the parent but only edits its subtask
+		if _, _, err := s.AddUserToTask(alice, int(bob.ID), int(parent.ID), models.RoleOwner); err != nil {
+			t.Fatal(err)
+		}
+		for _, role := range []models.Role{"", models.RoleEditor} {
+			if role != "" {
+				if _, _, err := s.AddUserToTask(alice, int(bob.ID), int(child.ID), role); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, err := s.DeleteTask(bob, int(parent.ID), nil); err == nil {
+				t.Errorf("deleted a subtask the caller has role %q on", role)
+			} else if _, ok := err.(*RoleError); !ok {
+				t.Errorf("deleting over a subtask the caller has role %q on: got %v, want a RoleError", role, err)
+			}
+			for _, id := range []uint{parent.ID, child.ID} {
+				if _, err := s.GetTask(alice, int(id)); err != nil {
+					t.Errorf("task %d after the refused delete: %v", id, err)
+				}
+			}
+		}
+
+		if _, err := s.DeleteTask(alice, int(parent.ID), nil); err != nil {
+			t.Fatal(err)
+		}
+		for _, id := range []uint{parent.ID, child.ID} {
+			if _, err := s.GetTask(alice, int(id)); err == nil || err.Error() != "record not found" {
+				t.Errorf("task %d after its parent was deleted: got %v, want record not found", id, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,135 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+	"time"
+	"todo-app/models"
+)
+
+func TestGetTasksFilters(t *testing.T) {
+	eachStore(t, func(t *testing.T, s TaskStore) {
+		users := addUsers(t, s, "alice", "bob", "dave")
+		alice, bob := users[0], users[1]
+
+		now := time.Now().UTC()
+		past, future, end := now.Add(-48*time.Hour), now.Add(48*time.Hour), now.Add(72*time.Hour)
+		create := func(u *models.User, priority models.Priority, due *time.Time) uint {
+			task, err := s.CreateTask(u, models.Task{Title: "task", Description: "d", Priority: priority, DueAt: due})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return task.ID
+		}
+		open1, open2, done1, done2 := create(alice, "1", &past), create(alice, "2", nil), create(alice, "1", &past), create(alice, "2", nil)
+		later := create(alice, "3", &future)
+		for _, id := range []uint{done1, done2} {
+			if _, err := s.UpdateTask(alice, complete(true), int(id)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// neither shows up for alice
+		bobs := create(bob, "1", &past)
+		if _, err := s.DeleteTask(alice, int(create(alice, "1", &past)), nil); err != nil {
+			t.Fatal(err)
+		}
+
+		// labels belong to the user who made them, bob's red is not alice's
+		label := func(u *models.User, name string, ids ...uint) {
+			l, err := s.CreateLabel(u, models.Label{Name: name})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, id := range ids {
+				if err := s.AttachLabel(u, int(id), int(l.ID)); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		for _, id := range []uint{open1, done2} {
+			if _, _, err := s.AddUserToTask(alice, int(bob.ID), int(id), models.RoleEditor); err != nil {
+				t.Fatal(err)
+			}
+		}
+		label(alice, "red", open1, open2)
+		label(alice, "blue", open2, later)
+		label(alice, "green")
+		label(bob, "red", done2, bobs)
+
+		yes, no := true, false
+		tests := []struct {
+			name   string
+			filter models.TaskFilter
+			want   []uint
+		}{
+			{"no filter", models.TaskFilter{}, []uint{open1, open2, done1, done2, later}},
+			{"open", models.TaskFilter{Completed: &no}, []uint{open1, open2, later}},
+			{"priority", models.TaskFilter{Priority: "1"}, []uint{open1, done1}},
+			{"completed and priority", models.TaskFilter{Completed: &yes, Priority: "2"}, []uint{done2}},
+			{"nothing matches", models.TaskFilter{Completed: &yes, Priority: "3"}, []uint{}},
+			// tasks without a due date are neither before nor after anything
+			{"due before", models.TaskFilter{DueBefore: &now}, []uint{open1, done1}},
+			{"due after", models.TaskFilter{DueAfter: &now}, []uint{later}},
+			{"due between, bounds left out", models.TaskFilter{DueAfter: &past, DueBefore: &future}, []uint{}},
+			{"open and due before", models.TaskFilter{Completed: &no, DueBefore: &end}, []uint{open1, later}},
+			// completed tasks are never overdue
+			{"overdue", models.TaskFilter{Overdue: &yes}, []uint{open1}},
+			{"not overdue", models.TaskFilter{Overdue: &no}, []uint{open2, done1, done2, later}},
+			{"overdue and completed", models.TaskFilter{Overdue: &yes, Completed: &yes}, []uint{}},
+			{"label", models.TaskFilter{Labels: []string{"red"}}, []uint{open1, open2}},
+			{"any label", models.TaskFilter{Labels: []string{"red", "blue"}}, []uint{open1, open2, later}},
+			{"all labels", models.TaskFilter{Labels: []string{"red", "blue"}, AllLabels: true}, []uint{open2}},
+			{"all labels, listed twice", models.TaskFilter{Labels: []string{"red", "red"}, AllLabels: true}, []uint{open1, open2}},
+			{"all labels, one unused", models.TaskFilter{Labels: []string{"red", "green"}, AllLabels: true}, []uint{}},
+			{"unknown label", models.TaskFilter{Labels: []string{"yellow"}}, []uint{}},
+			{"label and open", models.TaskFilter{Labels: []string{"blue"}, Completed: &no, Priority: "3"}, []uint{later}},
+		}
+		check := func(u *models.User, name string, filter models.TaskFilter, want []uint) {
+			page, err := s.GetTasks(u, filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []uint{}
+			for _, task := range page.Tasks {
+				got = append(got, task.ID)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s for %s: got tasks %v, want %v", name, u.Username, got, want)
+			}
+		}
+		for _, tt := range tests {
+			check(alice, tt.name, tt.filter, tt.want)
+		}
+		// open1 carries alice's red and bob is a member of it
+		check(bob, "label", models.TaskFilter{Labels: []string{"red"}}, []uint{done2, bobs})
+		check(bob, "all labels", models.TaskFilter{Labels: []string{"red", "blue"}, AllLabels: true}, []uint{})
+	})
+}
+
+func TestReopenTask(t *testing.T) {
+	eachStore(t, func(t *testing.T, s TaskStore) {
+		alice := addUsers(t, s, "alice", "dave")[0]
+		task, err := s.CreateTask(alice, models.Task{Title: "task", Description: "d", Priority: "1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		done, err := s.UpdateTask(alice, complete(true), int(task.ID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !done.Completed || done.CompletedAt == nil {
+			t.Fatalf("completing: got completed %v at %v", done.Completed, done.CompletedAt)
+		}
+		if _, err := s.UpdateTask(alice, complete(false), int(task.ID)); err != nil {
+			t.Fatal(err)
+		}
+		reopened, err := s.GetTask(alice, int(task.ID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reopened.Completed || reopened.CompletedAt != nil {
+			t.Errorf("reopening: got completed %v at %v, want open with no completion time", reopened.Completed, reopened.CompletedAt)
+		}
+	})
+}
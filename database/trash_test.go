@@ -0,0 +1,153 @@
+package database
+
+import (
+	"fmt"
+	"testing"
+	"time"
+	"todo-app/models"
+)
+
+func wantTrash(t *testing.T, s TaskStore, u *models.User, want ...uint) {
+	t.Helper()
+	trash, err := s.GetTrash(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(pageIDs(*trash)); got != fmt.Sprint(want) {
+		t.Errorf("trash of %s: got %s, want %v", u.Username, got, want)
+	}
+}
+
+func attachmentKeys(attachments []models.Attachment) []string {
+	var keys []string
+	for _, a := range attachments {
+		keys = append(keys, a.Key)
+	}
+	return keys
+}
+
+func TestTrash(t *testing.T) {
+	eachStore(t, func(t *testing.T, s TaskStore) {
+		users := addUsers(t, s, "alice", "bob", "dave")
+		alice, bob := users[0], users[1]
+
+		create := func(title string) *models.Task {
+			task, err := s.CreateTask(alice, models.Task{Title: title, Description: "d", Priority: "1"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return task
+		}
+		other, parent := create("other"), create("parent")
+		child, err := s.CreateSubtask(alice, int(parent.ID), models.Task{Title: "child", Description: "d", Priority: "1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := s.AddUserToTask(alice, int(bob.ID), int(other.ID), models.RoleEditor); err != nil {
+			t.Fatal(err)
+		}
+		for _, a := range []struct {
+			task uint
+			key  string
+		}{{other.ID, "other/file"}, {child.ID, "child/file"}} {
+			_, err := s.CreateAttachment(alice, int(a.task), models.Attachment{Filename: "file.txt", ContentType: "text/plain", Size: 5, Key: a.key})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if _, err := s.DeleteTask(alice, int(other.ID), nil); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+		if _, err := s.DeleteTask(alice, int(parent.ID), nil); err != nil {
+			t.Fatal(err)
+		}
+		// most recently deleted first, subtasks along with their parent
+		wantTrash(t, s, alice, parent.ID, child.ID, other.ID)
+		wantTrash(t, s, bob, other.ID)
+		trash, err := s.GetTrash(alice)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a, b := (*trash)[0].DeletedAt, (*trash)[1].DeletedAt; a == nil || b == nil || !a.Equal(*b) {
+			t.Errorf("a subtask and its parent were deleted at %v and %v", b, a)
+		}
+
+		if role, err := s.GetTrashedTaskRole(bob, int(other.ID)); err != nil || role != models.RoleEditor {
+			t.Errorf("bob's role on the trashed task: got %q, %v, want %q", role, err, models.RoleEditor)
+		}
+		if _, err := s.GetTrashedTaskRole(bob, int(parent.ID)); err == nil || err.Error() != "record not found" {
+			t.Errorf("bob's role on a task they can't reach: got %v, want record not found", err)
+		}
+
+		// restoring the parent brings back the subtask deleted with it
+		if _, err := s.RestoreTask(alice, int(child.ID)); err != ErrParentDeleted {
+			t.Errorf("restoring a subtask of a trashed task: got %v, want %v", err, ErrParentDeleted)
+		}
+		restored, err := s.RestoreTask(alice, int(parent.ID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if restored.DeletedAt != nil {
+			t.Error("restoring returned a deleted task")
+		}
+		wantCompleted(t, s, alice, false, parent.ID, child.ID)
+		wantTrash(t, s, alice, other.ID)
+
+		attachments, err := s.PurgeTask(alice, int(other.ID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if keys := fmt.Sprint(attachmentKeys(attachments)); keys != "[other/file]" {
+			t.Errorf("purging returned attachments %s, want [other/file]", keys)
+		}
+		wantTrash(t, s, alice)
+		if _, err := s.GetTrashedTaskRole(alice, int(other.ID)); err == nil || err.Error() != "record not found" {
+			t.Errorf("role on a purged task: got %v, want record not found", err)
+		}
+		if _, err := s.PurgeTask(alice, int(parent.ID)); err == nil || err.Error() != "record not found" {
+			t.Errorf("purging a task outside the trash: got %v, want record not found", err)
+		}
+	})
+}
+
+func TestPurgeTrash(t *testing.T) {
+	eachStore(t, func(t *testing.T, s TaskStore) {
+		alice := addUsers(t, s, "alice", "dave")[0]
+
+		parent, err := s.CreateTask(alice, models.Task{Title: "parent", Description: "d", Priority: "1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		child, err := s.CreateSubtask(alice, int(parent.ID), models.Task{Title: "child", Description: "d", Priority: "1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = s.CreateAttachment(alice, int(child.ID), models.Attachment{Filename: "file.txt", ContentType: "text/plain", Size: 5, Key: "child/file"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.DeleteTask(alice, int(parent.ID), nil); err != nil {
+			t.Fatal(err)
+		}
+
+		// tasks deleted after the cutoff stay in the trash
+		if attachments, err := s.PurgeTrash(time.Now().Add(-time.Hour)); err != nil || len(attachments) != 0 {
+			t.Errorf("purging tasks deleted an hour ago: got %v, %v, want nothing", attachmentKeys(attachments), err)
+		}
+		wantTrash(t, s, alice, parent.ID, child.ID)
+
+		attachments, err := s.PurgeTrash(time.Now().Add(time.Second))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if keys := fmt.Sprint(attachmentKeys(attachments)); keys != "[child/file]" {
+			t.Errorf("purging returned attachments %s, want [child/file]", keys)
+		}
+		wantTrash(t, s, alice)
+		if _, err := s.RestoreTask(alice, int(parent.ID)); err == nil || err.Error() != "record not found" {
+			t.Errorf("restoring a purged task: got %v, want record not found", err)
+		}
+	})
+}
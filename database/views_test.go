@@ -0,0 +1,110 @@
+package database
+
+import (
+	"fmt"
+	"testing"
+	"todo-app/models"
+)
+
+// wantViews checks the names of the views u gets, in order
+func wantViews(t *testing.T, s TaskStore, u *models.User, want ...string) {
+	t.Helper()
+	views, err := s.GetViews(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, v := range *views {
+		names = append(names, v.Name)
+	}
+	if fmt.Sprint(names) != fmt.Sprint(want) {
+		t.Errorf("views of %s: got %q, want %q", u.Username, names, want)
+	}
+}
+
+func TestSharedViews(t *testing.T) {
+	eachStore(t, func(t *testing.T, s TaskStore) {
+		users := addUsers(t, s, "alice", "bob", "carol", "erin", "dave")
+		alice, bob, carol, erin, dave := users[0], users[1], users[2], users[3], users[4]
+
+		views := make(map[string]uint)
+		for _, v := range []struct {
+			user   *models.User
+			name   string
+			shared bool
+		}{{alice, "alice own", false}, {bob, "bob private", false}, {bob, "bob shared", true}, {carol, "carol shared", true}, {erin, "erin shared", true}} {
+			view, err := s.CreateView(v.user, models.View{Name: v.name, Shared: v.shared})
+			if err != nil {
+				t.Fatal(err)
+			}
+			views[v.name] = view.ID
+		}
+		wantViews(t, s, alice, "alice own")
+
+		// sharing a task shares the views marked as shared
+		task, err := s.CreateTask(alice, models.Task{Title: "task", Description: "d", Priority: "1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := s.AddUserToTask(alice, int(bob.ID), int(task.ID), models.RoleViewer); err != nil {
+			t.Fatal(err)
+		}
+		wantViews(t, s, alice, "alice own", "bob shared")
+		wantViews(t, s, bob, "bob private", "bob shared")
+		if _, err := s.GetView(alice, int(views["bob private"])); err == nil || err.Error() != "record not found" {
+			t.Errorf("alice reading bob's private view: got %v, want record not found", err)
+		}
+
+		// so does a project, with or without tasks, and its tasks share the
+		// views of their members with the project's members
+		project, err := s.CreateProject(alice, models.Project{Name: "project"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.AddProjectMember(int(project.ID), int(carol.ID), models.RoleViewer); err != nil {
+			t.Fatal(err)
+		}
+		wantViews(t, s, alice, "alice own", "bob shared", "carol shared")
+		wantViews(t, s, carol, "carol shared")
+		inProject, err := s.CreateTask(alice, models.Task{Title: "in project", Description: "d", Priority: "1", ProjectID: &project.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := s.AddUserToTask(alice, int(erin.ID), int(inProject.ID), models.RoleViewer); err != nil {
+			t.Fatal(err)
+		}
+		wantViews(t, s, carol, "carol shared", "erin shared")
+		wantViews(t, s, erin, "carol shared", "erin shared")
+
+		// tasks in the trash share nothing
+		if _, err := s.DeleteTask(alice, int(task.ID), nil); err != nil {
+			t.Fatal(err)
+		}
+		wantViews(t, s, alice, "alice own", "carol shared", "erin shared")
+		wantViews(t, s, bob, "bob private", "bob shared")
+		if _, err := s.GetView(alice, int(views["bob shared"])); err == nil || err.Error() != "record not found" {
+			t.Errorf("alice reading the view of a user they no longer share a task with: got %v, want record not found", err)
+		}
+		if _, err := s.DeleteTask(alice, int(inProject.ID), nil); err != nil {
+			t.Fatal(err)
+		}
+		wantViews(t, s, erin, "erin shared")
+
+		// only the owner of a view changes it
+		if _, err := s.GetView(alice, int(views["carol shared"])); err != nil {
+			t.Fatal(err)
+		}
+		name := "renamed"
+		if _, err := s.UpdateView(alice, int(views["carol shared"]), models.UpdateView{Name: &name}); err == nil || err.Error() != "record not found" {
+			t.Errorf("alice renaming carol's view: got %v, want record not found", err)
+		}
+		if err := s.DeleteView(alice, int(views["carol shared"])); err == nil || err.Error() != "record not found" {
+			t.Errorf("alice deleting carol's view: got %v, want record not found", err)
+		}
+
+		wantViews(t, s, dave)
+		if _, err := s.GetView(dave, int(views["carol shared"])); err == nil || err.Error() != "record not found" {
+			t.Errorf("reading a view of another organization: got %v, want record not found", err)
+		}
+	})
+}
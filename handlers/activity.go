@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"todo-app/models"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+func (h *Handler) GetTaskHistory(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	intID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		log.Warning("Failed to parse task ID")
+		RespondError(w, http.StatusBadRequest, "Invalid Id")
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	history, err := h.store.GetTaskHistory(user, intID)
+	if err != nil {
+		log.Warningf("Failed to fetch task history: %s", err.Error())
+		if err.Error() == "record not found" {
+			RespondError(w, http.StatusNotFound, "Task not found")
+			return
+		}
+		RespondError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	data := map[string]interface{}{
+		"history": history,
+	}
+
+	res := Response{Status: "success", Message: "Fetched task history successfully", Data: data}
+	RespondJSON(w, http.StatusOK, &res)
+}
+
+// GetActivity is the feed of changes to every task the user can reach
+func (h *Handler) GetActivity(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	filter, err := parseActivityFilter(r.URL.Query())
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	activity, err := h.store.GetActivity(user, filter)
+	if err != nil {
+		log.Warningf("Failed to fetch activity: %s", err.Error())
+		RespondError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	data := map[string]interface{}{
+		"activity": activity,
+	}
+
+	res := Response{Status: "success", Message: "Fetched activity successfully", Data: data}
+	RespondJSON(w, http.StatusOK, &res)
+}
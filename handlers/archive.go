@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"todo-app/database"
+	"todo-app/models"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+func (h *Handler) ArchiveTask(w http.ResponseWriter, r *http.Request) {
+	h.setArchived(w, r, true)
+}
+
+func (h *Handler) UnarchiveTask(w http.ResponseWriter, r *http.Request) {
+	h.setArchived(w, r, false)
+}
+
+func (h *Handler) setArchived(w http.ResponseWriter, r *http.Request, archived bool) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	intID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		log.Warning("Failed to parse task ID")
+		RespondError(w, http.StatusBadRequest, "Invalid Id")
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	if !h.authorizeTask(w, user, intID, models.RoleEditor) {
+		return
+	}
+
+	var task *models.Task
+	action, verb := "Archive", "archived"
+	if archived {
+		task, err = h.store.ArchiveTask(user, intID)
+	} else {
+		action, verb = "Unarchive", "unarchived"
+		task, err = h.store.UnarchiveTask(user, intID)
+	}
+	if err != nil {
+		log.Warningf("%s task error: %s", action, err.Error())
+		if err.Error() == "record not found" {
+			RespondError(w, http.StatusNotFound, "Task not found")
+			return
+		}
+		if _, ok := err.(*database.RoleError); ok {
+			RespondError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		RespondError(w, http.StatusBadRequest, fmt.Sprintf("Failed to %s task", strings.ToLower(action)))
+		return
+	}
+
+	msg := message{
+		Username: user.Username,
+		Action:   action,
+		Message:  fmt.Sprintf("%s %s task: %s", user.Username, verb, task.Title),
+		Task:     task.ID,
+	}
+	sendEvent(msg)
+
+	data := map[string]interface{}{
+		"task": task,
+	}
+
+	res := Response{Status: "success", Message: fmt.Sprintf("%sd task successfully", action), Data: data}
+	RespondJSON(w, http.StatusOK, &res)
+}
+
+// AutoArchive archives tasks completed longer than after ago, checking every
+// interval until the process exits
+func (h *Handler) AutoArchive(after, interval time.Duration) {
+	for {
+		n, err := h.store.ArchiveCompleted(time.Now().Add(-after))
+		if err != nil {
+			log.Warningf("Failed to archive completed tasks: %s", err.Error())
+		} else if n > 0 {
+			log.Infof("Archived %d completed tasks", n)
+		}
+		time.Sleep(interval)
+	}
+}
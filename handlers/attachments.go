@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"todo-app/models"
+	"todo-app/util/blob"
+
+	"github.com/gorilla/mux"
+	uuid "github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// multipartOverhead leaves room for the multipart headers around the file
+const multipartOverhead = 1 << 20
+
+func (h *Handler) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	intID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		log.Warning("Failed to parse task ID")
+		RespondError(w, http.StatusBadRequest, "Invalid Id")
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	if !h.authorizeTask(w, user, intID, models.RoleEditor) {
+		return
+	}
+
+	req.Body = http.MaxBytesReader(w, req.Body, h.uploads.MaxSize+multipartOverhead)
+	mr, err := req.MultipartReader()
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Expected a multipart/form-data body")
+		return
+	}
+	var part io.Reader
+	var filename string
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			RespondError(w, http.StatusBadRequest, "Missing file field")
+			return
+		}
+		if err != nil {
+			respondUploadError(w, err)
+			return
+		}
+		if p.FormName() == "file" {
+			part, filename = p, filepath.Base(p.FileName())
+			break
+		}
+	}
+	if filename == "." || filename == "/" || len(filename) > 255 {
+		RespondError(w, http.StatusBadRequest, "Invalid file name")
+		return
+	}
+
+	// the type is sniffed from the contents, the client's claim isn't trusted
+	head := make([]byte, 512)
+	n, err := io.ReadFull(part, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		respondUploadError(w, err)
+		return
+	}
+	contentType := http.DetectContentType(head[:n])
+	if !h.uploadAllowed(contentType) {
+		RespondError(w, http.StatusUnsupportedMediaType, fmt.Sprintf("File type %s is not allowed", contentType))
+		return
+	}
+
+	key := fmt.Sprintf("tasks/%d/%s", intID, uuid.NewV4().String())
+	contents := &io.LimitedReader{R: io.MultiReader(bytes.NewReader(head[:n]), part), N: h.uploads.MaxSize + 1}
+	size, err := h.uploads.Blobs.Put(key, contents)
+	if err == nil && size > h.uploads.MaxSize {
+		err = errFileTooLarge
+	}
+	if err != nil {
+		log.Warningf("Store attachment error: %s", err.Error())
+		h.deleteBlob(key)
+		respondUploadError(w, err)
+		return
+	}
+
+	a := models.Attachment{Filename: filename, ContentType: contentType, Size: size, Key: key}
+	attachment, err := h.store.CreateAttachment(user, intID, a)
+	if err != nil {
+		log.Warningf("Create attachment error: %s", err.Error())
+		h.deleteBlob(key)
+		if err.Error() == "record not found" {
+			RespondError(w, http.StatusNotFound, "Task not found")
+			return
+		}
+		RespondError(w, http.StatusBadRequest, "Failed to create attachment")
+		return
+	}
+
+	data := map[string]interface{}{
+		"attachment": attachment,
+	}
+
+	res := Response{Status: "success", Message: "Attachment uploaded successfully", Data: data}
+	RespondJSON(w, http.StatusCreated, &res)
+}
+
+var errFileTooLarge = errors.New("file too large")
+
+// respondUploadError tells oversized uploads apart from broken ones
+func respondUploadError(w http.ResponseWriter, err error) {
+	if err == errFileTooLarge || err.Error() == "http: request body too large" {
+		RespondError(w, http.StatusRequestEntityTooLarge, "File too large")
+		return
+	}
+	RespondError(w, http.StatusBadRequest, "Failed to read upload")
+}
+
+func (h *Handler) uploadAllowed(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range h.uploads.Types {
+		if allowed == mediaType {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Handler) deleteBlob(key string) {
+	if err := h.uploads.Blobs.Delete(key); err != nil {
+		log.Warningf("Failed to delete blob %s: %s", key, err.Error())
+	}
+}
+
+func (h *Handler) GetAttachments(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	intID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		log.Warning("Failed to parse task ID")
+		RespondError(w, http.StatusBadRequest, "Invalid Id")
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	attachments, err := h.store.GetAttachments(user, intID)
+	if err != nil {
+		log.Warningf("Failed to fetch attachments: %s", err.Error())
+		if err.Error() == "record not found" {
+			RespondError(w, http.StatusNotFound, "Task not found")
+			return
+		}
+		RespondError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	data := map[string]interface{}{
+		"attachments": attachments,
+	}
+
+	res := Response{Status: "success", Message: "Fetched attachments successfully", Data: data}
+	RespondJSON(w, http.StatusOK, &res)
+}
+
+// DownloadAttachment streams the file to any member of the task
+func (h *Handler) DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	intID, intIDAttachment, ok := attachmentIDs(w, req)
+	if !ok {
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	attachment, err := h.store.GetAttachment(user, intID, intIDAttachment)
+	if err != nil {
+		log.Warningf("Failed to fetch attachment: %s", err.Error())
+		if err.Error() == "record not found" {
+			RespondError(w, http.StatusNotFound, "Attachment not found")
+			return
+		}
+		RespondError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	contents, err := h.uploads.Blobs.Get(attachment.Key)
+	if err != nil {
+		log.Warningf("Failed to open attachment: %s", err.Error())
+		if err == blob.ErrNotFound {
+			RespondError(w, http.StatusNotFound, "Attachment contents not found")
+			return
+		}
+		RespondError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	defer contents.Close()
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, contents); err != nil {
+		log.Warningf("Failed to send attachment: %s", err.Error())
+	}
+}
+
+func (h *Handler) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	intID, intIDAttachment, ok := attachmentIDs(w, req)
+	if !ok {
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	if !h.authorizeTask(w, user, intID, models.RoleEditor) {
+		return
+	}
+
+	attachment, err := h.store.DeleteAttachment(user, intID, intIDAttachment)
+	if err != nil {
+		log.Warningf("Delete attachment error: %s", err.Error())
+		if err.Error() == "record not found" {
+			RespondError(w, http.StatusNotFound, "Attachment not found")
+			return
+		}
+		RespondError(w, http.StatusBadRequest, "Failed to delete attachment")
+		return
+	}
+	h.deleteBlob(attachment.Key)
+
+	res := Response{Status: "success", Message: "Deleted attachment successfully"}
+	RespondJSON(w, http.StatusOK, &res)
+}
+
+func attachmentIDs(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	vars := mux.Vars(r)
+	intID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		log.Warning("Failed to parse task ID")
+		RespondError(w, http.StatusBadRequest, "Invalid Id")
+		return 0, 0, false
+	}
+	intIDAttachment, err := strconv.Atoi(vars["idAttachment"])
+	if err != nil {
+		log.Warning("Failed to parse attachment ID")
+		RespondError(w, http.StatusBadRequest, "Invalid attachment Id")
+		return 0, 0, false
+	}
+	return intID, intIDAttachment, true
+}
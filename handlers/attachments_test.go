@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestUploadAttachmentLimits(t *testing.T) {
+	f := newFixture(t)
+	path := fmt.Sprintf("/tasks/%d/attachments", f.task)
+	maxSize := int(f.handler.uploads.MaxSize)
+
+	for _, tt := range []struct {
+		name     string
+		filename string
+		contents []byte
+		want     int
+	}{
+		{"a file of the maximum size", "notes.txt", bytes.Repeat([]byte("a"), maxSize), http.StatusCreated},
+		{"a file one byte too large", "notes.txt", bytes.Repeat([]byte("a"), maxSize+1), http.StatusRequestEntityTooLarge},
+		// the body is cut off before the multipart reader gets to the end
+		{"a body over the limit", "notes.txt", bytes.Repeat([]byte("a"), maxSize+multipartOverhead+1), http.StatusRequestEntityTooLarge},
+		// the type is sniffed from the contents, not taken from the file name
+		{"a PNG", "notes.txt", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), http.StatusUnsupportedMediaType},
+		{"a PDF", "notes.txt", []byte("%PDF-1.4\nsome notes"), http.StatusUnsupportedMediaType},
+	} {
+		before, err := f.store.GetAttachments(f.users["alice"], int(f.task))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, contentType := uploadFile(t, tt.filename, tt.contents)
+		w := f.do("alice", http.MethodPost, path, body, contentType)
+		if w.Code != tt.want {
+			t.Errorf("uploading %s: got %d, want %d: %s", tt.name, w.Code, tt.want, w.Body)
+		}
+
+		after, err := f.store.GetAttachments(f.users["alice"], int(f.task))
+		if err != nil {
+			t.Fatal(err)
+		}
+		added := len(*after) - len(*before)
+		if tt.want == http.StatusCreated && added != 1 || tt.want != http.StatusCreated && added != 0 {
+			t.Errorf("uploading %s added %d attachments", tt.name, added)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"todo-app/database"
 	"todo-app/models"
 	"todo-app/util"
 
@@ -16,6 +17,8 @@ import (
 type Credentials struct {
 	Password string `json:"password" validate:"required"`
 	Username string `json:"username" validate:"required,gte=3"`
+	// OrgID picks the organization to sign in to, the user's first one by default
+	OrgID uint `json:"org_id"`
 }
 
 func (h *Handler) Signup(w http.ResponseWriter, r *http.Request) {
@@ -47,7 +50,7 @@ func (h *Handler) Signup(w http.ResponseWriter, r *http.Request) {
 		RespondError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	res := Response{"success", "Sign up successful", user}
+	res := Response{Status: "success", Message: "Sign up successful", Data: user}
 	RespondJSON(w, http.StatusCreated, &res)
 }
 
@@ -68,7 +71,13 @@ func (h *Handler) Signin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.store.GetUserByUsername(creds.Username)
+	// usernames are unique within an organization, org_id tells apart
+	// users of different ones with the same name
+	user, err := h.store.GetUserByUsername(creds.OrgID, creds.Username)
+	if err == database.ErrAmbiguousUsername {
+		RespondError(w, http.StatusBadRequest, "Several users have this username, sign in with an org_id")
+		return
+	}
 	if err != nil {
 		RespondError(w, http.StatusUnauthorized, "User not found")
 		return
@@ -79,7 +88,13 @@ func (h *Handler) Signin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tokens, err := h.tk.CreateToken(user.ID)
+	orgID, err := h.activeOrg(user.ID, creds.OrgID)
+	if err != nil {
+		RespondError(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	tokens, err := h.tk.CreateToken(user.ID, orgID)
 	if err != nil {
 		RespondError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -97,7 +112,7 @@ func (h *Handler) Signin(w http.ResponseWriter, r *http.Request) {
 		"refresh_token": tokens.RefreshToken,
 	}
 
-	res := Response{"success", "Sign in successful", data}
+	res := Response{Status: "success", Message: "Sign in successful", Data: data}
 	RespondJSON(w, http.StatusOK, &res)
 }
 
@@ -116,7 +131,7 @@ func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	res := Response{"success", "Logout successful", nil}
+	res := Response{Status: "success", Message: "Logout successful"}
 	RespondJSON(w, http.StatusOK, &res)
 }
 
@@ -185,6 +200,12 @@ func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
 			RespondError(w, http.StatusUnprocessableEntity, "Unauthorized")
 			return
 		}
+		floatOrgId, _ := claims["org_id"].(float64)
+		orgId, orgErr := h.activeOrg(userId, uint(floatOrgId))
+		if orgErr != nil {
+			RespondError(w, http.StatusForbidden, orgErr.Error())
+			return
+		}
 		delErr := h.au.DeleteRefresh(refreshUUID)
 		if delErr != nil {
 			RespondError(w, http.StatusUnauthorized, delErr.Error())
@@ -192,7 +213,7 @@ func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
 		}
 		//Create new pairs of refresh and access tokens
 		// uintUserID, _ := strconv.ParseUint(userId, 10, 32)
-		ts, createErr := h.tk.CreateToken(userId)
+		ts, createErr := h.tk.CreateToken(userId, orgId)
 		if createErr != nil {
 			RespondError(w, http.StatusForbidden, createErr.Error())
 			return
@@ -208,7 +229,7 @@ func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
 			"access_token":  ts.AccessToken,
 			"refresh_token": ts.RefreshToken,
 		}
-		res := Response{"success", "Tokens created sucessfully", tokens}
+		res := Response{Status: "success", Message: "Tokens created sucessfully", Data: tokens}
 		RespondJSON(w, http.StatusOK, &res)
 		return
 	} else {
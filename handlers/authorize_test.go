@@ -0,0 +1,489 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"todo-app/database"
+	"todo-app/models"
+	"todo-app/util/auth"
+	"todo-app/util/blob"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(ioutil.Discard)
+
+	// the handlers broadcast their events to the websocket endpoint of the
+	// server, which is expected on localhost:8080
+	if l, err := net.Listen("tcp", "localhost:8080"); err == nil {
+		go http.Serve(l, http.HandlerFunc(discardEvents))
+	}
+	os.Exit(m.Run())
+}
+
+func discardEvents(w http.ResponseWriter, r *http.Request) {
+	c, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer c.Close()
+	for {
+		if _, _, err := c.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+// testAuth stands in for the token and session services, the
+// Authorization header holds the ID of the user making the request
+type testAuth struct {
+	org uint
+}
+
+func (a testAuth) CreateToken(userId, orgId uint) (*auth.TokenDetails, error) {
+	return nil, errors.New("tokens aren't signed in tests")
+}
+
+func (a testAuth) GetTokenMetadata(r *http.Request) (*auth.AccessDetails, error) {
+	return &auth.AccessDetails{TokenUuid: r.Header.Get("Authorization"), OrgId: a.org}, nil
+}
+
+func (a testAuth) CreateAuth(uint, *auth.TokenDetails) error { return nil }
+
+func (a testAuth) FetchAuth(uuid string) (string, error) { return uuid, nil }
+
+func (a testAuth) DeleteRefresh(string) error { return nil }
+
+func (a testAuth) DeleteTokens(*auth.AccessDetails) error { return nil }
+
+// fixture is an organization where alice owns a task and a project, bob
+// edits them, carol views them and dave is a member of neither
+type fixture struct {
+	store   database.TaskStore
+	handler *Handler
+	blobs   blob.Store
+	router  *mux.Router
+	users   map[string]*models.User
+	task    uint
+	project uint
+	// blockers are tasks everyone can see, the second one already blocks task
+	blockers [2]uint
+	item     uint
+	file     uint
+}
+
+// roles are the roles of the fixture users on its task and project
+var roles = []struct {
+	name string
+	role models.Role
+}{
+	{"alice", models.RoleOwner},
+	{"bob", models.RoleEditor},
+	{"carol", models.RoleViewer},
+	{"dave", ""},
+}
+
+func newFixture(t *testing.T) *fixture {
+	s, err := database.NewMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "attachments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	blobs, err := blob.NewLocal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := &fixture{store: s, users: make(map[string]*models.User)}
+	for _, r := range roles {
+		u, err := s.AddUser(models.User{Username: r.name, Password: "secret"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.users[r.name] = u
+	}
+	alice := f.users["alice"]
+	for _, r := range roles[1:] {
+		if _, err := s.AddOrgMember(int(alice.OrgID), int(f.users[r.name].ID), models.RoleMember); err != nil {
+			t.Fatal(err)
+		}
+		f.users[r.name].OrgID = alice.OrgID
+	}
+
+	task, err := s.CreateTask(alice, models.Task{Title: "task", Description: "d", Priority: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.task = task.ID
+	project, err := s.CreateProject(alice, models.Project{Name: "project"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.project = project.ID
+	for _, r := range roles[1:3] {
+		if _, _, err := s.AddUserToTask(alice, int(f.users[r.name].ID), int(f.task), r.role); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.AddProjectMember(int(f.project), int(f.users[r.name].ID), r.role); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := range f.blockers {
+		blocker, err := s.CreateTask(alice, models.Task{Title: "blocker", Description: "d", Priority: "1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range roles[1:3] {
+			if _, _, err := s.AddUserToTask(alice, int(f.users[r.name].ID), int(blocker.ID), models.RoleViewer); err != nil {
+				t.Fatal(err)
+			}
+		}
+		f.blockers[i] = blocker.ID
+	}
+	if err := s.AddBlocker(alice, int(f.task), int(f.blockers[1])); err != nil {
+		t.Fatal(err)
+	}
+
+	item, err := s.AddChecklistItem(alice, int(f.task), models.AddChecklistItem{Text: "step"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.item = item.ID
+	if _, err := blobs.Put("file", bytes.NewBufferString("hello")); err != nil {
+		t.Fatal(err)
+	}
+	file, err := s.CreateAttachment(alice, int(f.task), models.Attachment{
+		Filename: "file.txt", ContentType: "text/plain", Size: 5, Key: "file",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.file = file.ID
+
+	uploads := Uploads{Blobs: blobs, MaxSize: 1 << 20, Types: []string{"text/plain"}}
+	f.blobs = blobs
+	f.handler = NewHandler(s, testAuth{alice.OrgID}, testAuth{alice.OrgID}, uploads)
+	f.router = newTestRouter(f.handler)
+	return f
+}
+
+// newTestRouter routes the endpoints that check task and project roles like
+// main does
+func newTestRouter(h *Handler) *mux.Router {
+	r := mux.NewRouter()
+	tasks := r.PathPrefix("/tasks").Subrouter()
+	tasks.HandleFunc("", h.GetTasks).Methods(http.MethodGet)
+	tasks.HandleFunc("/batch", h.RunBatch).Methods(http.MethodPost)
+	tasks.HandleFunc("/{id:[0-9]+}", h.GetTask).Methods(http.MethodGet)
+	tasks.HandleFunc("/{id:[0-9]+}", h.UpdateTask).Methods(http.MethodPatch)
+	tasks.HandleFunc("/{id:[0-9]+}", h.DeleteTask).Methods(http.MethodDelete)
+	tasks.HandleFunc("/{id:[0-9]+}/subtasks", h.CreateSubtask).Methods(http.MethodPost)
+	tasks.HandleFunc("/{id:[0-9]+}/blockers/{idBlocker:[0-9]+}", h.AddBlocker).Methods(http.MethodPost)
+	tasks.HandleFunc("/{id:[0-9]+}/blockers/{idBlocker:[0-9]+}", h.RemoveBlocker).Methods(http.MethodDelete)
+	tasks.HandleFunc("/{id:[0-9]+}/attachments", h.UploadAttachment).Methods(http.MethodPost)
+	tasks.HandleFunc("/{id:[0-9]+}/attachments/{idAttachment:[0-9]+}", h.DeleteAttachment).Methods(http.MethodDelete)
+	tasks.HandleFunc("/{id:[0-9]+}/comments", h.CreateComment).Methods(http.MethodPost)
+	tasks.HandleFunc("/{id:[0-9]+}/checklist", h.AddChecklistItem).Methods(http.MethodPost)
+	tasks.HandleFunc("/{id:[0-9]+}/checklist", h.ReorderChecklist).Methods(http.MethodPut)
+	tasks.HandleFunc("/{id:[0-9]+}/checklist/{idItem:[0-9]+}/toggle", h.ToggleChecklistItem).Methods(http.MethodPost)
+	tasks.HandleFunc("/{id:[0-9]+}/checklist/{idItem:[0-9]+}", h.DeleteChecklistItem).Methods(http.MethodDelete)
+	tasks.HandleFunc("/{id:[0-9]+}/archive", h.ArchiveTask).Methods(http.MethodPost)
+	tasks.HandleFunc("/{id:[0-9]+}/unarchive", h.UnarchiveTask).Methods(http.MethodPost)
+	tasks.HandleFunc("/{id:[0-9]+}/restore", h.RestoreTask).Methods(http.MethodPost)
+	tasks.HandleFunc("/{idTask:[0-9]+}/{idUser:[0-9]+}", h.AddUserToTask).Methods(http.MethodPost)
+	tasks.HandleFunc("/{idTask:[0-9]+}/{idUser:[0-9]+}", h.RemoveUserFromTask).Methods(http.MethodDelete)
+
+	trash := r.PathPrefix("/trash").Subrouter()
+	trash.HandleFunc("", h.GetTrash).Methods(http.MethodGet)
+	trash.HandleFunc("/{id:[0-9]+}", h.PurgeTask).Methods(http.MethodDelete)
+
+	projects := r.PathPrefix("/projects").Subrouter()
+	projects.HandleFunc("/{id:[0-9]+}", h.UpdateProject).Methods(http.MethodPatch)
+	projects.HandleFunc("/{id:[0-9]+}", h.DeleteProject).Methods(http.MethodDelete)
+	projects.HandleFunc("/{id:[0-9]+}/tasks", h.CreateProjectTask).Methods(http.MethodPost)
+	projects.HandleFunc("/{id:[0-9]+}/tasks", h.GetProjectTasks).Methods(http.MethodGet)
+	projects.HandleFunc("/{id:[0-9]+}/members/{idUser:[0-9]+}", h.AddProjectMember).Methods(http.MethodPost)
+	projects.HandleFunc("/{id:[0-9]+}/members/{idUser:[0-9]+}", h.RemoveProjectMember).Methods(http.MethodDelete)
+	return r
+}
+
+// do sends a request as the named user
+func (f *fixture) do(user, method, path string, body io.Reader, contentType string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, body)
+	req.Header.Set("Authorization", strconv.FormatUint(uint64(f.users[user].ID), 10))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	w := httptest.NewRecorder()
+	f.router.ServeHTTP(w, req)
+	return w
+}
+
+func (f *fixture) doJSON(user, method, path, body string) *httptest.ResponseRecorder {
+	return f.do(user, method, path, bytes.NewBufferString(body), "application/json")
+}
+
+// upload is a multipart body carrying a small text file
+func upload(t *testing.T) (io.Reader, string) {
+	return uploadFile(t, "notes.txt", []byte("some notes"))
+}
+
+// uploadFile is a multipart body carrying contents as the file field
+func uploadFile(t *testing.T, filename string, contents []byte) (io.Reader, string) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(contents)
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &body, mw.FormDataContentType()
+}
+
+// endpoint is a request that needs at least min on the fixture task or
+// project, it answers status when it's allowed
+type endpoint struct {
+	name   string
+	min    models.Role
+	status int
+	// setup prepares the fixture so that the request can succeed
+	setup   func(t *testing.T, f *fixture)
+	request func(t *testing.T, f *fixture, user string) *httptest.ResponseRecorder
+}
+
+func jsonRequest(method, path, body string) func(t *testing.T, f *fixture, user string) *httptest.ResponseRecorder {
+	return func(t *testing.T, f *fixture, user string) *httptest.ResponseRecorder {
+		return f.doJSON(user, method, fmt.Sprintf(path, f.task, f.project), body)
+	}
+}
+
+var taskEndpoints = []endpoint{
+	{
+		name: "get task", min: models.RoleViewer, status: http.StatusOK,
+		request: jsonRequest(http.MethodGet, "/tasks/%[1]d", ""),
+	},
+	{
+		name: "update task", min: models.RoleEditor, status: http.StatusOK,
+		request: jsonRequest(http.MethodPatch, "/tasks/%[1]d", `{"title":"renamed"}`),
+	},
+	{
+		name: "delete task", min: models.RoleOwner, status: http.StatusOK,
+		request: jsonRequest(http.MethodDelete, "/tasks/%[1]d", ""),
+	},
+	{
+		name: "create subtask", min: models.RoleEditor, status: http.StatusCreated,
+		request: jsonRequest(http.MethodPost, "/tasks/%[1]d/subtasks", `{"title":"sub","description":"d","priority":"1"}`),
+	},
+	{
+		name: "add blocker", min: models.RoleEditor, status: http.StatusOK,
+		request: func(t *testing.T, f *fixture, user string) *httptest.ResponseRecorder {
+			return f.doJSON(user, http.MethodPost, fmt.Sprintf("/tasks/%d/blockers/%d", f.task, f.blockers[0]), "")
+		},
+	},
+	{
+		name: "remove blocker", min: models.RoleEditor, status: http.StatusOK,
+		request: func(t *testing.T, f *fixture, user string) *httptest.ResponseRecorder {
+			return f.doJSON(user, http.MethodDelete, fmt.Sprintf("/tasks/%d/blockers/%d", f.task, f.blockers[1]), "")
+		},
+	},
+	{
+		name: "upload attachment", min: models.RoleEditor, status: http.StatusCreated,
+		request: func(t *testing.T, f *fixture, user string) *httptest.ResponseRecorder {
+			body, contentType := upload(t)
+			return f.do(user, http.MethodPost, fmt.Sprintf("/tasks/%d/attachments", f.task), body, contentType)
+		},
+	},
+	{
+		name: "delete attachment", min: models.RoleEditor, status: http.StatusOK,
+		request: func(t *testing.T, f *fixture, user string) *httptest.ResponseRecorder {
+			return f.doJSON(user, http.MethodDelete, fmt.Sprintf("/tasks/%d/attachments/%d", f.task, f.file), "")
+		},
+	},
+	{
+		name: "add checklist item", min: models.RoleEditor, status: http.StatusCreated,
+		request: jsonRequest(http.MethodPost, "/tasks/%[1]d/checklist", `{"text":"another step"}`),
+	},
+	{
+		name: "reorder checklist", min: models.RoleEditor, status: http.StatusOK,
+		request: func(t *testing.T, f *fixture, user string) *httptest.ResponseRecorder {
+			return f.doJSON(user, http.MethodPut, fmt.Sprintf("/tasks/%d/checklist", f.task), fmt.Sprintf(`{"order":[%d]}`, f.item))
+		},
+	},
+	{
+		name: "toggle checklist item", min: models.RoleEditor, status: http.StatusOK,
+		request: func(t *testing.T, f *fixture, user string) *httptest.ResponseRecorder {
+			return f.doJSON(user, http.MethodPost, fmt.Sprintf("/tasks/%d/checklist/%d/toggle", f.task, f.item), "")
+		},
+	},
+	{
+		name: "delete checklist item", min: models.RoleEditor, status: http.StatusOK,
+		request: func(t *testing.T, f *fixture, user string) *httptest.ResponseRecorder {
+			return f.doJSON(user, http.MethodDelete, fmt.Sprintf("/tasks/%d/checklist/%d", f.task, f.item), "")
+		},
+	},
+	{
+		name: "archive task", min: models.RoleEditor, status: http.StatusOK,
+		request: jsonRequest(http.MethodPost, "/tasks/%[1]d/archive", ""),
+	},
+	{
+		name: "unarchive task", min: models.RoleEditor, status: http.StatusOK,
+		setup: func(t *testing.T, f *fixture) {
+			if _, err := f.store.ArchiveTask(f.users["alice"], int(f.task)); err != nil {
+				t.Fatal(err)
+			}
+		},
+		request: jsonRequest(http.MethodPost, "/tasks/%[1]d/unarchive", ""),
+	},
+	{
+		name: "add task member", min: models.RoleOwner, status: http.StatusOK,
+		request: func(t *testing.T, f *fixture, user string) *httptest.ResponseRecorder {
+			return f.doJSON(user, http.MethodPost, fmt.Sprintf("/tasks/%d/%d", f.task, f.users["dave"].ID), `{"role":"viewer"}`)
+		},
+	},
+	{
+		name: "remove task member", min: models.RoleOwner, status: http.StatusOK,
+		request: func(t *testing.T, f *fixture, user string) *httptest.ResponseRecorder {
+			// owners remove others, the others may only leave
+			other := "carol"
+			if user == "carol" {
+				other = "bob"
+			}
+			return f.doJSON(user, http.MethodDelete, fmt.Sprintf("/tasks/%d/%d", f.task, f.users[other].ID), "")
+		},
+	},
+}
+
+var projectEndpoints = []endpoint{
+	{
+		name: "get project tasks", min: models.RoleViewer, status: http.StatusOK,
+		request: jsonRequest(http.MethodGet, "/projects/%[2]d/tasks", ""),
+	},
+	{
+		name: "update project", min: models.RoleEditor, status: http.StatusOK,
+		request: jsonRequest(http.MethodPatch, "/projects/%[2]d", `{"name":"renamed"}`),
+	},
+	{
+		name: "delete project", min: models.RoleOwner, status: http.StatusOK,
+		request: jsonRequest(http.MethodDelete, "/projects/%[2]d", ""),
+	},
+	{
+		name: "create project task", min: models.RoleEditor, status: http.StatusCreated,
+		request: jsonRequest(http.MethodPost, "/projects/%[2]d/tasks", `{"title":"task","description":"d","priority":"1"}`),
+	},
+	{
+		name: "add project member", min: models.RoleOwner, status: http.StatusOK,
+		request: func(t *testing.T, f *fixture, user string) *httptest.ResponseRecorder {
+			return f.doJSON(user, http.MethodPost, fmt.Sprintf("/projects/%d/members/%d", f.project, f.users["dave"].ID), `{"role":"viewer"}`)
+		},
+	},
+	{
+		name: "remove project member", min: models.RoleOwner, status: http.StatusOK,
+		request: func(t *testing.T, f *fixture, user string) *httptest.ResponseRecorder {
+			other := "carol"
+			if user == "carol" {
+				other = "bob"
+			}
+			return f.doJSON(user, http.MethodDelete, fmt.Sprintf("/projects/%d/members/%d", f.project, f.users[other].ID), "")
+		},
+	},
+}
+
+// testRoles sends every request as each fixture user: members with the role
+// get through, other members are forbidden and dave doesn't get to know the
+// task or project exists
+func testRoles(t *testing.T, endpoints []endpoint) {
+	for _, e := range endpoints {
+		for _, r := range roles {
+			e, r := e, r
+			t.Run(e.name+"/"+r.name, func(t *testing.T) {
+				f := newFixture(t)
+				if e.setup != nil {
+					e.setup(t, f)
+				}
+
+				want := e.status
+				switch {
+				case r.role == "":
+					want = http.StatusNotFound
+				case !r.role.AtLeast(e.min):
+					want = http.StatusForbidden
+				}
+				if w := e.request(t, f, r.name); w.Code != want {
+					t.Errorf("%s as %s: got %d, want %d: %s", e.name, r.role, w.Code, want, w.Body)
+				}
+			})
+		}
+	}
+}
+
+func TestTaskRoles(t *testing.T) {
+	testRoles(t, taskEndpoints)
+}
+
+func TestProjectRoles(t *testing.T) {
+	testRoles(t, projectEndpoints)
+}
+
+func TestMembersLeave(t *testing.T) {
+	for _, r := range roles[1:3] {
+		f := newFixture(t)
+		id := f.users[r.name].ID
+		if w := f.doJSON(r.name, http.MethodDelete, fmt.Sprintf("/tasks/%d/%d", f.task, id), ""); w.Code != http.StatusOK {
+			t.Errorf("%s leaving the task: got %d: %s", r.name, w.Code, w.Body)
+		}
+		if w := f.doJSON(r.name, http.MethodDelete, fmt.Sprintf("/projects/%d/members/%d", f.project, id), ""); w.Code != http.StatusOK {
+			t.Errorf("%s leaving the project: got %d: %s", r.name, w.Code, w.Body)
+		}
+		if w := f.doJSON(r.name, http.MethodGet, fmt.Sprintf("/tasks/%d", f.task), ""); w.Code != http.StatusNotFound {
+			t.Errorf("%s reading the task after leaving: got %d", r.name, w.Code)
+		}
+	}
+}
+
+func TestLastOwner(t *testing.T) {
+	f := newFixture(t)
+	alice := f.users["alice"].ID
+	requests := []struct {
+		method, path, body string
+	}{
+		{http.MethodDelete, fmt.Sprintf("/tasks/%d/%d", f.task, alice), ""},
+		{http.MethodPost, fmt.Sprintf("/tasks/%d/%d", f.task, alice), `{"role":"editor"}`},
+		{http.MethodDelete, fmt.Sprintf("/projects/%d/members/%d", f.project, alice), ""},
+		{http.MethodPost, fmt.Sprintf("/projects/%d/members/%d", f.project, alice), `{"role":"viewer"}`},
+	}
+	for _, r := range requests {
+		if w := f.doJSON("alice", r.method, r.path, r.body); w.Code != http.StatusConflict {
+			t.Errorf("%s %s by the last owner: got %d, want %d: %s", r.method, r.path, w.Code, http.StatusConflict, w.Body)
+		}
+	}
+
+	// with a second owner the first one can step down
+	bob := f.users["bob"].ID
+	if w := f.doJSON("alice", http.MethodPost, fmt.Sprintf("/tasks/%d/%d", f.task, bob), `{"role":"owner"}`); w.Code != http.StatusOK {
+		t.Fatalf("promoting bob: got %d: %s", w.Code, w.Body)
+	}
+	if w := f.doJSON("alice", http.MethodDelete, fmt.Sprintf("/tasks/%d/%d", f.task, alice), ""); w.Code != http.StatusOK {
+		t.Errorf("alice leaving after promoting bob: got %d: %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"todo-app/database"
+	"todo-app/models"
+	"todo-app/ws"
+
+	"github.com/go-playground/validator/v10"
+	log "github.com/sirupsen/logrus"
+)
+
+// batchItem reports how one operation of a batch went
+type batchItem struct {
+	Index  int          `json:"index"`
+	Op     string       `json:"op"`
+	Status int          `json:"status"`
+	Task   *models.Task `json:"task,omitempty"`
+	Error  string       `json:"error,omitempty"`
+}
+
+// batchDone describes each operation in the batch event
+var batchDone = map[string]string{
+	models.BatchCreate:   "created",
+	models.BatchUpdate:   "updated",
+	models.BatchComplete: "completed",
+	models.BatchDelete:   "deleted",
+}
+
+// RunBatch creates, updates, completes and deletes tasks in one transaction.
+// Atomic batches, the default, fail as a whole on the first operation that
+// fails. Partial batches keep what succeeds and report each operation.
+func (h *Handler) RunBatch(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var batch models.Batch
+	err = json.NewDecoder(r.Body).Decode(&batch)
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid JSON provided")
+		return
+	}
+
+	validate := validator.New()
+	err = validate.Struct(batch)
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	atomic := batch.Mode != models.BatchPartial
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	items := make([]batchItem, len(batch.Operations))
+	var ops []models.BatchOp
+	var indexes []int
+	for i, op := range batch.Operations {
+		items[i] = batchItem{Index: i, Op: op.Op}
+		status, err := checkBatchOp(&batch.Operations[i])
+		if err == nil {
+			status, err = h.batchIfMatch(user, &batch.Operations[i])
+		}
+		if err != nil {
+			items[i].Status, items[i].Error = status, err.Error()
+			if atomic {
+				respondBatchFailed(w, items[i])
+				return
+			}
+			continue
+		}
+		ops = append(ops, batch.Operations[i])
+		indexes = append(indexes, i)
+	}
+
+	results, err := h.store.RunBatch(user, ops, atomic)
+	if err != nil {
+		log.Warningf("Batch error: %s", err.Error())
+		RespondError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	var done []batchItem
+	for j, result := range results {
+		item := &items[indexes[j]]
+		if result.Err != nil {
+			item.Status, item.Error = batchErrorStatus(result.Err), batchErrorMessage(result.Err)
+			if atomic {
+				respondBatchFailed(w, *item)
+				return
+			}
+			continue
+		}
+		item.Status, item.Task = http.StatusOK, result.Task
+		if item.Op == models.BatchCreate {
+			item.Status = http.StatusCreated
+		}
+		done = append(done, *item)
+	}
+
+	if len(done) > 0 {
+		broadcastBatch(user, done)
+	}
+
+	data := map[string]interface{}{
+		"results": items,
+	}
+
+	res := Response{Status: "success", Message: fmt.Sprintf("Ran %d of %d operations successfully", len(done), len(items)), Data: data}
+	RespondJSON(w, http.StatusOK, &res)
+}
+
+// checkBatchOp validates an operation, returning the status to report when it
+// can't run. The caller's role on the task is checked by the store, in the
+// same transaction as the operation.
+func checkBatchOp(op *models.BatchOp) (int, error) {
+	switch op.Op {
+	case models.BatchCreate:
+		if op.Task == nil {
+			return http.StatusBadRequest, errors.New("task is required to create")
+		}
+		if err := checkNewTask(op.Task); err != nil {
+			return http.StatusBadRequest, err
+		}
+		return 0, nil
+	case models.BatchUpdate:
+		if op.Patch == nil {
+			return http.StatusBadRequest, errors.New("patch is required to update")
+		}
+	case models.BatchComplete, models.BatchDelete:
+	default:
+		return http.StatusBadRequest, fmt.Errorf("invalid op %q, use create, update, complete or delete", op.Op)
+	}
+
+	if op.ID < 1 {
+		return http.StatusBadRequest, fmt.Errorf("id is required to %s", op.Op)
+	}
+	return 0, nil
+}
+
+// batchIfMatch resolves the ETag in if_match to the version the store has to
+// find, like If-Match on a single task
+func (h *Handler) batchIfMatch(user *models.User, op *models.BatchOp) (int, error) {
+	if op.Op == models.BatchCreate {
+		return 0, nil
+	}
+	tags, ok := parseIfMatch(op.IfMatch)
+	if !ok {
+		return http.StatusPreconditionFailed, database.ErrVersionMismatch
+	}
+	if tags == nil {
+		return 0, nil
+	}
+	versions, err := h.matchETag(user, op.ID, tags)
+	if err != nil {
+		return batchErrorStatus(err), errors.New(batchErrorMessage(err))
+	}
+	op.Versions = versions
+	return 0, nil
+}
+
+// batchErrorStatus answers like the single task endpoints do
+func batchErrorStatus(err error) int {
+	if err.Error() == "record not found" {
+		return http.StatusNotFound
+	}
+	if _, ok := err.(*models.PatchError); ok || err == models.ErrInvalidSchedule || err == models.ErrRecurrenceNeedsDue {
+		return http.StatusBadRequest
+	}
+	if _, ok := err.(*database.RoleError); ok {
+		return http.StatusForbidden
+	}
+	if err == database.ErrBlocked {
+		return http.StatusConflict
+	}
+	if err == database.ErrVersionMismatch {
+		return http.StatusPreconditionFailed
+	}
+	log.Warningf("Batch operation error: %s", err.Error())
+	return http.StatusBadRequest
+}
+
+func batchErrorMessage(err error) string {
+	if err.Error() == "record not found" {
+		return "Task not found"
+	}
+	return err.Error()
+}
+
+// respondBatchFailed reports the operation that failed an atomic batch
+func respondBatchFailed(w http.ResponseWriter, item batchItem) {
+	data := map[string]interface{}{
+		"failed": item,
+	}
+
+	res := Response{Status: "error", Message: fmt.Sprintf("Operation %d failed, nothing was changed", item.Index), Data: data}
+	RespondJSON(w, item.Status, &res)
+}
+
+// broadcastBatch sends a single event for every task the batch changed
+func broadcastBatch(user *models.User, done []batchItem) {
+	c, err := ws.Connect()
+	if err != nil {
+		log.Error("dial Error:", err)
+		return
+	}
+
+	counts := make(map[string]int)
+	var tasks []uint
+	for _, item := range done {
+		counts[item.Op]++
+		tasks = append(tasks, item.Task.ID)
+	}
+	var parts []string
+	for _, op := range []string{models.BatchCreate, models.BatchUpdate, models.BatchComplete, models.BatchDelete} {
+		if counts[op] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[op], batchDone[op]))
+		}
+	}
+
+	msg := message{
+		Username: user.Username,
+		Action:   "Batch",
+		Message:  fmt.Sprintf("%s changed %d tasks: %s", user.Username, len(tasks), strings.Join(parts, ", ")),
+		Tasks:    tasks,
+	}
+	go func() {
+		err := c.WriteJSON(msg)
+		if err != nil {
+			log.Warn("write:", err)
+			return
+		}
+	}()
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"todo-app/database"
+	"todo-app/models"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+func (h *Handler) AddChecklistItem(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var item models.AddChecklistItem
+	err = json.NewDecoder(r.Body).Decode(&item)
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid JSON provided")
+		return
+	}
+
+	validate := validator.New()
+	err = validate.Struct(item)
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	intID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		log.Warning("Failed to parse task ID")
+		RespondError(w, http.StatusBadRequest, "Invalid Id")
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	if !h.authorizeTask(w, user, intID, models.RoleEditor) {
+		return
+	}
+
+	created, err := h.store.AddChecklistItem(user, intID, item)
+	if err != nil {
+		log.Warningf("Add checklist item error: %s", err.Error())
+		if err.Error() == "record not found" {
+			RespondError(w, http.StatusNotFound, "Task not found")
+			return
+		}
+		RespondError(w, http.StatusBadRequest, "Failed to add checklist item")
+		return
+	}
+
+	data := map[string]interface{}{
+		"item": created,
+	}
+
+	res := Response{Status: "success", Message: "Checklist item added successfully", Data: data}
+	RespondJSON(w, http.StatusCreated, &res)
+}
+
+func (h *Handler) ToggleChecklistItem(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	intID, intIDItem, ok := checklistIDs(w, req)
+	if !ok {
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	if !h.authorizeTask(w, user, intID, models.RoleEditor) {
+		return
+	}
+
+	item, err := h.store.ToggleChecklistItem(user, intID, intIDItem)
+	if err != nil {
+		log.Warningf("Toggle checklist item error: %s", err.Error())
+		if err.Error() == "record not found" {
+			RespondError(w, http.StatusNotFound, "Checklist item not found")
+			return
+		}
+		RespondError(w, http.StatusBadRequest, "Failed to toggle checklist item")
+		return
+	}
+
+	verb := "unchecked"
+	if item.Done {
+		verb = "checked"
+	}
+	msg := message{
+		Username: user.Username,
+		Action:   "Toggle Checklist Item",
+		Message:  fmt.Sprintf("%s %s %q on task %d", user.Username, verb, item.Text, item.TaskID),
+		Task:     item.TaskID,
+	}
+	sendEvent(msg)
+
+	data := map[string]interface{}{
+		"item": item,
+	}
+
+	res := Response{Status: "success", Message: "Toggled checklist item successfully", Data: data}
+	RespondJSON(w, http.StatusOK, &res)
+}
+
+func (h *Handler) ReorderChecklist(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var body models.ReorderChecklist
+	err = json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid JSON provided")
+		return
+	}
+
+	validate := validator.New()
+	err = validate.Struct(body)
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	intID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		log.Warning("Failed to parse task ID")
+		RespondError(w, http.StatusBadRequest, "Invalid Id")
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	if !h.authorizeTask(w, user, intID, models.RoleEditor) {
+		return
+	}
+
+	items, err := h.store.ReorderChecklist(user, intID, body.Order)
+	if err != nil {
+		log.Warningf("Reorder checklist error: %s", err.Error())
+		if err == database.ErrChecklistOrder {
+			RespondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err.Error() == "record not found" {
+			RespondError(w, http.StatusNotFound, "Task not found")
+			return
+		}
+		RespondError(w, http.StatusBadRequest, "Failed to reorder checklist")
+		return
+	}
+
+	data := map[string]interface{}{
+		"checklist": items,
+	}
+
+	res := Response{Status: "success", Message: "Reordered checklist successfully", Data: data}
+	RespondJSON(w, http.StatusOK, &res)
+}
+
+func (h *Handler) DeleteChecklistItem(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	intID, intIDItem, ok := checklistIDs(w, req)
+	if !ok {
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	if !h.authorizeTask(w, user, intID, models.RoleEditor) {
+		return
+	}
+
+	err = h.store.DeleteChecklistItem(user, intID, intIDItem)
+	if err != nil {
+		log.Warningf("Delete checklist item error: %s", err.Error())
+		if err.Error() == "record not found" {
+			RespondError(w, http.StatusNotFound, "Checklist item not found")
+			return
+		}
+		RespondError(w, http.StatusBadRequest, "Failed to delete checklist item")
+		return
+	}
+
+	res := Response{Status: "success", Message: "Deleted checklist item successfully"}
+	RespondJSON(w, http.StatusOK, &res)
+}
+
+func checklistIDs(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	vars := mux.Vars(r)
+	intID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		log.Warning("Failed to parse task ID")
+		RespondError(w, http.StatusBadRequest, "Invalid Id")
+		return 0, 0, false
+	}
+	intIDItem, err := strconv.Atoi(vars["idItem"])
+	if err != nil {
+		log.Warning("Failed to parse checklist item ID")
+		RespondError(w, http.StatusBadRequest, "Invalid checklist item Id")
+		return 0, 0, false
+	}
+	return intID, intIDItem, true
+}
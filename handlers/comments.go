@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"todo-app/models"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+func (h *Handler) CreateComment(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var body models.Comment
+	err = json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid JSON provided")
+		return
+	}
+
+	validate := validator.New()
+	err = validate.Struct(body)
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	// the rest is set by the store
+	c := models.Comment{ParentID: body.ParentID, Body: body.Body}
+
+	intID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		log.Warning("Failed to parse task ID")
+		RespondError(w, http.StatusBadRequest, "Invalid Id")
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	if c.Mentions, err = h.resolveMentions(user, c.Body); err != nil {
+		log.Warningf("Failed to resolve mentions: %s", err.Error())
+		RespondError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	comment, err := h.store.CreateComment(user, intID, c)
+	if err != nil {
+		log.Warningf("Create comment error: %s", err.Error())
+		if err.Error() == "record not found" {
+			RespondError(w, http.StatusNotFound, "Task or parent comment not found")
+			return
+		}
+		RespondError(w, http.StatusBadRequest, "Failed to create comment")
+		return
+	}
+
+	msg := message{
+		Username: user.Username,
+		Action:   "Comment",
+		Message:  fmt.Sprintf("%s commented on task %d", user.Username, comment.TaskID),
+		Task:     comment.TaskID,
+	}
+	for _, mentioned := range comment.Mentions {
+		msg.Mentions = append(msg.Mentions, mentioned.Username)
+	}
+	sendEvent(msg)
+
+	data := map[string]interface{}{
+		"comment": comment,
+	}
+
+	res := Response{Status: "success", Message: "Comment created successfully", Data: data}
+	RespondJSON(w, http.StatusCreated, &res)
+}
+
+// resolveMentions looks up the users mentioned in body, names that don't
+// belong to anyone in the user's organization are left as plain text
+func (h *Handler) resolveMentions(user *models.User, body string) ([]*models.User, error) {
+	var users []*models.User
+	for _, name := range models.ParseMentions(body) {
+		mentioned, err := h.store.GetUserByUsername(user.OrgID, name)
+		if err != nil {
+			if err.Error() == "record not found" {
+				continue
+			}
+			return nil, err
+		}
+		users = append(users, mentioned)
+	}
+	return users, nil
+}
+
+func (h *Handler) GetComments(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	intID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		log.Warning("Failed to parse task ID")
+		RespondError(w, http.StatusBadRequest, "Invalid Id")
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	comments, err := h.store.GetComments(user, intID)
+	if err != nil {
+		log.Warningf("Failed to fetch comments: %s", err.Error())
+		if err.Error() == "record not found" {
+			RespondError(w, http.StatusNotFound, "Task not found")
+			return
+		}
+		RespondError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	data := map[string]interface{}{
+		"comments": comments,
+	}
+
+	res := Response{Status: "success", Message: "Fetched comments successfully", Data: data}
+	RespondJSON(w, http.StatusOK, &res)
+}
+
+func (h *Handler) UpdateComment(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var c models.UpdateComment
+	err = json.NewDecoder(r.Body).Decode(&c)
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid JSON provided")
+		return
+	}
+
+	validate := validator.New()
+	err = validate.Struct(c)
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	intID, intIDComment, ok := commentIDs(w, req)
+	if !ok {
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	comment, ok := h.authorizeComment(w, user, intID, intIDComment, false)
+	if !ok {
+		return
+	}
+
+	update := models.Comment{Body: c.Body}
+	if update.Mentions, err = h.resolveMentions(user, c.Body); err != nil {
+		log.Warningf("Failed to resolve mentions: %s", err.Error())
+		RespondError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	comment, err = h.store.UpdateComment(user, intID, int(comment.ID), update)
+	if err != nil {
+		log.Warningf("Update comment error: %s", err.Error())
+		if err.Error() == "record not found" {
+			RespondError(w, http.StatusNotFound, "Comment not found")
+			return
+		}
+		RespondError(w, http.StatusBadRequest, "Failed to update comment")
+		return
+	}
+
+	data := map[string]interface{}{
+		"comment": comment,
+	}
+
+	res := Response{Status: "success", Message: "Updated comment successfully", Data: data}
+	RespondJSON(w, http.StatusOK, &res)
+}
+
+func (h *Handler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	intID, intIDComment, ok := commentIDs(w, req)
+	if !ok {
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	if _, ok := h.authorizeComment(w, user, intID, intIDComment, true); !ok {
+		return
+	}
+
+	err = h.store.DeleteComment(user, intID, intIDComment)
+	if err != nil {
+		log.Warningf("Delete comment error: %s", err.Error())
+		if err.Error() == "record not found" {
+			RespondError(w, http.StatusNotFound, "Comment not found")
+			return
+		}
+		RespondError(w, http.StatusBadRequest, "Failed to delete comment")
+		return
+	}
+
+	res := Response{Status: "success", Message: "Deleted comment successfully"}
+	RespondJSON(w, http.StatusOK, &res)
+}
+
+// authorizeComment returns the comment when user wrote it, task owners may
+// also moderate other people's comments when ownerToo is set
+func (h *Handler) authorizeComment(w http.ResponseWriter, user *models.User, idTask, idComment int, ownerToo bool) (*models.Comment, bool) {
+	comment, err := h.store.GetComment(user, idTask, idComment)
+	if err != nil {
+		if err.Error() == "record not found" {
+			RespondError(w, http.StatusNotFound, "Comment not found")
+			return nil, false
+		}
+		log.Warningf("Failed to fetch comment: %s", err.Error())
+		RespondError(w, http.StatusInternalServerError, "Something went wrong")
+		return nil, false
+	}
+	if comment.UserID == user.ID {
+		return comment, true
+	}
+
+	if ownerToo {
+		role, err := h.store.GetTaskRole(user, idTask)
+		if err != nil {
+			log.Warningf("Failed to get task role: %s", err.Error())
+			RespondError(w, http.StatusInternalServerError, "Something went wrong")
+			return nil, false
+		}
+		if role.AtLeast(models.RoleOwner) {
+			return comment, true
+		}
+	}
+	RespondError(w, http.StatusForbidden, "Forbidden: not the author of the comment")
+	return nil, false
+}
+
+func commentIDs(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	vars := mux.Vars(r)
+	intID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		log.Warning("Failed to parse task ID")
+		RespondError(w, http.StatusBadRequest, "Invalid Id")
+		return 0, 0, false
+	}
+	intIDComment, err := strconv.Atoi(vars["idComment"])
+	if err != nil {
+		log.Warning("Failed to parse comment ID")
+		RespondError(w, http.StatusBadRequest, "Invalid comment Id")
+		return 0, 0, false
+	}
+	return intID, intIDComment, true
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"todo-app/models"
+)
+
+func TestCreateCommentMentions(t *testing.T) {
+	f := newFixture(t)
+	// erin is in an organization of their own
+	if _, err := f.store.AddUser(models.User{Username: "erin", Password: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+
+	path := fmt.Sprintf("/tasks/%d/comments", f.task)
+	w := f.doJSON("alice", http.MethodPost, path, `{"body": "@bob, @dave and @bob: ask @erin or @nobody"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("commenting: got %d: %s", w.Code, w.Body)
+	}
+	comments, err := f.store.GetComments(f.users["alice"], int(f.task))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(comments) != 1 {
+		t.Fatalf("got %d comments, want 1", len(comments))
+	}
+	var names []string
+	for _, u := range comments[0].Mentions {
+		names = append(names, u.Username)
+	}
+	if got := fmt.Sprint(names); got != "[bob dave]" {
+		t.Errorf("mentions: got %s, want [bob dave]", got)
+	}
+
+	parent := comments[0].ID
+	other := fmt.Sprintf("/tasks/%d/comments", f.blockers[0])
+	if w := f.doJSON("alice", http.MethodPost, other, fmt.Sprintf(`{"body": "reply", "parent_id": %d}`, parent)); w.Code != http.StatusNotFound {
+		t.Errorf("replying on another task: got %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
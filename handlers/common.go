@@ -3,30 +3,48 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
 	"todo-app/database"
+	"todo-app/models"
 	"todo-app/util/auth"
+	"todo-app/util/blob"
 
 	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
 )
 
 type Handler struct {
-	store *database.Store
-	tk    auth.TokenInterface
-	au    auth.AuthInterface
+	store   database.TaskStore
+	tk      auth.TokenInterface
+	au      auth.AuthInterface
+	uploads Uploads
+}
+
+// Uploads configures task attachments
+type Uploads struct {
+	Blobs blob.Store
+	// MaxSize is in bytes
+	MaxSize int64
+	// Types are the accepted MIME types, matched against the sniffed content
+	Types []string
 }
 
 type Response struct {
 	Status  string      `json:"status"`
 	Message string      `json:"message"`
 	Data    interface{} `json:"data,omitempty"`
+	// NextCursor and PrevCursor page through task lists, they are left out
+	// on the first and last page
+	NextCursor *models.Cursor `json:"next_cursor,omitempty"`
+	PrevCursor *models.Cursor `json:"prev_cursor,omitempty"`
 }
 
 type KeyUser struct{}
 
-func NewHandler(s *database.Store, tk auth.TokenInterface, au auth.AuthInterface) *Handler {
-	return &Handler{s, tk, au}
+func NewHandler(s database.TaskStore, tk auth.TokenInterface, au auth.AuthInterface, uploads Uploads) *Handler {
+	return &Handler{s, tk, au, uploads}
 }
 
 func (h *Handler) CheckAuth(r *http.Request) (*http.Request, error) {
@@ -36,7 +54,7 @@ func (h *Handler) CheckAuth(r *http.Request) (*http.Request, error) {
 	}
 	strUserId, err := h.au.FetchAuth(metadata.TokenUuid)
 	if err != nil {
-		return nil, errors.Wrap(err, "Error fetching user auth details from redis store")
+		return nil, errors.Wrap(err, "Error fetching user auth details from session store")
 	}
 
 	userID, err := strconv.ParseUint(strUserId, 10, 64)
@@ -49,6 +67,12 @@ func (h *Handler) CheckAuth(r *http.Request) (*http.Request, error) {
 		return nil, err
 	}
 
+	// the organization comes from the signed token, membership is checked
+	// on every request so removed users lose access right away
+	if user.OrgID, err = h.activeOrg(user.ID, metadata.OrgId); err != nil {
+		return nil, err
+	}
+
 	// ctx := context.WithValue(r.Context(), "userId", userId)
 	ctx := context.WithValue(r.Context(), KeyUser{}, user)
 	req := r.WithContext(ctx)
@@ -56,6 +80,74 @@ func (h *Handler) CheckAuth(r *http.Request) (*http.Request, error) {
 	return req, nil
 }
 
+// ErrNotOrgMember is returned when a token names an organization the user
+// doesn't belong to
+var ErrNotOrgMember = errors.New("not a member of this organization")
+
+// activeOrg checks that idUser belongs to idOrg, picking their first
+// organization when idOrg is 0
+func (h *Handler) activeOrg(idUser, idOrg uint) (uint, error) {
+	if idOrg == 0 {
+		orgs, err := h.store.GetOrganizations(&models.User{ID: idUser})
+		if err != nil {
+			return 0, err
+		}
+		if len(*orgs) == 0 {
+			return 0, ErrNotOrgMember
+		}
+		return (*orgs)[0].ID, nil
+	}
+
+	if _, err := h.store.GetOrgRole(idUser, idOrg); err != nil {
+		if err.Error() == "record not found" {
+			return 0, ErrNotOrgMember
+		}
+		return 0, err
+	}
+	return idOrg, nil
+}
+
+// authorizeTask writes a 404 or 403 and returns false unless user holds at
+// least min on the task
+func (h *Handler) authorizeTask(w http.ResponseWriter, user *models.User, idTask int, min models.Role) bool {
+	role, err := h.store.GetTaskRole(user, idTask)
+	if err != nil {
+		if err.Error() == "record not found" {
+			RespondError(w, http.StatusNotFound, "Task not found")
+			return false
+		}
+		log.Warningf("Failed to get task role: %s", err.Error())
+		RespondError(w, http.StatusInternalServerError, "Something went wrong")
+		return false
+	}
+
+	if !role.AtLeast(min) {
+		RespondError(w, http.StatusForbidden, fmt.Sprintf("Forbidden: requires %s role on task", min))
+		return false
+	}
+	return true
+}
+
+// authorizeProject is authorizeTask for projects
+func (h *Handler) authorizeProject(w http.ResponseWriter, user *models.User, idProject int, min models.Role) bool {
+	role, err := h.store.GetProjectRole(user, idProject)
+	if err != nil {
+		if err.Error() == "record not found" {
+			RespondError(w, http.StatusNotFound, "Project not found")
+			return false
+		}
+		log.Warningf("Failed to get project role: %s", err.Error())
+		RespondError(w, http.StatusInternalServerError, "Something went wrong")
+		return false
+	}
+
+	if !role.AtLeast(min) {
+		RespondError(w, http.StatusForbidden, fmt.Sprintf("Forbidden: requires %s role on project", min))
+		return false
+	}
+	return true
+}
+
 func RespondJSON(w http.ResponseWriter, status int, payload interface{}) {
 	response, err := json.Marshal(payload)
 	if err != nil {
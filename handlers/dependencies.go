@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"todo-app/database"
+	"todo-app/models"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+func (h *Handler) AddBlocker(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	intID, intIDBlocker, ok := blockerIDs(w, req)
+	if !ok {
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	if !h.authorizeTask(w, user, intID, models.RoleEditor) {
+		return
+	}
+
+	err = h.store.AddBlocker(user, intID, intIDBlocker)
+	if err != nil {
+		log.Warningf("Add blocker error: %s", err.Error())
+		if err == database.ErrDependencyCycle {
+			RespondError(w, http.StatusConflict, err.Error())
+			return
+		}
+		if err.Error() == "record not found" {
+			RespondError(w, http.StatusNotFound, "Task not found")
+			return
+		}
+		RespondError(w, http.StatusBadRequest, "Failed to add blocker")
+		return
+	}
+
+	res := Response{Status: "success", Message: "Added blocker successfully"}
+	RespondJSON(w, http.StatusOK, &res)
+}
+
+func (h *Handler) RemoveBlocker(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	intID, intIDBlocker, ok := blockerIDs(w, req)
+	if !ok {
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	if !h.authorizeTask(w, user, intID, models.RoleEditor) {
+		return
+	}
+
+	err = h.store.RemoveBlocker(user, intID, intIDBlocker)
+	if err != nil {
+		log.Warningf("Remove blocker error: %s", err.Error())
+		if err.Error() == "record not found" {
+			RespondError(w, http.StatusNotFound, "Blocker not found")
+			return
+		}
+		RespondError(w, http.StatusBadRequest, "Failed to remove blocker")
+		return
+	}
+
+	res := Response{Status: "success", Message: "Removed blocker successfully"}
+	RespondJSON(w, http.StatusOK, &res)
+}
+
+func blockerIDs(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	vars := mux.Vars(r)
+	intID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		log.Warning("Failed to parse task ID")
+		RespondError(w, http.StatusBadRequest, "Invalid Id")
+		return 0, 0, false
+	}
+	intIDBlocker, err := strconv.Atoi(vars["idBlocker"])
+	if err != nil {
+		log.Warning("Failed to parse blocker ID")
+		RespondError(w, http.StatusBadRequest, "Invalid blocker Id")
+		return 0, 0, false
+	}
+	if intID == intIDBlocker {
+		RespondError(w, http.StatusConflict, database.ErrDependencyCycle.Error())
+		return 0, 0, false
+	}
+	return intID, intIDBlocker, true
+}
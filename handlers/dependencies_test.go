@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestCompleteBlocked(t *testing.T) {
+	f := newFixture(t)
+	path := fmt.Sprintf("/tasks/%d", f.task)
+
+	if w := f.doJSON("alice", http.MethodPatch, path, `{"completed":true}`); w.Code != http.StatusConflict {
+		t.Errorf("completing a blocked task: got %d, want %d: %s", w.Code, http.StatusConflict, w.Body)
+	}
+	if w := f.doJSON("alice", http.MethodPatch, path+"?force=true", `{"completed":true}`); w.Code != http.StatusOK {
+		t.Errorf("forcing the completion: got %d: %s", w.Code, w.Body)
+	}
+}
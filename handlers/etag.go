@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"todo-app/database"
+	"todo-app/models"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// taskETag is the strong ETag of a task as it is sent, its version followed
+// by a hash of the whole representation. Checklists, labels, attachments,
+// blockers, members and subtasks change what is sent without changing the
+// version, so the hash is what tells those apart.
+func taskETag(t *models.Task) string {
+	// a task always marshals, RespondJSON would fail on it otherwise
+	body, _ := json.Marshal(t)
+	sum := sha256.Sum256(body)
+	return fmt.Sprintf(`"%d-%x"`, t.Version, sum[:8])
+}
+
+// parseIfMatch returns the ETags listed in an If-Match header, none for an
+// empty header or "*". ok is false when the header lists nothing a task can
+// match, like weak ETags, which never pass the strong comparison If-Match
+// calls for.
+func parseIfMatch(header string) (tags []string, ok bool) {
+	if header == "" || strings.TrimSpace(header) == "*" {
+		return nil, true
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			continue
+		}
+		tags = append(tags, tag)
+	}
+	return tags, len(tags) > 0
+}
+
+// ifMatch compares If-Match with the ETag task idTask is sent with and
+// returns the version the store has to find, none when any version will do.
+// It writes the error response itself when it returns false.
+func (h *Handler) ifMatch(w http.ResponseWriter, r *http.Request, user *models.User, idTask int) ([]uint, bool) {
+	tags, ok := parseIfMatch(r.Header.Get("If-Match"))
+	if !ok {
+		RespondError(w, http.StatusPreconditionFailed, database.ErrVersionMismatch.Error())
+		return nil, false
+	}
+	if tags == nil {
+		return nil, true
+	}
+
+	versions, err := h.matchETag(user, idTask, tags)
+	if err != nil {
+		if err == database.ErrVersionMismatch {
+			RespondError(w, http.StatusPreconditionFailed, err.Error())
+			return nil, false
+		}
+		if err.Error() == "record not found" {
+			RespondError(w, http.StatusNotFound, "Task not found")
+			return nil, false
+		}
+		log.Warningf("Failed to Get Task: %s", err.Error())
+		RespondError(w, http.StatusInternalServerError, "Something went wrong")
+		return nil, false
+	}
+	return versions, true
+}
+
+// matchETag returns the version of task idTask when tags lists the ETag it is
+// sent with, ErrVersionMismatch when it doesn't
+func (h *Handler) matchETag(user *models.User, idTask int, tags []string) ([]uint, error) {
+	task, err := h.store.GetTask(user, idTask)
+	if err != nil {
+		return nil, err
+	}
+	etag := taskETag(task)
+	for _, tag := range tags {
+		if tag == etag {
+			// the task may still change before the store gets to it
+			return []uint{task.Version}, nil
+		}
+	}
+	return nil, database.ErrVersionMismatch
+}
+
+// notModified reports whether If-None-Match lists etag, comparing weakly
+func notModified(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
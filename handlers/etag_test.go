@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// patchIfMatch renames the fixture task as alice, sending ifMatch
+func patchIfMatch(f *fixture, ifMatch string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/tasks/%d", f.task), bytes.NewBufferString(`{"title":"renamed"}`))
+	req.Header.Set("Authorization", strconv.FormatUint(uint64(f.users["alice"].ID), 10))
+	req.Header.Set("If-Match", ifMatch)
+	w := httptest.NewRecorder()
+	f.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIfMatch(t *testing.T) {
+	f := newFixture(t)
+	etag := f.doJSON("alice", http.MethodGet, fmt.Sprintf("/tasks/%d", f.task), "").Header().Get("ETag")
+	version := strings.SplitN(strings.Trim(etag, `"`), "-", 2)[0]
+
+	for _, tag := range []string{
+		`"` + version + `-garbage"`,
+		`"` + version + `"`,
+		"W/" + etag,
+	} {
+		if w := patchIfMatch(f, tag); w.Code != http.StatusPreconditionFailed {
+			t.Errorf("If-Match %s: got %d, want %d", tag, w.Code, http.StatusPreconditionFailed)
+		}
+	}
+
+	// a new checklist item changes the ETag but not the version
+	if w := f.doJSON("alice", http.MethodPost, fmt.Sprintf("/tasks/%d/checklist", f.task), `{"text":"another step"}`); w.Code != http.StatusCreated {
+		t.Fatalf("adding a checklist item: got %d: %s", w.Code, w.Body)
+	}
+	if w := patchIfMatch(f, etag); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("If-Match from before the checklist changed: got %d, want %d", w.Code, http.StatusPreconditionFailed)
+	}
+
+	etag = f.doJSON("alice", http.MethodGet, fmt.Sprintf("/tasks/%d", f.task), "").Header().Get("ETag")
+	if w := patchIfMatch(f, `"0-0", `+etag); w.Code != http.StatusOK {
+		t.Errorf("If-Match listing the current ETag: got %d: %s", w.Code, w.Body)
+	}
+	if w := patchIfMatch(f, etag); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("If-Match after the task changed: got %d, want %d", w.Code, http.StatusPreconditionFailed)
+	}
+}
+
+func TestPatchETag(t *testing.T) {
+	f := newFixture(t)
+	w := patchIfMatch(f, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("PATCH: got %d: %s", w.Code, w.Body)
+	}
+	etag := w.Header().Get("ETag")
+	if got := f.doJSON("alice", http.MethodGet, fmt.Sprintf("/tasks/%d", f.task), "").Header().Get("ETag"); got != etag {
+		t.Errorf("GET after PATCH: got ETag %s, want %s", got, etag)
+	}
+
+	w = patchIfMatch(f, etag)
+	if w.Code != http.StatusOK {
+		t.Fatalf("If-Match from the last PATCH: got %d: %s", w.Code, w.Body)
+	}
+	if w := patchIfMatch(f, w.Header().Get("ETag")); w.Code != http.StatusOK {
+		t.Errorf("If-Match from the second PATCH: got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestBatchIfMatch(t *testing.T) {
+	f := newFixture(t)
+	etag := f.doJSON("alice", http.MethodGet, fmt.Sprintf("/tasks/%d", f.task), "").Header().Get("ETag")
+	version := strings.SplitN(strings.Trim(etag, `"`), "-", 2)[0]
+
+	batch := func(ifMatch string) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"operations":[{"op":"update","id":%d,"patch":{"priority":"3"},"if_match":%s}]}`, f.task, strconv.Quote(ifMatch))
+		return f.doJSON("alice", http.MethodPost, "/tasks/batch", body)
+	}
+	for _, tag := range []string{version, `"` + version + `"`, "W/" + etag} {
+		if w := batch(tag); w.Code != http.StatusPreconditionFailed {
+			t.Errorf("if_match %s: got %d, want %d", tag, w.Code, http.StatusPreconditionFailed)
+		}
+	}
+	if w := batch(etag); w.Code != http.StatusOK {
+		t.Errorf("if_match with the current ETag: got %d: %s", w.Code, w.Body)
+	}
+	if w := batch(etag); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("if_match after the task changed: got %d, want %d", w.Code, http.StatusPreconditionFailed)
+	}
+	if w := batch("*"); w.Code != http.StatusOK {
+		t.Errorf("if_match *: got %d: %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"todo-app/database"
+	"todo-app/models"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+func (h *Handler) CreateLabel(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var l models.Label
+	err = json.NewDecoder(r.Body).Decode(&l)
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid JSON provided")
+		return
+	}
+
+	validate := validator.New()
+	err = validate.Struct(l)
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	label, err := h.store.CreateLabel(user, l)
+	if err != nil {
+		log.Warningf("Create label error: %s", err.Error())
+		if err == database.ErrLabelExists {
+			RespondError(w, http.StatusConflict, err.Error())
+			return
+		}
+		RespondError(w, http.StatusBadRequest, "Failed to create label")
+		return
+	}
+
+	data := map[string]interface{}{
+		"label": label,
+	}
+
+	res := Response{Status: "success", Message: "Label created successfully", Data: data}
+	RespondJSON(w, http.StatusCreated, &res)
+}
+
+func (h *Handler) GetLabels(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	labels, err := h.store.GetLabels(user)
+	if err != nil {
+		log.Warningf("Failed to fetch labels: %s", err.Error())
+		RespondError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	data := map[string]interface{}{
+		"labels": labels,
+	}
+
+	res := Response{Status: "success", Message: "Fetched labels successfully", Data: data}
+	RespondJSON(w, http.StatusOK, &res)
+}
+
+func (h *Handler) UpdateLabel(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var l models.UpdateLabel
+	err = json.NewDecoder(r.Body).Decode(&l)
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid JSON provided")
+		return
+	}
+
+	validate := validator.New()
+	err = validate.Struct(l)
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	intID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		log.Warning("Failed to parse label ID")
+		RespondError(w, http.StatusBadRequest, "Invalid Id")
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	label, err := h.store.UpdateLabel(user, intID, l)
+	if err != nil {
+		log.Warningf("Update label error: %s", err.Error())
+		if err == database.ErrLabelExists {
+			RespondError(w, http.StatusConflict, err.Error())
+			return
+		}
+		if err.Error() == "record not found" {
+			RespondError(w, http.StatusNotFound, "Label not found")
+			return
+		}
+		RespondError(w, http.StatusBadRequest, "Failed to update label")
+		return
+	}
+
+	data := map[string]interface{}{
+		"label": label,
+	}
+
+	res := Response{Status: "success", Message: "Updated label successfully", Data: data}
+	RespondJSON(w, http.StatusOK, &res)
+}
+
+func (h *Handler) DeleteLabel(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	intID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		log.Warning("Failed to parse label ID")
+		RespondError(w, http.StatusBadRequest, "Invalid Id")
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	err = h.store.DeleteLabel(user, intID)
+	if err != nil {
+		log.Warningf("Delete label error: %s", err.Error())
+		if err.Error() == "record not found" {
+			RespondError(w, http.StatusNotFound, "Label not found")
+			return
+		}
+		RespondError(w, http.StatusBadRequest, "Failed to delete label")
+		return
+	}
+
+	res := Response{Status: "success", Message: "Deleted label successfully"}
+	RespondJSON(w, http.StatusOK, &res)
+}
+
+func (h *Handler) AttachLabel(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	intID, intIDLabel, ok := labelIDs(w, req)
+	if !ok {
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	err = h.store.AttachLabel(user, intID, intIDLabel)
+	if err != nil {
+		log.Warningf("Attach label error: %s", err.Error())
+		if err.Error() == "record not found" {
+			RespondError(w, http.StatusNotFound, "Task or label not found")
+			return
+		}
+		RespondError(w, http.StatusBadRequest, "Failed to attach label")
+		return
+	}
+
+	res := Response{Status: "success", Message: "Attached label successfully"}
+	RespondJSON(w, http.StatusOK, &res)
+}
+
+func (h *Handler) DetachLabel(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	intID, intIDLabel, ok := labelIDs(w, req)
+	if !ok {
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	err = h.store.DetachLabel(user, intID, intIDLabel)
+	if err != nil {
+		log.Warningf("Detach label error: %s", err.Error())
+		if err.Error() == "record not found" {
+			RespondError(w, http.StatusNotFound, "Label not found on task")
+			return
+		}
+		RespondError(w, http.StatusBadRequest, "Failed to detach label")
+		return
+	}
+
+	res := Response{Status: "success", Message: "Detached label successfully"}
+	RespondJSON(w, http.StatusOK, &res)
+}
+
+func labelIDs(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	vars := mux.Vars(r)
+	intID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		log.Warning("Failed to parse task ID")
+		RespondError(w, http.StatusBadRequest, "Invalid Id")
+		return 0, 0, false
+	}
+	intIDLabel, err := strconv.Atoi(vars["idLabel"])
+	if err != nil {
+		log.Warning("Failed to parse label ID")
+		RespondError(w, http.StatusBadRequest, "Invalid label Id")
+		return 0, 0, false
+	}
+	return intID, intIDLabel, true
+}
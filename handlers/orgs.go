@@ -0,0 +1,286 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"todo-app/database"
+	"todo-app/models"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+func (h *Handler) CreateOrganization(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var o models.Organization
+	err = json.NewDecoder(r.Body).Decode(&o)
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid JSON provided")
+		return
+	}
+
+	validate := validator.New()
+	err = validate.Struct(o)
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	org, err := h.store.CreateOrganization(user, o)
+	if err != nil {
+		log.Warningf("Create organization error: %s", err.Error())
+		RespondError(w, http.StatusBadRequest, "Failed to create organization")
+		return
+	}
+
+	data := map[string]interface{}{
+		"organization": org,
+	}
+
+	res := Response{Status: "success", Message: "Organization created successfully", Data: data}
+	RespondJSON(w, http.StatusCreated, &res)
+}
+
+func (h *Handler) GetOrganizations(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	orgs, err := h.store.GetOrganizations(user)
+	if err != nil {
+		log.Warningf("Failed to fetch organizations: %s", err.Error())
+		RespondError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	data := map[string]interface{}{
+		"organizations": orgs,
+		"active":        user.OrgID,
+	}
+
+	res := Response{Status: "success", Message: "Fetched organizations successfully", Data: data}
+	RespondJSON(w, http.StatusOK, &res)
+}
+
+func (h *Handler) GetOrganization(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	intID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		log.Warning("Failed to parse organization ID")
+		RespondError(w, http.StatusBadRequest, "Invalid Id")
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	org, err := h.store.GetOrganization(user, intID)
+	if err != nil {
+		log.Warningf("Failed to Get Organization: %s", err.Error())
+		if err.Error() == "record not found" {
+			RespondError(w, http.StatusNotFound, "Organization not found")
+			return
+		}
+		RespondError(w, http.StatusUnprocessableEntity, "Failed to Get Organization")
+		return
+	}
+
+	data := map[string]interface{}{
+		"organization": org,
+	}
+
+	res := Response{Status: "success", Message: "Fetched organization successfully", Data: data}
+	RespondJSON(w, http.StatusOK, &res)
+}
+
+// SwitchOrganization ends the current session and signs the user in to
+// another of their organizations
+func (h *Handler) SwitchOrganization(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	intID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		log.Warning("Failed to parse organization ID")
+		RespondError(w, http.StatusBadRequest, "Invalid Id")
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	orgID, err := h.activeOrg(user.ID, uint(intID))
+	if err != nil {
+		if err == ErrNotOrgMember {
+			RespondError(w, http.StatusNotFound, "Organization not found")
+			return
+		}
+		log.Warningf("Switch organization error: %s", err.Error())
+		RespondError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	tokens, err := h.tk.CreateToken(user.ID, orgID)
+	if err != nil {
+		RespondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := h.au.CreateAuth(user.ID, tokens); err != nil {
+		RespondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	metadata, err := h.tk.GetTokenMetadata(r)
+	if err == nil {
+		if err := h.au.DeleteTokens(metadata); err != nil {
+			log.Warningf("Failed to end previous session: %s", err.Error())
+		}
+	}
+
+	data := map[string]interface{}{
+		"access_token":  tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+	}
+
+	res := Response{Status: "success", Message: "Switched organization successfully", Data: data}
+	RespondJSON(w, http.StatusOK, &res)
+}
+
+func (h *Handler) AddOrgMember(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	intID, intIDUser, ok := memberIDs(w, req)
+	if !ok {
+		return
+	}
+
+	var body models.AddOrgMember
+	err = json.NewDecoder(r.Body).Decode(&body)
+	if err != nil && err != io.EOF {
+		RespondError(w, http.StatusBadRequest, "Invalid JSON provided")
+		return
+	}
+
+	validate := validator.New()
+	err = validate.Struct(body)
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	role := body.Role
+	if role == "" {
+		role = models.RoleMember
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	if !h.authorizeOrgOwner(w, user, intID, false) {
+		return
+	}
+
+	member, err := h.store.AddOrgMember(intID, intIDUser, role)
+	if err != nil {
+		log.Warningf("Add organization member error: %s", err.Error())
+		if err == database.ErrLastOrgOwner || err == database.ErrUsernameTaken {
+			RespondError(w, http.StatusConflict, err.Error())
+			return
+		}
+		if err.Error() == "record not found" {
+			RespondError(w, http.StatusNotFound, "User not found")
+			return
+		}
+		RespondError(w, http.StatusBadRequest, "Failed to add member to organization")
+		return
+	}
+
+	data := map[string]interface{}{
+		"member": member,
+	}
+
+	res := Response{Status: "success", Message: "Added member to organization successfully", Data: data}
+	RespondJSON(w, http.StatusOK, &res)
+}
+
+func (h *Handler) RemoveOrgMember(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	intID, intIDUser, ok := memberIDs(w, req)
+	if !ok {
+		return
+	}
+
+	// members may always leave an organization, only owners remove others
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	if !h.authorizeOrgOwner(w, user, intID, uint(intIDUser) == user.ID) {
+		return
+	}
+
+	err = h.store.RemoveOrgMember(intID, intIDUser)
+	if err != nil {
+		log.Warningf("Remove organization member error: %s", err.Error())
+		if err == database.ErrLastOrgOwner {
+			RespondError(w, http.StatusConflict, err.Error())
+			return
+		}
+		if err.Error() == "record not found" {
+			RespondError(w, http.StatusNotFound, "Member not found")
+			return
+		}
+		RespondError(w, http.StatusBadRequest, "Failed to remove member from organization")
+		return
+	}
+
+	res := Response{Status: "success", Message: "Removed member from organization successfully"}
+	RespondJSON(w, http.StatusOK, &res)
+}
+
+// authorizeOrgOwner writes a 404 or 403 and returns false unless user owns
+// the organization, or merely belongs to it when anyMember is set
+func (h *Handler) authorizeOrgOwner(w http.ResponseWriter, user *models.User, idOrg int, anyMember bool) bool {
+	role, err := h.store.GetOrgRole(user.ID, uint(idOrg))
+	if err != nil {
+		if err.Error() == "record not found" {
+			RespondError(w, http.StatusNotFound, "Organization not found")
+			return false
+		}
+		log.Warningf("Failed to get organization role: %s", err.Error())
+		RespondError(w, http.StatusInternalServerError, "Something went wrong")
+		return false
+	}
+
+	if !anyMember && role != models.RoleOwner {
+		RespondError(w, http.StatusForbidden, "Forbidden: requires owner role on organization")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestJSONPatchStatus(t *testing.T) {
+	f := newFixture(t)
+	path := fmt.Sprintf("/tasks/%d", f.task)
+
+	tests := []struct {
+		name  string
+		patch string
+		want  int
+	}{
+		{"failed test", `[{"op":"test","path":"/title","value":"other"},{"op":"replace","path":"/title","value":"renamed"}]`, http.StatusConflict},
+		{"invalid path", `[{"op":"remove","path":"/title/0"}]`, http.StatusBadRequest},
+		{"required field", `[{"op":"remove","path":"/description"}]`, http.StatusBadRequest},
+		{"not a patch", `{"op":"remove"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		w := f.do("alice", http.MethodPatch, path, bytes.NewBufferString(tt.patch), "application/json-patch+json")
+		if w.Code != tt.want {
+			t.Errorf("%s: got %d, want %d: %s", tt.name, w.Code, tt.want, w.Body)
+		}
+	}
+
+	// none of them changed the task
+	if w := f.doJSON("alice", http.MethodGet, path, ""); !bytes.Contains(w.Body.Bytes(), []byte(`"title":"task"`)) {
+		t.Errorf("task changed: %s", w.Body)
+	}
+	w := f.do("alice", http.MethodPatch, path, bytes.NewBufferString(`[{"op":"test","path":"/title","value":"task"},{"op":"replace","path":"/title","value":"renamed"}]`), "application/json-patch+json")
+	if w.Code != http.StatusOK {
+		t.Errorf("passing test: got %d: %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,404 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"todo-app/database"
+	"todo-app/models"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+func (h *Handler) CreateProject(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var p models.Project
+	err = json.NewDecoder(r.Body).Decode(&p)
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid JSON provided")
+		return
+	}
+
+	validate := validator.New()
+	err = validate.Struct(p)
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	project, err := h.store.CreateProject(user, p)
+	if err != nil {
+		log.Warningf("Create project error: %s", err.Error())
+		RespondError(w, http.StatusBadRequest, "Failed to create project")
+		return
+	}
+
+	data := map[string]interface{}{
+		"project": project,
+	}
+
+	res := Response{Status: "success", Message: "Project created successfully", Data: data}
+	RespondJSON(w, http.StatusCreated, &res)
+}
+
+func (h *Handler) GetProjects(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	projects, err := h.store.GetProjects(user)
+	if err != nil {
+		log.Warningf("Failed to fetch projects: %s", err.Error())
+		RespondError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	data := map[string]interface{}{
+		"projects": projects,
+	}
+
+	res := Response{Status: "success", Message: "Fetched projects successfully", Data: data}
+	RespondJSON(w, http.StatusOK, &res)
+}
+
+func (h *Handler) GetProject(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	intID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		log.Warning("Failed to parse project ID")
+		RespondError(w, http.StatusBadRequest, "Invalid Id")
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	project, err := h.store.GetProject(user, intID)
+	if err != nil {
+		log.Warningf("Failed to Get Project: %s", err.Error())
+		if err.Error() == "record not found" {
+			RespondError(w, http.StatusNotFound, "Project not found")
+			return
+		}
+		RespondError(w, http.StatusUnprocessableEntity, "Failed to Get Project")
+		return
+	}
+
+	data := map[string]interface{}{
+		"project": project,
+	}
+
+	res := Response{Status: "success", Message: "Fetched project successfully", Data: data}
+	RespondJSON(w, http.StatusOK, &res)
+}
+
+func (h *Handler) UpdateProject(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var p models.UpdateProject
+	err = json.NewDecoder(r.Body).Decode(&p)
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid JSON provided")
+		return
+	}
+
+	validate := validator.New()
+	err = validate.Struct(p)
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	intID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		log.Warning("Failed to parse project ID")
+		RespondError(w, http.StatusBadRequest, "Invalid Id")
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	if !h.authorizeProject(w, user, intID, models.RoleEditor) {
+		return
+	}
+
+	project, err := h.store.UpdateProject(user, intID, p)
+	if err != nil {
+		log.Warningf("Update project error: %s", err.Error())
+		if err.Error() == "record not found" {
+			RespondError(w, http.StatusNotFound, "Project not found")
+			return
+		}
+		RespondError(w, http.StatusBadRequest, "Failed to update project")
+		return
+	}
+
+	data := map[string]interface{}{
+		"project": project,
+	}
+
+	res := Response{Status: "success", Message: "Updated project successfully", Data: data}
+	RespondJSON(w, http.StatusOK, &res)
+}
+
+func (h *Handler) DeleteProject(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	intID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		log.Warning("Failed to parse project ID")
+		RespondError(w, http.StatusBadRequest, "Invalid Id")
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	if !h.authorizeProject(w, user, intID, models.RoleOwner) {
+		return
+	}
+
+	_, err = h.store.DeleteProject(user, intID)
+	if err != nil {
+		log.Warningf("Delete project error: %s", err.Error())
+		if err.Error() == "record not found" {
+			RespondError(w, http.StatusNotFound, "Project not found")
+			return
+		}
+		RespondError(w, http.StatusBadRequest, "Failed to delete project")
+		return
+	}
+
+	res := Response{Status: "success", Message: "Deleted project successfully"}
+	RespondJSON(w, http.StatusOK, &res)
+}
+
+func (h *Handler) GetProjectTasks(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	intID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		log.Warning("Failed to parse project ID")
+		RespondError(w, http.StatusBadRequest, "Invalid Id")
+		return
+	}
+
+	filter, err := parseTaskFilter(r.URL.Query())
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	idProject := uint(intID)
+	filter.ProjectID = &idProject
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	if !h.authorizeProject(w, user, intID, models.RoleViewer) {
+		return
+	}
+
+	page, err := h.store.GetTasks(user, filter)
+	if err != nil {
+		log.Warning("Failed to fetch tasks")
+		RespondError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	data := map[string]interface{}{
+		"tasks": page.Tasks,
+	}
+
+	res := Response{Status: "success", Message: "Fetched tasks successfully", Data: data, NextCursor: page.Next, PrevCursor: page.Prev}
+	RespondJSON(w, http.StatusOK, &res)
+}
+
+func (h *Handler) CreateProjectTask(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	intID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		log.Warning("Failed to parse project ID")
+		RespondError(w, http.StatusBadRequest, "Invalid Id")
+		return
+	}
+
+	t, err := readNewTask(r)
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	idProject := uint(intID)
+	t.ProjectID = &idProject
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	if !h.authorizeProject(w, user, intID, models.RoleEditor) {
+		return
+	}
+
+	task, err := h.store.CreateTask(user, t)
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	data := map[string]interface{}{
+		"task": task,
+	}
+
+	res := Response{Status: "success", Message: "Task Created sucessfully", Data: data}
+	RespondJSON(w, http.StatusCreated, &res)
+}
+
+func (h *Handler) AddProjectMember(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	intID, intIDUser, ok := memberIDs(w, req)
+	if !ok {
+		return
+	}
+
+	var body models.AddProjectMember
+	err = json.NewDecoder(r.Body).Decode(&body)
+	if err != nil && err != io.EOF {
+		RespondError(w, http.StatusBadRequest, "Invalid JSON provided")
+		return
+	}
+
+	validate := validator.New()
+	err = validate.Struct(body)
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	role := body.Role
+	if role == "" {
+		role = models.RoleEditor
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	if !h.authorizeProject(w, user, intID, models.RoleOwner) {
+		return
+	}
+
+	member, err := h.store.AddProjectMember(intID, intIDUser, role)
+	if err != nil {
+		log.Warningf("Add project member error: %s", err.Error())
+		if err == database.ErrLastProjectOwner {
+			RespondError(w, http.StatusConflict, err.Error())
+			return
+		}
+		if err.Error() == "record not found" {
+			RespondError(w, http.StatusNotFound, "User not found")
+			return
+		}
+		RespondError(w, http.StatusBadRequest, "Failed to add member to project")
+		return
+	}
+
+	data := map[string]interface{}{
+		"member": member,
+	}
+
+	res := Response{Status: "success", Message: "Added member to project successfully", Data: data}
+	RespondJSON(w, http.StatusOK, &res)
+}
+
+func (h *Handler) RemoveProjectMember(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	intID, intIDUser, ok := memberIDs(w, req)
+	if !ok {
+		return
+	}
+
+	// members may always leave a project, only owners remove others
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	minRole := models.RoleOwner
+	if uint(intIDUser) == user.ID {
+		minRole = models.RoleViewer
+	}
+	if !h.authorizeProject(w, user, intID, minRole) {
+		return
+	}
+
+	err = h.store.RemoveProjectMember(intID, intIDUser)
+	if err != nil {
+		log.Warningf("Remove project member error: %s", err.Error())
+		if err == database.ErrLastProjectOwner {
+			RespondError(w, http.StatusConflict, err.Error())
+			return
+		}
+		if err.Error() == "record not found" {
+			RespondError(w, http.StatusNotFound, "Member not found")
+			return
+		}
+		RespondError(w, http.StatusBadRequest, "Failed to remove member from project")
+		return
+	}
+
+	res := Response{Status: "success", Message: "Removed member from project successfully"}
+	RespondJSON(w, http.StatusOK, &res)
+}
+
+// memberIDs reads the {id}/members/{idUser} route variables
+func memberIDs(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	vars := mux.Vars(r)
+	intID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		log.Warning("Failed to parse ID")
+		RespondError(w, http.StatusBadRequest, "Invalid Id")
+		return 0, 0, false
+	}
+	intIDUser, err := strconv.Atoi(vars["idUser"])
+	if err != nil {
+		log.Warning("Failed to parse user ID")
+		RespondError(w, http.StatusBadRequest, "Invalid User Id")
+		return 0, 0, false
+	}
+	return intID, intIDUser, true
+}
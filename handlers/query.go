@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"todo-app/models"
+)
+
+// parseTaskFilter reads the GET /tasks query parameters
+func parseTaskFilter(v url.Values) (models.TaskFilter, error) {
+	var filter models.TaskFilter
+
+	loc := time.UTC
+	if tz := v.Get("tz"); tz != "" {
+		var err error
+		if loc, err = time.LoadLocation(tz); err != nil {
+			return filter, fmt.Errorf("invalid tz %q", tz)
+		}
+	}
+
+	if completed := v.Get("completed"); completed != "" {
+		b, err := strconv.ParseBool(completed)
+		if err != nil {
+			return filter, fmt.Errorf("invalid completed %q", completed)
+		}
+		filter.Completed = &b
+	}
+
+	if priority := v.Get("priority"); priority != "" {
+		if priority != "1" && priority != "2" && priority != "3" {
+			return filter, fmt.Errorf("invalid priority %q", priority)
+		}
+		filter.Priority = priority
+	}
+
+	for name, dst := range map[string]**time.Time{"due_before": &filter.DueBefore, "due_after": &filter.DueAfter} {
+		value := v.Get(name)
+		if value == "" {
+			continue
+		}
+		ts, err := models.ParseTime(value, loc)
+		if err != nil {
+			return filter, fmt.Errorf("invalid %s %q, use RFC 3339 or YYYY-MM-DD", name, value)
+		}
+		*dst = &ts
+	}
+
+	if overdue := v.Get("overdue"); overdue != "" {
+		b, err := strconv.ParseBool(overdue)
+		if err != nil {
+			return filter, fmt.Errorf("invalid overdue %q", overdue)
+		}
+		filter.Overdue = &b
+	}
+
+	filter.Labels = v["label"]
+	switch match := v.Get("label_match"); match {
+	case "", "any":
+	case "all":
+		filter.AllLabels = true
+	default:
+		return filter, fmt.Errorf("invalid label_match %q", match)
+	}
+
+	if archived := v.Get("include_archived"); archived != "" {
+		b, err := strconv.ParseBool(archived)
+		if err != nil {
+			return filter, fmt.Errorf("invalid include_archived %q", archived)
+		}
+		filter.IncludeArchived = b
+	}
+
+	if expr := v.Get("filter"); expr != "" {
+		var err error
+		if filter.Expr, err = models.ParseFilter(expr, loc); err != nil {
+			return filter, err
+		}
+	}
+
+	sort := v.Get("sort")
+	var err error
+	if filter.Sort, err = models.ParseSort(sort); err != nil {
+		return filter, fmt.Errorf("invalid sort %q: %s, sort by %s", sort, err, strings.Join(models.SortFields, ", "))
+	}
+
+	// without paging parameters every task is returned
+	if v.Get("limit") != "" || v.Get("cursor") != "" {
+		if filter.Limit, err = parseLimit(v); err != nil {
+			return filter, err
+		}
+	}
+
+	if cursor := v.Get("cursor"); cursor != "" {
+		if filter.Cursor, err = models.ParseCursor(cursor); err != nil {
+			return filter, err
+		}
+		// the position means nothing in another order
+		if filter.Cursor.Sort != models.FormatSort(filter.Sort) {
+			return filter, fmt.Errorf("cursor was made for sort %q", filter.Cursor.Sort)
+		}
+	}
+
+	return filter, nil
+}
+
+// parseActivityFilter reads the GET /activity query parameters
+func parseActivityFilter(v url.Values) (models.ActivityFilter, error) {
+	var filter models.ActivityFilter
+
+	if before := v.Get("before"); before != "" {
+		id, err := strconv.ParseUint(before, 10, 32)
+		if err != nil || id == 0 {
+			return filter, fmt.Errorf("invalid before %q", before)
+		}
+		filter.Before = uint(id)
+	}
+
+	var err error
+	filter.Limit, err = parseLimit(v)
+	return filter, err
+}
+
+// parseTaskSearch reads the GET /tasks/search query parameters
+func parseTaskSearch(v url.Values) (models.TaskSearch, error) {
+	search := models.TaskSearch{Query: v.Get("q")}
+	if search.Terms = models.SearchTerms(search.Query); len(search.Terms) == 0 {
+		return search, errors.New("q must contain a word to search for")
+	}
+
+	if archived := v.Get("include_archived"); archived != "" {
+		b, err := strconv.ParseBool(archived)
+		if err != nil {
+			return search, fmt.Errorf("invalid include_archived %q", archived)
+		}
+		search.IncludeArchived = b
+	}
+
+	var err error
+	search.Limit, err = parseLimit(v)
+	return search, err
+}
+
+// parseLimit reads the page size shared by the list endpoints
+func parseLimit(v url.Values) (int, error) {
+	limit := v.Get("limit")
+	if limit == "" {
+		return 50, nil
+	}
+	n, err := strconv.Atoi(limit)
+	if err != nil || n < 1 || n > 200 {
+		return 0, fmt.Errorf("invalid limit %q, use 1 to 200", limit)
+	}
+	return n, nil
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestGetTasksQuery(t *testing.T) {
+	f := newFixture(t)
+	for _, tt := range []struct {
+		query string
+		want  int
+	}{
+		{"due_before=2026-03-01&tz=Europe/Paris", http.StatusOK},
+		{"due_after=2026-03-01T09:00:00Z&overdue=false", http.StatusOK},
+		{"tz=Mars/Olympus_Mons", http.StatusBadRequest},
+		{"due_before=2026-03-01&tz=Mars/Olympus_Mons", http.StatusBadRequest},
+		{"due_before=tomorrow", http.StatusBadRequest},
+		{"due_after=2026-02-30", http.StatusBadRequest},
+		{"due_after=01/03/2026", http.StatusBadRequest},
+		{"overdue=maybe", http.StatusBadRequest},
+	} {
+		if w := f.doJSON("alice", http.MethodGet, "/tasks?"+tt.query, ""); w.Code != tt.want {
+			t.Errorf("GET /tasks?%s: got %d, want %d: %s", tt.query, w.Code, tt.want, w.Body)
+		}
+	}
+}
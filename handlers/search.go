@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+	"todo-app/models"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func (h *Handler) SearchTasks(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	search, err := parseTaskSearch(r.URL.Query())
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	results, err := h.store.SearchTasks(user, search)
+	if err != nil {
+		log.Warningf("Failed to search tasks: %s", err.Error())
+		RespondError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	data := map[string]interface{}{
+		"results": results,
+	}
+
+	res := Response{Status: "success", Message: "Searched tasks successfully", Data: data}
+	RespondJSON(w, http.StatusOK, &res)
+}
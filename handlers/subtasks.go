@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"todo-app/models"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+func (h *Handler) CreateSubtask(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	vars := mux.Vars(req)
+	intID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		log.Warning("Failed to parse task ID")
+		RespondError(w, http.StatusBadRequest, "Invalid Id")
+		return
+	}
+
+	t, err := readNewTask(r)
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	if !h.authorizeTask(w, user, intID, models.RoleEditor) {
+		return
+	}
+
+	task, err := h.store.CreateSubtask(user, intID, t)
+	if err != nil {
+		log.Warningf("Create subtask error: %s", err.Error())
+		if err.Error() == "record not found" {
+			RespondError(w, http.StatusNotFound, "Task not found")
+			return
+		}
+		RespondError(w, http.StatusBadRequest, "Failed to create subtask")
+		return
+	}
+
+	data := map[string]interface{}{
+		"task": task,
+	}
+
+	res := Response{Status: "success", Message: "Subtask created successfully", Data: data}
+	RespondJSON(w, http.StatusCreated, &res)
+}
+
+func (h *Handler) GetTaskTree(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	vars := mux.Vars(req)
+	intID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		log.Warning("Failed to parse task ID")
+		RespondError(w, http.StatusBadRequest, "Invalid Id")
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	task, err := h.store.GetTaskTree(user, intID)
+	if err != nil {
+		log.Warningf("Failed to Get Task tree: %s", err.Error())
+		if err.Error() == "record not found" {
+			RespondError(w, http.StatusNotFound, "Task not found")
+			return
+		}
+		RespondError(w, http.StatusUnprocessableEntity, "Failed to Get Task tree")
+		return
+	}
+
+	data := map[string]interface{}{
+		"task": task,
+	}
+
+	res := Response{Status: "success", Message: "Fetched task tree successfully", Data: data}
+	RespondJSON(w, http.StatusOK, &res)
+}
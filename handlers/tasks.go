@@ -2,9 +2,14 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
 	"strconv"
+	"time"
+	"todo-app/database"
 	"todo-app/models"
 	"todo-app/ws"
 
@@ -14,7 +19,6 @@ import (
 )
 
 func (h *Handler) CreateTask(w http.ResponseWriter, r *http.Request) {
-	var t models.Task
 	req, err := h.CheckAuth(r)
 	if err != nil {
 		log.Warningf("auth error: %s", err.Error())
@@ -22,14 +26,7 @@ func (h *Handler) CreateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = json.NewDecoder(r.Body).Decode(&t)
-	if err != nil {
-		RespondError(w, http.StatusBadRequest, "Invalid JSON provided")
-		return
-	}
-
-	validate := validator.New()
-	err = validate.Struct(t)
+	t, err := readNewTask(r)
 	if err != nil {
 		RespondError(w, http.StatusBadRequest, err.Error())
 		return
@@ -49,10 +46,44 @@ func (h *Handler) CreateTask(w http.ResponseWriter, r *http.Request) {
 		"task": task,
 	}
 
-	res := Response{"success", "Task Created sucessfully", data}
+	res := Response{Status: "success", Message: "Task Created sucessfully", Data: data}
 	RespondJSON(w, http.StatusOK, &res)
 }
 
+// readNewTask decodes and validates the body of a task creation request
+func readNewTask(r *http.Request) (models.Task, error) {
+	var t models.Task
+	err := json.NewDecoder(r.Body).Decode(&t)
+	if err != nil {
+		return t, errors.New("Invalid JSON provided")
+	}
+	return t, checkNewTask(&t)
+}
+
+// checkNewTask validates a task to be created and clears what only the store
+// sets
+func checkNewTask(t *models.Task) error {
+	validate := validator.New()
+	err := validate.Struct(t)
+	if err != nil {
+		return err
+	}
+
+	// fields that only the store sets
+	t.ParentID, t.ProjectID, t.SeriesID, t.Occurrence, t.OrgID = nil, nil, nil, 0, 0
+	t.CompletedAt, t.ArchivedAt, t.Version = nil, nil, 0
+	if t.Completed {
+		now := time.Now().UTC()
+		t.CompletedAt = &now
+	}
+
+	t.StartAt, t.DueAt = models.UTC(t.StartAt), models.UTC(t.DueAt)
+	if err := t.CheckSchedule(); err != nil {
+		return err
+	}
+	return t.CheckRecurrence()
+}
+
 func (h *Handler) GetTasks(w http.ResponseWriter, r *http.Request) {
 	req, err := h.CheckAuth(r)
 	if err != nil {
@@ -61,32 +92,24 @@ func (h *Handler) GetTasks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	v := r.URL.Query()
-	completed := v.Get("completed")
-	priority := v.Get("priority")
-
-	params := make(map[string]interface{})
-
-	if completed != "" {
-		params["completed"] = completed
-	}
-
-	if priority != "" {
-		params["priority"] = priority
+	filter, err := parseTaskFilter(r.URL.Query())
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
 	user := req.Context().Value(KeyUser{}).(*models.User)
-	tasks, err := h.store.GetTasks(user, params)
+	page, err := h.store.GetTasks(user, filter)
 	if err != nil {
 		log.Warning("Failed to fetch tasks")
 		RespondError(w, http.StatusInternalServerError, "Something went wrong")
 		return
 	}
 	data := map[string]interface{}{
-		"tasks": tasks,
+		"tasks": page.Tasks,
 	}
 
-	res := Response{"success", "Fetched tasks successfully", data}
+	res := Response{Status: "success", Message: "Fetched tasks successfully", Data: data, NextCursor: page.Next, PrevCursor: page.Prev}
 	RespondJSON(w, http.StatusOK, &res)
 }
 
@@ -119,11 +142,18 @@ func (h *Handler) GetTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	etag := taskETag(task)
+	w.Header().Set("ETag", etag)
+	if notModified(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
 	data := map[string]interface{}{
 		"task": task,
 	}
 
-	res := Response{"success", "Fetched task successfully", data}
+	res := Response{Status: "success", Message: "Fetched task successfully", Data: data}
 	RespondJSON(w, http.StatusOK, &res)
 }
 
@@ -152,8 +182,35 @@ func (h *Handler) AddUserToTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, task, err := h.store.AddUserToTask(&models.User{}, models.Task{}, intIDUser, intIDTask)
+	var body models.AddUserToTask
+	err = json.NewDecoder(r.Body).Decode(&body)
+	if err != nil && err != io.EOF {
+		RespondError(w, http.StatusBadRequest, "Invalid JSON provided")
+		return
+	}
+
+	validate := validator.New()
+	err = validate.Struct(body)
 	if err != nil {
+		RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	role := body.Role
+	if role == "" {
+		role = models.RoleEditor
+	}
+
+	if !h.authorizeTask(w, authUser, intIDTask, models.RoleOwner) {
+		return
+	}
+
+	user, task, err := h.store.AddUserToTask(authUser, intIDUser, intIDTask, role)
+	if err != nil {
+		if err == database.ErrLastOwner {
+			RespondError(w, http.StatusConflict, err.Error())
+			return
+		}
 		if err.Error() == "record not found" {
 			RespondError(w, http.StatusNotFound, "Task not found")
 			return
@@ -181,7 +238,7 @@ func (h *Handler) AddUserToTask(w http.ResponseWriter, r *http.Request) {
 		"user": user,
 	}
 
-	res := Response{"success", "Added user to task successfully", data}
+	res := Response{Status: "success", Message: "Added user to task successfully", Data: data}
 	RespondJSON(w, http.StatusOK, &res)
 }
 
@@ -205,9 +262,22 @@ func (h *Handler) RemoveUserFromTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, task, err := h.store.RemoveUserFromTask(&models.User{}, models.Task{}, intIDUser, intIDTask)
+	// members may always leave a task, only owners remove others
+	minRole := models.RoleOwner
+	if uint(intIDUser) == authUser.ID {
+		minRole = models.RoleViewer
+	}
+	if !h.authorizeTask(w, authUser, intIDTask, minRole) {
+		return
+	}
+
+	user, task, err := h.store.RemoveUserFromTask(authUser, intIDUser, intIDTask)
 
 	if err != nil {
+		if err == database.ErrLastOwner {
+			RespondError(w, http.StatusConflict, err.Error())
+			return
+		}
 		log.Warning(err.Error())
 		RespondError(w, http.StatusBadRequest, "Failed to remove user from task")
 		return
@@ -232,7 +302,7 @@ func (h *Handler) RemoveUserFromTask(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
-	res := Response{"success", "Removed user from task successfully", nil}
+	res := Response{Status: "success", Message: "Removed user from task successfully"}
 	RespondJSON(w, http.StatusOK, &res)
 }
 
@@ -244,17 +314,9 @@ func (h *Handler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var t models.UpdateTask
-	err = json.NewDecoder(r.Body).Decode(&t)
+	t, status, err := readTaskPatch(r)
 	if err != nil {
-		RespondError(w, http.StatusBadRequest, "Invalid JSON provided")
-		return
-	}
-
-	validate := validator.New()
-	err = validate.Struct(t)
-	if err != nil {
-		RespondError(w, http.StatusBadRequest, err.Error())
+		RespondError(w, status, err.Error())
 		return
 	}
 
@@ -268,6 +330,15 @@ func (h *Handler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 	}
 
 	user := req.Context().Value(KeyUser{}).(*models.User)
+	if !h.authorizeTask(w, user, intID, models.RoleEditor) {
+		return
+	}
+
+	var ok bool
+	if t.IfMatch, ok = h.ifMatch(w, r, user, intID); !ok {
+		return
+	}
+
 	task, err := h.store.UpdateTask(user, t, intID)
 	if err != nil {
 		log.Warningf("Update task error: %s", err.Error())
@@ -275,6 +346,22 @@ func (h *Handler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 			RespondError(w, http.StatusNotFound, "Task not found")
 			return
 		}
+		if _, ok := err.(*models.PatchError); ok || err == models.ErrInvalidSchedule || err == models.ErrRecurrenceNeedsDue {
+			RespondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if _, ok := err.(*database.RoleError); ok {
+			RespondError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		if err == models.ErrPatchTest || err == database.ErrBlocked {
+			RespondError(w, http.StatusConflict, err.Error())
+			return
+		}
+		if err == database.ErrVersionMismatch {
+			RespondError(w, http.StatusPreconditionFailed, err.Error())
+			return
+		}
 		RespondError(w, http.StatusBadRequest, "Failed to update task")
 		return
 	}
@@ -298,14 +385,57 @@ func (h *Handler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
+	// the ETag hashes the task as GET sends it, with what UpdateTask leaves out
+	if full, err := h.store.GetTask(user, intID); err == nil {
+		task = full
+		w.Header().Set("ETag", taskETag(task))
+	} else {
+		log.Warningf("Failed to Get Task: %s", err.Error())
+	}
+
 	data := map[string]interface{}{
 		"task": task,
 	}
 
-	res := Response{"success", "Updated task successfully", data}
+	res := Response{Status: "success", Message: "Updated task successfully", Data: data}
 	RespondJSON(w, http.StatusOK, &res)
 }
 
+// readTaskPatch decodes a JSON Patch or a merge patch, which is assumed for
+// plain JSON. Completing a blocked task takes force=true in the query, or in
+// a merge patch.
+func readTaskPatch(r *http.Request) (models.UpdateTask, int, error) {
+	var t models.UpdateTask
+	if force := r.URL.Query().Get("force"); force != "" {
+		b, err := strconv.ParseBool(force)
+		if err != nil {
+			return t, http.StatusBadRequest, fmt.Errorf("invalid force %q", force)
+		}
+		t.Force = b
+	}
+
+	// other endpoints don't look at the Content-Type either, so anything that
+	// isn't a JSON Patch is read as a merge patch
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/json-patch+json" {
+		if err := json.NewDecoder(r.Body).Decode(&t.Ops); err != nil || t.Ops == nil {
+			return t, http.StatusBadRequest, errors.New("Invalid JSON Patch provided")
+		}
+		return t, 0, nil
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&t.Merge); err != nil || t.Merge == nil {
+		return t, http.StatusBadRequest, errors.New("Invalid JSON provided")
+	}
+	if force, ok := t.Merge["force"]; ok {
+		if err := json.Unmarshal(force, &t.Force); err != nil {
+			return t, http.StatusBadRequest, errors.New("Invalid force")
+		}
+		delete(t.Merge, "force")
+	}
+	return t, 0, nil
+}
+
 func (h *Handler) DeleteTask(w http.ResponseWriter, r *http.Request) {
 	req, err := h.CheckAuth(r)
 	if err != nil {
@@ -324,7 +454,16 @@ func (h *Handler) DeleteTask(w http.ResponseWriter, r *http.Request) {
 	}
 
 	user := req.Context().Value(KeyUser{}).(*models.User)
-	task, err := h.store.DeleteTask(user, intID)
+	if !h.authorizeTask(w, user, intID, models.RoleOwner) {
+		return
+	}
+
+	ifMatch, ok := h.ifMatch(w, r, user, intID)
+	if !ok {
+		return
+	}
+
+	task, err := h.store.DeleteTask(user, intID, ifMatch)
 
 	if err != nil {
 		log.Warningf("Delete task error: %s", err.Error())
@@ -332,6 +471,14 @@ func (h *Handler) DeleteTask(w http.ResponseWriter, r *http.Request) {
 			RespondError(w, http.StatusNotFound, "Task not found")
 			return
 		}
+		if _, ok := err.(*database.RoleError); ok {
+			RespondError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		if err == database.ErrVersionMismatch {
+			RespondError(w, http.StatusPreconditionFailed, err.Error())
+			return
+		}
 		RespondError(w, http.StatusBadRequest, "Failed to delete task")
 		return
 	}
@@ -355,6 +502,6 @@ func (h *Handler) DeleteTask(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
-	res := Response{"success", "Deleted task successfully", nil}
+	res := Response{Status: "success", Message: "Deleted task successfully"}
 	RespondJSON(w, http.StatusOK, &res)
 }
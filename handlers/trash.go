@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+	"todo-app/database"
+	"todo-app/models"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+func (h *Handler) GetTrash(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	tasks, err := h.store.GetTrash(user)
+	if err != nil {
+		log.Warningf("Failed to fetch trash: %s", err.Error())
+		RespondError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	data := map[string]interface{}{
+		"tasks": tasks,
+	}
+
+	res := Response{Status: "success", Message: "Fetched trash successfully", Data: data}
+	RespondJSON(w, http.StatusOK, &res)
+}
+
+func (h *Handler) RestoreTask(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	intID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		log.Warning("Failed to parse task ID")
+		RespondError(w, http.StatusBadRequest, "Invalid Id")
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	if !h.authorizeTrashedTask(w, user, intID) {
+		return
+	}
+
+	task, err := h.store.RestoreTask(user, intID)
+	if err != nil {
+		log.Warningf("Restore task error: %s", err.Error())
+		if err == database.ErrParentDeleted {
+			RespondError(w, http.StatusConflict, err.Error())
+			return
+		}
+		if err.Error() == "record not found" {
+			RespondError(w, http.StatusNotFound, "Task not found in trash")
+			return
+		}
+		RespondError(w, http.StatusBadRequest, "Failed to restore task")
+		return
+	}
+
+	sendEvent(message{
+		Username: user.Username,
+		Action:   "Restore",
+		Message:  fmt.Sprintf("%s restored task: %s", user.Username, task.Title),
+		Task:     task.ID,
+	})
+
+	data := map[string]interface{}{
+		"task": task,
+	}
+
+	res := Response{Status: "success", Message: "Restored task successfully", Data: data}
+	RespondJSON(w, http.StatusOK, &res)
+}
+
+// PurgeTask deletes a task in the trash for good
+func (h *Handler) PurgeTask(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	intID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		log.Warning("Failed to parse task ID")
+		RespondError(w, http.StatusBadRequest, "Invalid Id")
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	if !h.authorizeTrashedTask(w, user, intID) {
+		return
+	}
+
+	attachments, err := h.store.PurgeTask(user, intID)
+	if err != nil {
+		log.Warningf("Purge task error: %s", err.Error())
+		if err.Error() == "record not found" {
+			RespondError(w, http.StatusNotFound, "Task not found in trash")
+			return
+		}
+		RespondError(w, http.StatusBadRequest, "Failed to delete task")
+		return
+	}
+	for _, a := range attachments {
+		h.deleteBlob(a.Key)
+	}
+
+	res := Response{Status: "success", Message: "Deleted task permanently"}
+	RespondJSON(w, http.StatusOK, &res)
+}
+
+// authorizeTrashedTask lets owners of a task in the trash restore or purge
+// it, like deleting it did
+func (h *Handler) authorizeTrashedTask(w http.ResponseWriter, user *models.User, idTask int) bool {
+	role, err := h.store.GetTrashedTaskRole(user, idTask)
+	if err != nil {
+		if err.Error() == "record not found" {
+			RespondError(w, http.StatusNotFound, "Task not found in trash")
+			return false
+		}
+		log.Warningf("Failed to get task role: %s", err.Error())
+		RespondError(w, http.StatusInternalServerError, "Something went wrong")
+		return false
+	}
+
+	if !role.AtLeast(models.RoleOwner) {
+		RespondError(w, http.StatusForbidden, fmt.Sprintf("Forbidden: requires %s role on task", models.RoleOwner))
+		return false
+	}
+	return true
+}
+
+// PurgeTrash deletes tasks that have been in the trash for longer than
+// retention, checking every interval until the process exits
+func (h *Handler) PurgeTrash(retention, interval time.Duration) {
+	for {
+		h.purgeTrash(time.Now().Add(-retention))
+		time.Sleep(interval)
+	}
+}
+
+// purgeTrash deletes the tasks deleted before cutoff along with the files of
+// their attachments
+func (h *Handler) purgeTrash(cutoff time.Time) {
+	attachments, err := h.store.PurgeTrash(cutoff)
+	if err != nil {
+		log.Warningf("Failed to purge trash: %s", err.Error())
+	}
+	for _, a := range attachments {
+		h.deleteBlob(a.Key)
+	}
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+	"todo-app/util/blob"
+)
+
+// wantBlob checks whether the fixture's attachment still has its file
+func wantBlob(t *testing.T, f *fixture, want bool) {
+	t.Helper()
+	r, err := f.blobs.Get("file")
+	if err == nil {
+		r.Close()
+	}
+	if want && err != nil {
+		t.Errorf("the attachment's file is gone: %v", err)
+	}
+	if !want && err != blob.ErrNotFound {
+		t.Errorf("the attachment's file: got %v, want %v", err, blob.ErrNotFound)
+	}
+}
+
+func TestPurgeTask(t *testing.T) {
+	f := newFixture(t)
+	path := fmt.Sprintf("/trash/%d", f.task)
+	if w := f.doJSON("alice", http.MethodDelete, fmt.Sprintf("/tasks/%d", f.task), ""); w.Code != http.StatusOK {
+		t.Fatalf("deleting the task: got %d: %s", w.Code, w.Body)
+	}
+
+	// only owners purge, like only owners delete
+	for _, r := range []struct {
+		user string
+		want int
+	}{{"bob", http.StatusForbidden}, {"carol", http.StatusForbidden}, {"dave", http.StatusNotFound}} {
+		if w := f.doJSON(r.user, http.MethodDelete, path, ""); w.Code != r.want {
+			t.Errorf("purging as %s: got %d, want %d", r.user, w.Code, r.want)
+		}
+	}
+	wantBlob(t, f, true)
+
+	if w := f.doJSON("alice", http.MethodDelete, path, ""); w.Code != http.StatusOK {
+		t.Fatalf("purging as the owner: got %d: %s", w.Code, w.Body)
+	}
+	wantBlob(t, f, false)
+	if w := f.doJSON("alice", http.MethodPost, fmt.Sprintf("/tasks/%d/restore", f.task), ""); w.Code != http.StatusNotFound {
+		t.Errorf("restoring a purged task: got %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestPurgeTrash(t *testing.T) {
+	f := newFixture(t)
+	if w := f.doJSON("alice", http.MethodDelete, fmt.Sprintf("/tasks/%d", f.task), ""); w.Code != http.StatusOK {
+		t.Fatalf("deleting the task: got %d: %s", w.Code, w.Body)
+	}
+
+	// tasks deleted within the retention stay
+	f.handler.purgeTrash(time.Now().Add(-time.Hour))
+	wantBlob(t, f, true)
+	if w := f.doJSON("alice", http.MethodGet, "/trash", ""); w.Code != http.StatusOK || !bytes.Contains(w.Body.Bytes(), []byte(`"title":"task"`)) {
+		t.Errorf("trash within the retention: got %d: %s", w.Code, w.Body)
+	}
+
+	f.handler.purgeTrash(time.Now().Add(time.Second))
+	wantBlob(t, f, false)
+	if w := f.doJSON("alice", http.MethodGet, "/trash", ""); w.Code != http.StatusOK || bytes.Contains(w.Body.Bytes(), []byte(`"title":"task"`)) {
+		t.Errorf("trash past the retention: got %d: %s", w.Code, w.Body)
+	}
+}
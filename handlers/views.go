@@ -0,0 +1,302 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"todo-app/database"
+	"todo-app/models"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+// viewQuery is the GET /tasks query a view stands for, with the paging
+// parameters taken from page
+func viewQuery(v *models.View, page url.Values) url.Values {
+	q := url.Values{}
+	for _, name := range []string{"tz", "limit", "cursor"} {
+		if value := page.Get(name); value != "" {
+			q.Set(name, value)
+		}
+	}
+	if v.Filter != "" {
+		q.Set("filter", v.Filter)
+	}
+	if v.Sort != "" {
+		q.Set("sort", v.Sort)
+	}
+	q.Set("include_archived", strconv.FormatBool(v.IncludeArchived))
+	return q
+}
+
+// checkView catches filters, sorts and groupings that GetViewTasks would fail on
+func checkView(v *models.View) error {
+	if _, err := parseTaskFilter(viewQuery(v, nil)); err != nil {
+		return err
+	}
+	return models.CheckGroupBy(v.GroupBy)
+}
+
+// viewID reads the view ID of the path
+func viewID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	intID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		log.Warning("Failed to parse view ID")
+		RespondError(w, http.StatusBadRequest, "Invalid Id")
+		return 0, false
+	}
+	return intID, true
+}
+
+// respondViewNotFound tells users a shared view isn't theirs to change
+func (h *Handler) respondViewNotFound(w http.ResponseWriter, user *models.User, id int) {
+	if _, err := h.store.GetView(user, id); err == nil {
+		RespondError(w, http.StatusForbidden, "Only the owner can change a view")
+		return
+	}
+	RespondError(w, http.StatusNotFound, "View not found")
+}
+
+func (h *Handler) CreateView(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var v models.View
+	err = json.NewDecoder(r.Body).Decode(&v)
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid JSON provided")
+		return
+	}
+
+	validate := validator.New()
+	err = validate.Struct(v)
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := checkView(&v); err != nil {
+		RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	view, err := h.store.CreateView(user, v)
+	if err != nil {
+		log.Warningf("Create view error: %s", err.Error())
+		if err == database.ErrViewExists {
+			RespondError(w, http.StatusConflict, err.Error())
+			return
+		}
+		RespondError(w, http.StatusBadRequest, "Failed to create view")
+		return
+	}
+
+	data := map[string]interface{}{
+		"view": view,
+	}
+
+	res := Response{Status: "success", Message: "View created successfully", Data: data}
+	RespondJSON(w, http.StatusCreated, &res)
+}
+
+func (h *Handler) GetViews(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	views, err := h.store.GetViews(user)
+	if err != nil {
+		log.Warningf("Failed to fetch views: %s", err.Error())
+		RespondError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	data := map[string]interface{}{
+		"views": views,
+	}
+
+	res := Response{Status: "success", Message: "Fetched views successfully", Data: data}
+	RespondJSON(w, http.StatusOK, &res)
+}
+
+func (h *Handler) GetView(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	intID, ok := viewID(w, req)
+	if !ok {
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	view, err := h.store.GetView(user, intID)
+	if err != nil {
+		if err.Error() == "record not found" {
+			RespondError(w, http.StatusNotFound, "View not found")
+			return
+		}
+		log.Warningf("Failed to fetch view: %s", err.Error())
+		RespondError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	data := map[string]interface{}{
+		"view": view,
+	}
+
+	res := Response{Status: "success", Message: "Fetched view successfully", Data: data}
+	RespondJSON(w, http.StatusOK, &res)
+}
+
+func (h *Handler) UpdateView(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var v models.UpdateView
+	err = json.NewDecoder(r.Body).Decode(&v)
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid JSON provided")
+		return
+	}
+
+	validate := validator.New()
+	err = validate.Struct(v)
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	// the fields left out were checked when they were saved
+	var changed models.View
+	v.Apply(&changed)
+	if err := checkView(&changed); err != nil {
+		RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	intID, ok := viewID(w, req)
+	if !ok {
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	view, err := h.store.UpdateView(user, intID, v)
+	if err != nil {
+		log.Warningf("Update view error: %s", err.Error())
+		if err == database.ErrViewExists {
+			RespondError(w, http.StatusConflict, err.Error())
+			return
+		}
+		if err.Error() == "record not found" {
+			h.respondViewNotFound(w, user, intID)
+			return
+		}
+		RespondError(w, http.StatusBadRequest, "Failed to update view")
+		return
+	}
+
+	data := map[string]interface{}{
+		"view": view,
+	}
+
+	res := Response{Status: "success", Message: "Updated view successfully", Data: data}
+	RespondJSON(w, http.StatusOK, &res)
+}
+
+func (h *Handler) DeleteView(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	intID, ok := viewID(w, req)
+	if !ok {
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	err = h.store.DeleteView(user, intID)
+	if err != nil {
+		log.Warningf("Delete view error: %s", err.Error())
+		if err.Error() == "record not found" {
+			h.respondViewNotFound(w, user, intID)
+			return
+		}
+		RespondError(w, http.StatusBadRequest, "Failed to delete view")
+		return
+	}
+
+	res := Response{Status: "success", Message: "Deleted view successfully"}
+	RespondJSON(w, http.StatusOK, &res)
+}
+
+// GetViewTasks lists the tasks of a view, out of the tasks the caller can
+// see. The limit, cursor and tz query parameters work as for GET /tasks.
+func (h *Handler) GetViewTasks(w http.ResponseWriter, r *http.Request) {
+	req, err := h.CheckAuth(r)
+	if err != nil {
+		log.Warningf("auth error: %s", err.Error())
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	intID, ok := viewID(w, req)
+	if !ok {
+		return
+	}
+
+	user := req.Context().Value(KeyUser{}).(*models.User)
+	view, err := h.store.GetView(user, intID)
+	if err != nil {
+		if err.Error() == "record not found" {
+			RespondError(w, http.StatusNotFound, "View not found")
+			return
+		}
+		log.Warningf("Failed to fetch view: %s", err.Error())
+		RespondError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	filter, err := parseTaskFilter(viewQuery(view, r.URL.Query()))
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := h.store.GetTasks(user, filter)
+	if err != nil {
+		log.Warningf("Failed to fetch view tasks: %s", err.Error())
+		RespondError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	data := map[string]interface{}{
+		"view":  view,
+		"tasks": page.Tasks,
+	}
+	if view.GroupBy != "" {
+		data["groups"] = models.GroupTasks(page.Tasks, view.GroupBy)
+	}
+
+	res := Response{Status: "success", Message: "Fetched view tasks successfully", Data: data, NextCursor: page.Next, PrevCursor: page.Prev}
+	RespondJSON(w, http.StatusOK, &res)
+}
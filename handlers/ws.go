@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"todo-app/ws"
 
 	"github.com/gorilla/websocket"
 	log "github.com/sirupsen/logrus"
@@ -18,6 +19,27 @@ type message struct {
 	Action   string `json:"action"`
 	Message  string `json:"message"`
 	Task     uint   `json:"task"`
+	// Tasks lists the tasks a batch changed
+	Tasks []uint `json:"tasks,omitempty"`
+	// Mentions names the users a comment mentions
+	Mentions []string `json:"mentions,omitempty"`
+}
+
+// sendEvent passes msg on to the websocket clients, it is dropped when the
+// websocket endpoint can't be reached
+func sendEvent(msg message) {
+	c, err := ws.Connect()
+	if err != nil {
+		log.Error("dial Error:", err)
+		return
+	}
+	go func() {
+		err := c.WriteJSON(msg)
+		if err != nil {
+			log.Warn("write:", err)
+			return
+		}
+	}()
 }
 
 var clients = make(map[*websocket.Conn]bool)
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 	"todo-app/database"
@@ -12,6 +13,7 @@ import (
 	"todo-app/middleware"
 	"todo-app/util"
 	"todo-app/util/auth"
+	"todo-app/util/blob"
 
 	"github.com/go-redis/redis/v7"
 	"github.com/gorilla/mux"
@@ -41,30 +43,92 @@ func NewRedisDB(host, port, password string) (*redis.Client, error) {
 	return redisClient, nil
 }
 
+// newSessionStore picks where token sessions live from TODO_SESSIONSTORE
+func newSessionStore(conf util.EnvVariables, store database.TaskStore) (auth.SessionStore, error) {
+	switch conf.SessionStore {
+	case "", "redis":
+		redisClient, err := NewRedisDB(conf.RedisHost, conf.RedisPort, conf.RedisPassword)
+		if err != nil {
+			return nil, err
+		}
+		return auth.NewRedisSessions(redisClient), nil
+	case "memory":
+		return auth.NewMemorySessions(time.Minute), nil
+	case "sql":
+		sqlStore, ok := store.(*database.SQLStore)
+		if !ok {
+			return nil, errors.Errorf("sql sessions need a sql database, not %q", conf.DatabaseType)
+		}
+		return auth.NewSQLSessions(sqlStore.DB), nil
+	default:
+		return nil, errors.Errorf("unknown session store %q", conf.SessionStore)
+	}
+}
+
+// newBlobStore picks where attachments live from TODO_BLOBSTORE
+func newBlobStore(conf util.EnvVariables) (blob.Store, error) {
+	switch conf.BlobStore {
+	case "", "local":
+		path := conf.BlobPath
+		if path == "" {
+			path = filepath.Join(conf.FileDBPath, "attachments")
+		}
+		return blob.NewLocal(path)
+	default:
+		return nil, errors.Errorf("unknown blob store %q", conf.BlobStore)
+	}
+}
+
 func main() {
-	log.Info("Starting Up Todolist API")
 	conf, err := getConfig()
 	if err != nil {
 		log.Fatalf("Failed to read config: %v", err)
 	}
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+
+	log.Info("Starting Up Todolist API")
+
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 
-	redisClient, err := NewRedisDB(conf.RedisHost, conf.RedisPort, conf.RedisPassword)
+	store, err := database.New()
 	if err != nil {
-		log.Fatalf("Failed to create redis store: %v", err)
+		log.Fatalf("Failed to create store: %v", err)
 	}
 
-	token := auth.NewToken()
-	rdAuth := auth.NewAuth(redisClient)
+	if sqlStore, ok := store.(*database.SQLStore); ok {
+		if err := sqlStore.CheckSchema(); err != nil {
+			log.Fatalf("Refusing to start: %v", err)
+		}
+	}
 
-	store, err := database.New()
+	sessions, err := newSessionStore(conf, store)
 	if err != nil {
-		log.Fatalf("Failed to create store: %v", err)
+		log.Fatalf("Failed to create session store: %v", err)
+	}
+
+	blobs, err := newBlobStore(conf)
+	if err != nil {
+		log.Fatalf("Failed to create blob store: %v", err)
 	}
 
-	handler := handlers.NewHandler(store, token, rdAuth)
+	token := auth.NewToken()
+	sessionAuth := auth.NewAuth(sessions)
+
+	uploads := handlers.Uploads{Blobs: blobs, MaxSize: conf.MaxUploadSize, Types: conf.UploadTypes}
+	handler := handlers.NewHandler(store, token, sessionAuth, uploads)
+	if conf.TrashRetention > 0 {
+		go handler.PurgeTrash(conf.TrashRetention, time.Hour)
+	}
+	if conf.AutoArchiveDays > 0 {
+		go handler.AutoArchive(time.Duration(conf.AutoArchiveDays)*24*time.Hour, time.Hour)
+	}
 
 	serveMux := mux.NewRouter()
 	serveMux.HandleFunc("/signup", handler.Signup).Methods("POST")
@@ -76,21 +140,93 @@ func main() {
 	tasksRouter := serveMux.PathPrefix("/tasks").Subrouter()
 	tasksRouter.Use(middleware.AuthMiddleware)
 	tasksRouter.HandleFunc("", handler.CreateTask).Methods("POST")
-	tasksRouter.HandleFunc("", handler.GetTasks).Queries("completed", "{completed:true|false}").Methods("GET")
-	tasksRouter.HandleFunc("", handler.GetTasks).Queries("priority", "{priority:[1-3]}").Methods("GET")
 	tasksRouter.HandleFunc("", handler.GetTasks).Methods("GET")
+	tasksRouter.HandleFunc("/search", handler.SearchTasks).Methods(http.MethodGet)
+	tasksRouter.HandleFunc("/batch", handler.RunBatch).Methods(http.MethodPost)
 	tasksRouter.HandleFunc("/{id:[0-9]+}", handler.GetTask).Methods("GET")
 	tasksRouter.HandleFunc("/{id:[0-9]+}", handler.UpdateTask).Methods(http.MethodPatch)
 	tasksRouter.HandleFunc("/{id:[0-9]+}", handler.DeleteTask).Methods(http.MethodDelete)
+	tasksRouter.HandleFunc("/{id:[0-9]+}/subtasks", handler.CreateSubtask).Methods(http.MethodPost)
+	tasksRouter.HandleFunc("/{id:[0-9]+}/tree", handler.GetTaskTree).Methods(http.MethodGet)
+	tasksRouter.HandleFunc("/{id:[0-9]+}/blockers/{idBlocker:[0-9]+}", handler.AddBlocker).Methods(http.MethodPost)
+	tasksRouter.HandleFunc("/{id:[0-9]+}/blockers/{idBlocker:[0-9]+}", handler.RemoveBlocker).Methods(http.MethodDelete)
+	tasksRouter.HandleFunc("/{id:[0-9]+}/labels/{idLabel:[0-9]+}", handler.AttachLabel).Methods(http.MethodPost)
+	tasksRouter.HandleFunc("/{id:[0-9]+}/labels/{idLabel:[0-9]+}", handler.DetachLabel).Methods(http.MethodDelete)
+	tasksRouter.HandleFunc("/{id:[0-9]+}/comments", handler.CreateComment).Methods(http.MethodPost)
+	tasksRouter.HandleFunc("/{id:[0-9]+}/comments", handler.GetComments).Methods(http.MethodGet)
+	tasksRouter.HandleFunc("/{id:[0-9]+}/comments/{idComment:[0-9]+}", handler.UpdateComment).Methods(http.MethodPatch)
+	tasksRouter.HandleFunc("/{id:[0-9]+}/comments/{idComment:[0-9]+}", handler.DeleteComment).Methods(http.MethodDelete)
+	tasksRouter.HandleFunc("/{id:[0-9]+}/attachments", handler.UploadAttachment).Methods(http.MethodPost)
+	tasksRouter.HandleFunc("/{id:[0-9]+}/attachments", handler.GetAttachments).Methods(http.MethodGet)
+	tasksRouter.HandleFunc("/{id:[0-9]+}/attachments/{idAttachment:[0-9]+}", handler.DownloadAttachment).Methods(http.MethodGet)
+	tasksRouter.HandleFunc("/{id:[0-9]+}/attachments/{idAttachment:[0-9]+}", handler.DeleteAttachment).Methods(http.MethodDelete)
+	tasksRouter.HandleFunc("/{id:[0-9]+}/checklist", handler.AddChecklistItem).Methods(http.MethodPost)
+	tasksRouter.HandleFunc("/{id:[0-9]+}/checklist", handler.ReorderChecklist).Methods(http.MethodPut)
+	tasksRouter.HandleFunc("/{id:[0-9]+}/checklist/{idItem:[0-9]+}/toggle", handler.ToggleChecklistItem).Methods(http.MethodPost)
+	tasksRouter.HandleFunc("/{id:[0-9]+}/checklist/{idItem:[0-9]+}", handler.DeleteChecklistItem).Methods(http.MethodDelete)
+	tasksRouter.HandleFunc("/{id:[0-9]+}/archive", handler.ArchiveTask).Methods(http.MethodPost)
+	tasksRouter.HandleFunc("/{id:[0-9]+}/unarchive", handler.UnarchiveTask).Methods(http.MethodPost)
+	tasksRouter.HandleFunc("/{id:[0-9]+}/restore", handler.RestoreTask).Methods(http.MethodPost)
+	tasksRouter.HandleFunc("/{id:[0-9]+}/history", handler.GetTaskHistory).Methods(http.MethodGet)
 	tasksRouter.HandleFunc("/{idTask:[0-9]+}/{idUser:[0-9]+}", handler.AddUserToTask).Methods(http.MethodPost)
 	tasksRouter.HandleFunc("/{idTask:[0-9]+}/{idUser:[0-9]+}", handler.RemoveUserFromTask).Methods(http.MethodDelete)
 
+	orgsRouter := serveMux.PathPrefix("/orgs").Subrouter()
+	orgsRouter.Use(middleware.AuthMiddleware)
+	orgsRouter.HandleFunc("", handler.CreateOrganization).Methods(http.MethodPost)
+	orgsRouter.HandleFunc("", handler.GetOrganizations).Methods(http.MethodGet)
+	orgsRouter.HandleFunc("/{id:[0-9]+}", handler.GetOrganization).Methods(http.MethodGet)
+	orgsRouter.HandleFunc("/{id:[0-9]+}/switch", handler.SwitchOrganization).Methods(http.MethodPost)
+	orgsRouter.HandleFunc("/{id:[0-9]+}/members/{idUser:[0-9]+}", handler.AddOrgMember).Methods(http.MethodPost)
+	orgsRouter.HandleFunc("/{id:[0-9]+}/members/{idUser:[0-9]+}", handler.RemoveOrgMember).Methods(http.MethodDelete)
+
+	projectsRouter := serveMux.PathPrefix("/projects").Subrouter()
+	projectsRouter.Use(middleware.AuthMiddleware)
+	projectsRouter.HandleFunc("", handler.CreateProject).Methods(http.MethodPost)
+	projectsRouter.HandleFunc("", handler.GetProjects).Methods(http.MethodGet)
+	projectsRouter.HandleFunc("/{id:[0-9]+}", handler.GetProject).Methods(http.MethodGet)
+	projectsRouter.HandleFunc("/{id:[0-9]+}", handler.UpdateProject).Methods(http.MethodPatch)
+	projectsRouter.HandleFunc("/{id:[0-9]+}", handler.DeleteProject).Methods(http.MethodDelete)
+	projectsRouter.HandleFunc("/{id:[0-9]+}/tasks", handler.CreateProjectTask).Methods(http.MethodPost)
+	projectsRouter.HandleFunc("/{id:[0-9]+}/tasks", handler.GetProjectTasks).Methods(http.MethodGet)
+	projectsRouter.HandleFunc("/{id:[0-9]+}/members/{idUser:[0-9]+}", handler.AddProjectMember).Methods(http.MethodPost)
+	projectsRouter.HandleFunc("/{id:[0-9]+}/members/{idUser:[0-9]+}", handler.RemoveProjectMember).Methods(http.MethodDelete)
+
+	labelsRouter := serveMux.PathPrefix("/labels").Subrouter()
+	labelsRouter.Use(middleware.AuthMiddleware)
+	labelsRouter.HandleFunc("", handler.CreateLabel).Methods(http.MethodPost)
+	labelsRouter.HandleFunc("", handler.GetLabels).Methods(http.MethodGet)
+	labelsRouter.HandleFunc("/{id:[0-9]+}", handler.UpdateLabel).Methods(http.MethodPatch)
+	labelsRouter.HandleFunc("/{id:[0-9]+}", handler.DeleteLabel).Methods(http.MethodDelete)
+
+	viewsRouter := serveMux.PathPrefix("/views").Subrouter()
+	viewsRouter.Use(middleware.AuthMiddleware)
+	viewsRouter.HandleFunc("", handler.CreateView).Methods(http.MethodPost)
+	viewsRouter.HandleFunc("", handler.GetViews).Methods(http.MethodGet)
+	viewsRouter.HandleFunc("/{id:[0-9]+}", handler.GetView).Methods(http.MethodGet)
+	viewsRouter.HandleFunc("/{id:[0-9]+}", handler.UpdateView).Methods(http.MethodPatch)
+	viewsRouter.HandleFunc("/{id:[0-9]+}", handler.DeleteView).Methods(http.MethodDelete)
+	viewsRouter.HandleFunc("/{id:[0-9]+}/tasks", handler.GetViewTasks).Methods(http.MethodGet)
+
+	trashRouter := serveMux.PathPrefix("/trash").Subrouter()
+	trashRouter.Use(middleware.AuthMiddleware)
+	trashRouter.HandleFunc("", handler.GetTrash).Methods(http.MethodGet)
+	trashRouter.HandleFunc("/{id:[0-9]+}", handler.PurgeTask).Methods(http.MethodDelete)
+
+	activityRouter := serveMux.PathPrefix("/activity").Subrouter()
+	activityRouter.Use(middleware.AuthMiddleware)
+	activityRouter.HandleFunc("", handler.GetActivity).Methods(http.MethodGet)
+
 	go handlers.Reader()
 
+	// attachments stream through the handlers, so the deadlines have to
+	// leave room for the largest upload on a slow connection
 	s := &http.Server{
-		Addr:         ":8080",
-		Handler:      serveMux,
-		WriteTimeout: 2 * time.Second,
+		Addr:              ":8080",
+		Handler:           serveMux,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       conf.TransferTimeout,
+		WriteTimeout:      conf.TransferTimeout,
 	}
 
 	go func() {
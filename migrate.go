@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"todo-app/database"
+
+	"github.com/pkg/errors"
+)
+
+const migrateUsage = "usage: migrate status|up|down|to <version>"
+
+// runMigrate handles `todo-app migrate ...`
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	store, err := database.New()
+	if err != nil {
+		return errors.Wrap(err, "failed to create store")
+	}
+	sqlStore, ok := store.(*database.SQLStore)
+	if !ok {
+		return errors.New("only sql databases have migrations")
+	}
+
+	switch args[0] {
+	case "status":
+	case "up":
+		err = sqlStore.MigrateUp()
+	case "down":
+		err = sqlStore.MigrateDown()
+	case "to":
+		if len(args) != 2 {
+			return errors.New(migrateUsage)
+		}
+		version, convErr := strconv.Atoi(args[1])
+		if convErr != nil {
+			return errors.Wrap(convErr, "invalid version")
+		}
+		err = sqlStore.MigrateTo(version)
+	default:
+		return errors.New(migrateUsage)
+	}
+	if err != nil {
+		return err
+	}
+
+	return printMigrationStatus(sqlStore)
+}
+
+func printMigrationStatus(s *database.SQLStore) error {
+	status, err := s.MigrationStatus()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+	for _, st := range status {
+		applied := "pending"
+		if st.AppliedAt != nil {
+			applied = st.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", st.Version, st.Name, applied)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,190 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Actions recorded in the activity log
+const (
+	ActivityCreate       = "create"
+	ActivityUpdate       = "update"
+	ActivityDelete       = "delete"
+	ActivityAddMember    = "add_member"
+	ActivityChangeRole   = "change_role"
+	ActivityRemoveMember = "remove_member"
+	ActivityRestore      = "restore"
+	ActivityPurge        = "purge"
+)
+
+// Activity is an entry of the append-only log of task changes
+type Activity struct {
+	ID        uint      `json:"id" gorm:"primary_key"`
+	CreatedAt time.Time `json:"created_at"`
+	OrgID     uint      `json:"-" gorm:"not null"`
+	TaskID    uint      `json:"task_id" gorm:"not null"`
+	ActorID   uint      `json:"actor_id" gorm:"not null"`
+	Action    string    `json:"action" gorm:"type:varchar(20);not null"`
+	// MemberID is the user added, removed or given another role
+	MemberID *uint   `json:"member_id,omitempty"`
+	Changes  Changes `json:"changes,omitempty" gorm:"type:text"`
+
+	Actor *User `gorm:"-" json:"actor,omitempty"`
+}
+
+// Change holds the value of a field before and after a mutation, nil where
+// the field was empty
+type Change struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// Changes maps field names to their change, stored as JSON
+type Changes map[string]Change
+
+func (c *Changes) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*c = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	default:
+		return fmt.Errorf("cannot scan %T into changes", value)
+	}
+}
+
+func (c Changes) Value() (driver.Value, error) {
+	if len(c) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(c)
+	return string(b), err
+}
+
+// ActivityFilter pages through an activity feed, newest first
+type ActivityFilter struct {
+	// Before only returns entries older than this ID
+	Before uint
+	// Limit caps the number of entries, all of them are returned when it is 0
+	Limit int
+}
+
+// taskFields are the fields compared by DiffTasks, as they are named in JSON
+var taskFields = []struct {
+	name  string
+	value func(t *Task) interface{}
+}{
+	{"title", func(t *Task) interface{} { return t.Title }},
+	{"description", func(t *Task) interface{} { return t.Description }},
+	{"priority", func(t *Task) interface{} { return string(t.Priority) }},
+	{"completed", func(t *Task) interface{} { return t.Completed }},
+	{"start_at", func(t *Task) interface{} { return timeValue(t.StartAt) }},
+	{"due_at", func(t *Task) interface{} { return timeValue(t.DueAt) }},
+	{"archived_at", func(t *Task) interface{} { return timeValue(t.ArchivedAt) }},
+	{"recurrence", func(t *Task) interface{} { return t.Recurrence }},
+	{"timezone", func(t *Task) interface{} { return t.Timezone }},
+	{"parent_id", func(t *Task) interface{} { return idValue(t.ParentID) }},
+	{"project_id", func(t *Task) interface{} { return idValue(t.ProjectID) }},
+}
+
+func timeValue(ts *time.Time) interface{} {
+	if ts == nil {
+		return nil
+	}
+	return ts.UTC().Format(time.RFC3339)
+}
+
+func idValue(id *uint) interface{} {
+	if id == nil {
+		return nil
+	}
+	return *id
+}
+
+// DiffTasks returns the fields that differ between before and after, either
+// may be nil for tasks that are being created or deleted
+func DiffTasks(before, after *Task) Changes {
+	changes := Changes{}
+	for _, field := range taskFields {
+		var b, a interface{}
+		if before != nil {
+			b = field.value(before)
+		}
+		if after != nil {
+			a = field.value(after)
+		}
+		if b == a {
+			continue
+		}
+		// creating or deleting a task leaves empty fields out
+		if (before == nil && isBlank(a)) || (after == nil && isBlank(b)) {
+			continue
+		}
+		changes[field.name] = Change{Before: b, After: a}
+	}
+	return changes
+}
+
+func isBlank(v interface{}) bool {
+	return v == nil || v == "" || v == false
+}
+
+// TaskActivity records a task being created (before is nil), updated or
+// deleted (after is nil) by actor, which is nil for changes the server makes
+// on its own
+func TaskActivity(actor *User, before, after *Task) Activity {
+	a := Activity{Action: ActivityUpdate, Changes: DiffTasks(before, after)}
+	if actor != nil {
+		a.ActorID = actor.ID
+	}
+	task := after
+	switch {
+	case before == nil:
+		a.Action = ActivityCreate
+	case after == nil:
+		a.Action = ActivityDelete
+		task = before
+	}
+	a.TaskID, a.OrgID = task.ID, task.OrgID
+	return a
+}
+
+// TrashActivity records actor restoring a task from the trash as after, or
+// purging it for good when after is nil. actor is nil for the background purge
+func TrashActivity(actor *User, before, after *Task) Activity {
+	a := Activity{TaskID: before.ID, OrgID: before.OrgID, Action: ActivityPurge}
+	if actor != nil {
+		a.ActorID = actor.ID
+	}
+	if after != nil {
+		a.Action = ActivityRestore
+		a.Changes = DiffTasks(before, after)
+	}
+	return a
+}
+
+// MemberActivity records actor changing the role of idMember on a task, an
+// empty role stands for not being a member
+func MemberActivity(actor *User, task *Task, idMember uint, before, after Role) Activity {
+	a := Activity{TaskID: task.ID, OrgID: task.OrgID, ActorID: actor.ID, MemberID: &idMember, Action: ActivityChangeRole}
+	switch {
+	case before == "":
+		a.Action = ActivityAddMember
+	case after == "":
+		a.Action = ActivityRemoveMember
+	}
+	a.Changes = Changes{"role": {Before: roleValue(before), After: roleValue(after)}}
+	return a
+}
+
+func roleValue(r Role) interface{} {
+	if r == "" {
+		return nil
+	}
+	return string(r)
+}
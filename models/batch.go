@@ -0,0 +1,67 @@
+package models
+
+import "encoding/json"
+
+// Operations of a batch
+const (
+	BatchCreate   = "create"
+	BatchUpdate   = "update"
+	BatchDelete   = "delete"
+	BatchComplete = "complete"
+)
+
+// Modes of a batch
+const (
+	// BatchAtomic applies every operation or none of them
+	BatchAtomic = "atomic"
+	// BatchPartial applies the operations that succeed and reports the
+	// others
+	BatchPartial = "partial"
+)
+
+// Batch is a list of task operations carried out in one transaction
+type Batch struct {
+	Mode       string    `json:"mode" validate:"omitempty,oneof=atomic partial"`
+	Operations []BatchOp `json:"operations" validate:"required,min=1,max=100"`
+}
+
+// BatchOp is one operation of a batch. ID names the task of every operation
+// but create, Task is the task to create and Patch is a merge patch for
+// update. IfMatch and Force work like If-Match and force do on a single task,
+// Versions are the task versions IfMatch was found to match.
+type BatchOp struct {
+	Op       string                     `json:"op"`
+	ID       int                        `json:"id"`
+	Task     *Task                      `json:"task"`
+	Patch    map[string]json.RawMessage `json:"patch"`
+	IfMatch  string                     `json:"if_match"`
+	Force    bool                       `json:"force"`
+	Versions []uint                     `json:"-"`
+}
+
+// Role is the task role the operation needs, create needs none
+func (op BatchOp) Role() Role {
+	switch op.Op {
+	case BatchUpdate, BatchComplete:
+		return RoleEditor
+	case BatchDelete:
+		return RoleOwner
+	}
+	return ""
+}
+
+// Update is the change an update or complete operation makes
+func (op BatchOp) Update() UpdateTask {
+	t := UpdateTask{Merge: op.Patch, IfMatch: op.Versions, Force: op.Force}
+	if op.Op == BatchComplete {
+		t.Merge = map[string]json.RawMessage{"completed": json.RawMessage("true")}
+	}
+	return t
+}
+
+// BatchResult is the outcome of one operation, Task is the created, updated
+// or deleted task
+type BatchResult struct {
+	Task *Task
+	Err  error
+}
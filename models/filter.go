@@ -0,0 +1,514 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// FilterExpr is a parsed filter expression, one of FilterAnd, FilterOr,
+// FilterNot and FilterCompare. See ParseFilter.
+type FilterExpr interface {
+	filterExpr()
+}
+
+type FilterAnd struct {
+	Left, Right FilterExpr
+}
+
+type FilterOr struct {
+	Left, Right FilterExpr
+}
+
+type FilterNot struct {
+	Expr FilterExpr
+}
+
+// FilterCompare compares a task field with a value. Value is a string, bool,
+// Priority, uint or time.Time to suit the field, or nil for null.
+type FilterCompare struct {
+	Field string
+	Op    string
+	Value interface{}
+}
+
+func (FilterAnd) filterExpr()     {}
+func (FilterOr) filterExpr()      {}
+func (FilterNot) filterExpr()     {}
+func (FilterCompare) filterExpr() {}
+
+// Filter operators, FilterContains matches part of a text case-insensitively.
+// SQLite's LOWER only folds ASCII, so there stored letters outside of it only
+// match a value in the same case.
+const (
+	FilterEq       = "="
+	FilterNe       = "!="
+	FilterLt       = "<"
+	FilterLe       = "<="
+	FilterGt       = ">"
+	FilterGe       = ">="
+	FilterContains = "~"
+)
+
+type filterType int
+
+const (
+	filterText filterType = iota
+	filterBool
+	filterPriority
+	filterID
+	filterTime
+)
+
+type filterField struct {
+	typ      filterType
+	nullable bool
+}
+
+// filterFields are the task fields a filter can use
+var filterFields = map[string]filterField{
+	"id":           {filterID, false},
+	"title":        {filterText, false},
+	"description":  {filterText, false},
+	"priority":     {filterPriority, false},
+	"completed":    {filterBool, false},
+	"start_at":     {filterTime, true},
+	"due_at":       {filterTime, true},
+	"completed_at": {filterTime, true},
+	"archived_at":  {filterTime, true},
+	"created_at":   {filterTime, false},
+	"updated_at":   {filterTime, false},
+	"project_id":   {filterID, true},
+	"parent_id":    {filterID, true},
+}
+
+// Limits that keep filters cheap to parse and to run
+const (
+	maxFilterLength = 1000
+	maxFilterDepth  = 20
+)
+
+// FilterError points at the token a filter expression can't be parsed at
+type FilterError struct {
+	// Pos counts characters from 1, it is 0 for errors about the whole filter
+	Pos   int
+	Token string
+	Msg   string
+}
+
+func (e *FilterError) Error() string {
+	if e.Pos == 0 {
+		return "invalid filter: " + e.Msg
+	}
+	if e.Token == "" {
+		return fmt.Sprintf("invalid filter at end of input: %s", e.Msg)
+	}
+	return fmt.Sprintf("invalid filter at position %d, %q: %s", e.Pos, e.Token, e.Msg)
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOp
+	tokenLParen
+	tokenRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	// value is the unquoted text of a string
+	value string
+	pos   int
+}
+
+// ParseFilter parses expressions like
+//
+//	priority>=2 AND completed=false AND title~"deploy"
+//
+// Comparisons are joined with AND, OR and NOT and grouped with parentheses.
+// Text fields take = != and ~ (contains), completed takes = and !=, and the
+// others also take < <= > >=. Times are RFC 3339 timestamps or dates read as
+// midnight in loc, both quoted. Fields that can be empty compare with null.
+func ParseFilter(s string, loc *time.Location) (FilterExpr, error) {
+	if utf8.RuneCountInString(s) > maxFilterLength {
+		return nil, &FilterError{Msg: fmt.Sprintf("filter is longer than %d characters", maxFilterLength)}
+	}
+	tokens, err := lexFilter(s)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &filterParser{tokens: tokens, loc: loc}
+	expr, err := p.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, p.errorAt(t, "expected AND, OR or the end of the filter")
+	}
+	return expr, nil
+}
+
+func lexFilter(s string) ([]token, error) {
+	var tokens []token
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		start := i
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: start + 1})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: start + 1})
+			i++
+		case r == '"':
+			var b strings.Builder
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				b.WriteRune(runes[i])
+			}
+			if i == len(runes) {
+				return nil, &FilterError{Pos: start + 1, Token: string(runes[start:]), Msg: "unterminated string"}
+			}
+			i++
+			tokens = append(tokens, token{kind: tokenString, text: string(runes[start:i]), value: b.String(), pos: start + 1})
+		case strings.ContainsRune("=!<>~", r):
+			op := string(r)
+			if i+1 < len(runes) {
+				switch two := string(runes[i : i+2]); two {
+				case "==", "!=", "<>", "<=", ">=":
+					op = two
+				}
+			}
+			if op == "!" {
+				return nil, &FilterError{Pos: start + 1, Token: op, Msg: "unknown operator, use !="}
+			}
+			i += len(op)
+			tokens = append(tokens, token{kind: tokenOp, text: op, pos: start + 1})
+		case unicode.IsDigit(r):
+			for i < len(runes) && unicode.IsDigit(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: string(runes[start:i]), pos: start + 1})
+		case unicode.IsLetter(r) || r == '_':
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: string(runes[start:i]), pos: start + 1})
+		default:
+			return nil, &FilterError{Pos: start + 1, Token: string(r), Msg: "unexpected character"}
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(runes) + 1}), nil
+}
+
+type filterParser struct {
+	tokens []token
+	next   int
+	loc    *time.Location
+}
+
+func (p *filterParser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *filterParser) take() token {
+	t := p.tokens[p.next]
+	if t.kind != tokenEOF {
+		p.next++
+	}
+	return t
+}
+
+func (p *filterParser) errorAt(t token, format string, args ...interface{}) error {
+	return &FilterError{Pos: t.pos, Token: t.text, Msg: fmt.Sprintf(format, args...)}
+}
+
+// keyword reports whether the next token is the keyword, which is
+// case-insensitive
+func (p *filterParser) keyword(word string) bool {
+	t := p.peek()
+	return t.kind == tokenIdent && strings.EqualFold(t.text, word)
+}
+
+func (p *filterParser) parseOr(depth int) (FilterExpr, error) {
+	left, err := p.parseAnd(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("OR") {
+		p.take()
+		right, err := p.parseAnd(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = FilterOr{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd(depth int) (FilterExpr, error) {
+	left, err := p.parseNot(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("AND") {
+		p.take()
+		right, err := p.parseNot(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = FilterAnd{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseNot(depth int) (FilterExpr, error) {
+	if depth > maxFilterDepth {
+		return nil, p.errorAt(p.peek(), "filter nests deeper than %d levels", maxFilterDepth)
+	}
+	if p.keyword("NOT") {
+		p.take()
+		expr, err := p.parseNot(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return FilterNot{expr}, nil
+	}
+
+	if p.peek().kind == tokenLParen {
+		p.take()
+		expr, err := p.parseOr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if t := p.take(); t.kind != tokenRParen {
+			return nil, p.errorAt(t, "expected )")
+		}
+		return expr, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *filterParser) parseCompare() (FilterExpr, error) {
+	name := p.take()
+	if name.kind != tokenIdent {
+		return nil, p.errorAt(name, "expected a field")
+	}
+	field, ok := filterFields[strings.ToLower(name.text)]
+	if !ok {
+		return nil, p.errorAt(name, "unknown field")
+	}
+
+	op := p.take()
+	if op.kind != tokenOp {
+		return nil, p.errorAt(op, "expected an operator after %s", name.text)
+	}
+	if op.text == "<>" {
+		op.text = FilterNe
+	}
+	if op.text == "==" {
+		op.text = FilterEq
+	}
+	if !field.takes(op.text) {
+		return nil, p.errorAt(op, "%s can't be compared with %s", name.text, op.text)
+	}
+
+	v := p.take()
+	value, err := p.value(field, v)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil && op.text != FilterEq && op.text != FilterNe {
+		return nil, p.errorAt(v, "null only works with = and !=")
+	}
+	return FilterCompare{Field: strings.ToLower(name.text), Op: op.text, Value: value}, nil
+}
+
+func (f filterField) takes(op string) bool {
+	switch op {
+	case FilterEq, FilterNe:
+		return true
+	case FilterContains:
+		return f.typ == filterText
+	case FilterLt, FilterLe, FilterGt, FilterGe:
+		return f.typ != filterText && f.typ != filterBool
+	}
+	return false
+}
+
+// value reads the value of a comparison with field
+func (p *filterParser) value(field filterField, t token) (interface{}, error) {
+	if t.kind == tokenIdent && strings.EqualFold(t.text, "null") {
+		if !field.nullable {
+			return nil, p.errorAt(t, "field is never null")
+		}
+		return nil, nil
+	}
+
+	switch field.typ {
+	case filterText:
+		if t.kind == tokenString {
+			return t.value, nil
+		}
+		return nil, p.errorAt(t, "expected a quoted string")
+	case filterBool:
+		if t.kind == tokenIdent && (strings.EqualFold(t.text, "true") || strings.EqualFold(t.text, "false")) {
+			return strings.EqualFold(t.text, "true"), nil
+		}
+		return nil, p.errorAt(t, "expected true or false")
+	case filterPriority:
+		s := t.text
+		if t.kind == tokenString {
+			s = t.value
+		}
+		if (t.kind == tokenNumber || t.kind == tokenString) && (s == "1" || s == "2" || s == "3") {
+			return Priority(s), nil
+		}
+		return nil, p.errorAt(t, "expected a priority of 1, 2 or 3")
+	case filterID:
+		if t.kind == tokenNumber {
+			if id, err := strconv.ParseUint(t.text, 10, 32); err == nil {
+				return uint(id), nil
+			}
+		}
+		return nil, p.errorAt(t, "expected an ID")
+	default:
+		if t.kind == tokenString {
+			if ts, err := ParseTime(t.value, p.loc); err == nil {
+				return ts, nil
+			}
+		}
+		return nil, p.errorAt(t, "expected a quoted RFC 3339 timestamp or YYYY-MM-DD date")
+	}
+}
+
+// MatchFilter evaluates a filter against a task the way the sql store's
+// WHERE clause does. Empty fields only match = null and != anything else.
+func MatchFilter(expr FilterExpr, t *Task) bool {
+	switch e := expr.(type) {
+	case FilterAnd:
+		return MatchFilter(e.Left, t) && MatchFilter(e.Right, t)
+	case FilterOr:
+		return MatchFilter(e.Left, t) || MatchFilter(e.Right, t)
+	case FilterNot:
+		return !MatchFilter(e.Expr, t)
+	case FilterCompare:
+		return e.match(t)
+	}
+	return false
+}
+
+func (c FilterCompare) match(t *Task) bool {
+	field := c.field(t)
+	if field == nil || c.Value == nil {
+		switch c.Op {
+		case FilterEq:
+			return field == nil && c.Value == nil
+		case FilterNe:
+			return (field == nil) != (c.Value == nil)
+		}
+		return false
+	}
+
+	var cmp int
+	switch v := c.Value.(type) {
+	case string:
+		if c.Op == FilterContains {
+			return strings.Contains(strings.ToLower(field.(string)), strings.ToLower(v))
+		}
+		cmp = strings.Compare(field.(string), v)
+	case bool:
+		if field.(bool) != v {
+			cmp = 1
+		}
+	case Priority:
+		cmp = strings.Compare(string(field.(Priority)), string(v))
+	case uint:
+		switch id := field.(uint); {
+		case id < v:
+			cmp = -1
+		case id > v:
+			cmp = 1
+		}
+	case time.Time:
+		cmp = compareTimes(field.(time.Time), v)
+	}
+
+	switch c.Op {
+	case FilterEq:
+		return cmp == 0
+	case FilterNe:
+		return cmp != 0
+	case FilterLt:
+		return cmp < 0
+	case FilterLe:
+		return cmp <= 0
+	case FilterGt:
+		return cmp > 0
+	case FilterGe:
+		return cmp >= 0
+	}
+	return false
+}
+
+// field returns the value of the compared field, nil when it is empty
+func (c FilterCompare) field(t *Task) interface{} {
+	ts := func(v *time.Time) interface{} {
+		if v == nil {
+			return nil
+		}
+		return *v
+	}
+	id := func(v *uint) interface{} {
+		if v == nil {
+			return nil
+		}
+		return *v
+	}
+
+	switch c.Field {
+	case "id":
+		return t.ID
+	case "title":
+		return t.Title
+	case "description":
+		return t.Description
+	case "priority":
+		return t.Priority
+	case "completed":
+		return t.Completed
+	case "start_at":
+		return ts(t.StartAt)
+	case "due_at":
+		return ts(t.DueAt)
+	case "completed_at":
+		return ts(t.CompletedAt)
+	case "archived_at":
+		return ts(t.ArchivedAt)
+	case "created_at":
+		return t.CreatedAt
+	case "updated_at":
+		return t.UpdatedAt
+	case "project_id":
+		return id(t.ProjectID)
+	case "parent_id":
+		return id(t.ParentID)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func cmp(field, op string, value interface{}) FilterCompare {
+	return FilterCompare{Field: field, Op: op, Value: value}
+}
+
+func TestParseFilter(t *testing.T) {
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	done := cmp("completed", FilterEq, true)
+	high := cmp("priority", FilterGe, Priority("2"))
+	deploy := cmp("title", FilterContains, "deploy")
+
+	tests := []struct {
+		filter string
+		want   FilterExpr
+	}{
+		{`completed=true`, done},
+		{`priority>=2`, high},
+		{`priority>="2"`, high},
+		{`title~"deploy"`, deploy},
+		// AND binds tighter than OR, NOT tighter than both
+		{`completed=true OR priority>=2 AND title~"deploy"`, FilterOr{done, FilterAnd{high, deploy}}},
+		{`completed=true AND priority>=2 OR title~"deploy"`, FilterOr{FilterAnd{done, high}, deploy}},
+		{`NOT completed=true AND priority>=2`, FilterAnd{FilterNot{done}, high}},
+		{`NOT (completed=true AND priority>=2)`, FilterNot{FilterAnd{done, high}}},
+		{`(completed=true OR priority>=2) AND title~"deploy"`, FilterAnd{FilterOr{done, high}, deploy}},
+		{`((completed=true))`, done},
+		{`NOT NOT completed=true`, FilterNot{FilterNot{done}}},
+		// chains group from the left
+		{`completed=true OR priority>=2 OR title~"deploy"`, FilterOr{FilterOr{done, high}, deploy}},
+		// keywords, fields and literals ignore case
+		{`Completed=TRUE or PRIORITY>=2`, FilterOr{done, high}},
+		{`completed==true`, done},
+		{`completed<>true`, cmp("completed", FilterNe, true)},
+		{`title = "say \"hi\""`, cmp("title", FilterEq, `say "hi"`)},
+		{`due_at=null`, cmp("due_at", FilterEq, nil)},
+		{`parent_id != NULL`, cmp("parent_id", FilterNe, nil)},
+		{`project_id=3`, cmp("project_id", FilterEq, uint(3))},
+		{`due_at<"2026-03-02"`, cmp("due_at", FilterLt, day)},
+		{`due_at<"2026-03-02T00:00:00Z"`, cmp("due_at", FilterLt, day)},
+		// values are only ever values
+		{`title="x' OR '1'='1"`, cmp("title", FilterEq, "x' OR '1'='1")},
+		{`title~"%_\\"`, cmp("title", FilterContains, `%_\`)},
+		{`description="); DROP TABLE tasks; --"`, cmp("description", FilterEq, "); DROP TABLE tasks; --")},
+	}
+	for _, tt := range tests {
+		got, err := ParseFilter(tt.filter, time.UTC)
+		if err != nil {
+			t.Errorf("%s: %v", tt.filter, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %#v, want %#v", tt.filter, got, tt.want)
+		}
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   *FilterError
+	}{
+		{``, &FilterError{1, "", "expected a field"}},
+		{`completed=`, &FilterError{11, "", "expected true or false"}},
+		{`completed=true AND`, &FilterError{19, "", "expected a field"}},
+		{`completed=true priority>=2`, &FilterError{16, "priority", "expected AND, OR or the end of the filter"}},
+		{`(completed=true`, &FilterError{16, "", "expected )"}},
+		{`completed=true)`, &FilterError{15, ")", "expected AND, OR or the end of the filter"}},
+		{`title~"deploy`, &FilterError{7, `"deploy`, "unterminated string"}},
+		{`title ! "x"`, &FilterError{7, "!", "unknown operator, use !="}},
+		{`title="x" && completed=true`, &FilterError{11, "&", "unexpected character"}},
+		{`completed true`, &FilterError{11, "true", "expected an operator after completed"}},
+		{`title<"x"`, &FilterError{6, "<", "title can't be compared with <"}},
+		{`completed~true`, &FilterError{10, "~", "completed can't be compared with ~"}},
+		{`title=deploy`, &FilterError{7, "deploy", "expected a quoted string"}},
+		{`priority=4`, &FilterError{10, "4", "expected a priority of 1, 2 or 3"}},
+		{`id=99999999999`, &FilterError{4, "99999999999", "expected an ID"}},
+		{`due_at<"tomorrow"`, &FilterError{8, `"tomorrow"`, "expected a quoted RFC 3339 timestamp or YYYY-MM-DD date"}},
+		{`due_at<null`, &FilterError{8, "null", "null only works with = and !="}},
+		{`title=null`, &FilterError{7, "null", "field is never null"}},
+		// positions count characters, not bytes
+		{`title="é" OR nope=1`, &FilterError{14, "nope", "unknown field"}},
+		{strings.Repeat("(", 22) + "completed=true", &FilterError{22, "(", "filter nests deeper than 20 levels"}},
+		{strings.Repeat("NOT ", 22) + "completed=true", &FilterError{85, "NOT", "filter nests deeper than 20 levels"}},
+		{strings.Repeat("x", 1001), &FilterError{Msg: "filter is longer than 1000 characters"}},
+	}
+	for _, tt := range tests {
+		_, err := ParseFilter(tt.filter, time.UTC)
+		if !reflect.DeepEqual(err, tt.want) {
+			t.Errorf("%.40s: got %v, want %v", tt.filter, err, tt.want)
+		}
+	}
+}
+
+// TestFilterFields checks that a filter only reaches the fields it lists
+func TestFilterFields(t *testing.T) {
+	for _, field := range []string{"id", "title", "description", "priority", "completed", "start_at", "due_at",
+		"completed_at", "archived_at", "created_at", "updated_at", "project_id", "parent_id"} {
+		if _, ok := filterFields[field]; !ok {
+			t.Errorf("%s can't be filtered on", field)
+		}
+	}
+	for _, filter := range []string{
+		`password="secret"`, `user_id=1`, `org_id=1`, `deleted_at=null`, `search_vector=null`, `version=1`,
+		`tasks.id=1`, `"title"="x"`, `title--="x"`, `1=1`, `(SELECT 1)=1`,
+	} {
+		if _, err := ParseFilter(filter, time.UTC); err == nil {
+			t.Errorf("%s: parsed a filter on a field it doesn't list", filter)
+		}
+	}
+}
+
+func TestMatchFilter(t *testing.T) {
+	due := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	project := uint(3)
+	scheduled := &Task{Title: "Deploy API", Priority: "2", DueAt: &due, ProjectID: &project}
+	unscheduled := &Task{Title: "notes", Priority: "1"}
+
+	tests := []struct {
+		filter           string
+		scheduled, plain bool
+	}{
+		{`title~"deploy"`, true, false},
+		{`title="deploy api"`, false, false},
+		{`title!="notes"`, true, false},
+		{`priority>1 OR title="notes"`, true, true},
+		{`NOT (priority>1 OR title="notes")`, false, false},
+		// empty fields only match = null and != a value
+		{`due_at=null`, false, true},
+		{`due_at!=null`, true, false},
+		{`due_at<"2026-04-01"`, true, false},
+		{`due_at>="2026-04-01"`, false, false},
+		{`NOT due_at<"2026-04-01"`, false, true},
+		{`due_at!="2026-04-01"`, true, true},
+		{`project_id=3`, true, false},
+		{`project_id!=3`, false, true},
+		{`parent_id=null AND completed_at=null`, true, true},
+	}
+	for _, tt := range tests {
+		expr, err := ParseFilter(tt.filter, time.UTC)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := MatchFilter(expr, scheduled); got != tt.scheduled {
+			t.Errorf("%s on a scheduled task: got %v, want %v", tt.filter, got, tt.scheduled)
+		}
+		if got := MatchFilter(expr, unscheduled); got != tt.plain {
+			t.Errorf("%s on an unscheduled task: got %v, want %v", tt.filter, got, tt.plain)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+)
+
+var mentionRe = regexp.MustCompile(`(^|[^\w@])@([\w.-]+)`)
+
+// ParseMentions returns the distinct usernames mentioned as @username in body
+func ParseMentions(body string) []string {
+	seen := map[string]bool{}
+	names := []string{}
+	for _, m := range mentionRe.FindAllStringSubmatch(body, -1) {
+		// a mention can end a sentence
+		name := strings.TrimRight(m[2], ".-")
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMentions(t *testing.T) {
+	tests := []struct {
+		body string
+		want []string
+	}{
+		{"@bob", []string{"bob"}},
+		{"thanks @bob and @carol", []string{"bob", "carol"}},
+		{"@bob, @carol and @bob again", []string{"bob", "carol"}},
+		// punctuation around a mention isn't part of the name
+		{"ask @bob.", []string{"bob"}},
+		{"(@bob), @carol! @dave? @erin-", []string{"bob", "carol", "dave", "erin"}},
+		{"cc:@bob\n@carol", []string{"bob", "carol"}},
+		{"@first.last and @first_last", []string{"first.last", "first_last"}},
+		// an email address or a doubled @ mentions nobody
+		{"mail bob@example.com", []string{}},
+		{"@@bob", []string{}},
+		{"@ @. @-", []string{}},
+		{"", []string{}},
+	}
+	for _, tt := range tests {
+		if got := ParseMentions(tt.body); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseMentions(%q): got %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
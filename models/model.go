@@ -3,26 +3,39 @@ package models
 import (
 	"database/sql/driver"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/jinzhu/gorm"
 )
 
-type priority string
+type Priority string
 type password string
 
 const (
-	low  priority = "1"
-	mid  priority = "2"
-	high priority = "3"
+	low  Priority = "1"
+	mid  Priority = "2"
+	high Priority = "3"
 )
 
-func (p *priority) Scan(value interface{}) error {
-	*p = priority(value.([]byte))
+func (p *Priority) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		*p = Priority(v)
+	case string:
+		*p = Priority(v)
+	case int64:
+		// sqlite gives the priority column integer affinity
+		*p = Priority(strconv.FormatInt(v, 10))
+	default:
+		return fmt.Errorf("cannot scan %T into priority", value)
+	}
 	return nil
 }
 
-func (p priority) Value() (driver.Value, error) {
+func (p Priority) Value() (driver.Value, error) {
 	return string(p), nil
 }
 
@@ -31,9 +44,13 @@ type User struct {
 	CreatedAt time.Time  `json:"-"`
 	UpdatedAt time.Time  `json:"-"`
 	DeletedAt *time.Time `json:"-" sql:"index"`
-	Username  string     `gorm:"type:varchar(50);not null;unique" json:"username" validate:"required,gte=3"`
+	Username  string     `gorm:"type:varchar(50);not null" json:"username" validate:"required,gte=3"`
 	Password  string     `gorm:"not null" json:"password" validate:"required"`
 	Tasks     []*Task    `gorm:"many2many:user_tasks;" json:"-"`
+
+	// OrgID is the organization the user is acting in, taken from their
+	// access token. Stores scope every query by it.
+	OrgID uint `gorm:"-" json:"-"`
 }
 
 // Avoid returning Password
@@ -54,12 +71,425 @@ type Task struct {
 
 	// ENUM not supported in postgres
 	// Priority  string `gorm:"type:ENUM(1', '2', '3');default:'1'" json:"priority"`
-	Priority  priority `sql:"type:priority" gorm:"default:'1'" json:"priority"`
-	Completed bool     `json:"completed" gorm:"default:'false'"`
-	Users     []*User  `gorm:"many2many:user_tasks;" json:"users,omitempty"`
+	Priority  Priority   `sql:"type:priority" gorm:"default:'1'" json:"priority"`
+	Completed bool       `json:"completed" gorm:"default:false"`
+	StartAt   *time.Time `json:"start_at,omitempty"`
+	DueAt     *time.Time `json:"due_at,omitempty"`
+	// CompletedAt is when the task was last completed
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	// ArchivedAt keeps the task out of GET /tasks, see TaskFilter.IncludeArchived
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+	// Version goes up with every change to the task, it is sent as the ETag
+	Version uint `gorm:"not null;default:1" json:"version"`
+
+	// Recurrence is an RRULE, see ParseRecurrence. Every occurrence of a
+	// recurring task shares the ID of the first one as SeriesID.
+	Recurrence string `gorm:"type:varchar(255)" json:"recurrence,omitempty"`
+	// Timezone is the IANA zone the recurrence is computed in, UTC when empty
+	Timezone   string `gorm:"type:varchar(64)" json:"timezone,omitempty" validate:"lte=64"`
+	SeriesID   *uint  `json:"series_id,omitempty"`
+	Occurrence int    `json:"occurrence,omitempty"`
+
+	// ParentID makes this a subtask
+	ParentID *uint `json:"parent_id,omitempty"`
+	// ProjectID shares the task with every member of the project
+	ProjectID *uint `json:"project_id,omitempty"`
+	OrgID     uint  `json:"org_id"`
+
+	Users []*User `gorm:"many2many:user_tasks;" json:"users,omitempty"`
+	// NextOccurrence is set when completing the task spawned the next one
+	NextOccurrence *Task     `gorm:"-" json:"next_occurrence,omitempty"`
+	Subtasks       []*Task   `gorm:"-" json:"subtasks,omitempty"`
+	Progress       *Progress `gorm:"-" json:"progress,omitempty"`
+
+	// Blocked is true while any task in BlockedBy is still open
+	Blocked   bool   `gorm:"-" json:"blocked"`
+	BlockedBy []uint `gorm:"-" json:"blocked_by,omitempty"`
+
+	// Labels holds the caller's own labels on the task
+	Labels []Label `gorm:"-" json:"labels,omitempty"`
+
+	Attachments []Attachment `gorm:"-" json:"attachments,omitempty"`
+	// Checklist holds the task's checklist ordered by position
+	Checklist []ChecklistItem `gorm:"-" json:"checklist,omitempty"`
+}
+
+// Progress counts the completed subtasks below a task, at any depth
+type Progress struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+// NewProgress counts the descendants of a task, nil when there are none
+func NewProgress(descendants []Task) *Progress {
+	if len(descendants) == 0 {
+		return nil
+	}
+
+	p := &Progress{Total: len(descendants)}
+	for _, t := range descendants {
+		if t.Completed {
+			p.Done++
+		}
+	}
+	return p
+}
+
+// BuildTree nests descendants under root by ParentID
+func BuildTree(root *Task, descendants []Task) {
+	byID := map[uint]*Task{root.ID: root}
+	for i := range descendants {
+		byID[descendants[i].ID] = &descendants[i]
+	}
+	for i := range descendants {
+		child := &descendants[i]
+		if parent, ok := byID[*child.ParentID]; ok {
+			parent.Subtasks = append(parent.Subtasks, child)
+		}
+	}
+}
+
+var ErrInvalidSchedule = errors.New("start_at must not be after due_at")
+
+// CheckSchedule makes sure a task doesn't start after it is due
+func (t Task) CheckSchedule() error {
+	if t.StartAt != nil && t.DueAt != nil && t.StartAt.After(*t.DueAt) {
+		return ErrInvalidSchedule
+	}
+	return nil
+}
+
+var ErrRecurrenceNeedsDue = errors.New("recurring tasks need a due_at")
+
+// CheckRecurrence validates the rule and the timezone and stores the rule
+// in canonical form
+func (t *Task) CheckRecurrence() error {
+	if _, err := t.location(); err != nil {
+		return err
+	}
+	if t.Recurrence == "" {
+		return nil
+	}
+
+	r, err := ParseRecurrence(t.Recurrence)
+	if err != nil {
+		return err
+	}
+	if t.DueAt == nil {
+		return ErrRecurrenceNeedsDue
+	}
+	t.Recurrence = r.String()
+	return nil
+}
+
+// location loads Timezone
+func (t *Task) location() (*time.Location, error) {
+	// LoadLocation reads "" as UTC and "Local" as the server's zone
+	if t.Timezone == "Local" {
+		return nil, fmt.Errorf("unknown timezone %q", t.Timezone)
+	}
+	loc, err := time.LoadLocation(t.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", t.Timezone)
+	}
+	return loc, nil
+}
+
+// StartSeries makes a recurring task the first occurrence of its own series,
+// it reports whether anything changed
+func (t *Task) StartSeries() bool {
+	if t.Recurrence == "" || t.SeriesID != nil {
+		return false
+	}
+	id := t.ID
+	t.SeriesID, t.Occurrence = &id, 1
+	return true
+}
+
+// NextInSeries builds the occurrence after t, or nil when t doesn't recur or
+// its series is over
+func (t Task) NextInSeries() *Task {
+	if t.Recurrence == "" || t.DueAt == nil {
+		return nil
+	}
+	r, err := ParseRecurrence(t.Recurrence)
+	if err != nil {
+		return nil
+	}
+	if r.Location, err = t.location(); err != nil {
+		return nil
+	}
+	due, ok := r.Next(*t.DueAt, t.Occurrence)
+	if !ok {
+		return nil
+	}
+
+	next := &Task{
+		Title:       t.Title,
+		Description: t.Description,
+		Priority:    t.Priority,
+		Recurrence:  t.Recurrence,
+		Timezone:    t.Timezone,
+		SeriesID:    t.SeriesID,
+		Occurrence:  t.Occurrence + 1,
+		DueAt:       &due,
+		ProjectID:   t.ProjectID,
+		OrgID:       t.OrgID,
+	}
+	if t.StartAt != nil {
+		start := t.StartAt.Add(due.Sub(*t.DueAt))
+		next.StartAt = &start
+	}
+	return next
+}
+
+// Overdue reports whether an open task is past its due date
+func (t Task) Overdue(now time.Time) bool {
+	return !t.Completed && t.DueAt != nil && t.DueAt.Before(now)
+}
+
+// UTC returns a copy of ts in UTC. Timestamps are stored in UTC so that every
+// backend compares them the same way.
+func UTC(ts *time.Time) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	utc := ts.UTC()
+	return &utc
+}
+
+// ParseTime accepts RFC 3339 timestamps, or dates which are read as midnight
+// in loc. The result is always in UTC.
+func ParseTime(value string, loc *time.Location) (time.Time, error) {
+	if ts, err := time.Parse(time.RFC3339, value); err == nil {
+		return ts.UTC(), nil
+	}
+	ts, err := time.ParseInLocation("2006-01-02", value, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return ts.UTC(), nil
+}
+
+type Role string
+
+const (
+	RoleOwner  Role = "owner"
+	RoleEditor Role = "editor"
+	RoleViewer Role = "viewer"
+
+	// RoleMember is the organization role of everyone but the owners
+	RoleMember Role = "member"
+)
+
+var roleRank = map[Role]int{RoleViewer: 1, RoleEditor: 2, RoleOwner: 3}
+
+// AtLeast reports whether r grants everything min does
+func (r Role) AtLeast(min Role) bool {
+	return roleRank[r] >= roleRank[min]
+}
+
+// TaskDependency says TaskID can't be completed before BlockerID
+type TaskDependency struct {
+	TaskID    uint `gorm:"primary_key;auto_increment:false"`
+	BlockerID uint `gorm:"primary_key;auto_increment:false"`
+}
+
+// Organization is a tenant, users only see the tasks, projects and labels
+// of the organization they are signed in to
+type Organization struct {
+	gorm.Model
+	Name    string      `gorm:"type:varchar(50);not null" json:"name" validate:"required,lte=50"`
+	Members []OrgMember `gorm:"-" json:"members,omitempty"`
+}
+
+// OrgMember is a row of the org_members table, owners manage the members
+type OrgMember struct {
+	OrgID  uint  `gorm:"primary_key;auto_increment:false" json:"-"`
+	UserID uint  `gorm:"primary_key;auto_increment:false" json:"-"`
+	Role   Role  `gorm:"type:varchar(10);not null" json:"role"`
+	User   *User `gorm:"-" json:"user,omitempty"`
+	// Username is copied from the user so that it can be unique per
+	// organization
+	Username string `gorm:"type:varchar(50);not null" json:"-"`
+}
+
+type AddOrgMember struct {
+	Role Role `json:"role" validate:"omitempty,oneof=owner member"`
+}
+
+// Project groups tasks, its members can reach every task in it with their
+// project role
+type Project struct {
+	gorm.Model
+	Name        string          `gorm:"type:varchar(50);not null" json:"name" validate:"required,lte=50"`
+	Description string          `gorm:"type:varchar(200)" json:"description" validate:"lte=200"`
+	OrgID       uint            `json:"org_id"`
+	Members     []ProjectMember `gorm:"-" json:"members,omitempty"`
+}
+
+type UpdateProject struct {
+	Name        string `json:"name" validate:"omitempty,lte=50"`
+	Description string `json:"description" validate:"omitempty,lte=200"`
+}
+
+// ProjectMember is a row of the project_members table
+type ProjectMember struct {
+	ProjectID uint  `gorm:"primary_key;auto_increment:false" json:"-"`
+	UserID    uint  `gorm:"primary_key;auto_increment:false" json:"-"`
+	Role      Role  `gorm:"type:varchar(10);not null" json:"role"`
+	User      *User `gorm:"-" json:"user,omitempty"`
 }
 
+type AddProjectMember struct {
+	Role Role `json:"role" validate:"omitempty,oneof=owner editor viewer"`
+}
+
+// Label belongs to a single user, who can put it on any task they can see
+type Label struct {
+	ID        uint      `json:"id" gorm:"primary_key"`
+	CreatedAt time.Time `json:"-"`
+	UpdatedAt time.Time `json:"-"`
+	UserID    uint      `json:"-" gorm:"not null"`
+	OrgID     uint      `json:"-" gorm:"not null"`
+	Name      string    `gorm:"type:varchar(50);not null" json:"name" validate:"required,lte=50"`
+	Color     string    `gorm:"type:varchar(7)" json:"color" validate:"omitempty,hexcolor"`
+}
+
+type UpdateLabel struct {
+	Name  string `json:"name" validate:"omitempty,lte=50"`
+	Color string `json:"color" validate:"omitempty,hexcolor"`
+}
+
+// TaskLabel is a row of the task_labels join table
+type TaskLabel struct {
+	TaskID  uint `gorm:"primary_key;auto_increment:false"`
+	LabelID uint `gorm:"primary_key;auto_increment:false"`
+}
+
+// Comment is posted on a task by one of its members, replies point at the
+// comment they answer with ParentID
+type Comment struct {
+	gorm.Model
+	TaskID   uint   `gorm:"not null" json:"task_id"`
+	UserID   uint   `gorm:"not null" json:"user_id"`
+	ParentID *uint  `json:"parent_id,omitempty"`
+	Body     string `gorm:"type:varchar(2000);not null" json:"body" validate:"required,lte=2000"`
+
+	Author   *User      `gorm:"-" json:"author,omitempty"`
+	Mentions []*User    `gorm:"-" json:"mentions,omitempty"`
+	Replies  []*Comment `gorm:"-" json:"replies,omitempty"`
+}
+
+type UpdateComment struct {
+	Body string `json:"body" validate:"required,lte=2000"`
+}
+
+// CommentMention is a row of the comment_mentions join table
+type CommentMention struct {
+	CommentID uint `gorm:"primary_key;auto_increment:false"`
+	UserID    uint `gorm:"primary_key;auto_increment:false"`
+}
+
+// CommentThreads nests replies under the comment they answer and returns
+// the top level comments, replies to missing comments are dropped
+func CommentThreads(comments []Comment) []*Comment {
+	byID := map[uint]*Comment{}
+	for i := range comments {
+		byID[comments[i].ID] = &comments[i]
+	}
+	threads := []*Comment{}
+	for i := range comments {
+		c := &comments[i]
+		if c.ParentID == nil {
+			threads = append(threads, c)
+		} else if parent, ok := byID[*c.ParentID]; ok {
+			parent.Replies = append(parent.Replies, c)
+		}
+	}
+	return threads
+}
+
+// Attachment describes a file uploaded to a task, the contents are kept in
+// blob storage under Key
+type Attachment struct {
+	gorm.Model
+	TaskID      uint   `gorm:"not null" json:"task_id"`
+	UserID      uint   `gorm:"not null" json:"user_id"`
+	Filename    string `gorm:"type:varchar(255);not null" json:"filename"`
+	ContentType string `gorm:"type:varchar(100);not null" json:"content_type"`
+	Size        int64  `gorm:"not null" json:"size"`
+	Key         string `gorm:"column:blob_key;type:varchar(255);not null" json:"-"`
+}
+
+// ChecklistItem is a lightweight step of a task, items are numbered from 0
+// by Position without gaps
+type ChecklistItem struct {
+	ID        uint      `json:"id" gorm:"primary_key"`
+	CreatedAt time.Time `json:"-"`
+	UpdatedAt time.Time `json:"-"`
+	TaskID    uint      `json:"task_id" gorm:"not null"`
+	Text      string    `gorm:"type:varchar(200);not null" json:"text" validate:"required,lte=200"`
+	Done      bool      `json:"done" gorm:"not null;default:false"`
+	Position  int       `json:"position" gorm:"not null"`
+}
+
+type AddChecklistItem struct {
+	Text string `json:"text" validate:"required,lte=200"`
+	// Position inserts the item there instead of at the end
+	Position *int `json:"position" validate:"omitempty,gte=0"`
+}
+
+type ReorderChecklist struct {
+	// Order lists every item ID of the checklist in the new order
+	Order []uint `json:"order" validate:"required"`
+}
+
+// UserTask is a row of the user_tasks join table
+type UserTask struct {
+	UserID uint `gorm:"primary_key;auto_increment:false"`
+	TaskID uint `gorm:"primary_key;auto_increment:false"`
+	Role   Role `gorm:"type:varchar(10);not null"`
+}
+
+type AddUserToTask struct {
+	Role Role `json:"role" validate:"omitempty,oneof=owner editor viewer"`
+}
+
+// UpdateTask is a patch of the editable fields of a task, either an RFC 7396
+// merge patch in Merge or an RFC 6902 JSON Patch in Ops, see Apply
 type UpdateTask struct {
-	Priority  string `json:"priority" validate:"omitempty,oneof=1 2 3"`
-	Completed bool   `json:"completed" validate:"omitempty"`
+	Merge map[string]json.RawMessage
+	Ops   []PatchOp
+	// Force completes a task even though its blockers are still open
+	Force bool
+	// IfMatch lists the versions the task must be at, any version will do
+	// when it is empty
+	IfMatch []uint
+}
+
+// TaskFilter narrows down and orders the tasks returned by GetTasks
+type TaskFilter struct {
+	Completed *bool
+	Priority  string
+	DueBefore *time.Time
+	DueAfter  *time.Time
+	// Overdue selects open tasks whose due date has passed, or the opposite
+	Overdue *bool
+	// Labels are label names, tasks need any of them or all of them with
+	// AllLabels
+	Labels    []string
+	AllLabels bool
+	ProjectID *uint
+	// IncludeArchived returns archived tasks too
+	IncludeArchived bool
+	// Expr is a filter expression the tasks have to match, see ParseFilter
+	Expr FilterExpr
+
+	// Sort orders the tasks, by ID when it is empty
+	Sort []SortKey
+	// Limit is the size of a page, 0 returns every task
+	Limit int
+	// Cursor picks the page after or before a task, see TaskPage
+	Cursor *Cursor
 }
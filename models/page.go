@@ -0,0 +1,242 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Fields GetTasks can sort by
+const (
+	SortPriority  = "priority"
+	SortCreatedAt = "created_at"
+	SortUpdatedAt = "updated_at"
+	SortDueAt     = "due_at"
+	SortTitle     = "title"
+)
+
+// SortFields lists the fields tasks can be sorted by
+var SortFields = []string{SortPriority, SortCreatedAt, SortUpdatedAt, SortDueAt, SortTitle}
+
+// SortKey orders tasks by one field, ties go to the next key and finally to
+// the task ID
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// ParseSort reads a comma separated list of fields, descending when
+// prefixed with "-"
+func ParseSort(s string) ([]SortKey, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	var keys []SortKey
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ",") {
+		k := SortKey{Field: strings.TrimPrefix(part, "-"), Desc: strings.HasPrefix(part, "-")}
+		if !isSortField(k.Field) {
+			return nil, fmt.Errorf("can't sort by %q", k.Field)
+		}
+		if seen[k.Field] {
+			return nil, fmt.Errorf("%s is listed twice", k.Field)
+		}
+		seen[k.Field] = true
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// FormatSort is the inverse of ParseSort
+func FormatSort(keys []SortKey) string {
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k.Field
+		if k.Desc {
+			parts[i] = "-" + k.Field
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func isSortField(field string) bool {
+	for _, f := range SortFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// CompareTasks orders a and b by keys and then by ID. Tasks without a due
+// date come last whichever way due_at is sorted.
+func CompareTasks(a, b *Task, keys []SortKey) int {
+	for _, k := range keys {
+		var c int
+		switch k.Field {
+		case SortPriority:
+			c = strings.Compare(string(a.Priority), string(b.Priority))
+		case SortCreatedAt:
+			c = compareTimes(a.CreatedAt, b.CreatedAt)
+		case SortUpdatedAt:
+			c = compareTimes(a.UpdatedAt, b.UpdatedAt)
+		case SortTitle:
+			c = strings.Compare(a.Title, b.Title)
+		case SortDueAt:
+			if a.DueAt == nil || b.DueAt == nil {
+				if a.DueAt != nil {
+					return -1
+				}
+				if b.DueAt != nil {
+					return 1
+				}
+				continue
+			}
+			c = compareTimes(*a.DueAt, *b.DueAt)
+		}
+		if k.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case a.ID < b.ID:
+		return -1
+	case a.ID > b.ID:
+		return 1
+	}
+	return 0
+}
+
+func compareTimes(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+	return 0
+}
+
+// ErrInvalidCursor is returned for cursors that weren't made by NewTaskPage
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is the position of a task in a sorted list of tasks. The page it
+// points to starts after the task, or ends before it with Before. It is sent
+// to clients as an opaque string.
+type Cursor struct {
+	// Sort is the order the cursor was made for, see FormatSort
+	Sort   string `json:"sort"`
+	Before bool   `json:"before,omitempty"`
+
+	ID        uint       `json:"id"`
+	Priority  Priority   `json:"priority,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	DueAt     *time.Time `json:"due_at,omitempty"`
+	Title     string     `json:"title,omitempty"`
+}
+
+// cursorFields keeps Cursor's own MarshalJSON from calling itself
+type cursorFields Cursor
+
+// newCursor keeps the fields of t that keys sort by
+func newCursor(t *Task, keys []SortKey, before bool) *Cursor {
+	c := &Cursor{Sort: FormatSort(keys), Before: before, ID: t.ID}
+	for _, k := range keys {
+		switch k.Field {
+		case SortPriority:
+			c.Priority = t.Priority
+		case SortCreatedAt:
+			createdAt := t.CreatedAt
+			c.CreatedAt = &createdAt
+		case SortUpdatedAt:
+			updatedAt := t.UpdatedAt
+			c.UpdatedAt = &updatedAt
+		case SortDueAt:
+			c.DueAt = t.DueAt
+		case SortTitle:
+			c.Title = t.Title
+		}
+	}
+	return c
+}
+
+// ParseCursor decodes a cursor sent by a client
+func ParseCursor(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c cursorFields
+	if err := json.Unmarshal(b, &c); err != nil || c.ID == 0 {
+		return nil, ErrInvalidCursor
+	}
+	return (*Cursor)(&c), nil
+}
+
+func (c *Cursor) MarshalJSON() ([]byte, error) {
+	b, err := json.Marshal((*cursorFields)(c))
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+// Task is a stand-in for the task at the cursor, good for CompareTasks
+func (c *Cursor) Task() *Task {
+	t := &Task{Priority: c.Priority, DueAt: c.DueAt, Title: c.Title}
+	t.ID = c.ID
+	if c.CreatedAt != nil {
+		t.CreatedAt = *c.CreatedAt
+	}
+	if c.UpdatedAt != nil {
+		t.UpdatedAt = *c.UpdatedAt
+	}
+	return t
+}
+
+// TaskPage is one page of GetTasks with cursors to the pages on either
+// side, which are nil where there are no more tasks
+type TaskPage struct {
+	Tasks []Task
+	Next  *Cursor
+	Prev  *Cursor
+}
+
+// NewTaskPage makes a page of tasks that were read in the direction of
+// filter.Cursor. Stores read one task more than filter.Limit to tell whether
+// there are more beyond the page.
+func NewTaskPage(tasks []Task, filter TaskFilter) *TaskPage {
+	before := filter.Cursor != nil && filter.Cursor.Before
+	more := filter.Limit > 0 && len(tasks) > filter.Limit
+	if more {
+		tasks = tasks[:filter.Limit]
+	}
+	if before {
+		for i, j := 0, len(tasks)-1; i < j; i, j = i+1, j-1 {
+			tasks[i], tasks[j] = tasks[j], tasks[i]
+		}
+	}
+
+	page := &TaskPage{Tasks: tasks}
+	if len(tasks) == 0 {
+		return page
+	}
+
+	// the side the page was read from has more tasks whenever there is a cursor
+	if more || before {
+		page.Next = newCursor(&tasks[len(tasks)-1], filter.Sort, false)
+	}
+	if (more && before) || (filter.Cursor != nil && !before) {
+		page.Prev = newCursor(&tasks[0], filter.Sort, true)
+	}
+	return page
+}
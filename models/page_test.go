@@ -0,0 +1,104 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCompareTasks(t *testing.T) {
+	early := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	late := early.Add(24 * time.Hour)
+	task := func(id uint, priority Priority, due *time.Time) *Task {
+		t := &Task{Priority: priority, DueAt: due}
+		t.ID = id
+		return t
+	}
+
+	tests := []struct {
+		name string
+		a, b *Task
+		sort string
+		want int
+	}{
+		{"by id", task(1, "3", nil), task(2, "1", nil), "", -1},
+		{"priority", task(1, "3", nil), task(2, "1", nil), "priority", 1},
+		{"priority descending", task(1, "3", nil), task(2, "1", nil), "-priority", -1},
+		{"priority tie goes to id", task(2, "2", nil), task(1, "2", nil), "-priority", 1},
+		{"due_at", task(1, "1", &late), task(2, "1", &early), "due_at", 1},
+		{"due_at descending", task(1, "1", &late), task(2, "1", &early), "-due_at", -1},
+		{"no due date last", task(1, "1", nil), task(2, "1", &late), "due_at", 1},
+		{"no due date last descending", task(1, "1", nil), task(2, "1", &late), "-due_at", 1},
+		{"no due date on both goes to the next key", task(1, "1", nil), task(2, "3", nil), "due_at,-priority", 1},
+		{"same task", task(1, "1", &early), task(1, "1", &early), "due_at,priority", 0},
+	}
+	for _, tt := range tests {
+		keys, err := ParseSort(tt.sort)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := CompareTasks(tt.a, tt.b, keys); got != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNewTaskPage(t *testing.T) {
+	read := func(ids ...uint) []Task {
+		tasks := make([]Task, len(ids))
+		for i, id := range ids {
+			tasks[i].ID = id
+		}
+		return tasks
+	}
+	after := &Cursor{ID: 1}
+	before := &Cursor{ID: 9, Before: true}
+
+	tests := []struct {
+		name       string
+		tasks      []Task
+		cursor     *Cursor
+		want       []uint
+		next, prev uint
+	}{
+		{"first page", read(1, 2, 3), nil, []uint{1, 2}, 2, 0},
+		{"only page", read(1, 2), nil, []uint{1, 2}, 0, 0},
+		{"page after a cursor", read(2, 3, 4), after, []uint{2, 3}, 3, 2},
+		{"last page", read(2, 3), after, []uint{2, 3}, 0, 2},
+		// pages before a cursor are read backwards
+		{"page before a cursor", read(8, 7, 6), before, []uint{7, 8}, 8, 7},
+		{"first page read backwards", read(8, 7), before, []uint{7, 8}, 8, 0},
+		{"nothing past the cursor", read(), after, nil, 0, 0},
+	}
+	for _, tt := range tests {
+		page := NewTaskPage(tt.tasks, TaskFilter{Limit: 2, Cursor: tt.cursor})
+		var got []uint
+		for _, task := range page.Tasks {
+			got = append(got, task.ID)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got tasks %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: got tasks %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+		for _, c := range []struct {
+			name   string
+			cursor *Cursor
+			id     uint
+			before bool
+		}{{"next", page.Next, tt.next, false}, {"prev", page.Prev, tt.prev, true}} {
+			switch {
+			case c.id == 0 && c.cursor != nil:
+				t.Errorf("%s: got a %s cursor at %d, want none", tt.name, c.name, c.cursor.ID)
+			case c.id != 0 && c.cursor == nil:
+				t.Errorf("%s: got no %s cursor, want one at %d", tt.name, c.name, c.id)
+			case c.cursor != nil && (c.cursor.ID != c.id || c.cursor.Before != c.before):
+				t.Errorf("%s: got a %s cursor at %d before %v, want %d", tt.name, c.name, c.cursor.ID, c.cursor.Before, c.id)
+			}
+		}
+	}
+}
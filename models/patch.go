@@ -0,0 +1,258 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// PatchOp is one operation of an RFC 6902 JSON Patch
+type PatchOp struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// PatchError is returned for patches that can't be applied or that would
+// leave the task invalid
+type PatchError struct {
+	msg string
+}
+
+func (e *PatchError) Error() string {
+	return e.msg
+}
+
+func patchErrorf(format string, args ...interface{}) error {
+	return &PatchError{fmt.Sprintf(format, args...)}
+}
+
+// ErrPatchTest is returned when a test operation of a JSON Patch fails
+var ErrPatchTest = errors.New("patch test failed")
+
+// editableTask holds the fields a patch can change, as they are named in JSON.
+// Empty optional fields are left out like they are from a task.
+type editableTask struct {
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Priority    Priority   `json:"priority"`
+	Completed   bool       `json:"completed"`
+	StartAt     *time.Time `json:"start_at,omitempty"`
+	DueAt       *time.Time `json:"due_at,omitempty"`
+	Recurrence  string     `json:"recurrence,omitempty"`
+	Timezone    string     `json:"timezone,omitempty"`
+}
+
+// requiredFields can be changed but not removed or set to null
+var requiredFields = []string{"title", "description", "priority", "completed"}
+
+var editableFields = map[string]bool{
+	"title": true, "description": true, "priority": true, "completed": true,
+	"start_at": true, "due_at": true, "recurrence": true, "timezone": true,
+}
+
+// Apply patches the editable fields of t and checks the result, leaving t
+// alone when it fails. Completion times and series are up to the store.
+func (p UpdateTask) Apply(t *Task) error {
+	doc, err := editableDoc(t)
+	if err != nil {
+		return err
+	}
+
+	if p.Ops != nil {
+		err = applyOps(doc, p.Ops)
+	} else {
+		err = applyMerge(doc, p.Merge)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, name := range requiredFields {
+		if v, ok := doc[name]; !ok || bytes.Equal(v, []byte("null")) {
+			return patchErrorf("%s can't be removed", name)
+		}
+	}
+	var e editableTask
+	for name, v := range doc {
+		if err := json.Unmarshal(v, e.field(name)); err != nil {
+			return patchErrorf("invalid %s", name)
+		}
+	}
+
+	patched := *t
+	patched.Title, patched.Description = e.Title, e.Description
+	patched.Priority, patched.Completed = e.Priority, e.Completed
+	patched.StartAt, patched.DueAt = UTC(e.StartAt), UTC(e.DueAt)
+	patched.Recurrence, patched.Timezone = e.Recurrence, e.Timezone
+	if err := patched.checkEditable(); err != nil {
+		return err
+	}
+	if err := patched.CheckSchedule(); err != nil {
+		return err
+	}
+	if err := patched.CheckRecurrence(); err != nil {
+		if err == ErrRecurrenceNeedsDue {
+			return err
+		}
+		return patchErrorf("invalid recurrence: %s", err)
+	}
+	*t = patched
+	return nil
+}
+
+// checkEditable enforces the limits that creating a task gets from its
+// validate tags and columns
+func (t *Task) checkEditable() error {
+	switch {
+	case t.Title == "":
+		return patchErrorf("title is required")
+	case utf8.RuneCountInString(t.Title) > 50:
+		return patchErrorf("title must be at most 50 characters")
+	case t.Description == "":
+		return patchErrorf("description is required")
+	case utf8.RuneCountInString(t.Description) > 200:
+		return patchErrorf("description must be at most 200 characters")
+	case len(t.Timezone) > 64:
+		return patchErrorf("timezone must be at most 64 characters")
+	case t.Priority != "1" && t.Priority != "2" && t.Priority != "3":
+		return patchErrorf("priority must be 1, 2 or 3")
+	}
+	return nil
+}
+
+// editableDoc is the JSON object patches are applied to
+func editableDoc(t *Task) (map[string]json.RawMessage, error) {
+	b, err := json.Marshal(editableTask{
+		Title: t.Title, Description: t.Description, Priority: t.Priority, Completed: t.Completed,
+		StartAt: t.StartAt, DueAt: t.DueAt, Recurrence: t.Recurrence, Timezone: t.Timezone,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]json.RawMessage
+	return doc, json.Unmarshal(b, &doc)
+}
+
+func (e *editableTask) field(name string) interface{} {
+	switch name {
+	case "title":
+		return &e.Title
+	case "description":
+		return &e.Description
+	case "priority":
+		return &e.Priority
+	case "completed":
+		return &e.Completed
+	case "start_at":
+		return &e.StartAt
+	case "due_at":
+		return &e.DueAt
+	case "timezone":
+		return &e.Timezone
+	default:
+		return &e.Recurrence
+	}
+}
+
+// applyMerge follows RFC 7396, which comes down to setting or removing
+// fields since every editable field is a scalar
+func applyMerge(doc, merge map[string]json.RawMessage) error {
+	for name, v := range merge {
+		if !editableFields[name] {
+			return patchErrorf("%s can't be changed", name)
+		}
+		if bytes.Equal(bytes.TrimSpace(v), []byte("null")) {
+			delete(doc, name)
+			continue
+		}
+		doc[name] = v
+	}
+	return nil
+}
+
+// applyOps follows RFC 6902 for paths that name a single editable field
+func applyOps(doc map[string]json.RawMessage, ops []PatchOp) error {
+	for i, op := range ops {
+		name, err := opField(op.Path)
+		if err != nil {
+			return patchErrorf("operation %d: %s", i, err)
+		}
+		_, exists := doc[name]
+
+		switch op.Op {
+		case "add", "replace":
+			if op.Value == nil {
+				return patchErrorf("operation %d: missing value", i)
+			}
+			if op.Op == "replace" && !exists {
+				return patchErrorf("operation %d: %s is not set", i, name)
+			}
+			doc[name] = op.Value
+		case "remove":
+			if !exists {
+				return patchErrorf("operation %d: %s is not set", i, name)
+			}
+			delete(doc, name)
+		case "move", "copy":
+			from, err := opField(op.From)
+			if err != nil {
+				return patchErrorf("operation %d: from %s", i, err)
+			}
+			v, ok := doc[from]
+			if !ok {
+				return patchErrorf("operation %d: %s is not set", i, from)
+			}
+			if op.Op == "move" {
+				delete(doc, from)
+			}
+			doc[name] = v
+		case "test":
+			if op.Value == nil {
+				return patchErrorf("operation %d: missing value", i)
+			}
+			equal, err := jsonEqual(doc[name], op.Value)
+			if err != nil {
+				return patchErrorf("operation %d: invalid value", i)
+			}
+			if !equal {
+				return ErrPatchTest
+			}
+		default:
+			return patchErrorf("operation %d: unknown op %q", i, op.Op)
+		}
+	}
+	return nil
+}
+
+// opField returns the editable field a JSON Pointer names
+func opField(path string) (string, error) {
+	if !strings.HasPrefix(path, "/") || strings.Count(path, "/") != 1 {
+		return "", fmt.Errorf("path %q must name a field", path)
+	}
+	name := strings.NewReplacer("~1", "/", "~0", "~").Replace(path[1:])
+	if !editableFields[name] {
+		return "", fmt.Errorf("%s can't be changed", name)
+	}
+	return name, nil
+}
+
+// jsonEqual compares JSON values, a missing field equals null
+func jsonEqual(a, b json.RawMessage) (bool, error) {
+	var va, vb interface{}
+	if a != nil {
+		if err := json.Unmarshal(a, &va); err != nil {
+			return false, err
+		}
+	}
+	if err := json.Unmarshal(b, &vb); err != nil {
+		return false, err
+	}
+	return reflect.DeepEqual(va, vb), nil
+}
@@ -0,0 +1,142 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// patchTask is a task with every editable field set
+func patchTask() Task {
+	start := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	due := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	done := due.Add(-time.Hour)
+	return Task{
+		Title: "task", Description: "d", Priority: "2", Completed: true, CompletedAt: &done,
+		StartAt: &start, DueAt: &due, Recurrence: "FREQ=DAILY", Timezone: "UTC",
+	}
+}
+
+func merge(t *testing.T, patch string) UpdateTask {
+	t.Helper()
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(patch), &m); err != nil {
+		t.Fatal(err)
+	}
+	return UpdateTask{Merge: m}
+}
+
+func ops(t *testing.T, patch string) UpdateTask {
+	t.Helper()
+	var o []PatchOp
+	if err := json.Unmarshal([]byte(patch), &o); err != nil {
+		t.Fatal(err)
+	}
+	return UpdateTask{Ops: o}
+}
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+		want  func(t *Task)
+	}{
+		{"nothing", `{}`, func(t *Task) {}},
+		{"set", `{"title":"renamed","priority":"3"}`, func(t *Task) { t.Title, t.Priority = "renamed", "3" }},
+		{"null clears optional fields", `{"recurrence":null,"timezone":null}`, func(t *Task) { t.Recurrence, t.Timezone = "", "" }},
+		{"null clears the schedule", `{"start_at":null,"due_at":null,"recurrence":null}`, func(t *Task) {
+			t.StartAt, t.DueAt, t.Recurrence = nil, nil, ""
+		}},
+		// the store clears CompletedAt once it sees the task reopened
+		{"reopen", `{"completed":false}`, func(t *Task) { t.Completed = false }},
+	}
+	for _, tt := range tests {
+		task, want := patchTask(), patchTask()
+		tt.want(&want)
+		if err := merge(t, tt.patch).Apply(&task); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(task, want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, task, want)
+		}
+	}
+}
+
+func TestJSONPatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+		want  func(t *Task)
+	}{
+		{"replace", `[{"op":"replace","path":"/title","value":"renamed"}]`, func(t *Task) { t.Title = "renamed" }},
+		{"remove", `[{"op":"remove","path":"/recurrence"}]`, func(t *Task) { t.Recurrence = "" }},
+		{"passing test", `[{"op":"test","path":"/priority","value":"2"},{"op":"add","path":"/priority","value":"1"}]`, func(t *Task) {
+			t.Priority = "1"
+		}},
+		{"test of an unset field", `[{"op":"remove","path":"/timezone"},{"op":"test","path":"/timezone","value":null}]`, func(t *Task) {
+			t.Timezone = ""
+		}},
+		{"copy", `[{"op":"copy","from":"/title","path":"/description"}]`, func(t *Task) { t.Description = "task" }},
+		{"move", `[{"op":"move","from":"/start_at","path":"/due_at"}]`, func(t *Task) { t.DueAt, t.StartAt = t.StartAt, nil }},
+	}
+	for _, tt := range tests {
+		task, want := patchTask(), patchTask()
+		tt.want(&want)
+		if err := ops(t, tt.patch).Apply(&task); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(task, want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, task, want)
+		}
+	}
+}
+
+func TestPatchErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch UpdateTask
+		want  error
+	}{
+		{"merge null title", merge(t, `{"title":null}`), &PatchError{"title can't be removed"}},
+		{"merge null completed", merge(t, `{"completed":null}`), &PatchError{"completed can't be removed"}},
+		{"merge unknown field", merge(t, `{"id":2}`), &PatchError{"id can't be changed"}},
+		{"merge empty title", merge(t, `{"title":""}`), &PatchError{"title is required"}},
+		{"merge wrong type", merge(t, `{"completed":"yes"}`), &PatchError{"invalid completed"}},
+		{"merge invalid priority", merge(t, `{"priority":"4"}`), &PatchError{"priority must be 1, 2 or 3"}},
+		{"merge recurrence without due", merge(t, `{"due_at":null,"start_at":null}`), ErrRecurrenceNeedsDue},
+		{"failed test", ops(t, `[{"op":"test","path":"/title","value":"other"}]`), ErrPatchTest},
+		{"remove title", ops(t, `[{"op":"remove","path":"/title"}]`), &PatchError{"title can't be removed"}},
+		{"remove priority", ops(t, `[{"op":"remove","path":"/priority"}]`), &PatchError{"priority can't be removed"}},
+		{"remove unset field", ops(t, `[{"op":"remove","path":"/timezone"},{"op":"remove","path":"/timezone"}]`),
+			&PatchError{"operation 1: timezone is not set"}},
+		{"remove unknown field", ops(t, `[{"op":"remove","path":"/id"}]`), &PatchError{"operation 0: id can't be changed"}},
+		{"remove nested path", ops(t, `[{"op":"remove","path":"/title/0"}]`), &PatchError{`operation 0: path "/title/0" must name a field`}},
+		{"remove escaped path", ops(t, `[{"op":"remove","path":"/due~1at~0"}]`), &PatchError{"operation 0: due/at~ can't be changed"}},
+		{"remove root", ops(t, `[{"op":"remove","path":""}]`), &PatchError{`operation 0: path "" must name a field`}},
+		{"move from unset field", ops(t, `[{"op":"remove","path":"/timezone"},{"op":"move","from":"/timezone","path":"/recurrence"}]`),
+			&PatchError{"operation 1: timezone is not set"}},
+		{"move from invalid path", ops(t, `[{"op":"move","from":"title","path":"/description"}]`),
+			&PatchError{`operation 0: from path "title" must name a field`}},
+		{"move a required field away", ops(t, `[{"op":"move","from":"/title","path":"/recurrence"}]`), &PatchError{"title can't be removed"}},
+		{"copy to unknown field", ops(t, `[{"op":"copy","from":"/title","path":"/owner"}]`), &PatchError{"operation 0: owner can't be changed"}},
+		{"copy from unknown field", ops(t, `[{"op":"copy","from":"/id","path":"/title"}]`), &PatchError{"operation 0: from id can't be changed"}},
+		{"replace unset field", ops(t, `[{"op":"remove","path":"/timezone"},{"op":"replace","path":"/timezone","value":"UTC"}]`),
+			&PatchError{"operation 1: timezone is not set"}},
+		{"add without value", ops(t, `[{"op":"add","path":"/title"}]`), &PatchError{"operation 0: missing value"}},
+		{"unknown op", ops(t, `[{"op":"increment","path":"/priority"}]`), &PatchError{`operation 0: unknown op "increment"`}},
+	}
+	for _, tt := range tests {
+		task := patchTask()
+		err := tt.patch.Apply(&task)
+		if !reflect.DeepEqual(err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+		// a failed patch leaves the task alone
+		if !reflect.DeepEqual(task, patchTask()) {
+			t.Errorf("%s: task changed to %+v", tt.name, task)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	Daily   = "DAILY"
+	Weekly  = "WEEKLY"
+	Monthly = "MONTHLY"
+	Yearly  = "YEARLY"
+)
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// Recurrence is the subset of an RFC 5545 RRULE that tasks support, e.g.
+// FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;COUNT=10. Dates are computed in
+// Location, so weekdays and days of the month are the ones seen there.
+type Recurrence struct {
+	Freq     string
+	Interval int
+	ByDay    []time.Weekday
+	Until    *time.Time
+	// UntilDate is set for an UNTIL without a time, which takes in the whole
+	// day in Location
+	UntilDate bool
+	Count     int
+	// Location is UTC when nil
+	Location *time.Location
+}
+
+// ParseRecurrence reads an RRULE string, a leading "RRULE:" is allowed
+func ParseRecurrence(rule string) (*Recurrence, error) {
+	r := &Recurrence{Interval: 1}
+	rule = strings.TrimPrefix(strings.TrimSpace(rule), "RRULE:")
+
+	for _, part := range strings.Split(rule, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid recurrence part %q", part)
+		}
+		key, value := strings.ToUpper(kv[0]), strings.ToUpper(kv[1])
+
+		switch key {
+		case "FREQ":
+			switch value {
+			case Daily, Weekly, Monthly, Yearly:
+				r.Freq = value
+			default:
+				return nil, fmt.Errorf("unsupported FREQ %q", value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid INTERVAL %q", value)
+			}
+			r.Interval = n
+		case "BYDAY":
+			for _, day := range strings.Split(value, ",") {
+				wd, ok := weekdays[day]
+				if !ok {
+					return nil, fmt.Errorf("invalid BYDAY %q", day)
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		case "UNTIL":
+			until, date, err := parseUntil(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid UNTIL %q", value)
+			}
+			r.Until, r.UntilDate = &until, date
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid COUNT %q", value)
+			}
+			r.Count = n
+		default:
+			return nil, fmt.Errorf("unsupported recurrence part %q", key)
+		}
+	}
+
+	if r.Freq == "" {
+		return nil, fmt.Errorf("recurrence needs a FREQ")
+	}
+	if len(r.ByDay) > 0 && r.Freq != Weekly {
+		return nil, fmt.Errorf("BYDAY is only supported with FREQ=WEEKLY")
+	}
+	if r.Until != nil && r.Count > 0 {
+		return nil, fmt.Errorf("UNTIL and COUNT can't be combined")
+	}
+	return r, nil
+}
+
+// parseUntil reads an UNTIL value and reports whether it is a date
+func parseUntil(value string) (time.Time, bool, error) {
+	if t, err := time.Parse("20060102", value); err == nil {
+		return t, true, nil
+	}
+	if t, err := time.Parse("20060102T150405Z", value); err == nil {
+		return t, false, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	return t, false, err
+}
+
+// Next returns the occurrence that follows prev, the due date of occurrence
+// number n of the series. ok is false once the series is over.
+func (r *Recurrence) Next(prev time.Time, n int) (next time.Time, ok bool) {
+	if r.Count > 0 && n >= r.Count {
+		return time.Time{}, false
+	}
+
+	loc := r.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	prev = prev.In(loc)
+	switch r.Freq {
+	case Daily:
+		next = prev.AddDate(0, 0, r.Interval)
+	case Weekly:
+		next = r.nextWeekly(prev)
+	case Monthly:
+		next = addKeepingDay(prev, 0, r.Interval)
+	case Yearly:
+		next = addKeepingDay(prev, r.Interval, 0)
+	}
+
+	if r.Until != nil {
+		end := *r.Until
+		if r.UntilDate {
+			year, month, day := r.Until.Date()
+			end = time.Date(year, month, day+1, 0, 0, 0, 0, loc).Add(-time.Nanosecond)
+		}
+		if next.After(end) {
+			return time.Time{}, false
+		}
+	}
+	return next.UTC(), true
+}
+
+// nextWeekly finds the next listed weekday, moving on by INTERVAL weeks once
+// prev's week is used up
+func (r *Recurrence) nextWeekly(prev time.Time) time.Time {
+	if len(r.ByDay) == 0 {
+		return prev.AddDate(0, 0, 7*r.Interval)
+	}
+
+	days := append([]time.Weekday{}, r.ByDay...)
+	sort.Slice(days, func(i, j int) bool { return weekOffset(days[i]) < weekOffset(days[j]) })
+
+	// weeks start on Monday as in RFC 5545
+	for _, day := range days {
+		if weekOffset(day) > weekOffset(prev.Weekday()) {
+			return prev.AddDate(0, 0, weekOffset(day)-weekOffset(prev.Weekday()))
+		}
+	}
+	weekStart := prev.AddDate(0, 0, -weekOffset(prev.Weekday()))
+	return weekStart.AddDate(0, 0, 7*r.Interval+weekOffset(days[0]))
+}
+
+func weekOffset(d time.Weekday) int {
+	return (int(d) + 6) % 7
+}
+
+// addKeepingDay adds years and months, skipping periods that don't have the
+// day of month, so Jan 31 monthly goes to Mar 31 rather than Mar 3
+func addKeepingDay(t time.Time, years, months int) time.Time {
+	for i := 1; ; i++ {
+		next := t.AddDate(years*i, months*i, 0)
+		if next.Day() == t.Day() {
+			return next
+		}
+	}
+}
+
+// String formats the rule back to RRULE syntax
+func (r *Recurrence) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		var days []string
+		for _, wd := range r.ByDay {
+			days = append(days, strings.ToUpper(wd.String()[:2]))
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.UntilDate {
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+	} else if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	return strings.Join(parts, ";")
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecurrenceInTimezone(t *testing.T) {
+	la, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Skip("no timezone database:", err)
+	}
+	// Monday evening in Los Angeles is Tuesday in UTC
+	due := time.Date(2026, 3, 2, 18, 0, 0, 0, la).UTC()
+
+	tests := []struct {
+		rule     string
+		timezone string
+		want     []time.Time
+	}{
+		{"FREQ=WEEKLY;BYDAY=MO,WE", "America/Los_Angeles", []time.Time{
+			time.Date(2026, 3, 4, 18, 0, 0, 0, la),
+			time.Date(2026, 3, 9, 18, 0, 0, 0, la),
+		}},
+		{"FREQ=WEEKLY;BYDAY=MO,WE", "", []time.Time{
+			time.Date(2026, 3, 4, 2, 0, 0, 0, time.UTC),
+			time.Date(2026, 3, 9, 2, 0, 0, 0, time.UTC),
+		}},
+		// the wall clock time is kept across the switch to daylight saving time
+		{"FREQ=DAILY;UNTIL=20260309", "America/Los_Angeles", []time.Time{
+			time.Date(2026, 3, 3, 18, 0, 0, 0, la),
+			time.Date(2026, 3, 4, 18, 0, 0, 0, la),
+			time.Date(2026, 3, 5, 18, 0, 0, 0, la),
+			time.Date(2026, 3, 6, 18, 0, 0, 0, la),
+			time.Date(2026, 3, 7, 18, 0, 0, 0, la),
+			time.Date(2026, 3, 8, 18, 0, 0, 0, la),
+			time.Date(2026, 3, 9, 18, 0, 0, 0, la),
+		}},
+	}
+	for _, tt := range tests {
+		task := Task{Recurrence: tt.rule, Timezone: tt.timezone, DueAt: &due}
+		task.ID = 1
+		if err := task.CheckRecurrence(); err != nil {
+			t.Fatal(err)
+		}
+		task.StartSeries()
+
+		var got []time.Time
+		for next := task.NextInSeries(); next != nil && len(got) < 10; next = next.NextInSeries() {
+			got = append(got, *next.DueAt)
+		}
+		if len(got) < len(tt.want) || (strings.Contains(tt.rule, "UNTIL") && len(got) != len(tt.want)) {
+			t.Errorf("%s in %q: got %v, want %v", tt.rule, tt.timezone, got, tt.want)
+			continue
+		}
+		for i, want := range tt.want {
+			if !got[i].Equal(want) {
+				t.Errorf("%s in %q: occurrence %d is %v, want %v", tt.rule, tt.timezone, i+2, got[i], want)
+			}
+		}
+	}
+}
+
+func TestUnknownTimezone(t *testing.T) {
+	due := time.Now()
+	for _, tz := range []string{"Mars/Olympus", "Local"} {
+		task := Task{Recurrence: "FREQ=DAILY", Timezone: tz, DueAt: &due}
+		if err := task.CheckRecurrence(); err == nil {
+			t.Errorf("timezone %q was accepted", tz)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package models
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// TaskSearch is a full-text search over the tasks a user can see
+type TaskSearch struct {
+	Query string
+	// Terms are the words of Query, see SearchTerms
+	Terms           []string
+	Limit           int
+	IncludeArchived bool
+}
+
+// SearchResult is a task matching a search. Matches in the title rank above
+// ones in the description, which rank above ones in comments. Snippet is an
+// HTML excerpt with the matching words wrapped in <mark> tags.
+type SearchResult struct {
+	Task    Task    `json:"task"`
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+// SearchTerms splits a query into lowercase words, dropping punctuation
+func SearchTerms(q string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	for _, term := range words(strings.ToLower(q)) {
+		if !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+func words(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Weights of the parts of a task, the defaults of Postgres' ts_rank
+const (
+	titleWeight       = 1.0
+	descriptionWeight = 0.4
+	commentWeight     = 0.2
+)
+
+type searchPart struct {
+	text   string
+	weight float64
+}
+
+// MatchTask is the search for stores without Postgres' text search. Every
+// term has to start a word of the task or its comments.
+func MatchTask(terms []string, t *Task, comments []string) (SearchResult, bool) {
+	parts := []searchPart{{t.Title, titleWeight}, {t.Description, descriptionWeight}}
+	for _, c := range comments {
+		parts = append(parts, searchPart{c, commentWeight})
+	}
+
+	found := make(map[string]bool)
+	var rank float64
+	for _, p := range parts {
+		for _, w := range words(strings.ToLower(p.text)) {
+			for _, term := range terms {
+				if strings.HasPrefix(w, term) {
+					found[term] = true
+					rank += p.weight
+				}
+			}
+		}
+	}
+	if len(terms) == 0 || len(found) < len(terms) {
+		return SearchResult{}, false
+	}
+
+	texts := make([]string, len(parts))
+	for i, p := range parts {
+		texts[i] = p.text
+	}
+	return SearchResult{Task: *t, Rank: rank, Snippet: snippet(terms, strings.Join(texts, " "))}, true
+}
+
+// snippet picks the words around the first match in text, like ts_headline
+// does with its default MinWords and MaxWords. The words are HTML escaped
+// so that only the <mark> tags are markup.
+func snippet(terms []string, text string) string {
+	fields := strings.Fields(text)
+	matches := func(field string) bool {
+		for _, w := range words(strings.ToLower(field)) {
+			for _, term := range terms {
+				if strings.HasPrefix(w, term) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	start := 0
+	for i, f := range fields {
+		if matches(f) {
+			start = i - 5
+			break
+		}
+	}
+	if start < 0 {
+		start = 0
+	}
+	end := start + 35
+	if end > len(fields) {
+		end = len(fields)
+	}
+
+	out := make([]string, 0, end-start)
+	for _, f := range fields[start:end] {
+		escaped := html.EscapeString(f)
+		if matches(f) {
+			escaped = "<mark>" + escaped + "</mark>"
+		}
+		out = append(out, escaped)
+	}
+	return strings.Join(out, " ")
+}
+
+// SortResults puts the best matches first and keeps the first limit
+func SortResults(results []SearchResult, limit int) []SearchResult {
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Rank != results[j].Rank {
+			return results[i].Rank > results[j].Rank
+		}
+		return results[i].Task.ID < results[j].Task.ID
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
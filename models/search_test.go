@@ -0,0 +1,91 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSearchTerms(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", nil},
+		{"Deploy", []string{"deploy"}},
+		{"deploy, API!  deploy", []string{"deploy", "api"}},
+		{"v2.1 café", []string{"v2", "1", "café"}},
+		{`"'%_--`, nil},
+	}
+	for _, tt := range tests {
+		if got := SearchTerms(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SearchTerms(%q): got %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestMatchTask(t *testing.T) {
+	task := &Task{Title: "Deploy the API", Description: "after the release notes"}
+	comments := []string{"Reviewed by ops", "deploys on Friday"}
+
+	tests := []struct {
+		query string
+		ok    bool
+		rank  float64
+	}{
+		{"deploy", true, titleWeight + commentWeight},
+		{"release", true, descriptionWeight},
+		{"ops", true, commentWeight},
+		// terms match the start of words only
+		{"ploy", false, 0},
+		{"deploy ops", true, titleWeight + 2*commentWeight},
+		// every term has to match
+		{"deploy missing", false, 0},
+		{"", false, 0},
+	}
+	for _, tt := range tests {
+		got, ok := MatchTask(SearchTerms(tt.query), task, comments)
+		if ok != tt.ok {
+			t.Errorf("%q: got match %v, want %v", tt.query, ok, tt.ok)
+			continue
+		}
+		if ok && got.Rank != tt.rank {
+			t.Errorf("%q: got rank %v, want %v", tt.query, got.Rank, tt.rank)
+		}
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	tests := []struct {
+		name  string
+		terms []string
+		text  string
+		want  string
+	}{
+		{"marks every match", []string{"fix"}, "fix the fixtures", "<mark>fix</mark> the <mark>fixtures</mark>"},
+		{"escapes around marks", []string{"fix"}, `a&b <b>"fix"</b>`, `a&amp;b <mark>&lt;b&gt;&#34;fix&#34;&lt;/b&gt;</mark>`},
+		{"starts a few words before the match", []string{"k"},
+			"a b c d e f g h i j k", "f g h i j <mark>k</mark>"},
+	}
+	for _, tt := range tests {
+		if got := snippet(tt.terms, tt.text); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSortResults(t *testing.T) {
+	result := func(id uint, rank float64) SearchResult {
+		r := SearchResult{Rank: rank}
+		r.Task.ID = id
+		return r
+	}
+	results := []SearchResult{result(1, 0.2), result(2, 1), result(3, 0.4), result(4, 1)}
+
+	var got []uint
+	for _, r := range SortResults(results, 3) {
+		got = append(got, r.Task.ID)
+	}
+	if want := []uint{2, 4, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
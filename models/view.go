@@ -0,0 +1,115 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Fields a view can group its tasks by
+const (
+	GroupPriority  = "priority"
+	GroupCompleted = "completed"
+	GroupProject   = "project_id"
+)
+
+// GroupFields lists the fields a view can group its tasks by
+var GroupFields = []string{GroupPriority, GroupCompleted, GroupProject}
+
+// View is a saved task list of one user. Filter and Sort take the same
+// values as the filter and sort parameters of GET /tasks. A shared view can
+// be read by the users the owner shares tasks with.
+type View struct {
+	ID              uint      `json:"id" gorm:"primary_key"`
+	CreatedAt       time.Time `json:"-"`
+	UpdatedAt       time.Time `json:"-"`
+	UserID          uint      `json:"user_id" gorm:"not null"`
+	OrgID           uint      `json:"-" gorm:"not null"`
+	Name            string    `gorm:"type:varchar(50);not null" json:"name" validate:"required,lte=50"`
+	Filter          string    `gorm:"type:varchar(1000);not null" json:"filter" validate:"lte=1000"`
+	Sort            string    `gorm:"type:varchar(100);not null" json:"sort" validate:"lte=100"`
+	GroupBy         string    `gorm:"type:varchar(20);not null" json:"group_by"`
+	IncludeArchived bool      `gorm:"not null;default:false" json:"include_archived"`
+	Shared          bool      `gorm:"not null;default:false" json:"shared"`
+}
+
+// UpdateView changes the fields that are set
+type UpdateView struct {
+	Name            *string `json:"name" validate:"omitempty,gt=0,lte=50"`
+	Filter          *string `json:"filter" validate:"omitempty,lte=1000"`
+	Sort            *string `json:"sort" validate:"omitempty,lte=100"`
+	GroupBy         *string `json:"group_by"`
+	IncludeArchived *bool   `json:"include_archived"`
+	Shared          *bool   `json:"shared"`
+}
+
+// Apply copies the fields that are set onto v
+func (p UpdateView) Apply(v *View) {
+	if p.Name != nil {
+		v.Name = *p.Name
+	}
+	if p.Filter != nil {
+		v.Filter = *p.Filter
+	}
+	if p.Sort != nil {
+		v.Sort = *p.Sort
+	}
+	if p.GroupBy != nil {
+		v.GroupBy = *p.GroupBy
+	}
+	if p.IncludeArchived != nil {
+		v.IncludeArchived = *p.IncludeArchived
+	}
+	if p.Shared != nil {
+		v.Shared = *p.Shared
+	}
+}
+
+// CheckGroupBy returns an error unless field is empty or one of GroupFields
+func CheckGroupBy(field string) error {
+	if field == "" {
+		return nil
+	}
+	for _, f := range GroupFields {
+		if f == field {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid group_by %q, group by %s", field, strings.Join(GroupFields, ", "))
+}
+
+// TaskGroup holds the IDs of the tasks sharing a value of the grouped field,
+// Key is null for tasks without a project
+type TaskGroup struct {
+	Key     interface{} `json:"key"`
+	TaskIDs []uint      `json:"task_ids"`
+}
+
+// GroupTasks groups the tasks of a page by field, in the order the groups
+// first appear
+func GroupTasks(tasks []Task, field string) []TaskGroup {
+	groups := []TaskGroup{}
+	index := make(map[interface{}]int)
+	for _, t := range tasks {
+		var key interface{}
+		switch field {
+		case GroupPriority:
+			key = string(t.Priority)
+		case GroupCompleted:
+			key = t.Completed
+		case GroupProject:
+			if t.ProjectID != nil {
+				key = *t.ProjectID
+			}
+		}
+
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, TaskGroup{Key: key, TaskIDs: []uint{}})
+		}
+		groups[i].TaskIDs = append(groups[i].TaskIDs, t.ID)
+	}
+	return groups
+}
@@ -6,7 +6,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/go-redis/redis/v7"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
@@ -19,18 +18,19 @@ type AuthInterface interface {
 }
 
 type service struct {
-	client *redis.Client
+	sessions SessionStore
 }
 
 // var _ AuthInterface = &service{}
 
-func NewAuth(client *redis.Client) *service {
-	return &service{client: client}
+func NewAuth(sessions SessionStore) *service {
+	return &service{sessions: sessions}
 }
 
 type AccessDetails struct {
 	TokenUuid string
 	UserId    uint
+	OrgId     uint
 }
 
 type TokenDetails struct {
@@ -42,29 +42,24 @@ type TokenDetails struct {
 	RtExpires    int64
 }
 
-//Save token metadata to Redis
+//Save token metadata to the session store
 func (tk *service) CreateAuth(userId uint, td *TokenDetails) error {
 	at := time.Unix(td.AtExpires, 0) //converting Unix to UTC(to Time object)
 	rt := time.Unix(td.RtExpires, 0)
 	now := time.Now()
 
-	atCreated, err := tk.client.Set(td.TokenUuid, userId, at.Sub(now)).Result()
-	if err != nil {
+	if err := tk.sessions.Set(td.TokenUuid, userId, at.Sub(now)); err != nil {
 		return err
 	}
-	rtCreated, err := tk.client.Set(td.RefreshUuid, userId, rt.Sub(now)).Result()
-	if err != nil {
+	if err := tk.sessions.Set(td.RefreshUuid, userId, rt.Sub(now)); err != nil {
 		return err
 	}
-	if atCreated == "0" || rtCreated == "0" {
-		return errors.New("no record inserted")
-	}
 	return nil
 }
 
 //Check the metadata saved
 func (tk *service) FetchAuth(tokenUuid string) (string, error) {
-	userid, err := tk.client.Get(tokenUuid).Result()
+	userid, err := tk.sessions.Get(tokenUuid)
 	if err != nil {
 		return "", err
 	}
@@ -76,13 +71,13 @@ func (tk *service) DeleteTokens(authD *AccessDetails) error {
 	//get the refresh uuid
 	refreshUuid := fmt.Sprintf("%s++%s", authD.TokenUuid, strconv.FormatUint(uint64(authD.UserId), 10))
 	//delete access token
-	deletedAt, err := tk.client.Del(authD.TokenUuid).Result()
+	deletedAt, err := tk.sessions.Del(authD.TokenUuid)
 	if err != nil {
 		log.Warning("Failed to delete access token")
 		return err
 	}
 	//delete refresh token
-	deletedRt, err := tk.client.Del(refreshUuid).Result()
+	deletedRt, err := tk.sessions.Del(refreshUuid)
 	if err != nil {
 		log.Warning("Failed to delete refresh token")
 		return err
@@ -97,15 +92,15 @@ func (tk *service) DeleteTokens(authD *AccessDetails) error {
 
 func (tk *service) DeleteRefresh(refreshUuid string) error {
 	//delete refresh token
-	deleted, err := tk.client.Del(refreshUuid).Result()
+	deleted, err := tk.sessions.Del(refreshUuid)
 	if err != nil || deleted == 0 {
 		return errors.New("Invalid refresh token")
 	}
 	// delete access token if any
 	atUUID := strings.Split(refreshUuid, "++")[0]
-	_, err = tk.client.Get(atUUID).Result()
-	if err != redis.Nil {
-		deletedAt, delErr := tk.client.Del(atUUID).Result()
+	_, err = tk.sessions.Get(atUUID)
+	if err != ErrSessionNotFound {
+		deletedAt, delErr := tk.sessions.Del(atUUID)
 		if delErr != nil || deletedAt == 0 {
 			return errors.New("Failed to delete access token")
 		}
@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v7"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// ErrSessionNotFound is returned for keys that were never set or have expired
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionStore keeps token uuids mapped to user IDs until they expire.
+// A ttl of zero or less never expires, as with redis SET.
+type SessionStore interface {
+	Set(key string, userId uint, ttl time.Duration) error
+	Get(key string) (string, error)
+	// Del returns the number of keys removed
+	Del(key string) (int64, error)
+}
+
+type redisSessions struct {
+	client *redis.Client
+}
+
+func NewRedisSessions(client *redis.Client) SessionStore {
+	return &redisSessions{client: client}
+}
+
+func (s *redisSessions) Set(key string, userId uint, ttl time.Duration) error {
+	return s.client.Set(key, userId, ttl).Err()
+}
+
+func (s *redisSessions) Get(key string) (string, error) {
+	val, err := s.client.Get(key).Result()
+	if err == redis.Nil {
+		return "", ErrSessionNotFound
+	}
+	return val, err
+}
+
+func (s *redisSessions) Del(key string) (int64, error) {
+	return s.client.Del(key).Result()
+}
+
+type memorySession struct {
+	userId  string
+	expires time.Time
+}
+
+func (m memorySession) expired(now time.Time) bool {
+	return !m.expires.IsZero() && !now.Before(m.expires)
+}
+
+type memorySessions struct {
+	mu       sync.Mutex
+	sessions map[string]memorySession
+}
+
+// NewMemorySessions keeps sessions in process, expired entries are swept every
+// interval
+func NewMemorySessions(interval time.Duration) SessionStore {
+	s := &memorySessions{sessions: make(map[string]memorySession)}
+	go s.sweep(interval)
+	return s
+}
+
+func (s *memorySessions) sweep(interval time.Duration) {
+	for now := range time.Tick(interval) {
+		s.mu.Lock()
+		for key, session := range s.sessions {
+			if session.expired(now) {
+				delete(s.sessions, key)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+func (s *memorySessions) Set(key string, userId uint, ttl time.Duration) error {
+	session := memorySession{userId: strconv.FormatUint(uint64(userId), 10)}
+	if ttl > 0 {
+		session.expires = time.Now().Add(ttl)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[key] = session
+	return nil
+}
+
+func (s *memorySessions) Get(key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[key]
+	if !ok || session.expired(time.Now()) {
+		return "", ErrSessionNotFound
+	}
+	return session.userId, nil
+}
+
+func (s *memorySessions) Del(key string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[key]
+	if !ok {
+		return 0, nil
+	}
+	delete(s.sessions, key)
+	if session.expired(time.Now()) {
+		return 0, nil
+	}
+	return 1, nil
+}
+
+// Session is a row of the sessions table used by the sql session store
+type Session struct {
+	Token     string `gorm:"primary_key"`
+	UserID    uint   `gorm:"not null"`
+	ExpiresAt *time.Time
+}
+
+type sqlSessions struct {
+	db *gorm.DB
+}
+
+// NewSQLSessions stores sessions in a table of the main database, the table is
+// created by the database migrations
+func NewSQLSessions(db *gorm.DB) SessionStore {
+	return &sqlSessions{db: db}
+}
+
+// live restricts a query to sessions that have not expired
+func (s *sqlSessions) live() *gorm.DB {
+	return s.db.Model(&Session{}).Where("expires_at IS NULL OR expires_at > ?", time.Now())
+}
+
+func (s *sqlSessions) Set(key string, userId uint, ttl time.Duration) error {
+	session := Session{Token: key, UserID: userId}
+	if ttl > 0 {
+		expires := time.Now().Add(ttl)
+		session.ExpiresAt = &expires
+	}
+
+	// clear out whatever has expired since the last login
+	if err := s.db.Where("expires_at <= ?", time.Now()).Delete(&Session{}).Error; err != nil {
+		return err
+	}
+	return s.db.Save(&session).Error
+}
+
+func (s *sqlSessions) Get(key string) (string, error) {
+	var session Session
+	err := s.live().Where("token = ?", key).First(&session).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return "", ErrSessionNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatUint(uint64(session.UserID), 10), nil
+}
+
+func (s *sqlSessions) Del(key string) (int64, error) {
+	var count int64
+	if err := s.live().Where("token = ?", key).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	if err := s.db.Where("token = ?", key).Delete(&Session{}).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+)
+
+// eachSessions runs test against a fresh memory and sql session store
+func eachSessions(t *testing.T, test func(t *testing.T, s SessionStore)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewMemorySessions(time.Minute))
+	})
+	t.Run("sql", func(t *testing.T) {
+		db, err := gorm.Open("sqlite3", ":memory:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		// every new connection to :memory: would get its own empty database
+		db.DB().SetMaxOpenConns(1)
+		if err := db.AutoMigrate(&Session{}).Error; err != nil {
+			t.Fatal(err)
+		}
+		test(t, NewSQLSessions(db))
+	})
+}
+
+func wantSession(t *testing.T, s SessionStore, key, want string) {
+	t.Helper()
+	got, err := s.Get(key)
+	if want == "" {
+		if err != ErrSessionNotFound {
+			t.Errorf("Get(%q): got %q, %v, want %v", key, got, err, ErrSessionNotFound)
+		}
+		return
+	}
+	if err != nil || got != want {
+		t.Errorf("Get(%q): got %q, %v, want %q", key, got, err, want)
+	}
+}
+
+func TestSessions(t *testing.T) {
+	eachSessions(t, func(t *testing.T, s SessionStore) {
+		wantSession(t, s, "missing", "")
+
+		if err := s.Set("access", 7, time.Hour); err != nil {
+			t.Fatal(err)
+		}
+		wantSession(t, s, "access", "7")
+
+		// setting a key again replaces it
+		if err := s.Set("access", 8, time.Hour); err != nil {
+			t.Fatal(err)
+		}
+		wantSession(t, s, "access", "8")
+
+		// a ttl of zero never expires
+		if err := s.Set("forever", 9, 0); err != nil {
+			t.Fatal(err)
+		}
+		wantSession(t, s, "forever", "9")
+	})
+}
+
+func TestSessionsExpire(t *testing.T) {
+	eachSessions(t, func(t *testing.T, s SessionStore) {
+		if err := s.Set("short", 7, 50*time.Millisecond); err != nil {
+			t.Fatal(err)
+		}
+		wantSession(t, s, "short", "7")
+
+		time.Sleep(100 * time.Millisecond)
+		wantSession(t, s, "short", "")
+		if n, err := s.Del("short"); err != nil || n != 0 {
+			t.Errorf("Del of an expired session: got %d, %v, want 0", n, err)
+		}
+	})
+}
+
+func TestSessionsRevoke(t *testing.T) {
+	eachSessions(t, func(t *testing.T, s SessionStore) {
+		if err := s.Set("refresh", 7, time.Hour); err != nil {
+			t.Fatal(err)
+		}
+		if n, err := s.Del("refresh"); err != nil || n != 1 {
+			t.Errorf("Del: got %d, %v, want 1", n, err)
+		}
+		wantSession(t, s, "refresh", "")
+		if n, err := s.Del("refresh"); err != nil || n != 0 {
+			t.Errorf("Del again: got %d, %v, want 0", n, err)
+		}
+	})
+}
@@ -20,12 +20,13 @@ func NewToken() *tokenService {
 }
 
 type TokenInterface interface {
-	CreateToken(userId uint) (*TokenDetails, error)
+	// CreateToken signs tokens for userId acting in organization orgId
+	CreateToken(userId, orgId uint) (*TokenDetails, error)
 	GetTokenMetadata(*http.Request) (*AccessDetails, error)
 	// ExtractTokenMetadata(*http.Request) (*AccessDetails, error)
 }
 
-func (t *tokenService) CreateToken(userId uint) (*TokenDetails, error) {
+func (t *tokenService) CreateToken(userId, orgId uint) (*TokenDetails, error) {
 	conf, err := util.GetConfig()
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to Read config file")
@@ -39,6 +40,7 @@ func (t *tokenService) CreateToken(userId uint) (*TokenDetails, error) {
 	atClaims["access_uuid"] = td.TokenUuid
 	// atClaims["user_id"] = strconv.FormatUint(uint64(userId), 10)
 	atClaims["user_id"] = userId
+	atClaims["org_id"] = orgId
 	atClaims["exp"] = td.AtExpires
 
 	at := jwt.NewWithClaims(jwt.SigningMethodHS256, atClaims)
@@ -56,6 +58,7 @@ func (t *tokenService) CreateToken(userId uint) (*TokenDetails, error) {
 	rtClaims["refresh_uuid"] = td.RefreshUuid
 	// rtClaims["user_id"] = strconv.FormatUint(uint64(userId), 10)
 	rtClaims["user_id"] = userId
+	rtClaims["org_id"] = orgId
 	rtClaims["exp"] = td.RtExpires
 	rt := jwt.NewWithClaims(jwt.SigningMethodHS256, rtClaims)
 
@@ -127,9 +130,13 @@ func (t *tokenService) GetTokenMetadata(r *http.Request) (*AccessDetails, error)
 			return nil, errors.New("unauthorized")
 		}
 
+		// tokens signed before organizations existed have none
+		orgId, _ := claims["org_id"].(float64)
+
 		return &AccessDetails{
 			TokenUuid: accessUUID,
 			UserId:    uintUserId,
+			OrgId:     uint(orgId),
 		}, nil
 	}
 	return nil, errors.New("Error extracting token data")
@@ -0,0 +1,20 @@
+package blob
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNotFound is returned for keys that were never stored or are deleted
+var ErrNotFound = errors.New("blob not found")
+
+// Store keeps file contents under opaque keys, metadata lives in the
+// database. Keys are slash separated paths built by the caller.
+type Store interface {
+	// Put writes everything r returns under key and reports the size
+	Put(key string, r io.Reader) (int64, error)
+	Get(key string) (io.ReadCloser, error)
+	// Delete is a no-op for missing keys
+	Delete(key string) error
+}
@@ -0,0 +1,74 @@
+package blob
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalKeys(t *testing.T) {
+	root, err := ioutil.TempDir("", "blobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	dir := filepath.Join(root, "store")
+	s, err := NewLocal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// keys that would reach outside dir are refused by every method
+	for _, key := range []string{"", "/", "..", "../outside", "tasks/../../outside", "tasks/..", "/../outside"} {
+		if _, err := s.Put(key, bytes.NewBufferString("x")); err == nil {
+			t.Errorf("Put(%q) was accepted", key)
+		}
+		if r, err := s.Get(key); err == nil || err == ErrNotFound {
+			if r != nil {
+				r.Close()
+			}
+			t.Errorf("Get(%q): got %v, want an invalid key error", key, err)
+		}
+		if err := s.Delete(key); err == nil {
+			t.Errorf("Delete(%q) was accepted", key)
+		}
+	}
+	entries, err := ioutil.ReadDir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("files were written next to the store: %d entries", len(entries))
+	}
+
+	// a leading slash stays below dir
+	for _, key := range []string{"tasks/1/file", "/tasks/1/other"} {
+		if n, err := s.Put(key, bytes.NewBufferString("hello")); err != nil || n != 5 {
+			t.Fatalf("Put(%q): got %d, %v", key, n, err)
+		}
+		r, err := s.Get(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil || string(b) != "hello" {
+			t.Errorf("Get(%q): got %q, %v", key, b, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "tasks", "1", "other")); err != nil {
+		t.Errorf("a key with a leading slash isn't below the store: %v", err)
+	}
+
+	if err := s.Delete("tasks/1/file"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get("tasks/1/file"); err != ErrNotFound {
+		t.Errorf("Get after Delete: got %v, want %v", err, ErrNotFound)
+	}
+	if err := s.Delete("tasks/1/file"); err != nil {
+		t.Errorf("deleting a missing key: %v", err)
+	}
+}
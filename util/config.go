@@ -1,20 +1,42 @@
 package util
 
 import (
+	"time"
+
 	"github.com/kelseyhightower/envconfig"
 	"github.com/pkg/errors"
 )
 
 type EnvVariables struct {
+	DatabaseType  string
+	FileDBPath    string
+	SQLiteDBName  string `default:"todo.db"`
+	BoltDBName    string `default:"todo.bolt"`
 	PGUsername    string
 	PGPassword    string
 	PGHost        string
 	PGName        string
 	AccessSecret  string
+	SessionStore  string `default:"redis"`
 	RedisPort     string
 	RedisHost     string
 	RedisPassword string
 	RefreshSecret string
+	// BlobStore keeps attachments, only "local" for now, in BlobPath or
+	// an attachments directory next to the file databases
+	BlobStore string `default:"local"`
+	BlobPath  string
+	// MaxUploadSize is in bytes, UploadTypes are the accepted MIME types
+	MaxUploadSize int64    `default:"10485760"`
+	UploadTypes   []string `default:"image/png,image/jpeg,image/gif,image/webp,application/pdf,text/plain"`
+	// TransferTimeout bounds reading a request and writing its response,
+	// uploads and downloads of MaxUploadSize have to fit in it
+	TransferTimeout time.Duration `default:"5m"`
+	// TrashRetention is how long deleted tasks can be restored before they
+	// are purged, 0 keeps them until they are purged by hand
+	TrashRetention time.Duration `default:"720h"`
+	// AutoArchiveDays archives tasks completed that many days ago, 0 never does
+	AutoArchiveDays int
 }
 
 func GetConfig() (EnvVariables, error) {